@@ -0,0 +1,410 @@
+package analysis
+
+import (
+	"fmt"
+	"monkey/ast"
+	"strings"
+)
+
+// The different kinds of problems the analyzer reports
+const (
+	UNREACHABLE     = "unreachable"
+	CONSTANT_BRANCH = "constant-condition"
+	MISSING_RETURN  = "missing-return"
+	SHADOWING       = "shadowing"
+)
+
+// A single non-fatal problem found while analyzing a program
+type Diagnostic struct {
+	Kind    string
+	Message string
+	Node    ast.Node // the node the diagnostic refers to
+	Related ast.Node // another node involved, if any (ex: the binding that's shadowed)
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s", d.Kind, d.Message)
+}
+
+// Enables the optional checks, the zero value only runs the default ones
+type Options struct {
+	// report let bindings and parameters that shadow a binding of an outer scope,
+	// off by default since shadowing is often intentional
+	Shadowing bool
+}
+
+type Analyzer struct {
+	diagnostics []Diagnostic
+	opts        Options
+	// names bound in each enclosing scope (global scope first), functions and loops create new scopes
+	scopes []map[string]*ast.Identifier
+}
+
+/**
+Walks the whole program (including function bodies) and reports:
+- statements that can never run because they come after a return
+- branches that can never run because their condition is a constant
+- functions that explicitly return a value on some paths but fall off the end on others
+**/
+func Analyze(program *ast.Program) []Diagnostic {
+	return AnalyzeWithOptions(program, Options{})
+}
+
+// Same as Analyze, also running the optional checks enabled in opts
+func AnalyzeWithOptions(program *ast.Program, opts Options) []Diagnostic {
+	a := &Analyzer{diagnostics: []Diagnostic{}, opts: opts}
+	a.pushScope()
+	a.analyzeStatements(program.Statements)
+	return a.diagnostics
+}
+
+func (a *Analyzer) report(kind string, node ast.Node, format string, args ...interface{}) {
+	a.diagnostics = append(a.diagnostics, Diagnostic{Kind: kind, Message: fmt.Sprintf(format, args...), Node: node})
+}
+
+func (a *Analyzer) analyzeStatements(stmts []ast.Statement) {
+	for idx, stmt := range stmts {
+		a.analyzeStatement(stmt)
+
+		// anything after a statement that always returns will never run
+		if alwaysReturns(stmt) && idx < len(stmts)-1 {
+			a.report(UNREACHABLE, stmts[idx+1], "unreachable code after return: %s", stmts[idx+1].String())
+			// keep analyzing the remaining statements, but only report them once
+			for _, rest := range stmts[idx+1:] {
+				a.analyzeStatement(rest)
+			}
+			return
+		}
+	}
+}
+
+func (a *Analyzer) analyzeStatement(stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		// declared first, the value can already refer to it (ex: recursive functions)
+		a.declare(stmt.Name, "let")
+		a.analyzeExpression(stmt.Value)
+
+	case *ast.ReturnStatement:
+		a.analyzeExpression(stmt.ReturnValue)
+
+	case *ast.YieldStatement:
+		a.analyzeExpression(stmt.Value)
+
+	case *ast.ExpressionStatement:
+		a.analyzeExpression(stmt.Expression)
+
+	case *ast.BlockStatement:
+		a.analyzeStatements(stmt.Statements)
+
+	case *ast.ForLoopStatement:
+		if value, ok := constantTruthiness(stmt.LoopCondition); ok && !value {
+			a.report(CONSTANT_BRANCH, stmt, "loop condition %s is always false, the loop body never runs", stmt.LoopCondition.String())
+		}
+		// every iteration has its own scope, the counter and the lets in the body live in it
+		a.pushScope()
+		a.analyzeStatement(stmt.CounterVar)
+		a.analyzeStatements(stmt.LoopBlock.Statements)
+		a.popScope()
+
+	case *ast.ForInStatement:
+		a.analyzeExpression(stmt.Iterable)
+		a.pushScope()
+		a.declare(stmt.Variable, "loop variable")
+		a.analyzeStatements(stmt.Body.Statements)
+		a.popScope()
+	}
+}
+
+func (a *Analyzer) analyzeExpression(exp ast.Expression) {
+	switch exp := exp.(type) {
+	case *ast.IfExpression:
+		if value, ok := constantTruthiness(exp.Condition); ok {
+			if !value {
+				a.report(CONSTANT_BRANCH, exp.Consequence, "condition %s is always false, the consequence never runs", exp.Condition.String())
+			} else if exp.Alternative != nil {
+				a.report(CONSTANT_BRANCH, exp.Alternative, "condition %s is always true, the else branch never runs", exp.Condition.String())
+			}
+		}
+
+		a.analyzeExpression(exp.Condition)
+		a.analyzeStatements(exp.Consequence.Statements)
+
+		if exp.Alternative != nil {
+			a.analyzeStatements(exp.Alternative.Statements)
+		}
+
+	case *ast.MatchExpression:
+		a.analyzeExpression(exp.Subject)
+
+		for idx, arm := range exp.Arms {
+			// _ matches anything, the arms after it can't
+			if arm.IsWildcard() && idx < len(exp.Arms)-1 {
+				a.report(UNREACHABLE, exp.Arms[idx+1], "unreachable match arm after _: %s", exp.Arms[idx+1].String())
+			}
+
+			a.analyzeExpression(arm.Pattern)
+			a.analyzeStatements(arm.Body.Statements)
+		}
+
+	case *ast.FunctionLiteral:
+		a.analyzeFunctionLiteral(exp)
+
+	case *ast.PrefixExpression:
+		a.analyzeExpression(exp.Right)
+
+	case *ast.PostfixExpression:
+		a.analyzeExpression(exp.Left)
+
+	case *ast.InterpolatedString:
+		a.analyzeExpressions(exp.Parts)
+
+	case *ast.InfixExpression:
+		a.analyzeExpression(exp.Left)
+		a.analyzeExpression(exp.Right)
+
+	case *ast.CallExpression:
+		a.analyzeExpression(exp.Function)
+		a.analyzeExpressions(exp.Arguments)
+
+	case *ast.InternalFunctionCall:
+		a.analyzeExpression(exp.Caller)
+		a.analyzeExpressions(exp.Arguments)
+
+	case *ast.ArrayLiteral:
+		a.analyzeExpressions(exp.Elements)
+
+	case *ast.HashLiteral:
+		for _, key := range exp.OrderedKeys() {
+			a.analyzeExpression(key)
+			a.analyzeExpression(exp.Pairs[key])
+		}
+
+	case *ast.IndexExpression:
+		a.analyzeExpression(exp.Left)
+		a.analyzeExpression(exp.Index)
+
+	case *ast.IndexAssignment:
+		a.analyzeExpression(exp.Left)
+		a.analyzeExpression(exp.Index)
+		a.analyzeExpression(exp.Value)
+
+	case *ast.AssignmentExpression:
+		a.analyzeExpression(exp.Value)
+	}
+}
+
+func (a *Analyzer) analyzeExpressions(exps []ast.Expression) {
+	for _, exp := range exps {
+		a.analyzeExpression(exp)
+	}
+}
+
+func (a *Analyzer) analyzeFunctionLiteral(fn *ast.FunctionLiteral) {
+	a.pushScope()
+	for _, param := range fn.Parameters {
+		a.declare(param, "parameter")
+	}
+	a.analyzeStatements(fn.Body.Statements)
+	a.popScope()
+
+	/**
+	Functions implicitly return the value of their last expression,
+	so we only complain about functions that clearly expect to return something
+	(they use an explicit return or declare a return type) but have paths that don't.
+	**/
+	if fn.ReturnType == nil && !containsReturn(fn.Body) {
+		return
+	}
+
+	if !blockReturns(fn.Body) {
+		a.report(MISSING_RETURN, fn, "not all paths in %s return a value", signature(fn))
+	}
+}
+
+func (a *Analyzer) pushScope() {
+	a.scopes = append(a.scopes, map[string]*ast.Identifier{})
+}
+
+func (a *Analyzer) popScope() {
+	a.scopes = a.scopes[:len(a.scopes)-1]
+}
+
+// Binds the name in the current scope, reporting it when it shadows the binding of an outer scope
+func (a *Analyzer) declare(ident *ast.Identifier, kind string) {
+	current := a.scopes[len(a.scopes)-1]
+
+	// binding the same name again in the same scope reassigns it, it isn't shadowing
+	if _, exists := current[ident.Value]; exists {
+		return
+	}
+	current[ident.Value] = ident
+
+	if !a.opts.Shadowing {
+		return
+	}
+
+	for idx := len(a.scopes) - 2; idx >= 0; idx-- {
+		if outer, ok := a.scopes[idx][ident.Value]; ok {
+			a.diagnostics = append(a.diagnostics, Diagnostic{
+				Kind:    SHADOWING,
+				Message: fmt.Sprintf("%s %s at %s shadows %s declared at %s", kind, ident.Value, position(ident), outer.Value, position(outer)),
+				Node:    ident,
+				Related: outer,
+			})
+			return
+		}
+	}
+}
+
+// line:column of the identifier
+func position(ident *ast.Identifier) string {
+	return fmt.Sprintf("%d:%d", ident.Token.Line, ident.Token.Column)
+}
+
+// fn(x, y), used to refer to a function without printing its whole body
+func signature(fn *ast.FunctionLiteral) string {
+	params := []string{}
+	for _, p := range fn.Parameters {
+		params = append(params, p.String())
+	}
+	return fn.TokenLiteral() + "(" + strings.Join(params, ", ") + ")"
+}
+
+// Whether the statement unconditionally stops the evaluation of its block
+func alwaysReturns(stmt ast.Statement) bool {
+	switch stmt := stmt.(type) {
+	case *ast.ReturnStatement:
+		return true
+	case *ast.BlockStatement:
+		for _, s := range stmt.Statements {
+			if alwaysReturns(s) {
+				return true
+			}
+		}
+	case *ast.ExpressionStatement:
+		ifExp, ok := stmt.Expression.(*ast.IfExpression)
+		if !ok || ifExp.Alternative == nil {
+			return false
+		}
+		return alwaysReturns(ifExp.Consequence) && alwaysReturns(ifExp.Alternative)
+	}
+
+	return false
+}
+
+/**
+Whether every path through the block produces a value, either by:
+- an explicit return
+- ending in an expression (implicit return of the last expression)
+**/
+func blockReturns(block *ast.BlockStatement) bool {
+	if alwaysReturns(block) {
+		return true
+	}
+
+	if len(block.Statements) == 0 {
+		return false
+	}
+
+	last, ok := block.Statements[len(block.Statements)-1].(*ast.ExpressionStatement)
+	if !ok || last.Expression == nil {
+		return false
+	}
+
+	// an if without an else doesn't produce a value when its condition is false
+	if ifExp, ok := last.Expression.(*ast.IfExpression); ok {
+		if ifExp.Alternative == nil {
+			return false
+		}
+		return blockReturns(ifExp.Consequence) && blockReturns(ifExp.Alternative)
+	}
+
+	// assignments evaluate to nothing
+	if _, ok := last.Expression.(*ast.AssignmentExpression); ok {
+		return false
+	}
+
+	return true
+}
+
+// Whether the block contains a return statement that belongs to the current function
+func containsReturn(block *ast.BlockStatement) bool {
+	for _, stmt := range block.Statements {
+		switch stmt := stmt.(type) {
+		case *ast.ReturnStatement:
+			return true
+		case *ast.BlockStatement:
+			if containsReturn(stmt) {
+				return true
+			}
+		case *ast.ForLoopStatement:
+			if containsReturn(stmt.LoopBlock) {
+				return true
+			}
+		case *ast.ForInStatement:
+			if containsReturn(stmt.Body) {
+				return true
+			}
+		case *ast.ExpressionStatement:
+			ifExp, ok := stmt.Expression.(*ast.IfExpression)
+			if !ok {
+				continue
+			}
+			if containsReturn(ifExp.Consequence) || (ifExp.Alternative != nil && containsReturn(ifExp.Alternative)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+/**
+Returns the truthiness of an expression if it can be known without evaluating the program.
+ex: true, !false, 1 > 2, "hello" (strings and integers are always truthy)
+**/
+func constantTruthiness(exp ast.Expression) (bool, bool) {
+	switch exp := exp.(type) {
+	case *ast.Boolean:
+		return exp.Value, true
+	case *ast.NullLiteral:
+		return false, true
+	case *ast.IntegerLiteral, *ast.CharLiteral, *ast.FloatLiteral, *ast.StringLiteral:
+		return true, true
+	case *ast.PrefixExpression:
+		if exp.Operator != "!" {
+			return false, false
+		}
+		value, ok := constantTruthiness(exp.Right)
+		return !value, ok
+	case *ast.InfixExpression:
+		left, lok := exp.Left.(*ast.IntegerLiteral)
+		right, rok := exp.Right.(*ast.IntegerLiteral)
+		if !lok || !rok {
+			return false, false
+		}
+
+		switch exp.Operator {
+		case "<":
+			return left.Value < right.Value, true
+		case ">":
+			return left.Value > right.Value, true
+		case "==":
+			return left.Value == right.Value, true
+		case "!=":
+			return left.Value != right.Value, true
+		}
+	}
+
+	return false, false
+}
+
+/**
+Dev notes:
+
+- The analyzer never evaluates anything, it only looks at the shape of the AST.
+- Constant conditions are limited to literals (and comparisons between integer literals),
+  anything involving identifiers could change at runtime.
+- Diagnostics are not errors, a program with diagnostics can still be evaluated.
+**/
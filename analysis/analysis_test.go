@@ -0,0 +1,103 @@
+package analysis
+
+import (
+	"monkey/lexer"
+	"monkey/parser"
+	"strings"
+	"testing"
+)
+
+func testAnalyze(t *testing.T, input string) []Diagnostic {
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser has errors: %v", p.Errors())
+	}
+
+	return Analyze(program)
+}
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedKinds []string
+	}{
+		{"let x = 5; x + 1;", []string{}},
+		{"fn(x) { return x; x + 1; }", []string{UNREACHABLE}},
+		{"fn(x) { if (x) { return 1; } else { return 2; }; puts(x); }", []string{UNREACHABLE}},
+		{"if (false) { 1 }", []string{CONSTANT_BRANCH}},
+		{"if (1 > 2) { 1 } else { 2 }", []string{CONSTANT_BRANCH}},
+		{"if (!false) { 1 } else { 2 }", []string{CONSTANT_BRANCH}},
+		{"if (true) { 1 }", []string{}},
+		// implicit returns are fine
+		{"fn(x) { x * 2 }", []string{}},
+		{"fn(x) { if (x > 1) { return 1; }; 2 }", []string{}},
+		{"fn(x) { if (x > 1) { return 1; } }", []string{MISSING_RETURN}},
+		{"fn(x) -> int { let y = x; }", []string{MISSING_RETURN}},
+		{"fn(x) { if (x > 1) { return 1; } else { 2 } }", []string{}},
+		// a nested function's return doesn't count for the outer one
+		{"fn() { let f = fn() { return 1; }; }", []string{}},
+		{`match (1) { 1 => "a", _ => "b" }`, []string{}},
+		{`match (1) { _ => "a", 1 => "b", 2 => "c" }`, []string{UNREACHABLE}},
+		{`fn(x) { match (x) { 1 => { return 1; 2 }, _ => if (false) { 3 } } }`, []string{UNREACHABLE, CONSTANT_BRANCH}},
+	}
+
+	for _, tt := range tests {
+		diagnostics := testAnalyze(t, tt.input)
+
+		if len(diagnostics) != len(tt.expectedKinds) {
+			t.Errorf("%q: expected %d diagnostics, got %d: %v", tt.input, len(tt.expectedKinds), len(diagnostics), diagnostics)
+			continue
+		}
+
+		for idx, kind := range tt.expectedKinds {
+			if diagnostics[idx].Kind != kind {
+				t.Errorf("%q: expected diagnostic of kind %q, got %q", tt.input, kind, diagnostics[idx].Kind)
+			}
+		}
+	}
+}
+
+func TestShadowing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 1; let f = fn() { let x = 2; x };", []string{"shadowing: let x at 1:31 shadows x declared at 1:5"}},
+		{"let x = 1;\nlet f = fn(x) { x };", []string{"shadowing: parameter x at 2:12 shadows x declared at 1:5"}},
+		{"let f = fn(x) { fn() { let x = 1; x } };", []string{"shadowing: let x at 1:28 shadows x declared at 1:12"}},
+		// reassigning in the same scope isn't shadowing
+		{"let x = 1; let x = 2; if (true) { let x = 3; }", []string{}},
+		{"let f = fn(x) { x }; let g = fn(x) { x };", []string{}},
+		// every loop iteration has its own scope
+		{"let x = 1; for (x in [1]) { x }", []string{"shadowing: loop variable x at 1:17 shadows x declared at 1:5"}},
+		{"let i = 1; for (let i = 0; i < 3; i++) { let j = i; }", []string{"shadowing: let i at 1:21 shadows i declared at 1:5"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser has errors: %v", p.Errors())
+		}
+
+		diagnostics := AnalyzeWithOptions(program, Options{Shadowing: true})
+		messages := []string{}
+		for _, diagnostic := range diagnostics {
+			messages = append(messages, diagnostic.String())
+		}
+
+		if strings.Join(messages, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("%q: expected %v, got %v", tt.input, tt.expected, messages)
+		}
+
+		// off by default
+		if len(tt.expected) != 0 && len(Analyze(program)) != 0 {
+			t.Errorf("%q: shadowing should only be reported when enabled", tt.input)
+		}
+	}
+}
@@ -3,7 +3,11 @@ package ast
 import (
 	"bytes"
 	"monkey/token"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"sync/atomic"
 )
 
 // Node Interface for our AST
@@ -53,9 +57,10 @@ func (p *Program) String() string {
 
 // Implements Statement and Node interface
 type LetStatement struct {
-	Token token.Token // the token.LET token
-	Name  *Identifier //identifier for the binding (ex: x in let x = 5)
-	Value Expression  //expression that produces the value (the 5 in let x = 5)
+	Token    token.Token // the token.LET (or token.CONST) token
+	Name     *Identifier //identifier for the binding (ex: x in let x = 5)
+	Value    Expression  //expression that produces the value (the 5 in let x = 5)
+	Constant bool        // declared with const, the binding can't be reassigned
 }
 
 func (ls *LetStatement) statementNode()       {}
@@ -65,6 +70,11 @@ func (ls *LetStatement) String() string {
 
 	out.WriteString(ls.TokenLiteral() + " ")
 	out.WriteString(ls.Name.String())
+
+	if ls.Name.Type != nil {
+		out.WriteString(": " + ls.Name.Type.String())
+	}
+
 	out.WriteString(" = ")
 
 	if ls.Value != nil {
@@ -88,14 +98,54 @@ note:
 
 // ex: the x in let x = 5
 type Identifier struct {
-	Token token.Token // the token.IDENT token
-	Value string
+	// where the evaluator last found the binding, see Resolution()
+	// (first so it's 64-bit aligned for the atomic operations on 32-bit platforms)
+	resolution uint64
+	Token      token.Token // the token.IDENT token
+	Value      string
+	Type       *TypeAnnotation // optional, only set on bindings: let x: int, fn(x: int)
 }
 
 func (i *Identifier) expressionNode()      {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
 func (i *Identifier) String() string       { return i.Value }
 
+/**
+Returns the scope depth and slot where the evaluator last found this identifier's binding
+(see object.Environment.Resolve), ok is false if it hasn't been evaluated yet.
+
+note: it's only a hint, the same node can be evaluated in different environments.
+Atomic so programs shared between goroutines (ex: through a parse cache) can be evaluated concurrently.
+**/
+func (i *Identifier) Resolution() (depth, slot int, ok bool) {
+	packed := atomic.LoadUint64(&i.resolution)
+	if packed == 0 {
+		return 0, 0, false
+	}
+
+	return int(packed>>32) - 1, int(uint32(packed)), true
+}
+
+// Remembers where the binding was found, see Resolution()
+func (i *Identifier) SetResolution(depth, slot int) {
+	atomic.StoreUint64(&i.resolution, uint64(depth+1)<<32|uint64(uint32(slot)))
+}
+
+/**
+An optional type annotation: the int in let x: int = 5 or fn(x: int) -> int { x }
+
+note:
+- annotations are ignored by the evaluator.
+- they're only used by the types package for best-effort static checking.
+**/
+type TypeAnnotation struct {
+	Token token.Token // the type name token: int, string, etc
+	Name  string
+}
+
+func (ta *TypeAnnotation) TokenLiteral() string { return ta.Token.Literal }
+func (ta *TypeAnnotation) String() string       { return ta.Name }
+
 type ReturnStatement struct {
 	Token       token.Token //the 'return' token
 	ReturnValue Expression
@@ -109,7 +159,7 @@ func (rs *ReturnStatement) String() string {
 	out.WriteString(rs.TokenLiteral() + " ")
 
 	if rs.ReturnValue != nil {
-		out.WriteString(rs.TokenLiteral() + " ")
+		out.WriteString(rs.ReturnValue.String())
 	}
 
 	out.WriteString(";")
@@ -117,6 +167,18 @@ func (rs *ReturnStatement) String() string {
 	return out.String()
 }
 
+// yield <expression>; (only inside of generator functions: fn*() { yield 1; })
+type YieldStatement struct {
+	Token token.Token // the 'yield' token
+	Value Expression
+}
+
+func (ys *YieldStatement) statementNode()       {}
+func (ys *YieldStatement) TokenLiteral() string { return ys.Token.Literal }
+func (ys *YieldStatement) String() string {
+	return ys.TokenLiteral() + " " + ys.Value.String() + ";"
+}
+
 // A statement that consists of only one expression
 // ex: let x = 5;
 // the expression here being 5 (which generates a value)
@@ -144,6 +206,26 @@ func (il *IntegerLiteral) expressionNode()      {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
 
+// ex: 'a', evaluates to its code point (97)
+type CharLiteral struct {
+	Token token.Token
+	Value rune
+}
+
+func (cl *CharLiteral) expressionNode()      {}
+func (cl *CharLiteral) TokenLiteral() string { return cl.Token.Literal }
+func (cl *CharLiteral) String() string       { return strconv.QuoteRune(cl.Value) }
+
+// ex: 3.14
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode()      {}
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
+
 type PrefixExpression struct {
 	Token    token.Token //the prefix token: !, -
 	Operator string      // !, -
@@ -166,6 +248,25 @@ func (pe *PrefixExpression) String() string {
 	return out.String()
 }
 
+type PostfixExpression struct {
+	Token    token.Token // the postfix token: ++, --
+	Operator string      // ++, --
+	Left     Expression  // the binding being updated -> ex: i++, arr[0]--, etc.
+}
+
+func (pe *PostfixExpression) expressionNode()      {}
+func (pe *PostfixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PostfixExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(pe.Left.String())
+	out.WriteString(pe.Operator)
+	out.WriteString(")")
+
+	return out.String()
+}
+
 type InfixExpression struct {
 	Token    token.Token // the operator token: -, +, etc
 	Left     Expression
@@ -195,6 +296,15 @@ func (b *Boolean) expressionNode()      {}
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
 func (b *Boolean) String() string       { return b.Token.Literal }
 
+// null, the absence of a value
+type NullLiteral struct {
+	Token token.Token
+}
+
+func (n *NullLiteral) expressionNode()      {}
+func (n *NullLiteral) TokenLiteral() string { return n.Token.Literal }
+func (n *NullLiteral) String() string       { return n.Token.Literal }
+
 // if (condition) <consequence> else <alternative>
 type IfExpression struct {
 	Token       token.Token // the 'if' token
@@ -221,6 +331,55 @@ func (ie *IfExpression) String() string {
 	return out.String()
 }
 
+/**
+match (x) { 1 => "one", 2 => "two", _ => "other" }
+
+The arms are tried in order, the first one whose pattern is == to the subject is evaluated.
+_ matches anything. Arms can have a block as their body: 1 => { puts("one"); 1 }
+**/
+type MatchExpression struct {
+	Token   token.Token // the 'match' token
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+func (me *MatchExpression) expressionNode()      {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) String() string {
+	var out bytes.Buffer
+
+	arms := []string{}
+	for _, arm := range me.Arms {
+		arms = append(arms, arm.String())
+	}
+
+	out.WriteString("match")
+	out.WriteString(me.Subject.String())
+	out.WriteString(" {")
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+type MatchArm struct {
+	Token   token.Token // the first token of the pattern
+	Pattern Expression
+	// 1 => "one" is parsed as 1 => { "one" }
+	Body *BlockStatement
+}
+
+// Whether the arm matches anything: _ => ...
+func (ma *MatchArm) IsWildcard() bool {
+	ident, ok := ma.Pattern.(*Identifier)
+	return ok && ident.Value == "_"
+}
+
+func (ma *MatchArm) TokenLiteral() string { return ma.Token.Literal }
+func (ma *MatchArm) String() string {
+	return ma.Pattern.String() + " => " + ma.Body.String()
+}
+
 type BlockStatement struct {
 	Token      token.Token
 	Statements []Statement
@@ -258,7 +417,9 @@ outerFunc(a, b, fn(c, d) { return a > b; });
 type FunctionLiteral struct {
 	Token      token.Token
 	Parameters []*Identifier   // (x,y,z)
+	ReturnType *TypeAnnotation // optional: the int in fn(x) -> int { x }
 	Body       *BlockStatement // { x + y; }, { foo > bar; }
+	Generator  bool            // fn*() { yield 1; }
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
@@ -269,14 +430,25 @@ func (fl *FunctionLiteral) String() string {
 	params := []string{}
 	// fn(x,y,z)
 	for _, p := range fl.Parameters {
+		if p.Type != nil {
+			params = append(params, p.String()+": "+p.Type.String())
+			continue
+		}
 		params = append(params, p.String())
 	}
 
 	//fn(params)
 	out.WriteString(fl.TokenLiteral())
+	if fl.Generator {
+		out.WriteString("*")
+	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") ")
+
+	if fl.ReturnType != nil {
+		out.WriteString("-> " + fl.ReturnType.String() + " ")
+	}
 	out.WriteString(fl.Body.String())
 
 	return out.String()
@@ -328,6 +500,35 @@ func (sl *StringLiteral) expressionNode()      {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
 func (sl *StringLiteral) String() string       { return sl.Token.Literal }
 
+/**
+A string with ${expressions} in it: "hello ${name}, you are ${age + 1}"
+Parts alternate between the text (string literals, possibly empty) and the expressions:
+["hello ", name, ", you are ", (age + 1), ""]
+**/
+type InterpolatedString struct {
+	Token token.Token // the STRING_START token
+	Parts []Expression
+}
+
+func (is *InterpolatedString) expressionNode()      {}
+func (is *InterpolatedString) TokenLiteral() string { return is.Token.Literal }
+func (is *InterpolatedString) String() string {
+	var out bytes.Buffer
+
+	for _, part := range is.Parts {
+		if text, isText := part.(*StringLiteral); isText {
+			out.WriteString(text.Value)
+			continue
+		}
+
+		out.WriteString("${")
+		out.WriteString(part.String())
+		out.WriteString("}")
+	}
+
+	return out.String()
+}
+
 type ArrayLiteral struct {
 	Token    token.Token // the '[' token
 	Elements []Expression
@@ -352,9 +553,10 @@ func (al *ArrayLiteral) String() string {
 }
 
 type IndexExpression struct {
-	Token token.Token // the [ token
-	Left  Expression  // the object being access (array, some identifier, function call, etc)
-	Index Expression  // any expression as long as it produces an integer
+	Token    token.Token // the [ token (?[ for optional access)
+	Left     Expression  // the object being access (array, some identifier, function call, etc)
+	Index    Expression  // any expression as long as it produces an integer
+	Optional bool        // x?[0]: null instead of an error when x is null
 }
 
 func (ie *IndexExpression) expressionNode()      {}
@@ -363,6 +565,9 @@ func (ie *IndexExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
 	out.WriteString(ie.Left.String())
+	if ie.Optional {
+		out.WriteString("?")
+	}
 	out.WriteString("[")
 	out.WriteString(ie.Index.String())
 	out.WriteString("])")
@@ -398,6 +603,31 @@ type HashLiteral struct {
 	Pairs map[Expression]Expression
 }
 
+/**
+The keys in the order they're written in the source. Pairs is a map, ranging over it
+gives a different order every time, which evaluation and checkers reporting warnings shouldn't depend on.
+**/
+func (hl *HashLiteral) OrderedKeys() []Expression {
+	keys := make([]Expression, 0, len(hl.Pairs))
+	for key := range hl.Pairs {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return sourceStart(keys[i]) < sourceStart(keys[j])
+	})
+
+	return keys
+}
+
+// The byte offset of the node's token, nodes don't overlap so it orders them like the source
+func sourceStart(node Node) int {
+	if tok, ok := tokenOf(reflect.ValueOf(node)); ok {
+		return tok.Start
+	}
+	return 0
+}
+
 func (hl *HashLiteral) expressionNode()      {}
 func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
 func (hl *HashLiteral) String() string {
@@ -417,10 +647,11 @@ func (hl *HashLiteral) String() string {
 }
 
 type InternalFunctionCall struct {
-	Token              token.Token  // the '.' token
-	CallerIdentifier   *Identifier  //someArray, someHash, etc
+	Token              token.Token  // the '.' token ('?.' for optional calls)
+	Caller             Expression   //someArray, "a string", other.call(), etc
 	FunctionIdentifier *Identifier  // pop, delete, etc.
 	Arguments          []Expression //(1,2,3), (), etc.
+	Optional           bool         // x?.pop(): null instead of an error when x is null
 }
 
 func (ifc *InternalFunctionCall) expressionNode()      {}
@@ -434,7 +665,7 @@ func (ifc *InternalFunctionCall) String() string {
 		args = append(args, a.String())
 	}
 
-	out.WriteString(ifc.CallerIdentifier.String())   //someArray, someHash, etc
+	out.WriteString(ifc.Caller.String())             //someArray, someHash, etc
 	out.WriteString(ifc.Token.Literal)               // .
 	out.WriteString(ifc.FunctionIdentifier.String()) // delete, pop
 	out.WriteString("(")
@@ -495,3 +726,28 @@ func (fl *ForLoopStatement) String() string {
 	return out.String()
 
 }
+
+// for (<variable> in <iterable>) { <statements> };
+type ForInStatement struct {
+	Token    token.Token // the 'for' token
+	Variable *Identifier // bound to every element in turn (ex: x in for (x in arr))
+	Iterable Expression  // array, string, hash or generator
+	Body     *BlockStatement
+}
+
+func (fi *ForInStatement) statementNode()       {}
+func (fi *ForInStatement) TokenLiteral() string { return fi.Token.Literal }
+func (fi *ForInStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(fi.TokenLiteral())
+	out.WriteString("(")
+	out.WriteString(fi.Variable.String())
+	out.WriteString(" in ")
+	out.WriteString(fi.Iterable.String())
+	out.WriteString(")")
+	out.WriteString("{")
+	out.WriteString(fi.Body.String())
+	out.WriteString("};")
+
+	return out.String()
+}
@@ -34,3 +34,32 @@ func TestString(t *testing.T) {
 		t.Errorf("program.String() is wrong. got=%q", program.String())
 	}
 }
+
+func TestDump(t *testing.T) {
+	program := &Program{
+		Statements: []Statement{
+			&LetStatement{
+				Token: token.Token{Type: token.LET, Literal: "let", Line: 1, Column: 1},
+				Name:  &Identifier{Token: token.Token{Type: token.IDENT, Literal: "x", Line: 1, Column: 5}, Value: "x"},
+				Value: &InfixExpression{
+					Token:    token.Token{Type: token.PLUS, Literal: "+", Line: 1, Column: 11},
+					Operator: "+",
+					Left:     &IntegerLiteral{Token: token.Token{Type: token.INT, Literal: "1", Line: 1, Column: 9}, Value: 1},
+					Right:    &IntegerLiteral{Token: token.Token{Type: token.INT, Literal: "2", Line: 1, Column: 13}, Value: 2},
+				},
+			},
+		},
+	}
+
+	expected := `Program
+  Statements[0]: LetStatement "let" 1:1
+    Name: Identifier "x" 1:5
+    Value: InfixExpression "+" 1:11
+      Left: IntegerLiteral "1" 1:9
+      Right: IntegerLiteral "2" 1:13
+`
+
+	if got := Dump(program); got != expected {
+		t.Errorf("wrong dump, expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
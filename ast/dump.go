@@ -0,0 +1,126 @@
+package ast
+
+import (
+	"bytes"
+	"fmt"
+	"monkey/token"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+/**
+Renders the node and all of its children as an indented tree, one node per line:
+node type, the field it's stored in, its token literal and where that token is in the source.
+
+ex: 1 + 2 * 3
+ExpressionStatement "1" 1:1
+  Expression: InfixExpression "+" 1:3
+    Left: IntegerLiteral "1" 1:1
+    Right: InfixExpression "*" 1:7
+      Left: IntegerLiteral "2" 1:5
+      Right: IntegerLiteral "3" 1:9
+
+Children are found through reflection, so new node types show up without changes here.
+**/
+func Dump(node Node) string {
+	var out bytes.Buffer
+	dump(&out, node, "", 0)
+	return out.String()
+}
+
+var (
+	nodeType  = reflect.TypeOf((*Node)(nil)).Elem()
+	tokenType = reflect.TypeOf(token.Token{})
+)
+
+func dump(out *bytes.Buffer, node Node, label string, depth int) {
+	value := reflect.ValueOf(node)
+	if !value.IsValid() || (value.Kind() == reflect.Ptr && value.IsNil()) {
+		return
+	}
+
+	out.WriteString(strings.Repeat("  ", depth))
+	if label != "" {
+		out.WriteString(label + ": ")
+	}
+	out.WriteString(reflect.Indirect(value).Type().Name())
+
+	if tok, ok := tokenOf(value); ok {
+		fmt.Fprintf(out, " %q %d:%d", tok.Literal, tok.Line, tok.Column)
+	}
+	out.WriteString("\n")
+
+	value = reflect.Indirect(value)
+	if value.Kind() != reflect.Struct {
+		return
+	}
+
+	for idx := 0; idx < value.NumField(); idx++ {
+		field := value.Type().Field(idx)
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+
+		dumpField(out, field.Name, value.Field(idx), depth+1)
+	}
+}
+
+func dumpField(out *bytes.Buffer, name string, field reflect.Value, depth int) {
+	switch field.Kind() {
+	case reflect.Slice:
+		for idx := 0; idx < field.Len(); idx++ {
+			if child, ok := asNode(field.Index(idx)); ok {
+				dump(out, child, fmt.Sprintf("%s[%d]", name, idx), depth)
+			}
+		}
+
+	case reflect.Map:
+		// sorted so the output doesn't change between runs (ex: hash literal pairs)
+		keys := field.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+
+		for _, key := range keys {
+			if child, ok := asNode(key); ok {
+				dump(out, child, name+" key", depth)
+			}
+			if child, ok := asNode(field.MapIndex(key)); ok {
+				dump(out, child, name+" value", depth)
+			}
+		}
+
+	default:
+		if child, ok := asNode(field); ok {
+			dump(out, child, name, depth)
+		}
+	}
+}
+
+func asNode(value reflect.Value) (Node, bool) {
+	if !value.IsValid() || !value.Type().Implements(nodeType) && value.Kind() != reflect.Interface {
+		return nil, false
+	}
+
+	if (value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface) && value.IsNil() {
+		return nil, false
+	}
+
+	node, ok := value.Interface().(Node)
+	return node, ok
+}
+
+func tokenOf(value reflect.Value) (token.Token, bool) {
+	value = reflect.Indirect(value)
+	if value.Kind() != reflect.Struct {
+		return token.Token{}, false
+	}
+
+	field := value.FieldByName("Token")
+	if !field.IsValid() || field.Type() != tokenType {
+		return token.Token{}, false
+	}
+
+	return field.Interface().(token.Token), true
+}
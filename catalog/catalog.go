@@ -0,0 +1,206 @@
+package catalog
+
+import (
+	"fmt"
+	"sync"
+)
+
+/**
+Stable identifier for every parser and runtime error.
+
+- E1xxx: parser errors
+- R2xxx: runtime (evaluator) errors
+- H3xxx: hints added to the message of an error, they aren't errors on their own
+- W4xxx: runtime warnings (object.Diagnostic), the script keeps running
+
+Codes never change once released, so tools can match on them instead of on the message text.
+**/
+type Code string
+
+// Parser errors
+const (
+	UNEXPECTED_TOKEN     Code = "E1001"
+	NO_PREFIX_PARSE_FN   Code = "E1002"
+	INVALID_INTEGER      Code = "E1003"
+	INVALID_FLOAT        Code = "E1004"
+	INVALID_ESCAPE       Code = "E1005"
+	ILLEGAL_CHARACTER    Code = "E1006"
+	INVALID_ASSIGNMENT   Code = "E1007"
+	INVALID_SEPARATOR    Code = "E1008"
+	FLOAT_OUT_OF_RANGE   Code = "E1009"
+	INT_OUT_OF_RANGE     Code = "E1010"
+	TOO_DEEPLY_NESTED    Code = "E1011"
+	INVALID_CHAR_LITERAL Code = "E1012"
+	UNTERMINATED_HEREDOC Code = "E1013"
+	LITERAL_TOO_LARGE    Code = "E1014"
+	AMBIGUOUS_POSTFIX    Code = "E1015"
+)
+
+// Runtime errors
+const (
+	UNKNOWN_PREFIX_OPERATOR  Code = "R2001"
+	UNKNOWN_INFIX_OPERATOR   Code = "R2002"
+	IDENTIFIER_NOT_FOUND     Code = "R2003"
+	TYPE_MISMATCH            Code = "R2004"
+	NOT_A_FUNCTION           Code = "R2005"
+	INDEX_NOT_SUPPORTED      Code = "R2006"
+	UNUSABLE_HASH_KEY        Code = "R2007"
+	INDEX_ASSIGNMENT         Code = "R2008"
+	INVALID_INDEX            Code = "R2009"
+	INVALID_LOOP_CONDITION   Code = "R2010"
+	WRONG_ARGUMENT_COUNT     Code = "R2011"
+	WRONG_ARGUMENT_TYPE      Code = "R2012"
+	ARGUMENT_NOT_SUPPORTED   Code = "R2013"
+	NEGATIVE_INDEX           Code = "R2014"
+	INVALID_CHARACTER        Code = "R2015"
+	INVALID_CODE_POINT       Code = "R2016"
+	YIELD_OUTSIDE_GENERATOR  Code = "R2017"
+	GENERATOR_RUNNING        Code = "R2018"
+	FILE_ERROR               Code = "R2019"
+	INVALID_PATTERN          Code = "R2020"
+	INVALID_NUMBER           Code = "R2021"
+	INVALID_BASE             Code = "R2022"
+	NEGATIVE_REPEAT          Code = "R2023"
+	MEMORY_LIMIT             Code = "R2024"
+	DIVISION_BY_ZERO         Code = "R2025"
+	UNKNOWN_MEMBER           Code = "R2026"
+	INVALID_FLAG             Code = "R2027"
+	NEGATIVE_SHIFT           Code = "R2028"
+	INVALID_CSV              Code = "R2029"
+	UNKNOWN_POSTFIX_OPERATOR Code = "R2030"
+	INVALID_TOML             Code = "R2031"
+	INVALID_YAML             Code = "R2032"
+	NOT_ITERABLE             Code = "R2033"
+	FUEL_LIMIT               Code = "R2034"
+	DEPTH_LIMIT              Code = "R2035"
+	CONSTANT_ASSIGNMENT      Code = "R2036"
+	INVALID_DELAY            Code = "R2037"
+	LOOP_RUNNING             Code = "R2038"
+	INTERNAL_ERROR           Code = "R2039"
+	INDEX_OUT_OF_RANGE       Code = "R2040"
+	INVALID_TIME             Code = "R2041"
+	NO_DOCUMENTATION         Code = "R2042"
+	INVALID_SIZE             Code = "R2043"
+	ASSIGNMENT_OUT_OF_RANGE  Code = "R2044"
+)
+
+// Hints
+const (
+	DID_YOU_MEAN Code = "H3001"
+)
+
+// Runtime warnings
+const (
+	INTEGER_DIVISION_TRUNCATED Code = "W4001"
+)
+
+// Default (english) message for every code, used as a fmt format string
+var English = map[Code]string{
+	UNEXPECTED_TOKEN:     "expected next token to be %s, got %s instead",
+	NO_PREFIX_PARSE_FN:   "no prefix parse function for %s found",
+	INVALID_INTEGER:      "could not parse %q as integer",
+	INVALID_FLOAT:        "could not parse %q as float",
+	INVALID_ESCAPE:       "invalid escape sequence %s in string",
+	ILLEGAL_CHARACTER:    "illegal character %q",
+	INVALID_ASSIGNMENT:   "cannot assign to %s",
+	INVALID_SEPARATOR:    "invalid number %s, underscores can only go between digits",
+	FLOAT_OUT_OF_RANGE:   "float %s is out of range",
+	INT_OUT_OF_RANGE:     "integer literal %s is out of range for int64 (%d to %d), consider using a float",
+	TOO_DEEPLY_NESTED:    "expression is nested too deeply (more than %d levels)",
+	INVALID_CHAR_LITERAL: "invalid character literal %s, expected a single character between single quotes",
+	UNTERMINATED_HEREDOC: "heredoc %s is never closed, expected %s on a line of its own",
+	LITERAL_TOO_LARGE:    "%s literal is too large (more than %d %s)",
+	AMBIGUOUS_POSTFIX:    "ambiguous %s followed by %s, separate them with a space (a - -1) or a ; (a--; 1)",
+
+	UNKNOWN_PREFIX_OPERATOR:  "unknown operator: %s%s",
+	UNKNOWN_INFIX_OPERATOR:   "unknown operator: %s %s %s",
+	IDENTIFIER_NOT_FOUND:     "identifier not found: %s",
+	TYPE_MISMATCH:            "type mismatch: %s %s %s",
+	NOT_A_FUNCTION:           "not a function: %s",
+	INDEX_NOT_SUPPORTED:      "index operator not supported: %s",
+	UNUSABLE_HASH_KEY:        "unusable as hash key: %s",
+	INDEX_ASSIGNMENT:         "index assignment not supported: %s, expected a type of HASH or ARRAY",
+	INVALID_INDEX:            "invalid index value passed, expected an INTEGER, got %s",
+	INVALID_LOOP_CONDITION:   "invalid loop condition, expected a BOOLEAN, got %s",
+	WRONG_ARGUMENT_COUNT:     "%s: expected %s, got %d",
+	WRONG_ARGUMENT_TYPE:      "%s: argument %d must be %s, got %s",
+	ARGUMENT_NOT_SUPPORTED:   "argument to `%s` not supported, got %s",
+	NEGATIVE_INDEX:           "negative indexes not supported (yet), recieved value of %d",
+	INVALID_CHARACTER:        "argument to `%s` must be a single character, got %q",
+	INVALID_CODE_POINT:       "argument to `%s` is not a valid code point, got %d",
+	YIELD_OUTSIDE_GENERATOR:  "yield outside of a generator function",
+	GENERATOR_RUNNING:        "generator is already running",
+	FILE_ERROR:               "`%s` failed: %s",
+	INVALID_PATTERN:          "invalid pattern passed to `%s`: %q",
+	INVALID_NUMBER:           "`%s` could not parse %q as a number",
+	INVALID_BASE:             "base passed to `%s` must be between %d and %d, got %d",
+	NEGATIVE_REPEAT:          "cannot repeat %s a negative number of times, got %d",
+	MEMORY_LIMIT:             "memory limit exceeded: repeating %s %d times needs more than %d bytes",
+	DIVISION_BY_ZERO:         "division by zero: %d %s %d",
+	UNKNOWN_MEMBER:           "module %s has no member %s",
+	INVALID_FLAG:             "`%s` failed: %s",
+	NEGATIVE_SHIFT:           "negative shift count: %d %s %d",
+	INVALID_CSV:              "`%s` failed: %s",
+	UNKNOWN_POSTFIX_OPERATOR: "unknown operator: %s%s",
+	INVALID_TOML:             "`%s` failed: %s",
+	INVALID_YAML:             "`%s` failed: %s",
+	NOT_ITERABLE:             "cannot iterate over %s, expected an ARRAY, STRING, HASH or GENERATOR",
+	FUEL_LIMIT:               "fuel limit exceeded: evaluated more than %d nodes",
+	DEPTH_LIMIT:              "depth limit exceeded: more than %d nested function calls",
+	CONSTANT_ASSIGNMENT:      "cannot assign to %s, it was declared with const",
+	INVALID_DELAY:            "`%s` needs a delay of at least %d ms, got %d",
+	LOOP_RUNNING:             "run_loop: the event loop is already running",
+	INTERNAL_ERROR:           "internal interpreter error: %v",
+	INDEX_OUT_OF_RANGE:       "`%s`: index %d is out of range for %d elements",
+	INVALID_TIME:             "`%s` failed: %s",
+	NO_DOCUMENTATION:         "no documentation for %s, only builtin functions are documented",
+	INVALID_SIZE:             "`%s` needs a size of at least 1, got %d",
+	ASSIGNMENT_OUT_OF_RANGE:  "index %d is out of range for %d elements",
+
+	DID_YOU_MEAN: "did you mean '%s'?",
+
+	INTEGER_DIVISION_TRUNCATED: "integer division discards the remainder: %d / %d is %d, divide a float to get %s",
+}
+
+// The catalog currently in use, Use can swap it while interpreters are looking up messages
+var current = struct {
+	sync.RWMutex
+	messages map[Code]string
+}{messages: English}
+
+/**
+Replaces the message catalog, ex: to show translated diagnostics.
+
+- codes missing from the given catalog fall back to the english message
+- the messages should use the same fmt verbs (in the same order) as the english ones
+- the catalog is copied, changing the map afterwards doesn't change the messages
+- safe to call while scripts are running, the catalog is shared by every interpreter in the process
+**/
+func Use(catalog map[Code]string) {
+	messages := make(map[Code]string, len(catalog))
+	for code, msg := range catalog {
+		messages[code] = msg
+	}
+
+	current.Lock()
+	defer current.Unlock()
+	current.messages = messages
+}
+
+// Returns the format string for the code in the current catalog
+func Lookup(code Code) string {
+	current.RLock()
+	msg, ok := current.messages[code]
+	current.RUnlock()
+
+	if ok {
+		return msg
+	}
+
+	return English[code]
+}
+
+// Formats the message for the given code with the current catalog
+func Message(code Code, args ...interface{}) string {
+	return fmt.Sprintf(Lookup(code), args...)
+}
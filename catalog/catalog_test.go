@@ -0,0 +1,119 @@
+package catalog
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestMessage(t *testing.T) {
+	msg := Message(TYPE_MISMATCH, "INTEGER", "+", "BOOLEAN")
+
+	if msg != "type mismatch: INTEGER + BOOLEAN" {
+		t.Errorf("wrong message, got %q", msg)
+	}
+}
+
+func TestUseCatalog(t *testing.T) {
+	defer Use(English)
+
+	Use(map[Code]string{
+		TYPE_MISMATCH: "types incompatibles : %s %s %s",
+	})
+
+	if msg := Message(TYPE_MISMATCH, "INTEGER", "+", "BOOLEAN"); msg != "types incompatibles : INTEGER + BOOLEAN" {
+		t.Errorf("wrong translated message, got %q", msg)
+	}
+
+	// missing translations fall back to english
+	if msg := Message(NOT_A_FUNCTION, "INTEGER"); msg != "not a function: INTEGER" {
+		t.Errorf("wrong fallback message, got %q", msg)
+	}
+}
+
+func TestUseWhileLookingUp(t *testing.T) {
+	defer Use(English)
+
+	french := map[Code]string{NOT_A_FUNCTION: "pas une fonction : %s"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				if msg := Message(NOT_A_FUNCTION, "INTEGER"); msg != "not a function: INTEGER" && msg != "pas une fonction : INTEGER" {
+					t.Errorf("unexpected message %q", msg)
+					return
+				}
+			}
+		}()
+	}
+
+	for j := 0; j < 1000; j++ {
+		if j%2 == 0 {
+			Use(french)
+		} else {
+			Use(English)
+		}
+	}
+	wg.Wait()
+
+	// the catalog was copied
+	Use(french)
+	french[NOT_A_FUNCTION] = "changed %s"
+	if msg := Message(NOT_A_FUNCTION, "INTEGER"); msg != "pas une fonction : INTEGER" {
+		t.Errorf("changing the map after Use shouldn't change the messages, got %q", msg)
+	}
+}
+
+func TestEveryCodeHasAMessage(t *testing.T) {
+	codes := []Code{
+		UNEXPECTED_TOKEN, NO_PREFIX_PARSE_FN, INVALID_INTEGER, INVALID_FLOAT, INVALID_ESCAPE, ILLEGAL_CHARACTER,
+		INVALID_ASSIGNMENT,
+		INVALID_SEPARATOR,
+		FLOAT_OUT_OF_RANGE,
+		INT_OUT_OF_RANGE,
+		TOO_DEEPLY_NESTED,
+		INVALID_CHAR_LITERAL,
+		UNTERMINATED_HEREDOC,
+		LITERAL_TOO_LARGE,
+		AMBIGUOUS_POSTFIX,
+		UNKNOWN_PREFIX_OPERATOR, UNKNOWN_INFIX_OPERATOR, IDENTIFIER_NOT_FOUND, TYPE_MISMATCH,
+		NOT_A_FUNCTION, INDEX_NOT_SUPPORTED, UNUSABLE_HASH_KEY, INDEX_ASSIGNMENT, INVALID_INDEX,
+		INVALID_LOOP_CONDITION, WRONG_ARGUMENT_COUNT, WRONG_ARGUMENT_TYPE, ARGUMENT_NOT_SUPPORTED,
+		NEGATIVE_INDEX, INVALID_CHARACTER, INVALID_CODE_POINT, YIELD_OUTSIDE_GENERATOR, GENERATOR_RUNNING,
+		FILE_ERROR, INVALID_PATTERN, INVALID_NUMBER, INVALID_BASE,
+		NEGATIVE_REPEAT, MEMORY_LIMIT, DIVISION_BY_ZERO,
+		UNKNOWN_MEMBER, INVALID_FLAG, NEGATIVE_SHIFT,
+		INVALID_CSV,
+		UNKNOWN_POSTFIX_OPERATOR,
+		INVALID_TOML,
+		INVALID_YAML,
+		NOT_ITERABLE,
+		FUEL_LIMIT, DEPTH_LIMIT,
+		CONSTANT_ASSIGNMENT,
+		INVALID_DELAY,
+		LOOP_RUNNING,
+		INTERNAL_ERROR,
+		INDEX_OUT_OF_RANGE,
+		INVALID_TIME,
+		NO_DOCUMENTATION,
+		INVALID_SIZE,
+		ASSIGNMENT_OUT_OF_RANGE,
+		DID_YOU_MEAN,
+		INTEGER_DIVISION_TRUNCATED,
+	}
+
+	seen := map[Code]bool{}
+
+	for _, code := range codes {
+		if seen[code] {
+			t.Errorf("code %s is used more than once", code)
+		}
+		seen[code] = true
+
+		if _, ok := English[code]; !ok {
+			t.Errorf("code %s has no english message", code)
+		}
+	}
+}
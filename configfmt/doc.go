@@ -0,0 +1,10 @@
+/**
+Package configfmt decodes and encodes the TOML and YAML subsets used for config files.
+
+Both decoders return nil, bool, int64, float64, string, []interface{} and map[string]interface{}.
+YAML is limited to block mappings and sequences, single line flow collections, quoted and plain
+scalars on a single line and block scalars (| and >). Anchors, aliases, tags, complex keys,
+multiple documents and values spanning several lines (other than block scalars) return an error.
+See DecodeYAML and DecodeTOML for the details.
+**/
+package configfmt
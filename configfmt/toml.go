@@ -0,0 +1,824 @@
+package configfmt
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+/**
+Decodes a TOML document into a map (the root table).
+
+Supported:
+- key = value pairs, with bare, quoted and dotted keys (a.b.c = 1)
+- tables ([server]), dotted tables ([server.http]) and arrays of tables ([[servers]])
+- basic, literal and multi-line strings, integers (decimal, 0x, 0o, 0b and _ separators),
+  floats (including inf and nan), booleans, arrays and inline tables
+- dates and times are returned as strings, there's no date type to map them to
+**/
+func DecodeTOML(text string) (map[string]interface{}, error) {
+	p := &tomlParser{
+		src:         []rune(text),
+		line:        1,
+		root:        make(map[string]interface{}),
+		tables:      make(map[string]bool),
+		arrayTables: make(map[string]bool),
+	}
+	p.current = p.root
+
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+
+	return p.root, nil
+}
+
+type tomlParser struct {
+	src  []rune
+	pos  int
+	line int
+
+	root    map[string]interface{}
+	current map[string]interface{}
+	// headers already seen, so [a] can't be defined twice
+	tables map[string]bool
+	// arrays created by [[a]], the only arrays [[a]] can append to
+	arrayTables map[string]bool
+}
+
+func (p *tomlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *tomlParser) peek() rune {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *tomlParser) next() rune {
+	ch := p.peek()
+	p.pos++
+	if ch == '\n' {
+		p.line++
+	}
+	return ch
+}
+
+func (p *tomlParser) hasPrefix(prefix string) bool {
+	return strings.HasPrefix(string(p.src[p.pos:min(p.pos+len(prefix), len(p.src))]), prefix)
+}
+
+func (p *tomlParser) skipSpaces() {
+	for p.peek() == ' ' || p.peek() == '\t' {
+		p.next()
+	}
+}
+
+func (p *tomlParser) skipComment() {
+	if p.peek() != '#' {
+		return
+	}
+	for !p.eof() && p.peek() != '\n' {
+		p.next()
+	}
+}
+
+// Skips whitespace, newlines and comments (between statements and inside arrays)
+func (p *tomlParser) skipBlank() {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\r', '\n':
+			p.next()
+		case '#':
+			p.skipComment()
+		default:
+			return
+		}
+	}
+}
+
+// Only whitespace and a comment can follow a statement on its line
+func (p *tomlParser) endOfLine() error {
+	p.skipSpaces()
+	p.skipComment()
+
+	if p.peek() == '\r' {
+		p.next()
+	}
+
+	if !p.eof() && p.peek() != '\n' {
+		return p.errorf("unexpected %q after the value", p.peek())
+	}
+	return nil
+}
+
+func (p *tomlParser) parse() error {
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return nil
+		}
+
+		var err error
+		switch {
+		case p.hasPrefix("[["):
+			err = p.parseArrayTable()
+		case p.peek() == '[':
+			err = p.parseTable()
+		default:
+			err = p.parseKeyValue(p.current)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err := p.endOfLine(); err != nil {
+			return err
+		}
+	}
+}
+
+// [a.b]
+func (p *tomlParser) parseTable() error {
+	p.next()
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+
+	if p.next() != ']' {
+		return p.errorf("expected ] to close the table header")
+	}
+
+	path := strings.Join(keys, ".")
+	if p.tables[path] {
+		return p.errorf("table [%s] is defined twice", path)
+	}
+	p.tables[path] = true
+
+	table, err := p.descend(p.root, keys)
+	if err != nil {
+		return err
+	}
+
+	p.current = table
+	return nil
+}
+
+// [[a.b]]
+func (p *tomlParser) parseArrayTable() error {
+	p.next()
+	p.next()
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+
+	if !p.hasPrefix("]]") {
+		return p.errorf("expected ]] to close the array of tables header")
+	}
+	p.next()
+	p.next()
+
+	parent, err := p.descend(p.root, keys[:len(keys)-1])
+	if err != nil {
+		return err
+	}
+
+	path := strings.Join(keys, ".")
+	last := keys[len(keys)-1]
+	table := make(map[string]interface{})
+
+	switch existing := parent[last].(type) {
+	case nil:
+		parent[last] = []interface{}{table}
+		p.arrayTables[path] = true
+
+	case []interface{}:
+		if !p.arrayTables[path] {
+			return p.errorf("%s is not an array of tables", path)
+		}
+		parent[last] = append(existing, table)
+
+	default:
+		return p.errorf("%s is already defined", path)
+	}
+
+	p.current = table
+	return nil
+}
+
+/**
+Returns the table at the given path, creating the missing ones.
+Going through an array of tables uses its last table: [[a]] then [a.b] defines b in the last a.
+**/
+func (p *tomlParser) descend(table map[string]interface{}, keys []string) (map[string]interface{}, error) {
+	for _, key := range keys {
+		switch existing := table[key].(type) {
+		case nil:
+			child := make(map[string]interface{})
+			table[key] = child
+			table = child
+
+		case map[string]interface{}:
+			table = existing
+
+		case []interface{}:
+			last, ok := lastTable(existing)
+			if !ok {
+				return nil, p.errorf("%s is not a table", key)
+			}
+			table = last
+
+		default:
+			return nil, p.errorf("%s is not a table", key)
+		}
+	}
+
+	return table, nil
+}
+
+func lastTable(arr []interface{}) (map[string]interface{}, bool) {
+	if len(arr) == 0 {
+		return nil, false
+	}
+	table, ok := arr[len(arr)-1].(map[string]interface{})
+	return table, ok
+}
+
+// key = value, the key can be dotted
+func (p *tomlParser) parseKeyValue(table map[string]interface{}) error {
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+
+	if p.next() != '=' {
+		return p.errorf("expected = after the key %s", strings.Join(keys, "."))
+	}
+	p.skipSpaces()
+
+	value, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+
+	parent, err := p.descend(table, keys[:len(keys)-1])
+	if err != nil {
+		return err
+	}
+
+	last := keys[len(keys)-1]
+	if _, exists := parent[last]; exists {
+		return p.errorf("key %s is defined twice", strings.Join(keys, "."))
+	}
+
+	parent[last] = value
+	return nil
+}
+
+// a, "a b", 'a', a.b."c"
+func (p *tomlParser) parseKey() ([]string, error) {
+	keys := []string{}
+
+	for {
+		p.skipSpaces()
+
+		var key string
+		var err error
+
+		switch p.peek() {
+		case '"':
+			p.next()
+			key, err = p.parseBasicString()
+		case '\'':
+			p.next()
+			key, err = p.parseLiteralString()
+		default:
+			start := p.pos
+			for isBareKeyChar(p.peek()) {
+				p.next()
+			}
+			key = string(p.src[start:p.pos])
+			if key == "" {
+				err = p.errorf("expected a key, got %q", p.peek())
+			}
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+		p.skipSpaces()
+
+		if p.peek() != '.' {
+			return keys, nil
+		}
+		p.next()
+	}
+}
+
+func isBareKeyChar(ch rune) bool {
+	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9' || ch == '_' || ch == '-'
+}
+
+func (p *tomlParser) parseValue() (interface{}, error) {
+	switch {
+	case p.hasPrefix(`"""`):
+		p.pos += 3
+		return p.parseMultilineString(`"""`, true)
+	case p.hasPrefix(`'''`):
+		p.pos += 3
+		return p.parseMultilineString(`'''`, false)
+	case p.peek() == '"':
+		p.next()
+		return p.parseBasicString()
+	case p.peek() == '\'':
+		p.next()
+		return p.parseLiteralString()
+	case p.peek() == '[':
+		p.next()
+		return p.parseArray()
+	case p.peek() == '{':
+		p.next()
+		return p.parseInlineTable()
+	}
+
+	return p.parseBareValue()
+}
+
+// "...", the opening quote was consumed
+func (p *tomlParser) parseBasicString() (string, error) {
+	var out strings.Builder
+
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+
+		ch := p.next()
+		switch ch {
+		case '"':
+			return out.String(), nil
+		case '\\':
+			if err := p.parseEscape(&out); err != nil {
+				return "", err
+			}
+		default:
+			out.WriteRune(ch)
+		}
+	}
+}
+
+// '...', the opening quote was consumed (no escapes)
+func (p *tomlParser) parseLiteralString() (string, error) {
+	start := p.pos
+
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+		if p.next() == '\'' {
+			return string(p.src[start : p.pos-1]), nil
+		}
+	}
+}
+
+// """...""" or '''...''', the opening delimiter was consumed
+func (p *tomlParser) parseMultilineString(delimiter string, escapes bool) (string, error) {
+	var out strings.Builder
+
+	// a newline right after the delimiter isn't part of the string
+	if p.hasPrefix("\r\n") {
+		p.next()
+	}
+	if p.peek() == '\n' {
+		p.next()
+	}
+
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated multi-line string")
+		}
+
+		if p.hasPrefix(delimiter) {
+			p.pos += len(delimiter)
+			return out.String(), nil
+		}
+
+		ch := p.next()
+		if ch != '\\' || !escapes {
+			out.WriteRune(ch)
+			continue
+		}
+
+		// a backslash at the end of a line trims the newline and the whitespace after it
+		if rest := p.peek(); rest == '\n' || rest == ' ' || rest == '\t' || rest == '\r' {
+			for strings.ContainsRune(" \t\r\n", p.peek()) && !p.eof() {
+				p.next()
+			}
+			continue
+		}
+
+		if err := p.parseEscape(&out); err != nil {
+			return "", err
+		}
+	}
+}
+
+// The char after a backslash: \n, \t, é, etc
+func (p *tomlParser) parseEscape(out *strings.Builder) error {
+	ch := p.next()
+
+	switch ch {
+	case 'b':
+		out.WriteRune('\b')
+	case 't':
+		out.WriteRune('\t')
+	case 'n':
+		out.WriteRune('\n')
+	case 'f':
+		out.WriteRune('\f')
+	case 'r':
+		out.WriteRune('\r')
+	case '"':
+		out.WriteRune('"')
+	case '\\':
+		out.WriteRune('\\')
+	case 'u', 'U':
+		size := 4
+		if ch == 'U' {
+			size = 8
+		}
+		if p.pos+size > len(p.src) {
+			return p.errorf("invalid escape sequence \\%c", ch)
+		}
+
+		code, err := strconv.ParseUint(string(p.src[p.pos:p.pos+size]), 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return p.errorf("invalid escape sequence \\%c%s", ch, string(p.src[p.pos:p.pos+size]))
+		}
+		p.pos += size
+		out.WriteRune(rune(code))
+	default:
+		return p.errorf("invalid escape sequence \\%c", ch)
+	}
+
+	return nil
+}
+
+// [1, 2, 3], can span several lines and end with a comma
+func (p *tomlParser) parseArray() (interface{}, error) {
+	arr := []interface{}{}
+
+	for {
+		p.skipBlank()
+		if p.peek() == ']' {
+			p.next()
+			return arr, nil
+		}
+
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, value)
+
+		p.skipBlank()
+		switch p.next() {
+		case ',':
+		case ']':
+			return arr, nil
+		default:
+			return nil, p.errorf("expected , or ] in array")
+		}
+	}
+}
+
+// {a = 1, b = "x"}, on a single line
+func (p *tomlParser) parseInlineTable() (interface{}, error) {
+	table := make(map[string]interface{})
+
+	p.skipSpaces()
+	if p.peek() == '}' {
+		p.next()
+		return table, nil
+	}
+
+	for {
+		if err := p.parseKeyValue(table); err != nil {
+			return nil, err
+		}
+
+		p.skipSpaces()
+		switch p.next() {
+		case ',':
+		case '}':
+			return table, nil
+		default:
+			return nil, p.errorf("expected , or } in inline table")
+		}
+	}
+}
+
+// true, false, numbers, dates
+func (p *tomlParser) parseBareValue() (interface{}, error) {
+	start := p.pos
+	for isBareValueChar(p.peek()) {
+		p.next()
+	}
+
+	// 1979-05-27 07:32:00, a date and a time separated by a space
+	if p.pos-start == 10 && p.peek() == ' ' && p.pos+1 < len(p.src) && isDigit(p.src[p.pos+1]) {
+		p.next()
+		for isBareValueChar(p.peek()) {
+			p.next()
+		}
+	}
+
+	word := string(p.src[start:p.pos])
+
+	switch {
+	case word == "" && (p.eof() || p.peek() == '\n'):
+		return nil, p.errorf("expected a value")
+	case word == "":
+		return nil, p.errorf("expected a value, got %q", p.peek())
+	case word == "true":
+		return true, nil
+	case word == "false":
+		return false, nil
+	case isTOMLDate(word):
+		return word, nil
+	}
+
+	if value, ok := parseTOMLNumber(word); ok {
+		return value, nil
+	}
+
+	return nil, p.errorf("invalid value %s", word)
+}
+
+func isBareValueChar(ch rune) bool {
+	return isBareKeyChar(ch) || ch == '+' || ch == '.' || ch == ':'
+}
+
+func isDigit(ch rune) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+// 1979-05-27, 07:32:00, 1979-05-27T07:32:00Z
+func isTOMLDate(word string) bool {
+	return len(word) >= 8 && isDigit(rune(word[0])) && (word[4] == '-' || word[2] == ':')
+}
+
+func parseTOMLNumber(word string) (interface{}, bool) {
+	switch strings.TrimLeft(word, "+-") {
+	case "inf":
+		if strings.HasPrefix(word, "-") {
+			return math.Inf(-1), true
+		}
+		return math.Inf(1), true
+	case "nan":
+		return math.NaN(), true
+	}
+
+	clean := strings.ReplaceAll(word, "_", "")
+
+	for prefix, base := range map[string]int{"0x": 16, "0o": 8, "0b": 2} {
+		if strings.HasPrefix(clean, prefix) {
+			value, err := strconv.ParseInt(clean[2:], base, 64)
+			return value, err == nil
+		}
+	}
+
+	if strings.ContainsAny(clean, ".eE") {
+		value, err := strconv.ParseFloat(clean, 64)
+		return value, err == nil
+	}
+
+	value, err := strconv.ParseInt(clean, 10, 64)
+	return value, err == nil
+}
+
+/**
+Encodes a map as a TOML document, the opposite of DecodeTOML.
+
+- keys are written in sorted order, plain values first, then the tables ([a]) and arrays of tables ([[a]])
+- TOML has no null, nil values return an error
+**/
+func EncodeTOML(table map[string]interface{}) (string, error) {
+	var out strings.Builder
+
+	if err := encodeTOMLTable(&out, nil, table); err != nil {
+		return "", err
+	}
+
+	return out.String(), nil
+}
+
+func encodeTOMLTable(out *strings.Builder, path []string, table map[string]interface{}) error {
+	keys := sortedMapKeys(table)
+
+	for _, key := range keys {
+		value := table[key]
+		if isTable(value) || isArrayOfTables(value) {
+			continue
+		}
+
+		encoded, err := encodeTOMLValue(append(path, key), value)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%s = %s\n", tomlKey(key), encoded)
+	}
+
+	for _, key := range keys {
+		childPath := append(append([]string{}, path...), key)
+
+		switch value := table[key].(type) {
+		case map[string]interface{}:
+			if !isTable(value) {
+				continue
+			}
+			writeTOMLHeader(out, "[%s]\n", childPath)
+			if err := encodeTOMLTable(out, childPath, value); err != nil {
+				return err
+			}
+
+		case []interface{}:
+			if !isArrayOfTables(value) {
+				continue
+			}
+			for _, el := range value {
+				writeTOMLHeader(out, "[[%s]]\n", childPath)
+				if err := encodeTOMLTable(out, childPath, el.(map[string]interface{})); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Headers are separated from what comes before them by a blank line
+func writeTOMLHeader(out *strings.Builder, format string, path []string) {
+	if out.Len() != 0 {
+		out.WriteString("\n")
+	}
+
+	keys := make([]string, len(path))
+	for idx, key := range path {
+		keys[idx] = tomlKey(key)
+	}
+
+	fmt.Fprintf(out, format, strings.Join(keys, "."))
+}
+
+func isTable(value interface{}) bool {
+	_, ok := value.(map[string]interface{})
+	return ok
+}
+
+// A non empty array where every element is a table, written as [[key]] sections
+func isArrayOfTables(value interface{}) bool {
+	arr, ok := value.([]interface{})
+	if !ok || len(arr) == 0 {
+		return false
+	}
+
+	for _, el := range arr {
+		if !isTable(el) {
+			return false
+		}
+	}
+	return true
+}
+
+// Encodes a value on a single line: arrays and tables are written inline
+func encodeTOMLValue(path []string, value interface{}) (string, error) {
+	switch value := value.(type) {
+	case string:
+		return tomlString(value), nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	case int64:
+		return strconv.FormatInt(value, 10), nil
+	case float64:
+		return tomlFloat(value), nil
+
+	case []interface{}:
+		elements := make([]string, len(value))
+		for idx, el := range value {
+			encoded, err := encodeTOMLValue(path, el)
+			if err != nil {
+				return "", err
+			}
+			elements[idx] = encoded
+		}
+		return "[" + strings.Join(elements, ", ") + "]", nil
+
+	case map[string]interface{}:
+		pairs := []string{}
+		for _, key := range sortedMapKeys(value) {
+			encoded, err := encodeTOMLValue(append(path, key), value[key])
+			if err != nil {
+				return "", err
+			}
+			pairs = append(pairs, tomlKey(key)+" = "+encoded)
+		}
+		return "{" + strings.Join(pairs, ", ") + "}", nil
+
+	case nil:
+		return "", fmt.Errorf("%s is null, TOML has no null", strings.Join(path, "."))
+	}
+
+	return "", fmt.Errorf("%s: cannot encode %T", strings.Join(path, "."), value)
+}
+
+func tomlKey(key string) string {
+	if key == "" {
+		return `""`
+	}
+
+	for _, ch := range key {
+		if !isBareKeyChar(ch) {
+			return tomlString(key)
+		}
+	}
+	return key
+}
+
+func tomlString(s string) string {
+	var out strings.Builder
+	out.WriteRune('"')
+
+	for _, ch := range s {
+		switch ch {
+		case '"':
+			out.WriteString(`\"`)
+		case '\\':
+			out.WriteString(`\\`)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\t':
+			out.WriteString(`\t`)
+		case '\r':
+			out.WriteString(`\r`)
+		default:
+			if ch < 0x20 || ch == 0x7f {
+				fmt.Fprintf(&out, `\u%04X`, ch)
+			} else {
+				out.WriteRune(ch)
+			}
+		}
+	}
+
+	out.WriteRune('"')
+	return out.String()
+}
+
+// Floats always have a . or an exponent, so they're decoded as floats again
+func tomlFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
+
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+func sortedMapKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
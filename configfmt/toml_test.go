@@ -0,0 +1,176 @@
+package configfmt
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeTOML(t *testing.T) {
+	input := `# a comment
+title = "monke" # trailing comment
+version = 1_000
+ratio = 0.5
+mask = 0xff
+enabled = true
+released = 1979-05-27 07:32:00
+"quoted key" = 'C:\path'
+site.name = "example"
+ports = [
+  8000,
+  8001, # trailing comma
+]
+point = {x = 1, y = "two"}
+bio = """
+first line
+second \
+  line"""
+
+[server]
+host = "localhost"
+
+[server.http]
+timeout = -2.5e3
+
+[[plugins]]
+name = "a"
+
+[[plugins]]
+name = "b"
+
+[plugins.options]
+verbose = false
+`
+
+	expected := map[string]interface{}{
+		"title":      "monke",
+		"version":    int64(1000),
+		"ratio":      0.5,
+		"mask":       int64(255),
+		"enabled":    true,
+		"released":   "1979-05-27 07:32:00",
+		"quoted key": `C:\path`,
+		"site":       map[string]interface{}{"name": "example"},
+		"ports":      []interface{}{int64(8000), int64(8001)},
+		"point":      map[string]interface{}{"x": int64(1), "y": "two"},
+		"bio":        "first line\nsecond line",
+		"server": map[string]interface{}{
+			"host": "localhost",
+			"http": map[string]interface{}{"timeout": -2500.0},
+		},
+		"plugins": []interface{}{
+			map[string]interface{}{"name": "a"},
+			map[string]interface{}{"name": "b", "options": map[string]interface{}{"verbose": false}},
+		},
+	}
+
+	decoded, err := DecodeTOML(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("wrong result, expected\n%#v\ngot\n%#v", expected, decoded)
+	}
+}
+
+func TestDecodeTOMLSpecialFloats(t *testing.T) {
+	decoded, err := DecodeTOML("a = inf\nb = -inf\nc = nan")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !math.IsInf(decoded["a"].(float64), 1) || !math.IsInf(decoded["b"].(float64), -1) || !math.IsNaN(decoded["c"].(float64)) {
+		t.Errorf("wrong result, got %v", decoded)
+	}
+}
+
+func TestDecodeTOMLErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a = 1\na = 2", "line 2: key a is defined twice"},
+		{"[a]\n[a]", "line 2: table [a] is defined twice"},
+		{"a = 1\n[a]", "line 2: a is not a table"},
+		{"a = [1]\n[[a]]", "line 2: a is not an array of tables"},
+		{`a = "open`, "line 1: unterminated string"},
+		{`a = "\q"`, `line 1: invalid escape sequence \q`},
+		{"a = 1 2", `line 1: unexpected '2' after the value`},
+		{"a = nope", "line 1: invalid value nope"},
+		{"a 1", "line 1: expected = after the key a"},
+		{"a = [1 2]", "line 1: expected , or ] in array"},
+	}
+
+	for _, tt := range tests {
+		_, err := DecodeTOML(tt.input)
+
+		if err == nil {
+			t.Errorf("%q: expected error %q, got none", tt.input, tt.expected)
+			continue
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("%q: wrong error, expected %q got %q", tt.input, tt.expected, err.Error())
+		}
+	}
+}
+
+func TestEncodeTOML(t *testing.T) {
+	input := map[string]interface{}{
+		"title":   "say \"hi\"\n",
+		"count":   int64(3),
+		"ratio":   2.0,
+		"tags":    []interface{}{"a", int64(1)},
+		"my key":  true,
+		"point":   []interface{}{map[string]interface{}{"x": int64(1)}},
+		"server":  map[string]interface{}{"host": "localhost", "http": map[string]interface{}{"port": int64(80)}},
+		"inline":  []interface{}{[]interface{}{map[string]interface{}{"a": int64(1)}}},
+		"nothing": map[string]interface{}{},
+	}
+
+	expected := `count = 3
+inline = [[{a = 1}]]
+"my key" = true
+ratio = 2.0
+tags = ["a", 1]
+title = "say \"hi\"\n"
+
+[nothing]
+
+[[point]]
+x = 1
+
+[server]
+host = "localhost"
+
+[server.http]
+port = 80
+`
+
+	encoded, err := EncodeTOML(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if encoded != expected {
+		t.Errorf("wrong result, expected\n%s\ngot\n%s", expected, encoded)
+	}
+
+	decoded, err := DecodeTOML(encoded)
+	if err != nil {
+		t.Fatalf("could not decode the encoded document: %s", err)
+	}
+
+	if !reflect.DeepEqual(decoded, input) {
+		t.Errorf("round trip changed the value, expected\n%#v\ngot\n%#v", input, decoded)
+	}
+}
+
+func TestEncodeTOMLErrors(t *testing.T) {
+	_, err := EncodeTOML(map[string]interface{}{"a": map[string]interface{}{"b": nil}})
+
+	if err == nil || err.Error() != "a.b is null, TOML has no null" {
+		t.Errorf("wrong error, got %v", err)
+	}
+}
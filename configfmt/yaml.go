@@ -0,0 +1,831 @@
+package configfmt
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+/**
+Decodes a YAML document into a Go value.
+
+Supported:
+- block mappings and sequences nested by indentation, including "- key: value" items
+- flow collections on a single line ([1, 2], {a: 1})
+- plain, single and double quoted scalars, literal (|) and folded (>) block scalars
+- comments, a leading --- and a trailing ...
+
+Scalars follow the YAML 1.2 core schema: null and ~ are nil, true/false are booleans,
+numbers are int64 or float64, everything else is a string. Keys are always strings.
+
+Not supported (they return an error): anchors and aliases (&a, *a), tags (!!str), multiple documents,
+scalars and flow collections spanning several lines (quote a long value or use | or >), and
+complex keys (? key).
+**/
+func DecodeYAML(text string) (interface{}, error) {
+	p := &yamlParser{}
+
+	for idx, raw := range strings.Split(text, "\n") {
+		raw = strings.TrimRight(raw, "\r")
+		trimmed := strings.TrimLeft(raw, " ")
+
+		if strings.HasPrefix(trimmed, "\t") && strings.TrimSpace(trimmed) != "" {
+			return nil, fmt.Errorf("line %d: tabs can't be used for indentation", idx+1)
+		}
+
+		// document markers
+		if raw == "---" || strings.HasPrefix(raw, "--- ") {
+			if len(p.lines) != 0 && p.hasContent() {
+				return nil, fmt.Errorf("line %d: multiple documents are not supported", idx+1)
+			}
+			continue
+		}
+		if raw == "..." {
+			break
+		}
+
+		p.lines = append(p.lines, yamlLine{num: idx + 1, indent: len(raw) - len(trimmed), text: trimmed})
+	}
+
+	p.skipBlank()
+	if p.eof() {
+		return nil, nil
+	}
+
+	value, err := p.parseNode(-1)
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipBlank()
+	if !p.eof() {
+		return nil, p.errorf("unexpected indentation")
+	}
+
+	return value, nil
+}
+
+type yamlLine struct {
+	num    int
+	indent int
+	// the line without its indentation
+	text string
+}
+
+// The text without its comment and trailing whitespace, empty for blank or comment lines
+func (l yamlLine) content() string {
+	return strings.TrimRight(stripYAMLComment(l.text), " \t")
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func (p *yamlParser) errorf(format string, args ...interface{}) error {
+	line := 0
+	if p.pos < len(p.lines) {
+		line = p.lines[p.pos].num
+	} else if len(p.lines) != 0 {
+		line = p.lines[len(p.lines)-1].num
+	}
+
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func (p *yamlParser) eof() bool {
+	return p.pos >= len(p.lines)
+}
+
+func (p *yamlParser) hasContent() bool {
+	for _, line := range p.lines {
+		if line.content() != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *yamlParser) skipBlank() {
+	for !p.eof() && p.lines[p.pos].content() == "" {
+		p.pos++
+	}
+}
+
+// Parses the node starting at the current line, which has to be indented more than parent
+func (p *yamlParser) parseNode(parent int) (interface{}, error) {
+	line := p.lines[p.pos]
+	if line.indent <= parent {
+		return nil, nil
+	}
+
+	content := line.content()
+
+	if isSequenceItem(content) {
+		return p.parseSequence(line.indent)
+	}
+
+	if content == "?" || strings.HasPrefix(content, "? ") {
+		return nil, p.errorf("complex keys (? key) are not supported")
+	}
+
+	if _, _, ok, err := splitMappingKey(content); err != nil {
+		return nil, p.errorf("%s", err)
+	} else if ok {
+		return p.parseMapping(line.indent)
+	}
+
+	p.pos++
+	value, err := p.parseInline(content, line.num)
+	if err != nil {
+		return nil, err
+	}
+
+	return value, p.checkContinuation(parent, content)
+}
+
+func isSequenceItem(content string) bool {
+	return content == "-" || strings.HasPrefix(content, "- ")
+}
+
+// key: value lines at the given indentation
+func (p *yamlParser) parseMapping(indent int) (interface{}, error) {
+	mapping := make(map[string]interface{})
+
+	for {
+		p.skipBlank()
+		if p.eof() || p.lines[p.pos].indent < indent {
+			return mapping, nil
+		}
+
+		line := p.lines[p.pos]
+		if line.indent > indent {
+			return nil, p.errorf("unexpected indentation")
+		}
+
+		content := line.content()
+		if isSequenceItem(content) {
+			return nil, p.errorf("expected a mapping key, got a sequence item")
+		}
+
+		key, rest, ok, err := splitMappingKey(content)
+		if err != nil {
+			return nil, p.errorf("%s", err)
+		}
+		if !ok {
+			return nil, p.errorf("expected a mapping key (key: value), got %q", content)
+		}
+		if _, exists := mapping[key]; exists {
+			return nil, p.errorf("key %s is defined twice", key)
+		}
+
+		p.pos++
+		value, err := p.parseValue(indent, rest, true, line.num)
+		if err != nil {
+			return nil, err
+		}
+
+		mapping[key] = value
+	}
+}
+
+// - item lines at the given indentation
+func (p *yamlParser) parseSequence(indent int) (interface{}, error) {
+	sequence := []interface{}{}
+
+	for {
+		p.skipBlank()
+		if p.eof() || p.lines[p.pos].indent < indent {
+			return sequence, nil
+		}
+
+		line := p.lines[p.pos]
+		if line.indent > indent {
+			return nil, p.errorf("unexpected indentation")
+		}
+
+		content := line.content()
+		if !isSequenceItem(content) {
+			return sequence, nil
+		}
+
+		rest := strings.TrimLeft(content[1:], " ")
+
+		// - key: value, the rest of the item is parsed as if it started on its own line
+		if _, _, ok, _ := splitMappingKey(rest); ok || isSequenceItem(rest) {
+			offset := len(content) - len(rest)
+			p.lines[p.pos] = yamlLine{num: line.num, indent: indent + offset, text: line.text[offset:]}
+
+			value, err := p.parseNode(indent)
+			if err != nil {
+				return nil, err
+			}
+			sequence = append(sequence, value)
+			continue
+		}
+
+		p.pos++
+		value, err := p.parseValue(indent, rest, false, line.num)
+		if err != nil {
+			return nil, err
+		}
+
+		sequence = append(sequence, value)
+	}
+}
+
+/**
+The value after "key:" or "- ", when it's empty the value is the block on the next lines.
+A mapping's value can be a sequence at the same indentation:
+
+key:
+- a
+- b
+**/
+func (p *yamlParser) parseValue(indent int, rest string, inMapping bool, num int) (interface{}, error) {
+	if isBlockScalarHeader(rest) {
+		return p.parseBlockScalar(indent, rest)
+	}
+
+	if rest != "" {
+		value, err := p.parseInline(rest, num)
+		if err != nil {
+			return nil, err
+		}
+
+		return value, p.checkContinuation(indent, rest)
+	}
+
+	p.skipBlank()
+	if p.eof() {
+		return nil, nil
+	}
+
+	next := p.lines[p.pos]
+	if next.indent > indent || (inMapping && next.indent == indent && isSequenceItem(next.content())) {
+		return p.parseNode(indent - 1)
+	}
+
+	return nil, nil
+}
+
+/**
+An inline value followed by a line indented more than indent would continue on that line,
+only block scalars can span several lines.
+**/
+func (p *yamlParser) checkContinuation(indent int, value string) error {
+	p.skipBlank()
+	if p.eof() || p.lines[p.pos].indent <= indent {
+		return nil
+	}
+
+	// a nested key or item is reported as unexpected indentation by the caller
+	next := p.lines[p.pos].content()
+	if _, _, ok, _ := splitMappingKey(next); ok || isSequenceItem(next) {
+		return nil
+	}
+
+	switch value[0] {
+	case '"', '\'':
+		return p.errorf("multi-line quoted strings are not supported, use | or >")
+	case '[', '{':
+		return p.errorf("flow collections have to fit on a single line")
+	default:
+		return p.errorf("multi-line plain scalars are not supported, quote the value or use | or >")
+	}
+}
+
+var blockScalarHeader = regexp.MustCompile(`^[|>][+-]?$`)
+
+func isBlockScalarHeader(s string) bool {
+	return blockScalarHeader.MatchString(s)
+}
+
+/**
+| keeps the newlines of the block, > folds them into spaces (blank lines stay newlines).
+The chomping indicator decides what happens to the trailing newlines:
+"-" removes them all, "+" keeps them all, by default a single one is kept.
+**/
+func (p *yamlParser) parseBlockScalar(indent int, header string) (interface{}, error) {
+	folded := header[0] == '>'
+	chomp := header[1:]
+
+	lines := []string{}
+	blockIndent := -1
+
+	for ; !p.eof(); p.pos++ {
+		line := p.lines[p.pos]
+
+		if strings.TrimSpace(line.text) == "" {
+			lines = append(lines, "")
+			continue
+		}
+
+		if blockIndent == -1 {
+			if line.indent <= indent {
+				break
+			}
+			blockIndent = line.indent
+		}
+
+		if line.indent < blockIndent {
+			break
+		}
+
+		lines = append(lines, strings.Repeat(" ", line.indent-blockIndent)+line.text)
+	}
+
+	trailing := 0
+	for len(lines) != 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+		trailing++
+	}
+
+	var out strings.Builder
+	for idx, line := range lines {
+		switch {
+		case idx == 0:
+		case !folded:
+			out.WriteString("\n")
+		case line == "":
+			// a blank line in a folded block is a newline
+			out.WriteString("\n")
+			continue
+		case lines[idx-1] != "":
+			out.WriteString(" ")
+		}
+		out.WriteString(line)
+	}
+
+	if len(lines) == 0 {
+		return "", nil
+	}
+
+	switch chomp {
+	case "-":
+	case "+":
+		out.WriteString(strings.Repeat("\n", trailing+1))
+	default:
+		out.WriteString("\n")
+	}
+
+	return out.String(), nil
+}
+
+// A value on a single line (num): a scalar or a flow collection
+func (p *yamlParser) parseInline(s string, num int) (interface{}, error) {
+	if s != "" && strings.ContainsRune("&*!", rune(s[0])) {
+		return nil, fmt.Errorf("line %d: anchors, aliases and tags are not supported, got %q", num, s)
+	}
+
+	flow := &yamlFlow{src: s}
+	value, err := flow.value(false)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %s", num, err)
+	}
+
+	flow.skipSpaces()
+	if !flow.eof() {
+		return nil, fmt.Errorf("line %d: unexpected %q after the value", num, flow.src[flow.pos:])
+	}
+
+	return value, nil
+}
+
+/**
+Splits "key: value" into its key and value.
+ok is false when the content isn't a mapping entry (ex: a plain scalar or a flow collection).
+**/
+func splitMappingKey(content string) (key string, rest string, ok bool, err error) {
+	if content == "" || strings.ContainsRune("[{", rune(content[0])) {
+		return "", "", false, nil
+	}
+
+	if content[0] == '"' || content[0] == '\'' {
+		flow := &yamlFlow{src: content}
+		quoted, err := flow.quoted()
+		if err != nil {
+			return "", "", false, err
+		}
+
+		after := content[flow.pos:]
+		if after == ":" || strings.HasPrefix(after, ": ") {
+			return quoted, strings.TrimSpace(after[1:]), true, nil
+		}
+		return "", "", false, nil
+	}
+
+	for idx := 0; idx < len(content); idx++ {
+		if content[idx] != ':' {
+			continue
+		}
+		if idx == len(content)-1 || content[idx+1] == ' ' {
+			return strings.TrimSpace(content[:idx]), strings.TrimSpace(content[idx+1:]), true, nil
+		}
+	}
+
+	return "", "", false, nil
+}
+
+/**
+Removes a trailing # comment, the # has to start the line or follow a space.
+Quotes are only tracked when they start a scalar, so a plain don't # comment still works.
+**/
+func stripYAMLComment(text string) string {
+	var quote byte
+
+	for idx := 0; idx < len(text); idx++ {
+		ch := text[idx]
+
+		switch {
+		case quote == '"' && ch == '\\':
+			idx++
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			if idx == 0 || strings.ContainsRune(" \t[{,:", rune(text[idx-1])) {
+				quote = ch
+			}
+		case ch == '#':
+			if idx == 0 || text[idx-1] == ' ' || text[idx-1] == '\t' {
+				return text[:idx]
+			}
+		}
+	}
+
+	return text
+}
+
+// Parses flow values: [a, b], {a: 1}, "quoted", 'quoted' and plain scalars
+type yamlFlow struct {
+	src string
+	pos int
+}
+
+func (f *yamlFlow) eof() bool {
+	return f.pos >= len(f.src)
+}
+
+func (f *yamlFlow) skipSpaces() {
+	for !f.eof() && f.src[f.pos] == ' ' {
+		f.pos++
+	}
+}
+
+// inFlow: inside [] or {}, where , ] and } end a plain scalar
+func (f *yamlFlow) value(inFlow bool) (interface{}, error) {
+	f.skipSpaces()
+	if f.eof() {
+		return nil, nil
+	}
+
+	switch f.src[f.pos] {
+	case '[':
+		f.pos++
+		return f.sequence()
+	case '{':
+		f.pos++
+		return f.mapping()
+	case '"', '\'':
+		return f.quoted()
+	}
+
+	return resolveYAMLScalar(f.plain(inFlow, false)), nil
+}
+
+func (f *yamlFlow) sequence() (interface{}, error) {
+	sequence := []interface{}{}
+
+	for {
+		f.skipSpaces()
+		if f.eof() {
+			return nil, fmt.Errorf("unterminated flow sequence")
+		}
+		if f.src[f.pos] == ']' {
+			f.pos++
+			return sequence, nil
+		}
+
+		value, err := f.value(true)
+		if err != nil {
+			return nil, err
+		}
+		sequence = append(sequence, value)
+
+		f.skipSpaces()
+		if f.eof() {
+			return nil, fmt.Errorf("unterminated flow sequence")
+		}
+
+		switch f.src[f.pos] {
+		case ',':
+			f.pos++
+		case ']':
+		default:
+			return nil, fmt.Errorf("expected , or ] in flow sequence")
+		}
+	}
+}
+
+func (f *yamlFlow) mapping() (interface{}, error) {
+	mapping := make(map[string]interface{})
+
+	for {
+		f.skipSpaces()
+		if f.eof() {
+			return nil, fmt.Errorf("unterminated flow mapping")
+		}
+		if f.src[f.pos] == '}' {
+			f.pos++
+			return mapping, nil
+		}
+
+		var key string
+		if f.src[f.pos] == '"' || f.src[f.pos] == '\'' {
+			quoted, err := f.quoted()
+			if err != nil {
+				return nil, err
+			}
+			key = quoted
+		} else {
+			key = f.plain(true, true)
+		}
+
+		f.skipSpaces()
+		if f.eof() || f.src[f.pos] != ':' {
+			return nil, fmt.Errorf("expected : after the key %s in flow mapping", key)
+		}
+		f.pos++
+
+		value, err := f.value(true)
+		if err != nil {
+			return nil, err
+		}
+		mapping[key] = value
+
+		f.skipSpaces()
+		if f.eof() {
+			return nil, fmt.Errorf("unterminated flow mapping")
+		}
+
+		switch f.src[f.pos] {
+		case ',':
+			f.pos++
+		case '}':
+		default:
+			return nil, fmt.Errorf("expected , or } in flow mapping")
+		}
+	}
+}
+
+// "double quoted" (with escapes) or 'single quoted' ('' is a quote)
+func (f *yamlFlow) quoted() (string, error) {
+	quote := f.src[f.pos]
+	start := f.pos
+	f.pos++
+
+	for !f.eof() {
+		ch := f.src[f.pos]
+
+		if quote == '"' && ch == '\\' {
+			f.pos += 2
+			continue
+		}
+
+		if ch == quote {
+			// '' inside a single quoted string
+			if quote == '\'' && f.pos+1 < len(f.src) && f.src[f.pos+1] == '\'' {
+				f.pos += 2
+				continue
+			}
+
+			f.pos++
+			if quote == '\'' {
+				return strings.ReplaceAll(f.src[start+1:f.pos-1], "''", "'"), nil
+			}
+
+			unquoted, err := strconv.Unquote(f.src[start:f.pos])
+			if err != nil {
+				return "", fmt.Errorf("invalid escape sequence in %s", f.src[start:f.pos])
+			}
+			return unquoted, nil
+		}
+
+		f.pos++
+	}
+
+	return "", fmt.Errorf("unterminated string %s", f.src[start:])
+}
+
+// Reads a plain scalar, in a flow it ends at , ] } (and : for keys)
+func (f *yamlFlow) plain(inFlow bool, isKey bool) string {
+	start := f.pos
+
+	for !f.eof() {
+		ch := f.src[f.pos]
+		if inFlow && strings.ContainsRune(",]}", rune(ch)) {
+			break
+		}
+		if isKey && ch == ':' {
+			break
+		}
+		f.pos++
+	}
+
+	return strings.TrimSpace(f.src[start:f.pos])
+}
+
+var (
+	yamlInt   = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	yamlFloat = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+)
+
+// Resolves a plain scalar to its type (YAML 1.2 core schema)
+func resolveYAMLScalar(s string) interface{} {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF":
+		return math.Inf(1)
+	case "-.inf", "-.Inf", "-.INF":
+		return math.Inf(-1)
+	case ".nan", ".NaN", ".NAN":
+		return math.NaN()
+	}
+
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0o") {
+		base := 16
+		if s[1] == 'o' {
+			base = 8
+		}
+		if value, err := strconv.ParseInt(s[2:], base, 64); err == nil {
+			return value
+		}
+		return s
+	}
+
+	if yamlInt.MatchString(s) {
+		if value, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return value
+		}
+	}
+
+	if yamlFloat.MatchString(s) {
+		if value, err := strconv.ParseFloat(s, 64); err == nil {
+			return value
+		}
+	}
+
+	return s
+}
+
+/**
+Encodes a value as a YAML document, the opposite of DecodeYAML.
+
+- mappings are written with their keys sorted, nested collections use block style
+- empty collections are written as [] and {}
+- strings are only quoted when they would be read back as something else (ex: "true", "5", "a: b")
+**/
+func EncodeYAML(value interface{}) (string, error) {
+	if !isBlockCollection(value) {
+		scalar, err := yamlScalar(value)
+		if err != nil {
+			return "", err
+		}
+		return scalar + "\n", nil
+	}
+
+	lines, err := yamlBlock(value, 0)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+// Non empty maps and slices are written as blocks, everything else fits on the line
+func isBlockCollection(value interface{}) bool {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		return len(value) != 0
+	case []interface{}:
+		return len(value) != 0
+	}
+	return false
+}
+
+func yamlBlock(value interface{}, indent int) ([]string, error) {
+	pad := strings.Repeat(" ", indent)
+	lines := []string{}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedMapKeys(value) {
+			child := value[key]
+
+			if isBlockCollection(child) {
+				nested, err := yamlBlock(child, indent+2)
+				if err != nil {
+					return nil, err
+				}
+				lines = append(lines, pad+yamlString(key)+":")
+				lines = append(lines, nested...)
+				continue
+			}
+
+			scalar, err := yamlScalar(child)
+			if err != nil {
+				return nil, err
+			}
+			lines = append(lines, pad+yamlString(key)+": "+scalar)
+		}
+
+	case []interface{}:
+		for _, el := range value {
+			if isBlockCollection(el) {
+				nested, err := yamlBlock(el, indent+2)
+				if err != nil {
+					return nil, err
+				}
+				// - starts the first line of the nested block: "- key: value"
+				nested[0] = pad + "- " + nested[0][indent+2:]
+				lines = append(lines, nested...)
+				continue
+			}
+
+			scalar, err := yamlScalar(el)
+			if err != nil {
+				return nil, err
+			}
+			lines = append(lines, pad+"- "+scalar)
+		}
+	}
+
+	return lines, nil
+}
+
+func yamlScalar(value interface{}) (string, error) {
+	switch value := value.(type) {
+	case nil:
+		return "null", nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	case int64:
+		return strconv.FormatInt(value, 10), nil
+	case float64:
+		return yamlFloatString(value), nil
+	case string:
+		return yamlString(value), nil
+	case map[string]interface{}:
+		return "{}", nil
+	case []interface{}:
+		return "[]", nil
+	}
+
+	return "", fmt.Errorf("cannot encode %T", value)
+}
+
+func yamlFloatString(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return ".inf"
+	case math.IsInf(f, -1):
+		return "-.inf"
+	case math.IsNaN(f):
+		return ".nan"
+	}
+
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+// Quotes the string when it can't be written as a plain scalar
+func yamlString(s string) string {
+	if _, isString := resolveYAMLScalar(s).(string); !isString || s != strings.TrimSpace(s) {
+		return strconv.Quote(s)
+	}
+
+	if strings.ContainsRune("-?:,[]{}#&*!|>'\"%@`", rune(s[0])) ||
+		strings.HasSuffix(s, ":") || strings.Contains(s, ": ") || strings.Contains(s, " #") {
+		return strconv.Quote(s)
+	}
+
+	for _, ch := range s {
+		if ch < 0x20 || ch == 0x7f || !strconv.IsPrint(ch) {
+			return strconv.Quote(s)
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,207 @@
+package configfmt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeYAML(t *testing.T) {
+	input := `---
+# a comment
+name: monke # trailing comment
+version: 3
+ratio: 0.5
+enabled: yes
+debug: false
+nothing: ~
+empty:
+quoted: "tab\there"
+single: 'it''s'
+url: http://example.com
+sentence: don't # stop here
+tags: [a, 1, "c, d"]
+point: {x: 1, y: two}
+server:
+  host: localhost
+  ports:
+    - 8000
+    - 8001
+items:
+- name: a
+  size: 1
+- name: b
+- - nested
+  - list
+script: |
+  echo one
+    echo two
+
+folded: >-
+  a long
+  sentence
+
+  new paragraph
+...
+ignored: true
+`
+
+	expected := map[string]interface{}{
+		"name":     "monke",
+		"version":  int64(3),
+		"ratio":    0.5,
+		"enabled":  "yes",
+		"debug":    false,
+		"nothing":  nil,
+		"empty":    nil,
+		"quoted":   "tab\there",
+		"single":   "it's",
+		"url":      "http://example.com",
+		"sentence": "don't",
+		"tags":     []interface{}{"a", int64(1), "c, d"},
+		"point":    map[string]interface{}{"x": int64(1), "y": "two"},
+		"server": map[string]interface{}{
+			"host":  "localhost",
+			"ports": []interface{}{int64(8000), int64(8001)},
+		},
+		"items": []interface{}{
+			map[string]interface{}{"name": "a", "size": int64(1)},
+			map[string]interface{}{"name": "b"},
+			[]interface{}{"nested", "list"},
+		},
+		"script": "echo one\n  echo two\n",
+		"folded": "a long sentence\nnew paragraph",
+	}
+
+	decoded, err := DecodeYAML(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("wrong result, expected\n%#v\ngot\n%#v", expected, decoded)
+	}
+}
+
+func TestDecodeYAMLScalars(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"", nil},
+		{"hello", "hello"},
+		{"-12", int64(-12)},
+		{"0x1f", int64(31)},
+		{"1e3", 1000.0},
+		{"TRUE", true},
+		{`"5"`, "5"},
+		{"- a\n- b", []interface{}{"a", "b"}},
+		{"[]", []interface{}{}},
+		{"{}", map[string]interface{}{}},
+	}
+
+	for _, tt := range tests {
+		decoded, err := DecodeYAML(tt.input)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", tt.input, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(decoded, tt.expected) {
+			t.Errorf("%q: wrong result, expected %#v got %#v", tt.input, tt.expected, decoded)
+		}
+	}
+}
+
+func TestDecodeYAMLErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a: 1\na: 2", "line 2: key a is defined twice"},
+		{"a: 1\n  b: 2", "line 2: unexpected indentation"},
+		{"a: 1\nplain", `line 2: expected a mapping key (key: value), got "plain"`},
+		{"a: &anchor 1", `line 1: anchors, aliases and tags are not supported, got "&anchor 1"`},
+		{"a: [1, 2", "line 1: unterminated flow sequence"},
+		{`a: "open`, `line 1: unterminated string "open`},
+		{"a: 1\n---\nb: 2", "line 2: multiple documents are not supported"},
+		{"a:\n\t- 1", "line 2: tabs can't be used for indentation"},
+		{"a: first\n  second", "line 2: multi-line plain scalars are not supported, quote the value or use | or >"},
+		{"a:\n  first\n  second", "line 3: multi-line plain scalars are not supported, quote the value or use | or >"},
+		{"- first\n  second", "line 2: multi-line plain scalars are not supported, quote the value or use | or >"},
+		{"first\nsecond", "line 2: multi-line plain scalars are not supported, quote the value or use | or >"},
+		{"a: \"first\n  second\"", "line 1: unterminated string \"first"},
+		{"a: [1,\n  2]", "line 1: unterminated flow sequence"},
+		{"a: *anchor", `line 1: anchors, aliases and tags are not supported, got "*anchor"`},
+		{"a: !!str 1", `line 1: anchors, aliases and tags are not supported, got "!!str 1"`},
+		{"? a\n: 1", "line 1: complex keys (? key) are not supported"},
+	}
+
+	for _, tt := range tests {
+		_, err := DecodeYAML(tt.input)
+
+		if err == nil {
+			t.Errorf("%q: expected error %q, got none", tt.input, tt.expected)
+			continue
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("%q: wrong error, expected %q got %q", tt.input, tt.expected, err.Error())
+		}
+	}
+}
+
+func TestEncodeYAML(t *testing.T) {
+	input := map[string]interface{}{
+		"name":    "monke",
+		"count":   int64(3),
+		"ratio":   2.0,
+		"flag":    "true",
+		"number":  "5",
+		"colon":   "a: b",
+		"empty":   []interface{}{},
+		"nothing": nil,
+		"server":  map[string]interface{}{"host": "localhost", "ports": []interface{}{int64(80), int64(443)}},
+		"items": []interface{}{
+			map[string]interface{}{"name": "a", "size": int64(1)},
+			[]interface{}{"x", "y"},
+		},
+	}
+
+	expected := `colon: "a: b"
+count: 3
+empty: []
+flag: "true"
+items:
+  - name: a
+    size: 1
+  - - x
+    - y
+name: monke
+nothing: null
+number: "5"
+ratio: 2.0
+server:
+  host: localhost
+  ports:
+    - 80
+    - 443
+`
+
+	encoded, err := EncodeYAML(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if encoded != expected {
+		t.Errorf("wrong result, expected\n%s\ngot\n%s", expected, encoded)
+	}
+
+	decoded, err := DecodeYAML(encoded)
+	if err != nil {
+		t.Fatalf("could not decode the encoded document: %s", err)
+	}
+
+	if !reflect.DeepEqual(decoded, input) {
+		t.Errorf("round trip changed the value, expected\n%#v\ngot\n%#v", input, decoded)
+	}
+}
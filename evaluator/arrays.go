@@ -0,0 +1,217 @@
+package evaluator
+
+import (
+	"monkey/catalog"
+	"monkey/object"
+)
+
+/**
+Querying arrays without a manual loop:
+
+	index_of([1, 2, 3], 2)                 => 1 (-1 when it's not there)
+	find([1, 2, 3], fn(x) { x > 1 })       => 2 (null when nothing matches)
+	any([1, 2, 3], fn(x) { x > 2 })        => true
+	all([1, 2, 3], fn(x) { x > 2 })        => false
+	count([1, 2, 3], fn(x) { x % 2 == 1 }) => 2
+
+index_of compares with ==, the predicates are truthy or not (see isTruthy).
+An error in a predicate stops the search and is returned.
+**/
+func __index_of__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("index_of", args, object.Arg(object.ARRAY_OBJ), object.Arg()); err != nil {
+		return err
+	}
+
+	for idx, el := range args[0].(*object.Array).Elements {
+		// only == is evaluated, it doesn't need an environment
+		result := evalInfixExpression("==", el, args[1], nil)
+		if isError(result) {
+			return result
+		}
+		if isTruthy(result) {
+			return object.InternInteger(int64(idx))
+		}
+	}
+
+	return object.InternInteger(-1)
+}
+
+func __find__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("find", args, object.Arg(object.ARRAY_OBJ), object.Arg(object.FUNCTION_OBJ, object.BUILTIN_OBJ)); err != nil {
+		return err
+	}
+
+	for _, el := range args[0].(*object.Array).Elements {
+		result := callPredicate(args[1], el)
+		if isError(result) {
+			return result
+		}
+		if isTruthy(result) {
+			return el
+		}
+	}
+
+	return NULL
+}
+
+func __any__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("any", args, object.Arg(object.ARRAY_OBJ), object.Arg(object.FUNCTION_OBJ, object.BUILTIN_OBJ)); err != nil {
+		return err
+	}
+
+	matches, err := countMatches(args[0].(*object.Array), args[1], true)
+	if err != nil {
+		return err
+	}
+
+	return nativeBoolToBooleanObject(matches > 0)
+}
+
+func __all__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("all", args, object.Arg(object.ARRAY_OBJ), object.Arg(object.FUNCTION_OBJ, object.BUILTIN_OBJ)); err != nil {
+		return err
+	}
+
+	arr := args[0].(*object.Array)
+
+	// all of them match when none of them fails
+	for _, el := range arr.Elements {
+		result := callPredicate(args[1], el)
+		if isError(result) {
+			return result
+		}
+		if !isTruthy(result) {
+			return FALSE
+		}
+	}
+
+	return TRUE
+}
+
+func __count__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("count", args, object.Arg(object.ARRAY_OBJ), object.Arg(object.FUNCTION_OBJ, object.BUILTIN_OBJ)); err != nil {
+		return err
+	}
+
+	matches, err := countMatches(args[0].(*object.Array), args[1], false)
+	if err != nil {
+		return err
+	}
+
+	return object.InternInteger(matches)
+}
+
+// How many elements the predicate matches, stopping at the first one when firstOnly is set
+func countMatches(arr *object.Array, predicate object.Object, firstOnly bool) (int64, object.Object) {
+	var matches int64
+
+	for _, el := range arr.Elements {
+		result := callPredicate(predicate, el)
+		if isError(result) {
+			return 0, result
+		}
+		if !isTruthy(result) {
+			continue
+		}
+
+		matches++
+		if firstOnly {
+			break
+		}
+	}
+
+	return matches, nil
+}
+
+// Functions are called directly, applyFunction would take an element that's an array for a map call
+func callPredicate(predicate object.Object, el object.Object) object.Object {
+	if fn, ok := predicate.(*object.Function); ok && !fn.Generator {
+		return callFunction(fn, []object.Object{el})
+	}
+
+	return applyFunction(predicate, []object.Object{el})
+}
+
+/**
+Splitting and grouping arrays:
+
+	chunk([1, 2, 3, 4, 5], 2)                     => [[1, 2], [3, 4], [5]]
+	windows([1, 2, 3, 4], 2)                      => [[1, 2], [2, 3], [3, 4]]
+	group_by([1, 2, 3, 4], fn(x) { x % 2 == 0 })  => {false: [1, 3], true: [2, 4]}
+
+The arrays returned share the elements of the original one until one of them is written to, like slice.
+windows returns an empty array when the size is bigger than the array, group_by keeps the order of the elements
+within a group and stops at the first error returned by fn.
+**/
+func __chunk__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("chunk", args, object.Arg(object.ARRAY_OBJ), object.Arg(object.INTEGER_OBJ)); err != nil {
+		return err
+	}
+
+	arr, size := args[0].(*object.Array), args[1].(*object.Integer).Value
+	if size < 1 {
+		return newError(catalog.INVALID_SIZE, "chunk", size)
+	}
+
+	length := int64(len(arr.Elements))
+	chunks := []object.Object{}
+	for start := int64(0); start < length; start += size {
+		end := start + size
+		if end > length {
+			end = length
+		}
+
+		chunks = append(chunks, shareElements(arr, start, end))
+	}
+
+	return &object.Array{Elements: chunks}
+}
+
+func __windows__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("windows", args, object.Arg(object.ARRAY_OBJ), object.Arg(object.INTEGER_OBJ)); err != nil {
+		return err
+	}
+
+	arr, size := args[0].(*object.Array), args[1].(*object.Integer).Value
+	if size < 1 {
+		return newError(catalog.INVALID_SIZE, "windows", size)
+	}
+
+	windows := []object.Object{}
+	for start := int64(0); start+size <= int64(len(arr.Elements)); start++ {
+		windows = append(windows, shareElements(arr, start, start+size))
+	}
+
+	return &object.Array{Elements: windows}
+}
+
+func __group_by__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("group_by", args, object.Arg(object.ARRAY_OBJ), object.Arg(object.FUNCTION_OBJ, object.BUILTIN_OBJ)); err != nil {
+		return err
+	}
+
+	groups := make(map[object.HashKey]object.HashPair)
+
+	for _, el := range args[0].(*object.Array).Elements {
+		key := callPredicate(args[1], el)
+		if isError(key) {
+			return key
+		}
+
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return newError(catalog.UNUSABLE_HASH_KEY, key.Type())
+		}
+
+		pair, exists := groups[hashKey.HashKey()]
+		if !exists {
+			pair = object.HashPair{Key: key, Value: &object.Array{}}
+		}
+
+		group := pair.Value.(*object.Array)
+		group.Elements = append(group.Elements, el)
+		groups[hashKey.HashKey()] = pair
+	}
+
+	return object.NewHash(groups)
+}
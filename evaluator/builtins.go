@@ -2,71 +2,93 @@ package evaluator
 
 import (
 	"fmt"
+	"io"
+	"monkey/catalog"
 	"monkey/object"
+	"monkey/vfs"
+	"os"
+	"unicode/utf8"
 )
 
-type ErrorFormatter struct {
-	FuncName          string
-	ArgumentsExpected int //minimum arguments expected
-	Arguments         []object.Object
-}
-
 var BUILTIN = map[string]*object.Builtin{
 	//len()
-	"len":      {Fn: __len__},
-	"first":    {Fn: __first__},
-	"last":     {Fn: __last__},
-	"rest":     {Fn: __rest__},
-	"push":     {Fn: __push__},
-	"puts":     {Fn: __puts__},
-	"delete":   {Fn: __delete__},
-	"valuesAt": {Fn: __valuesAt__},
-	"toArray":  {Fn: __toArray__},
-	"dig":      {Fn: __dig__},
-	"map":      {Fn: __map__},
-	"pop":      {Fn: __pop__},
-	"shift":    {Fn: __shift__},
-	"slice":    {Fn: __slice__},
-}
-
-func checkForArrayErrors(formatter ErrorFormatter) object.Object {
-	args := formatter.Arguments
-	functionName := formatter.FuncName
-	argumentsExpected := formatter.ArgumentsExpected
-	if len(args) > argumentsExpected {
-		return newError("wrong number of arguments passed to %s. Got %d wanted %d", functionName, len(args), argumentsExpected)
-	}
-
-	if !isArray(args[0]) {
-		return newError("argument to `%s` must be ARRAY, got %s", functionName, args[0].Type())
-	}
-
-	return NULL
+	"len":           {Fn: __len__},
+	"first":         {Fn: __first__},
+	"last":          {Fn: __last__},
+	"rest":          {Fn: __rest__},
+	"push":          {Fn: __push__},
+	"puts":          {Fn: __puts__},
+	"delete":        {Fn: __delete__},
+	"valuesAt":      {Fn: __valuesAt__},
+	"toArray":       {Fn: __toArray__},
+	"dig":           {Fn: __dig__},
+	"map":           {Fn: __map__},
+	"index_of":      {Fn: __index_of__},
+	"find":          {Fn: __find__},
+	"any":           {Fn: __any__},
+	"all":           {Fn: __all__},
+	"count":         {Fn: __count__},
+	"chunk":         {Fn: __chunk__},
+	"windows":       {Fn: __windows__},
+	"group_by":      {Fn: __group_by__},
+	"pop":           {Fn: __pop__},
+	"set":           {Fn: __set__},
+	"insert":        {Fn: __insert__},
+	"remove":        {Fn: __remove__},
+	"shift":         {Fn: __shift__},
+	"slice":         {Fn: __slice__},
+	"chars":         {Fn: __chars__},
+	"bytes":         {Fn: __bytes__},
+	"byte_len":      {Fn: __byte_len__},
+	"reverse":       {Fn: __reverse__},
+	"lines":         {Fn: __lines__},
+	"ord":           {Fn: __ord__},
+	"chr":           {Fn: __chr__},
+	"get":           {Fn: __get__},
+	"withDefault":   {Fn: __withDefault__},
+	"next":          {Fn: __next__},
+	"take":          {Fn: __take__},
+	"inspect":       {Fn: __inspect__},
+	"read_file":     {Fn: readFileBuiltin(vfs.OS())},
+	"write_file":    {Fn: writeFileBuiltin(vfs.OS())},
+	"glob_match":    {Fn: __glob_match__},
+	"parse_int":     {Fn: __parse_int__},
+	"parse_float":   {Fn: __parse_float__},
+	"to_base":       {Fn: __to_base__},
+	"format_float":  {Fn: __format_float__},
+	"number_format": {Fn: __number_format__},
+	"csv_parse":     {Fn: __csv_parse__},
+	"csv_encode":    {Fn: __csv_encode__},
+	"toml_decode":   {Fn: __toml_decode__},
+	"toml_encode":   {Fn: __toml_encode__},
+	"yaml_decode":   {Fn: __yaml_decode__},
+	"yaml_encode":   {Fn: __yaml_encode__},
 }
 
+/**
+Number of elements of an array, bytes of a byte array, characters (not bytes) of a string:
+len("héllo") => 5, byte_len("héllo") => 6
+**/
 func __len__(args ...object.Object) object.Object {
-	// len() should only be passed 1 argument
-	if len(args) != 1 {
-		return newError("wrong number of arguments. got %d, wanted 1", len(args))
+	if err := object.CheckArgs("len", args, object.Arg(object.STRING_OBJ, object.ARRAY_OBJ, object.BYTES_OBJ)); err != nil {
+		return err
 	}
 
 	switch arg := args[0].(type) {
 
 	case *object.Array:
-		return &object.Integer{Value: int64(len(arg.Elements))}
+		return object.InternInteger(int64(len(arg.Elements)))
 
 	case *object.String:
-		return &object.Integer{Value: int64(len(arg.Value))}
+		return object.InternInteger(int64(utf8.RuneCountInString(arg.Value)))
 
 	default:
-		return newError("argument to `len` not supported, got %s", args[0].Type())
+		return object.InternInteger(int64(len(arg.(*object.Bytes).Value)))
 	}
 }
 
 func __first__(args ...object.Object) object.Object {
-	err := checkForArrayErrors(ErrorFormatter{FuncName: "first", ArgumentsExpected: 1, Arguments: args})
-
-	if err != NULL {
+	if err := object.CheckArgs("first", args, object.Arg(object.ARRAY_OBJ)); err != nil {
 		return err
 	}
 
@@ -80,9 +102,7 @@ func __first__(args ...object.Object) object.Object {
 }
 
 func __last__(args ...object.Object) object.Object {
-	err := checkForArrayErrors(ErrorFormatter{FuncName: "last", ArgumentsExpected: 1, Arguments: args})
-
-	if err != NULL {
+	if err := object.CheckArgs("last", args, object.Arg(object.ARRAY_OBJ)); err != nil {
 		return err
 	}
 
@@ -102,20 +122,16 @@ func __last__(args ...object.Object) object.Object {
 - Similar to the cdr function in Scheme (also similar to tail)
 **/
 func __rest__(args ...object.Object) object.Object {
-	err := checkForArrayErrors(ErrorFormatter{FuncName: "rest", ArgumentsExpected: 1, Arguments: args})
-
-	if err != NULL {
+	if err := object.CheckArgs("rest", args, object.Arg(object.ARRAY_OBJ)); err != nil {
 		return err
 	}
 
 	arr := args[0].(*object.Array)
 	length := len(arr.Elements)
 
+	// shares the elements, recursing with rest doesn't copy the array every time
 	if length > 0 {
-		newElements := make([]object.Object, length-1, length-1)
-		copy(newElements, arr.Elements[1:length])
-
-		return &object.Array{Elements: newElements}
+		return shareElements(arr, 1, int64(length))
 	}
 
 	return NULL
@@ -127,9 +143,7 @@ func __rest__(args ...object.Object) object.Object {
 - Arrays are immutable in monke-lang, so it doesn't modify the given array
 **/
 func __push__(args ...object.Object) object.Object {
-	err := checkForArrayErrors(ErrorFormatter{FuncName: "push", ArgumentsExpected: 2, Arguments: args})
-
-	if err != NULL {
+	if err := object.CheckArgs("push", args, object.Arg(object.ARRAY_OBJ), object.Arg()); err != nil {
 		return err
 	}
 
@@ -144,17 +158,29 @@ func __push__(args ...object.Object) object.Object {
 }
 
 func __puts__(args ...object.Object) object.Object {
+	return puts(os.Stdout, object.FormatOptions{}, args)
+}
+
+/**
+puts writing to out instead of stdout (ex: to capture what a script prints),
+values are formatted with env's display options, see object.Environment.SetDisplayOptions
+**/
+func Puts(env *object.Environment, out io.Writer) *object.Builtin {
+	return &object.Builtin{Doc: DOCS["puts"], Fn: func(args ...object.Object) object.Object {
+		return puts(out, env.DisplayOptions(), args)
+	}}
+}
+
+func puts(out io.Writer, opts object.FormatOptions, args []object.Object) object.Object {
 	for _, arg := range args {
-		fmt.Println(arg.Inspect())
+		fmt.Fprintln(out, object.Format(arg, opts))
 	}
 
 	return NULL
 }
 
 func __delete__(args ...object.Object) object.Object {
-	err := checkForHashErrors(ErrorFormatter{FuncName: "delete", ArgumentsExpected: 2, Arguments: args})
-
-	if err != NULL {
+	if err := object.CheckArgs("delete", args, object.Arg(object.HASH_OBJ), object.Arg(), object.RestArgs()); err != nil {
 		return err
 	}
 
@@ -167,19 +193,17 @@ func __delete__(args ...object.Object) object.Object {
 		hashKey, ok := arg.(object.Hashable)
 
 		if !ok {
-			return newError("Unusable value as hash key: %s", arg.Type())
+			return newError(catalog.UNUSABLE_HASH_KEY, arg.Type())
 		}
 
-		hash.Pairs[hashKey.HashKey()] = object.HashPair{Key: NULL, Value: NULL}
+		hash.Own()[hashKey.HashKey()] = object.HashPair{Key: NULL, Value: NULL}
 	}
 
 	return hash
 }
 
 func __valuesAt__(args ...object.Object) object.Object {
-	err := checkForHashErrors(ErrorFormatter{FuncName: "valuesAt", ArgumentsExpected: 2, Arguments: args})
-
-	if err != NULL {
+	if err := object.CheckArgs("valuesAt", args, object.Arg(object.HASH_OBJ), object.Arg(), object.RestArgs()); err != nil {
 		return err
 	}
 
@@ -194,20 +218,18 @@ func __valuesAt__(args ...object.Object) object.Object {
 		hashKey, ok := arg.(object.Hashable)
 
 		if !ok {
-			return newError("Unusable value as hash key: %s", arg.Type())
+			return newError(catalog.UNUSABLE_HASH_KEY, arg.Type())
 		}
 
 		// Grab the value at said key, append to array
-		arr.Elements = append(arr.Elements, hash.Pairs[hashKey.HashKey()].Value)
+		arr.Elements = append(arr.Elements, hash.Entries()[hashKey.HashKey()].Value)
 	}
 
 	return arr
 }
 
 func __toArray__(args ...object.Object) object.Object {
-	err := checkForHashErrors(ErrorFormatter{FuncName: "toArray", ArgumentsExpected: 1, Arguments: args})
-
-	if err != NULL {
+	if err := object.CheckArgs("toArray", args, object.Arg(object.HASH_OBJ)); err != nil {
 		return err
 	}
 
@@ -217,17 +239,18 @@ func __toArray__(args ...object.Object) object.Object {
 	// Create array object to store object values at x key
 	arr := &object.Array{}
 
-	for _, pair := range hash.Pairs {
-		arr.Elements = append(arr.Elements, pair.Key, pair.Value)
+	for _, pair := range hash.Entries() {
+		// deleted keys, see __delete__
+		if pair.Key != NULL {
+			arr.Elements = append(arr.Elements, pair.Key, pair.Value)
+		}
 	}
 
 	return arr
 }
 
 func __dig__(args ...object.Object) object.Object {
-	err := checkForHashErrors(ErrorFormatter{FuncName: "dig", ArgumentsExpected: 2, Arguments: args})
-
-	if err != NULL {
+	if err := object.CheckArgs("dig", args, object.Arg(object.HASH_OBJ), object.Arg(), object.RestArgs()); err != nil {
 		return err
 	}
 
@@ -241,10 +264,10 @@ func __dig__(args ...object.Object) object.Object {
 	hashKey, ok := args[1].(object.Hashable)
 
 	if !ok {
-		return newError("Unusable value as hash key: %s", args[1].Type())
+		return newError(catalog.UNUSABLE_HASH_KEY, args[1].Type())
 	}
 
-	extracted, exists := hash.Pairs[hashKey.HashKey()]
+	extracted, exists := hash.Entries()[hashKey.HashKey()]
 
 	if exists {
 		// if we only have 2 args (someInnerHash, key), and we've found the value exists then return it
@@ -261,33 +284,31 @@ func __dig__(args ...object.Object) object.Object {
 }
 
 func __map__(args ...object.Object) object.Object {
-	err := checkForArrayErrors(ErrorFormatter{FuncName: "map", ArgumentsExpected: 2, Arguments: args})
-
-	if err != NULL {
+	if err := object.CheckArgs("map", args, object.Arg(object.ARRAY_OBJ), object.Arg(object.FUNCTION_OBJ)); err != nil {
 		return err
 	}
 
 	//Grab the function from the args
-	function, exists := args[1].(*object.Function)
+	function := args[1].(*object.Function)
+
 	// remove the function from the list, so now we should only have the array arg
 	args = args[0:1]
 
-	if !exists {
-		return newError("second arguments to `map` should be a function, got %T instead", function.Type())
-	}
-
 	return applyFunction(function, args)
 }
 
 func __pop__(args ...object.Object) object.Object {
-	err := checkForArrayErrors(ErrorFormatter{FuncName: "pop", ArgumentsExpected: 1, Arguments: args})
-
-	if err != NULL {
+	if err := object.CheckArgs("pop", args, object.Arg(object.ARRAY_OBJ)); err != nil {
 		return err
 	}
 
 	arr := args[0].(*object.Array)
 
+	// nothing to remove, like first([]) and last([])
+	if len(arr.Elements) == 0 {
+		return NULL
+	}
+
 	val := arr.Elements[len(arr.Elements)-1]
 
 	arr.Elements = arr.Elements[0 : len(arr.Elements)-1]
@@ -296,14 +317,16 @@ func __pop__(args ...object.Object) object.Object {
 }
 
 func __shift__(args ...object.Object) object.Object {
-	err := checkForArrayErrors(ErrorFormatter{FuncName: "shift", ArgumentsExpected: 1, Arguments: args})
-
-	if err != NULL {
+	if err := object.CheckArgs("shift", args, object.Arg(object.ARRAY_OBJ)); err != nil {
 		return err
 	}
 
 	arr := args[0].(*object.Array)
 
+	if len(arr.Elements) == 0 {
+		return NULL
+	}
+
 	val := arr.Elements[0]
 
 	arr.Elements = arr.Elements[1:]
@@ -312,66 +335,309 @@ func __shift__(args ...object.Object) object.Object {
 }
 
 func __slice__(args ...object.Object) object.Object {
-	err := checkForArrayErrors(ErrorFormatter{FuncName: "slice", ArgumentsExpected: 3, Arguments: args})
-
-	if err != NULL {
+	if err := object.CheckArgs("slice", args, object.Arg(object.ARRAY_OBJ, object.STRING_OBJ), object.OptionalArg(object.INTEGER_OBJ), object.OptionalArg(object.INTEGER_OBJ)); err != nil {
 		return err
 	}
 
-	if len(args) > 3 {
-		return newError("Too many values passed to `slice`, expected 3 at most, got %d instead", len(args))
+	if str, ok := args[0].(*object.String); ok {
+		return sliceString(str, args[1:])
 	}
 
 	arr := args[0].(*object.Array)
-	arrCopy := &object.Array{Elements: arr.Elements[:]}
+	bounds := []int64{0, -1}
 
-	// If its just an array, return a copy of the array
-	if len(args[1:]) == 0 {
-		return arrCopy
+	// Make sure the indexes aren't negative
+	for pos, arg := range args[1:] {
+		obj := arg.(*object.Integer)
+		if obj.Value < 0 {
+			return newError(catalog.NEGATIVE_INDEX, obj.Value)
+		}
+		bounds[pos] = obj.Value
 	}
 
-	// Make sure all other args are int values
-	for _, arg := range args[1:] {
-		obj, isInt := arg.(*object.Integer)
+	// same as strings, indexes past the end stop at the end: slice([1, 2, 3], 5) => []
+	start, end := clampSlice(bounds, len(arr.Elements))
 
-		if !isInt {
-			return newError("expected an integer, got a type of %T instead", arg.Type())
-		}
+	return shareElements(arr, int64(start), int64(end))
+}
 
-		if isInt {
-			if obj.Value < 0 {
-				return newError("Negative indexes not supported (yet), recieved value of %d", obj.Value)
-			}
-		}
+/**
+Returns the value at the given key, or the default value if the key doesn't exist.
+- get(hash, key, default)
+- get(hash, key) => same as hash[key], falls back to the hash's default (or null)
+**/
+func __get__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("get", args, object.Arg(object.HASH_OBJ), object.Arg(), object.OptionalArg()); err != nil {
+		return err
+	}
+
+	hash := args[0].(*object.Hash)
 
+	hashKey, ok := args[1].(object.Hashable)
+	if !ok {
+		return newError(catalog.UNUSABLE_HASH_KEY, args[1].Type())
 	}
 
-	firstIdx := args[1].(*object.Integer).Value
+	// deleted keys are kept around with a null key, see __delete__
+	if pair, exists := hash.Entries()[hashKey.HashKey()]; exists && pair.Key != NULL {
+		return pair.Value
+	}
 
-	// two index values passed
 	if len(args) == 3 {
-		secondIdx := args[2].(*object.Integer).Value
-		arrCopy.Elements = arrCopy.Elements[firstIdx:secondIdx]
+		return args[2]
+	}
+
+	return hashDefault(hash)
+}
+
+/**
+Returns a copy of the hash that returns the given value when indexing a missing key.
+
+ex:
+let counts = withDefault({}, 0);
+counts["monke"] = counts["monke"] + 1;
+
+The value is shared by every missing key, so a function is called instead (without arguments)
+to get a new value each time: withDefault({}, fn() { [] }).
+**/
+func __withDefault__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("withDefault", args, object.Arg(object.HASH_OBJ), object.Arg()); err != nil {
+		return err
+	}
+
+	if fn, ok := args[1].(*object.Function); ok && len(fn.Parameters) != 0 {
+		return newError(catalog.ARGUMENT_NOT_SUPPORTED, "withDefault", "a function with parameters (it's called without arguments)")
+	}
 
-		return arrCopy
+	hash := args[0].(*object.Hash)
+	entries := hash.Entries()
+	pairs := make(map[object.HashKey]object.HashPair, len(entries))
+
+	for key, pair := range entries {
+		pairs[key] = pair
 	}
 
-	//only one index value passed
-	arrCopy.Elements = arrCopy.Elements[firstIdx:]
+	withDefault := object.NewHash(pairs)
+	withDefault.Default = args[1]
 
-	return arrCopy
+	return withDefault
 }
 
-func checkForHashErrors(formatter ErrorFormatter) object.Object {
-	args, functionName, argumentsExpected := formatter.Arguments, formatter.FuncName, formatter.ArgumentsExpected
+// The value of a missing key: the result of calling the default when it's a function, null without one
+func hashDefault(hash *object.Hash) object.Object {
+	switch hash.Default.(type) {
+	case nil:
+		return NULL
+	case *object.Function, *object.Builtin:
+		return applyFunction(hash.Default, []object.Object{})
+	default:
+		return hash.Default
+	}
+}
 
-	if len(args) < argumentsExpected {
-		return newError("wrong number of arguments, got %d wanted %d", len(args), argumentsExpected)
+// Splits a string into an array of single character strings: chars("abc") => [a, b, c]
+func __chars__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("chars", args, object.Arg(object.STRING_OBJ)); err != nil {
+		return err
 	}
 
-	if !isHash(args[0]) {
-		return newError("argument to `%s` must be HASH, got %s", functionName, args[0].Type())
+	str := args[0].(*object.String)
+	arr := &object.Array{Elements: []object.Object{}}
+
+	// ranging over a string gives us runes, so multi-byte characters stay in one piece
+	for _, r := range str.Value {
+		arr.Elements = append(arr.Elements, object.InternString(string(r)))
 	}
 
-	return NULL
+	return arr
+}
+
+// Returns the raw (UTF-8) bytes of a string: bytes("hi") => bytes[104, 105]
+func __bytes__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("bytes", args, object.Arg(object.STRING_OBJ)); err != nil {
+		return err
+	}
+
+	str := args[0].(*object.String)
+
+	return &object.Bytes{Value: []byte(str.Value)}
+}
+
+// Returns the code point of a single character string: ord("a") => 97
+func __ord__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("ord", args, object.Arg(object.STRING_OBJ)); err != nil {
+		return err
+	}
+
+	str := args[0].(*object.String)
+
+	if utf8.RuneCountInString(str.Value) != 1 {
+		return newError(catalog.INVALID_CHARACTER, "ord", str.Value)
+	}
+
+	r, _ := utf8.DecodeRuneInString(str.Value)
+
+	return object.InternInteger(int64(r))
+}
+
+// Returns the single character string for a code point: chr(97) => "a"
+func __chr__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("chr", args, object.Arg(object.INTEGER_OBJ)); err != nil {
+		return err
+	}
+
+	codePoint := args[0].(*object.Integer)
+
+	if codePoint.Value < 0 || codePoint.Value > utf8.MaxRune || !utf8.ValidRune(rune(codePoint.Value)) {
+		return newError(catalog.INVALID_CODE_POINT, "chr", codePoint.Value)
+	}
+
+	return object.InternString(string(rune(codePoint.Value)))
+}
+
+/**
+Returns the next value of a generator, null once the generator is finished.
+
+ex:
+let gen = fn*() { yield 1; };
+next(gen) => 1
+next(gen) => null
+**/
+func __next__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("next", args, object.Arg(object.GENERATOR_OBJ)); err != nil {
+		return err
+	}
+
+	gen := args[0].(*object.Generator)
+
+	val, ok := generatorNext(gen)
+	if !ok {
+		return NULL
+	}
+
+	return val
+}
+
+/**
+Returns an array with (up to) the next n values of a generator,
+useful for generators that never finish.
+The generator is closed afterwards (see object.Generator.Close), so its body doesn't wait for a value nobody asks for,
+use next to keep reading a generator.
+
+ex:
+let naturals = fn*() { for (let i = 0; i > -1; i = i + 1) { yield i; } };
+take(naturals(), 3) => [0, 1, 2]
+**/
+func __take__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("take", args, object.Arg(object.GENERATOR_OBJ), object.Arg(object.INTEGER_OBJ)); err != nil {
+		return err
+	}
+
+	gen, count := args[0].(*object.Generator), args[1].(*object.Integer)
+
+	if count.Value < 0 {
+		return newError(catalog.NEGATIVE_INDEX, count.Value)
+	}
+
+	// the body taking from its own generator gets an error, it can't be closed from there
+	defer func() {
+		if !gen.Running() {
+			gen.Close()
+		}
+	}()
+
+	elements := []object.Object{}
+
+	for i := int64(0); i < count.Value; i++ {
+		val, ok := generatorNext(gen)
+		if !ok {
+			break
+		}
+
+		if isError(val) {
+			return val
+		}
+
+		elements = append(elements, val)
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+/**
+Returns the string representation of a value, same as what the global puts prints.
+Options can be passed in a hash to keep large nested structures readable:
+
+- "depth": nested arrays/hashes deeper than this are printed as [...] / {...}
+- "elements": max number of elements/pairs printed per array/hash
+- "multiline": one element per line
+
+ex:
+inspect([1, [2, [3]]], { "depth": 2 }) => "[1, [2, [...]]]"
+**/
+func __inspect__(args ...object.Object) object.Object {
+	return inspect(object.FormatOptions{}, args)
+}
+
+// inspect starting from env's display options, the ones puts uses in env
+func Inspect(env *object.Environment) *object.Builtin {
+	return &object.Builtin{Doc: DOCS["inspect"], Fn: func(args ...object.Object) object.Object {
+		return inspect(env.DisplayOptions(), args)
+	}}
+}
+
+func inspect(opts object.FormatOptions, args []object.Object) object.Object {
+	if err := object.CheckArgs("inspect", args, object.Arg(), object.OptionalArg(object.HASH_OBJ)); err != nil {
+		return err
+	}
+
+	if len(args) == 2 {
+		hash := args[1].(*object.Hash)
+
+		for _, pair := range hash.Entries() {
+			// deleted keys, see __delete__
+			if pair.Key == NULL {
+				continue
+			}
+
+			if err := setFormatOption(&opts, pair.Key, pair.Value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return &object.String{Value: object.Format(args[0], opts)}
+}
+
+func setFormatOption(opts *object.FormatOptions, key, value object.Object) *object.Error {
+	switch key.Inspect() {
+	case "depth", "elements":
+		limit, ok := value.(*object.Integer)
+		if !ok {
+			return newError(catalog.WRONG_ARGUMENT_TYPE, "inspect", 2, optionType(object.INTEGER_OBJ, key), typeOf(value))
+		}
+
+		if key.Inspect() == "depth" {
+			opts.MaxDepth = int(limit.Value)
+		} else {
+			opts.MaxElements = int(limit.Value)
+		}
+
+	case "multiline":
+		multiline, ok := value.(*object.Boolean)
+		if !ok {
+			return newError(catalog.WRONG_ARGUMENT_TYPE, "inspect", 2, optionType(object.BOOLEAN_OBJ, key), typeOf(value))
+		}
+		opts.Multiline = multiline.Value
+
+	default:
+		return newError(catalog.ARGUMENT_NOT_SUPPORTED, "inspect", key.Inspect())
+	}
+
+	return nil
+}
+
+// The expected type of an option passed in a hash: INTEGER for "depth"
+func optionType(t object.ObjectType, key object.Object) string {
+	return fmt.Sprintf("%s for %q", t, key.Inspect())
 }
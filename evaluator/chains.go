@@ -0,0 +1,75 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/catalog"
+	"monkey/object"
+)
+
+/**
+Evaluates an infix expression and the ones nested on its left side without recursion.
+
+Left associative operators nest to the left, so 1 + 2 + 3 + 4 is ((1 + 2) + 3) + 4 and generated code
+(a sum of thousands of terms, etc) ends up with an AST as deep as the expression is long.
+Evaluating each left side with Eval would use a Go stack frame per operator, instead:
+- walk down the left sides first, keeping the infix expressions found on an explicit stack
+- evaluate the innermost left side, then apply the operators from the inside out
+
+Each nested infix expression still costs one unit of fuel and is seen by the hooks, in the same order as Eval.
+The node itself was already charged and hooked by Eval.
+**/
+func evalInfixChain(node *ast.InfixExpression, env *object.Environment) object.Object {
+	hooks := env.Hooks()
+	chain := []*ast.InfixExpression{node}
+
+	var result object.Object
+	for {
+		inner, ok := chain[len(chain)-1].Left.(*ast.InfixExpression)
+		if !ok {
+			result = Eval(chain[len(chain)-1].Left, env)
+			break
+		}
+
+		if !env.UseFuel() {
+			result = newError(catalog.FUEL_LIMIT, env.Limits().Fuel)
+			break
+		}
+
+		beforeNode(hooks, inner, env)
+		chain = append(chain, inner)
+	}
+
+	for idx := len(chain) - 1; idx >= 0; idx-- {
+		// an error on the left side is the result of every expression wrapping it
+		if !isError(result) {
+			result = evalInfixOperator(chain[idx], result, env)
+		}
+
+		// Eval calls the hooks after the outermost one
+		if idx > 0 {
+			afterNode(hooks, chain[idx], result)
+		}
+	}
+
+	return result
+}
+
+// Evaluates the right side of an infix expression and applies the operator, the left side is already evaluated
+func evalInfixOperator(node *ast.InfixExpression, left object.Object, env *object.Environment) object.Object {
+	// && and || only evaluate the right side when the left one doesn't decide the result
+	if node.Operator == "&&" || node.Operator == "||" {
+		return evalLogicalExpression(node, left, env)
+	}
+
+	right := Eval(node.Right, env)
+
+	if isError(right) {
+		return right
+	}
+
+	if node.Operator == "/" && bothAreIntegers(left, right) {
+		warnIntegerDivision(node.Token, left, right, env)
+	}
+
+	return evalInfixExpression(node.Operator, left, right, env)
+}
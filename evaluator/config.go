@@ -0,0 +1,169 @@
+package evaluator
+
+import (
+	"fmt"
+	"monkey/catalog"
+	"monkey/configfmt"
+	"monkey/object"
+)
+
+/**
+Decodes a TOML document into a hash:
+toml_decode("[server]\nport = 8080") => {server: {port: 8080}}
+
+Tables become hashes, dates and times are returned as strings.
+**/
+func __toml_decode__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("toml_decode", args, object.Arg(object.STRING_OBJ)); err != nil {
+		return err
+	}
+
+	decoded, err := configfmt.DecodeTOML(args[0].(*object.String).Value)
+	if err != nil {
+		return newError(catalog.INVALID_TOML, "toml_decode", err)
+	}
+
+	return configToObject(decoded)
+}
+
+/**
+Encodes a hash as a TOML document, the opposite of toml_decode.
+Keys have to be strings and TOML has no null, so null values return an error.
+**/
+func __toml_encode__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("toml_encode", args, object.Arg(object.HASH_OBJ)); err != nil {
+		return err
+	}
+
+	value, err := objectToConfig(args[0])
+	if err != nil {
+		return newError(catalog.INVALID_TOML, "toml_encode", err)
+	}
+
+	encoded, err := configfmt.EncodeTOML(value.(map[string]interface{}))
+	if err != nil {
+		return newError(catalog.INVALID_TOML, "toml_encode", err)
+	}
+
+	return &object.String{Value: encoded}
+}
+
+/**
+Decodes a YAML document into hashes, arrays, strings, numbers, booleans and null:
+yaml_decode("ports:\n  - 80\n  - 443") => {ports: [80, 443]}
+
+Anchors, aliases, tags and multiple documents aren't supported (see configfmt.DecodeYAML).
+**/
+func __yaml_decode__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("yaml_decode", args, object.Arg(object.STRING_OBJ)); err != nil {
+		return err
+	}
+
+	decoded, err := configfmt.DecodeYAML(args[0].(*object.String).Value)
+	if err != nil {
+		return newError(catalog.INVALID_YAML, "yaml_decode", err)
+	}
+
+	return configToObject(decoded)
+}
+
+// Encodes any data value as a YAML document, the opposite of yaml_decode
+func __yaml_encode__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("yaml_encode", args, object.Arg()); err != nil {
+		return err
+	}
+
+	value, err := objectToConfig(args[0])
+	if err != nil {
+		return newError(catalog.INVALID_YAML, "yaml_encode", err)
+	}
+
+	encoded, err := configfmt.EncodeYAML(value)
+	if err != nil {
+		return newError(catalog.INVALID_YAML, "yaml_encode", err)
+	}
+
+	return &object.String{Value: encoded}
+}
+
+// Decoded config values (see configfmt) => objects
+func configToObject(value interface{}) object.Object {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		pairs := make(map[object.HashKey]object.HashPair)
+		for key, el := range value {
+			hashKey := &object.String{Value: key}
+			pairs[hashKey.HashKey()] = object.HashPair{Key: hashKey, Value: configToObject(el)}
+		}
+		return object.NewHash(pairs)
+
+	case []interface{}:
+		arr := &object.Array{Elements: make([]object.Object, len(value))}
+		for idx, el := range value {
+			arr.Elements[idx] = configToObject(el)
+		}
+		return arr
+
+	case string:
+		return &object.String{Value: value}
+	case int64:
+		return object.InternInteger(value)
+	case float64:
+		return &object.Float{Value: value}
+	case bool:
+		return nativeBoolToBooleanObject(value)
+	}
+
+	return NULL
+}
+
+// Objects => values configfmt can encode, hash keys have to be strings
+func objectToConfig(obj object.Object) (interface{}, error) {
+	switch obj := obj.(type) {
+	case *object.Hash:
+		table := make(map[string]interface{})
+
+		for _, pair := range obj.Entries() {
+			// deleted keys, see __delete__
+			if pair.Key == NULL {
+				continue
+			}
+
+			key, ok := pair.Key.(*object.String)
+			if !ok {
+				return nil, fmt.Errorf("keys must be strings, got %s", pair.Key.Type())
+			}
+
+			value, err := objectToConfig(pair.Value)
+			if err != nil {
+				return nil, err
+			}
+			table[key.Value] = value
+		}
+		return table, nil
+
+	case *object.Array:
+		arr := make([]interface{}, len(obj.Elements))
+		for idx, el := range obj.Elements {
+			value, err := objectToConfig(el)
+			if err != nil {
+				return nil, err
+			}
+			arr[idx] = value
+		}
+		return arr, nil
+
+	case *object.String:
+		return obj.Value, nil
+	case *object.Integer:
+		return obj.Value, nil
+	case *object.Float:
+		return obj.Value, nil
+	case *object.Boolean:
+		return obj.Value, nil
+	case *object.Null:
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("cannot encode %s", typeOf(obj))
+}
@@ -0,0 +1,209 @@
+package evaluator
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"monkey/catalog"
+	"monkey/object"
+	"sort"
+	"unicode/utf8"
+)
+
+// Settings shared by csv_parse and csv_encode, see csvOptions
+type csvSettings struct {
+	header    bool
+	separator rune
+}
+
+/**
+Parses CSV text into an array of rows, every row an array of strings:
+csv_parse("a,b\n1,2") => [[a, b], [1, 2]]
+
+options (optional hash):
+- "header": the first row names the columns, the other rows are returned as hashes:
+  csv_parse("a,b\n1,2", {"header": true}) => [{a: 1, b: 2}]
+- "separator": a single character, "," by default
+
+Quoted fields can contain separators, quotes ("") and newlines. Every row needs as many fields as the first one.
+**/
+func __csv_parse__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("csv_parse", args, object.Arg(object.STRING_OBJ), object.OptionalArg(object.HASH_OBJ)); err != nil {
+		return err
+	}
+
+	settings, err := csvOptions("csv_parse", args)
+	if err != nil {
+		return err
+	}
+
+	reader := csv.NewReader(bytes.NewBufferString(args[0].(*object.String).Value))
+	reader.Comma = settings.separator
+
+	records, readErr := reader.ReadAll()
+	if readErr != nil {
+		return newError(catalog.INVALID_CSV, "csv_parse", readErr)
+	}
+
+	rows := &object.Array{Elements: []object.Object{}}
+
+	if !settings.header {
+		for _, record := range records {
+			rows.Elements = append(rows.Elements, stringArray(record))
+		}
+		return rows
+	}
+
+	if len(records) == 0 {
+		return rows
+	}
+
+	columns := records[0]
+	for _, record := range records[1:] {
+		row := make(map[object.HashKey]object.HashPair)
+
+		for idx, column := range columns {
+			key := &object.String{Value: column}
+			row[key.HashKey()] = object.HashPair{Key: key, Value: &object.String{Value: record[idx]}}
+		}
+
+		rows.Elements = append(rows.Elements, object.NewHash(row))
+	}
+
+	return rows
+}
+
+/**
+Encodes rows into CSV text, the opposite of csv_parse:
+csv_encode([["a", "b"], [1, 2]]) => "a,b\n1,2\n"
+
+- rows are arrays, or hashes: the sorted keys of the first hash are written as a header,
+  and every row is written in that column order (missing keys are empty)
+- strings are written as they are, null as an empty field, anything else as puts would print it
+- options (optional hash): "separator", same as csv_parse
+**/
+func __csv_encode__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("csv_encode", args, object.Arg(object.ARRAY_OBJ), object.OptionalArg(object.HASH_OBJ)); err != nil {
+		return err
+	}
+
+	settings, err := csvOptions("csv_encode", args)
+	if err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	writer := csv.NewWriter(&out)
+	writer.Comma = settings.separator
+
+	var columns []string
+
+	for idx, row := range args[0].(*object.Array).Elements {
+		var record []string
+
+		switch row := row.(type) {
+		case *object.Array:
+			for _, el := range row.Elements {
+				record = append(record, csvField(el))
+			}
+
+		case *object.Hash:
+			if columns == nil {
+				columns = sortedKeys(row)
+				writer.Write(columns)
+			}
+
+			for _, column := range columns {
+				key := &object.String{Value: column}
+				pair, exists := row.Entries()[key.HashKey()]
+
+				if !exists || pair.Key == NULL {
+					record = append(record, "")
+					continue
+				}
+				record = append(record, csvField(pair.Value))
+			}
+
+		default:
+			rowType := fmt.Sprintf("ARRAY or HASH for row %d", idx+1)
+			return newError(catalog.WRONG_ARGUMENT_TYPE, "csv_encode", 1, rowType, typeOf(row))
+		}
+
+		writer.Write(record)
+	}
+
+	writer.Flush()
+	if writeErr := writer.Error(); writeErr != nil {
+		return newError(catalog.INVALID_CSV, "csv_encode", writeErr)
+	}
+
+	return &object.String{Value: out.String()}
+}
+
+// Reads the options hash passed as the second argument, if any
+func csvOptions(funcName string, args []object.Object) (csvSettings, *object.Error) {
+	settings := csvSettings{separator: ','}
+
+	if len(args) != 2 {
+		return settings, nil
+	}
+
+	for _, pair := range args[1].(*object.Hash).Entries() {
+		// deleted keys, see __delete__
+		if pair.Key == NULL {
+			continue
+		}
+
+		switch pair.Key.Inspect() {
+		case "header":
+			header, ok := pair.Value.(*object.Boolean)
+			if !ok {
+				return settings, newError(catalog.WRONG_ARGUMENT_TYPE, funcName, 2, optionType(object.BOOLEAN_OBJ, pair.Key), typeOf(pair.Value))
+			}
+			settings.header = header.Value
+
+		case "separator":
+			separator, ok := pair.Value.(*object.String)
+			if !ok {
+				return settings, newError(catalog.WRONG_ARGUMENT_TYPE, funcName, 2, optionType(object.STRING_OBJ, pair.Key), typeOf(pair.Value))
+			}
+
+			r, size := utf8.DecodeRuneInString(separator.Value)
+			if size == 0 || size != len(separator.Value) || r == '"' || r == '\n' || r == '\r' {
+				return settings, newError(catalog.INVALID_CHARACTER, funcName, separator.Value)
+			}
+			settings.separator = r
+
+		default:
+			return settings, newError(catalog.ARGUMENT_NOT_SUPPORTED, funcName, pair.Key.Inspect())
+		}
+	}
+
+	return settings, nil
+}
+
+func csvField(value object.Object) string {
+	switch value := value.(type) {
+	case *object.String:
+		return value.Value
+	case *object.Null:
+		return ""
+	default:
+		return object.Format(value, object.FormatOptions{})
+	}
+}
+
+// Keys of the hash as strings, sorted so the columns don't depend on the map's order
+func sortedKeys(hash *object.Hash) []string {
+	keys := []string{}
+
+	for _, pair := range hash.Entries() {
+		if pair.Key == NULL {
+			continue
+		}
+		keys = append(keys, csvField(pair.Key))
+	}
+
+	sort.Strings(keys)
+	return keys
+}
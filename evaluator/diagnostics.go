@@ -0,0 +1,38 @@
+package evaluator
+
+import (
+	"monkey/catalog"
+	"monkey/object"
+	"monkey/token"
+)
+
+/**
+Reports integer divisions that discard a remainder (W4001) when diagnostics are on, see object.Environment.SetDiagnostics:
+
+	7 / 2  // integer division discards the remainder: 7 / 2 is 3, divide a float to get 3.5
+
+Helps scripts written expecting float division, the result is still 3.
+The diagnostic points at the / operator.
+**/
+func warnIntegerDivision(operator token.Token, left, right object.Object, env *object.Environment) {
+	report := env.Diagnostics()
+	if report == nil {
+		return
+	}
+
+	leftVal := left.(*object.Integer).Value
+	rightVal := right.(*object.Integer).Value
+
+	// nothing is discarded when dividing by zero (and % would panic)
+	if rightVal == 0 || leftVal%rightVal == 0 {
+		return
+	}
+
+	exact := &object.Float{Value: float64(leftVal) / float64(rightVal)}
+	report(object.Diagnostic{
+		Code:    string(catalog.INTEGER_DIVISION_TRUNCATED),
+		Message: catalog.Message(catalog.INTEGER_DIVISION_TRUNCATED, leftVal, rightVal, leftVal/rightVal, exact.Inspect()),
+		Line:    operator.Line,
+		Column:  operator.Column,
+	})
+}
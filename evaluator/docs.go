@@ -0,0 +1,246 @@
+package evaluator
+
+import (
+	"fmt"
+	"io"
+	"monkey/catalog"
+	"monkey/object"
+	"sort"
+	"strings"
+)
+
+/**
+Documentation of the global builtins, by name. Attached to the builtins in BUILTIN on init,
+the ones created for an environment (repeat, set_timeout, etc) get theirs when they're created.
+
+Shown by help("len") and the REPL's :doc len, see Describe.
+**/
+var DOCS = docsByName(
+	&object.BuiltinDoc{Name: "len", Signature: "len(value)", Help: "Number of elements of an array, bytes of a byte array, characters (not bytes) of a string.\nlen(\"héllo\") => 5"},
+	&object.BuiltinDoc{Name: "first", Signature: "first(array)", Help: "The first element of the array, null when it's empty."},
+	&object.BuiltinDoc{Name: "last", Signature: "last(array)", Help: "The last element of the array, null when it's empty."},
+	&object.BuiltinDoc{Name: "rest", Signature: "rest(array)", Help: "A new array with every element except the first one, null when it's empty."},
+	&object.BuiltinDoc{Name: "push", Signature: "push(array, value)", Help: "A new array with the value added at the end, the array isn't changed."},
+	&object.BuiltinDoc{Name: "puts", Signature: "puts(values...)", Help: "Prints every value on its own line, returns null."},
+	&object.BuiltinDoc{Name: "delete", Signature: "delete(hash, keys...)", Help: "Removes the keys from the hash, returns the hash."},
+	&object.BuiltinDoc{Name: "valuesAt", Signature: "valuesAt(hash, keys...)", Help: "An array with the value of every key, in the order of the keys."},
+	&object.BuiltinDoc{Name: "toArray", Signature: "toArray(hash)", Help: "An array with the keys and values of the hash: [key, value, key, value, ...]"},
+	&object.BuiltinDoc{Name: "dig", Signature: "dig(hash, keys...)", Help: "Follows the keys through nested hashes, dig(h, \"a\", \"b\") is h[\"a\"][\"b\"]."},
+	&object.BuiltinDoc{Name: "map", Signature: "map(array, fn)", Help: "A new array with the result of calling fn with every element."},
+	&object.BuiltinDoc{Name: "index_of", Signature: "index_of(array, value)", Help: "Index of the first element equal (==) to the value, -1 when there's none."},
+	&object.BuiltinDoc{Name: "find", Signature: "find(array, fn)", Help: "The first element fn returns a truthy value for, null when there's none."},
+	&object.BuiltinDoc{Name: "any", Signature: "any(array, fn)", Help: "Whether fn returns a truthy value for at least one element."},
+	&object.BuiltinDoc{Name: "all", Signature: "all(array, fn)", Help: "Whether fn returns a truthy value for every element."},
+	&object.BuiltinDoc{Name: "count", Signature: "count(array, fn)", Help: "How many elements fn returns a truthy value for."},
+	&object.BuiltinDoc{Name: "chunk", Signature: "chunk(array, size)", Help: "The array split into arrays of size elements, the last one can be shorter.\nchunk([1, 2, 3], 2) => [[1, 2], [3]]"},
+	&object.BuiltinDoc{Name: "windows", Signature: "windows(array, size)", Help: "Every run of size consecutive elements, in order.\nwindows([1, 2, 3], 2) => [[1, 2], [2, 3]]"},
+	&object.BuiltinDoc{Name: "group_by", Signature: "group_by(array, fn)", Help: "A hash of the elements grouped by what fn returns for them.\ngroup_by([\"ab\", \"c\"], len) => {2: [ab], 1: [c]}"},
+	&object.BuiltinDoc{Name: "pop", Signature: "pop(array)", Help: "Removes the last element from the array and returns it, null when it's empty."},
+	&object.BuiltinDoc{Name: "set", Signature: "set(hash, key, value)", Help: "A copy of the hash with the key set to the value, the hash isn't changed."},
+	&object.BuiltinDoc{Name: "insert", Signature: "insert(array, index, value)", Help: "A copy of the array with the value inserted at the index (len(array) adds it at the end)."},
+	&object.BuiltinDoc{Name: "remove", Signature: "remove(array, index)", Help: "A copy of the array without the element at the index."},
+	&object.BuiltinDoc{Name: "shift", Signature: "shift(array)", Help: "Removes the first element from the array and returns it, null when it's empty."},
+	&object.BuiltinDoc{Name: "slice", Signature: "slice(value[, start[, end]])", Help: "The elements of an array (or characters of a string) from start up to end, indexes past the end stop at the end.\nslice(\"héllo\", 1, 3) => \"él\""},
+	&object.BuiltinDoc{Name: "chars", Signature: "chars(str)", Help: "An array with every character of the string: chars(\"abc\") => [a, b, c]"},
+	&object.BuiltinDoc{Name: "bytes", Signature: "bytes(str)", Help: "The UTF-8 bytes of the string: bytes(\"hi\") => bytes[104, 105]"},
+	&object.BuiltinDoc{Name: "byte_len", Signature: "byte_len(str)", Help: "Size of the string's UTF-8 encoding: byte_len(\"héllo\") => 6"},
+	&object.BuiltinDoc{Name: "reverse", Signature: "reverse(str)", Help: "The characters of the string in reverse order."},
+	&object.BuiltinDoc{Name: "lines", Signature: "lines(str)", Help: "The lines of the string, split on \\n and \\r\\n."},
+	&object.BuiltinDoc{Name: "ord", Signature: "ord(char)", Help: "Code point of a single character string: ord(\"a\") => 97"},
+	&object.BuiltinDoc{Name: "chr", Signature: "chr(code)", Help: "Single character string for a code point: chr(97) => \"a\""},
+	&object.BuiltinDoc{Name: "get", Signature: "get(hash, key[, default])", Help: "The value at the key, or the default when the key doesn't exist."},
+	&object.BuiltinDoc{Name: "withDefault", Signature: "withDefault(hash, value)", Help: "A copy of the hash that returns the value when indexing a missing key (or deleted one).\nThe value is shared, a function without parameters is called for every missing key instead: withDefault({}, fn() { [] })"},
+	&object.BuiltinDoc{Name: "next", Signature: "next(generator)", Help: "The next value of the generator, null once it's finished."},
+	&object.BuiltinDoc{Name: "take", Signature: "take(generator, n)", Help: "An array with (up to) the next n values of the generator, the generator is closed afterwards."},
+	&object.BuiltinDoc{Name: "inspect", Signature: "inspect(value[, options])", Help: "The value as puts prints it.\noptions: {\"depth\": 2, \"elements\": 10, \"multiline\": true}"},
+	&object.BuiltinDoc{Name: "read_file", Signature: "read_file(path)", Help: "The contents of the file as a string."},
+	&object.BuiltinDoc{Name: "write_file", Signature: "write_file(path, contents)", Help: "Replaces the contents of the file (creating it if needed) with a string or bytes."},
+	&object.BuiltinDoc{Name: "glob", Signature: "glob(dir, pattern)", Help: "The paths of the entries in dir matching the pattern, sorted: glob(\"lib\", \"*.mk\")"},
+	&object.BuiltinDoc{Name: "glob_match", Signature: "glob_match(pattern, name)", Help: "Whether the name matches the pattern (*, ?, [a-z]): glob_match(\"*.txt\", \"notes.txt\") => true"},
+	&object.BuiltinDoc{Name: "parse_int", Signature: "parse_int(str[, base])", Help: "Parses an integer, in base 10 by default: parse_int(\"ff\", 16) => 255"},
+	&object.BuiltinDoc{Name: "parse_float", Signature: "parse_float(str)", Help: "Parses a float, exponents are allowed: parse_float(\"1e3\") => 1000.0"},
+	&object.BuiltinDoc{Name: "to_base", Signature: "to_base(n, base)", Help: "The integer written in the base: to_base(255, 16) => \"ff\""},
+	&object.BuiltinDoc{Name: "format_float", Signature: "format_float(n, decimals)", Help: "The number rounded to the decimals: format_float(3.14159, 2) => \"3.14\""},
+	&object.BuiltinDoc{Name: "number_format", Signature: "number_format(n[, decimals[, thousands_sep[, decimal_sep]]])", Help: "The number with its thousands grouped, rounded to the decimals (0 by default).\nnumber_format(1234567.891, 2, \".\", \",\") => \"1.234.567,89\""},
+	&object.BuiltinDoc{Name: "csv_parse", Signature: "csv_parse(text[, options])", Help: "Parses CSV text into an array of rows.\noptions: {\"header\": true, \"separator\": \";\"}"},
+	&object.BuiltinDoc{Name: "csv_encode", Signature: "csv_encode(rows[, options])", Help: "Encodes rows (arrays or hashes) as CSV text.\noptions: {\"separator\": \";\"}"},
+	&object.BuiltinDoc{Name: "toml_decode", Signature: "toml_decode(text)", Help: "Decodes a TOML document into a hash."},
+	&object.BuiltinDoc{Name: "toml_encode", Signature: "toml_encode(hash)", Help: "Encodes a hash as a TOML document."},
+	&object.BuiltinDoc{Name: "yaml_decode", Signature: "yaml_decode(text)", Help: "Decodes a YAML document into hashes, arrays and scalars."},
+	&object.BuiltinDoc{Name: "yaml_encode", Signature: "yaml_encode(value)", Help: "Encodes a value as a YAML document."},
+
+	// created for every environment
+	&object.BuiltinDoc{Name: "runtime_info", Signature: "runtime_info()", Help: "The interpreter's version, capabilities and limits, as a hash."},
+	&object.BuiltinDoc{Name: "repeat", Signature: "repeat(str, n)", Help: "The string repeated n times: repeat(\"ab\", 3) => \"ababab\""},
+	&object.BuiltinDoc{Name: "pad_left", Signature: "pad_left(str, width[, fill])", Help: "The string with the fill (a space by default) added before it up to the width: pad_left(\"7\", 3, \"0\") => \"007\""},
+	&object.BuiltinDoc{Name: "pad_right", Signature: "pad_right(str, width[, fill])", Help: "The string with the fill (a space by default) added after it up to the width: pad_right(\"id\", 4) => \"id  \""},
+	&object.BuiltinDoc{Name: "set_timeout", Signature: "set_timeout(fn, ms)", Help: "Calls fn once, ms milliseconds from now (see run_loop). Returns the timer's id."},
+	&object.BuiltinDoc{Name: "set_interval", Signature: "set_interval(fn, ms)", Help: "Calls fn every ms milliseconds (see run_loop). Returns the timer's id."},
+	&object.BuiltinDoc{Name: "clear_timer", Signature: "clear_timer(id)", Help: "Cancels a timer, returns whether it was still pending."},
+	&object.BuiltinDoc{Name: "run_loop", Signature: "run_loop()", Help: "Runs the timers as they're due until there are none left."},
+	&object.BuiltinDoc{Name: "args", Signature: "args()", Help: "The arguments passed to the script, as an array of strings."},
+	&object.BuiltinDoc{Name: "help", Signature: "help(name)", Help: "Prints the documentation of a builtin, method or module member: help(\"len\"), help(\"upper\"), help(\"time.now\")"},
+)
+
+/**
+Documentation of the methods that aren't global builtins (see METHODS), the others share the builtin's.
+Attached to the methods when METHODS is filled.
+**/
+var METHOD_DOCS = docsByName(
+	&object.BuiltinDoc{Name: "upper", Signature: "str.upper()", Help: "The string in uppercase: \"Hello\".upper() => \"HELLO\""},
+	&object.BuiltinDoc{Name: "lower", Signature: "str.lower()", Help: "The string in lowercase: \"Hello\".lower() => \"hello\""},
+	&object.BuiltinDoc{Name: "trim", Signature: "str.trim()", Help: "The string without its leading and trailing whitespace."},
+	&object.BuiltinDoc{Name: "split", Signature: "str.split([separator])", Help: "The parts of the string between the separators, without one it splits around runs of whitespace.\n\"a,b\".split(\",\") => [a, b]"},
+	&object.BuiltinDoc{Name: "replace", Signature: "str.replace(old, new)", Help: "The string with every occurrence of old replaced by new."},
+	&object.BuiltinDoc{Name: "starts_with", Signature: "str.starts_with(prefix)", Help: "Whether the string starts with the prefix."},
+	&object.BuiltinDoc{Name: "ends_with", Signature: "str.ends_with(suffix)", Help: "Whether the string ends with the suffix."},
+	&object.BuiltinDoc{Name: "join", Signature: "array.join([separator])", Help: "The elements as puts prints them, joined by the separator: [1, 2].join(\", \") => \"1, 2\""},
+	&object.BuiltinDoc{Name: "format", Signature: "t.format([layout])", Help: "The time written with the layout, RFC 3339 by default: t.format(\"2006-01-02\")"},
+	&object.BuiltinDoc{Name: "unix", Signature: "t.unix()", Help: "Seconds since the epoch, the opposite of time.unix(seconds)."},
+	&object.BuiltinDoc{Name: "year", Signature: "t.year()", Help: "The year of the time."},
+	&object.BuiltinDoc{Name: "month", Signature: "t.month()", Help: "The month of the time, from 1 to 12."},
+	&object.BuiltinDoc{Name: "day", Signature: "t.day()", Help: "The day of the month of the time."},
+	&object.BuiltinDoc{Name: "hour", Signature: "t.hour()", Help: "The hour of the time, from 0 to 23."},
+	&object.BuiltinDoc{Name: "minute", Signature: "t.minute()", Help: "The minute of the time."},
+	&object.BuiltinDoc{Name: "second", Signature: "t.second()", Help: "The second of the time."},
+	&object.BuiltinDoc{Name: "weekday", Signature: "t.weekday()", Help: "The day of the week of the time: \"Monday\""},
+	&object.BuiltinDoc{Name: "seconds", Signature: "d.seconds()", Help: "The duration in seconds, as a float: 90.5"},
+	&object.BuiltinDoc{Name: "ms", Signature: "d.ms()", Help: "The whole milliseconds in the duration, the unit set_timeout takes."},
+)
+
+// Documentation of the members of the modules (time, flags), by module.member
+var MODULE_DOCS = docsByName(
+	&object.BuiltinDoc{Name: "time.now", Signature: "time.now()", Help: "The current (local) time."},
+	&object.BuiltinDoc{Name: "time.unix", Signature: "time.unix(seconds)", Help: "The time that many seconds after the epoch, in UTC."},
+	&object.BuiltinDoc{Name: "time.parse", Signature: "time.parse(text[, layout])", Help: "Parses a time, as RFC 3339 by default: time.parse(\"2024-05-01T12:00:00Z\")"},
+	&object.BuiltinDoc{Name: "time.format", Signature: "time.format(t[, layout])", Help: "The time written with the layout, RFC 3339 by default."},
+	&object.BuiltinDoc{Name: "time.duration", Signature: "time.duration(text)", Help: "Parses a duration, units go from ns to h: time.duration(\"1h30m\")"},
+	&object.BuiltinDoc{Name: "time.since", Signature: "time.since(t)", Help: "How long ago the time was, time.now() - t."},
+	&object.BuiltinDoc{Name: "flags.string", Signature: "flags.string(name, default[, help])", Help: "Declares a string option, --name value."},
+	&object.BuiltinDoc{Name: "flags.int", Signature: "flags.int(name, default[, help])", Help: "Declares an integer option, --name 3."},
+	&object.BuiltinDoc{Name: "flags.bool", Signature: "flags.bool(name, default[, help])", Help: "Declares a boolean option, --name sets it to true."},
+	&object.BuiltinDoc{Name: "flags.parse", Signature: "flags.parse()", Help: "A hash with the value of every declared option, null after printing the usage for --help."},
+	&object.BuiltinDoc{Name: "flags.args", Signature: "flags.args()", Help: "The arguments left after the options, empty until flags.parse() is called."},
+)
+
+func init() {
+	for name, builtin := range BUILTIN {
+		builtin.Doc = DOCS[name]
+	}
+}
+
+func docsByName(docs ...*object.BuiltinDoc) map[string]*object.BuiltinDoc {
+	byName := make(map[string]*object.BuiltinDoc, len(docs))
+	for _, doc := range docs {
+		byName[doc.Name] = doc
+	}
+
+	return byName
+}
+
+/**
+Returns the documentation of the builtin bound to name in env:
+
+	len(value)
+	    Number of elements of an array, ...
+
+- a method name that isn't bound (upper, join) returns the method's documentation
+- module.member (time.now) returns the member's, a module name lists the signatures of its members
+
+Names that aren't bound, or bound to something without documentation (user functions, etc), are errors.
+**/
+func Describe(name string, env *object.Environment) (string, *object.Error) {
+	if dot := strings.Index(name, "."); dot != -1 {
+		return describeMember(name[:dot], name[dot+1:], env)
+	}
+
+	value, ok := env.Get(name)
+	if !ok {
+		if doc := methodDoc(name); doc != nil {
+			return doc.String(), nil
+		}
+		return "", identifierNotFound(name, env)
+	}
+
+	switch value := value.(type) {
+	case *object.Builtin:
+		if value.Doc != nil {
+			return value.Doc.String(), nil
+		}
+	case *object.Module:
+		return describeModule(value), nil
+	}
+
+	return "", newError(catalog.NO_DOCUMENTATION, name)
+}
+
+func describeMember(moduleName, memberName string, env *object.Environment) (string, *object.Error) {
+	value, ok := env.Get(moduleName)
+	if !ok {
+		return "", identifierNotFound(moduleName, env)
+	}
+
+	module, ok := value.(*object.Module)
+	if !ok {
+		return "", newError(catalog.NO_DOCUMENTATION, moduleName+"."+memberName)
+	}
+
+	member, ok := module.Members[memberName]
+	if !ok {
+		return "", newError(catalog.UNKNOWN_MEMBER, module.Name, memberName)
+	}
+
+	builtin, ok := member.(*object.Builtin)
+	if !ok || builtin.Doc == nil {
+		return "", newError(catalog.NO_DOCUMENTATION, moduleName+"."+memberName)
+	}
+
+	return builtin.Doc.String(), nil
+}
+
+// The module's name, then the signature of every member (sorted)
+func describeModule(module *object.Module) string {
+	signatures := []string{}
+	for name, member := range module.Members {
+		if builtin, ok := member.(*object.Builtin); ok && builtin.Doc != nil {
+			signatures = append(signatures, builtin.Doc.Signature)
+		} else {
+			signatures = append(signatures, module.Name+"."+name)
+		}
+	}
+	sort.Strings(signatures)
+
+	return "module " + module.Name + "\n    " + strings.Join(signatures, "\n    ")
+}
+
+// The documentation of the methods called name, the same for every type that has one
+func methodDoc(name string) *object.BuiltinDoc {
+	if doc, ok := METHOD_DOCS[name]; ok {
+		return doc
+	}
+
+	for _, methods := range METHODS {
+		if _, ok := methods[name]; ok {
+			return DOCS[name]
+		}
+	}
+
+	return nil
+}
+
+// help("len") writes the documentation of len to out, see Describe
+func HelpBuiltin(env *object.Environment, out io.Writer) *object.Builtin {
+	return &object.Builtin{Doc: DOCS["help"], Fn: func(args ...object.Object) object.Object {
+		if err := object.CheckArgs("help", args, object.Arg(object.STRING_OBJ)); err != nil {
+			return err
+		}
+
+		doc, err := Describe(args[0].(*object.String).Value, env)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(out, doc)
+		return NULL
+	}}
+}
@@ -1,18 +1,50 @@
 package evaluator
 
 import (
-	"fmt"
 	"monkey/ast"
+	"monkey/catalog"
 	"monkey/object"
+	"strings"
 )
 
 var (
 	TRUE  = &object.Boolean{Value: true}
 	FALSE = &object.Boolean{Value: false}
-	NULL  = &object.Null{}
+	NULL  = object.NULL
 )
 
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	// every node costs one unit of fuel, see object.Limits
+	if !env.UseFuel() {
+		return newError(catalog.FUEL_LIMIT, env.Limits().Fuel)
+	}
+
+	hooks := env.Hooks()
+	if len(hooks) == 0 {
+		return eval(node, env)
+	}
+
+	beforeNode(hooks, node, env)
+	result := eval(node, env)
+	afterNode(hooks, node, result)
+
+	return result
+}
+
+func beforeNode(hooks []object.Hook, node ast.Node, env *object.Environment) {
+	for _, hook := range hooks {
+		hook.BeforeNode(node, env)
+	}
+}
+
+// reverse order, so the first hook wraps all the others
+func afterNode(hooks []object.Hook, node ast.Node, result object.Object) {
+	for idx := len(hooks) - 1; idx >= 0; idx-- {
+		hooks[idx].AfterNode(node, result)
+	}
+}
+
+func eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	//statements
 	case *ast.Program:
@@ -24,7 +56,13 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 
 	//expressions
 	case *ast.IntegerLiteral:
-		return &object.Integer{Value: node.Value}
+		return object.InternInteger(node.Value)
+
+	case *ast.CharLiteral:
+		return object.InternInteger(int64(node.Value))
+
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
 
 	case *ast.LetStatement:
 		// evaluate the value
@@ -34,8 +72,17 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return val
 		}
 
-		// assign the value to the identifier: let x = 0
-		env.Set(node.Name.Value, val)
+		// a constant can't be declared again in the same scope, inner scopes can shadow it
+		if env.IsConstant(node.Name.Value) {
+			return newError(catalog.CONSTANT_ASSIGNMENT, node.Name.Value)
+		}
+
+		// assign the value to the identifier: let x = 0, const x = 0
+		if node.Constant {
+			env.SetConstant(node.Name.Value, val)
+		} else {
+			env.Set(node.Name.Value, val)
+		}
 
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
@@ -48,6 +95,9 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
 
+	case *ast.NullLiteral:
+		return NULL
+
 	case *ast.PrefixExpression:
 		// the operand
 		right := Eval(node.Right, env)
@@ -57,19 +107,11 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		// now evaluate the operand with the operator
 		return evalPrefixExpression(node.Operator, right)
 
-	case *ast.InfixExpression:
-		left := Eval(node.Left, env)
-		right := Eval(node.Right, env)
-
-		if isError(left) {
-			return left
-		}
-
-		if isError(right) {
-			return left
-		}
+	case *ast.PostfixExpression:
+		return evalPostfixExpression(node, env)
 
-		return evalInfixExpression(node.Operator, left, right)
+	case *ast.InfixExpression:
+		return evalInfixChain(node, env)
 
 	case *ast.BlockStatement:
 		return evalBlockStatement(node, env)
@@ -78,7 +120,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		params := node.Parameters
 		body := node.Body
 		// note: the env set here is the env/scope the function was defined in
-		return &object.Function{Parameters: params, Env: env, Body: body}
+		return &object.Function{Parameters: params, Env: env, Body: body, Generator: node.Generator}
 
 	case *ast.CallExpression:
 		function := Eval(node.Function, env)
@@ -95,6 +137,9 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
 
+	case *ast.MatchExpression:
+		return evalMatchExpression(node, env)
+
 	case *ast.ReturnStatement:
 		val := Eval(node.ReturnValue, env)
 		if isError(val) {
@@ -102,8 +147,14 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		}
 		return &object.ReturnValue{Value: val}
 
+	case *ast.YieldStatement:
+		return evalYieldStatement(node, env)
+
 	case *ast.StringLiteral:
-		return &object.String{Value: node.Value}
+		return object.InternString(node.Value)
+
+	case *ast.InterpolatedString:
+		return evalInterpolatedString(node, env)
 
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, env)
@@ -120,6 +171,11 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return left
 		}
 
+		// x?[0], see nullSafe
+		if left == NULL && nullSafe(node.Optional, node.Left) {
+			return NULL
+		}
+
 		// The index itself
 		index := Eval(node.Index, env)
 		if isError(index) {
@@ -153,31 +209,40 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return evalHashLiteral(node, env)
 
 	case *ast.InternalFunctionCall:
-		// someArr, someHash
-		caller_ident := Eval(node.CallerIdentifier, env)
+		// someArr, someHash, "a string", etc
+		caller := Eval(node.Caller, env)
 
-		if isError(caller_ident) {
-			return caller_ident
+		if isError(caller) {
+			return caller
+		}
+		// x?.method(), see nullSafe
+		if caller == NULL && nullSafe(node.Optional, node.Caller) {
+			return NULL
+		}
+		// flags.parse(), etc
+		if module, isModule := caller.(*object.Module); isModule {
+			return evalModuleCall(module, node, env)
+		}
+		// .pop(), .upper(), etc: the caller's method or any function in scope
+		fn, ok := lookupMethod(caller, node.FunctionIdentifier.Value)
+		if !ok {
+			fn = Eval(node.FunctionIdentifier, env)
 		}
-		// .pop(), .delete(), etc
-		func_ident := Eval(node.FunctionIdentifier, env)
 
-		if isError(func_ident) {
-			return caller_ident
+		if isError(fn) {
+			return fn
 		}
 		// (1,2,3), ("a", "b", "c"), etc
 		args := evalExpressions(node.Arguments, env)
 
-		for _, arg := range args {
-			if isError(arg) {
-				return nil
-			}
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
 		}
 		//  ( someArr/someHash, (1,2,3) )
-		newArgs := append([]object.Object{caller_ident}, args...)
+		newArgs := append([]object.Object{caller}, args...)
 
 		// call the function as usual builtInFunc(objectIdentifier, args)
-		return applyFunction(func_ident, newArgs)
+		return applyFunction(fn, newArgs)
 
 	case *ast.AssignmentExpression:
 		// x, y, someIdentifier
@@ -185,7 +250,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 
 		_, exists := env.Get(node.Name.Value)
 		if !exists {
-			return newError(`Identifier "%s" not found`, node.Name.Value)
+			return identifierNotFound(node.Name.Value, env)
 		}
 
 		val := Eval(node.Value, env)
@@ -194,32 +259,15 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return val
 		}
 
-		env.Set(node.Name.Value, val)
-
-	case *ast.ForLoopStatement:
-		// Lets set the counter var in the env
-		counterVar := Eval(node.CounterVar, env)
-
-		if isError(counterVar) {
-			return counterVar
+		if _, ok := env.Assign(node.Name.Value, val); !ok {
+			return newError(catalog.CONSTANT_ASSIGNMENT, node.Name.Value)
 		}
 
-		// Now lets make sure it actually got set / exists
-		counterVar, exists := env.Get(node.CounterVar.Name.Value)
-
-		if !exists {
-			return counterVar
-		}
-
-		// Lets make sure we have a valid for loop condition
-		loopCondition := Eval(node.LoopCondition, env)
-
-		if isError(loopCondition) {
-			return loopCondition
-		}
+	case *ast.ForLoopStatement:
+		return evalForLoopStatement(node, env)
 
-		// Now run the for loop.
-		return applyForLoop(node, env)
+	case *ast.ForInStatement:
+		return evalForInStatement(node, env)
 	}
 
 	return nil
@@ -259,7 +307,7 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 	case "-":
 		return evalMinusPrefixOperatorExpression(right)
 	default:
-		return newError("unknown operator: %s%s", operator, right.Type())
+		return newError(catalog.UNKNOWN_PREFIX_OPERATOR, operator, right.Type())
 	}
 }
 
@@ -281,37 +329,76 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 }
 
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
+	if float, ok := right.(*object.Float); ok {
+		return &object.Float{Value: -float.Value}
+	}
+
 	if right.Type() != object.INTEGER_OBJ {
-		return newError("unknown operator: -%s", right.Type())
+		return newError(catalog.UNKNOWN_PREFIX_OPERATOR, "-", right.Type())
 	}
 
 	//extract value from *object.Integer via type assertion
 	value := right.(*object.Integer).Value
 	// return integer object with negated value
-	return &object.Integer{Value: -value}
+	return object.InternInteger(-value)
 }
 
-func evalInfixExpression(operator string, left object.Object, right object.Object) object.Object {
+func evalInfixExpression(operator string, left object.Object, right object.Object, env *object.Environment) object.Object {
+	if isHash(left) || isHash(right) {
+		if result, ok := evalOperatorMethod(operator, left, right); ok {
+			return result
+		}
+	}
+
 	switch {
+	case operator == "in" || operator == "!in":
+		return evalMembershipExpression(operator, left, right, env)
+	case isRepetition(operator, left, right):
+		return evalRepetition(left, right, env.Limits())
 	case bothAreIntegers(left, right):
 		return evalIntegerInfixExpression(operator, left, right)
+	case bothAreNumbers(left, right):
+		return evalFloatInfixExpression(operator, left, right)
+	case isTimeValue(left) || isTimeValue(right):
+		return evalTimeInfixExpression(operator, left, right)
+	// compared by value, interning only saves memory: "a" == "a" doesn't depend on it
+	case bothAreStrings(left, right):
+		return evalStringInfixExpression(operator, left, right)
 	case operator == "==":
 		return nativeBoolToBooleanObject(left == right)
 	case operator == "!=":
 		return nativeBoolToBooleanObject(left != right)
 	case left.Type() != right.Type():
-		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
-	case bothAreStrings(left, right):
-		return evalStringInfixExpression(operator, left, right)
+		return newError(catalog.TYPE_MISMATCH, left.Type(), operator, right.Type())
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newError(catalog.UNKNOWN_INFIX_OPERATOR, left.Type(), operator, right.Type())
+	}
+}
+
+/**
+Short-circuiting && and ||, the result is the operand that decided it (not always a boolean):
+- a && b: a when it's falsy, b otherwise
+- a || b: a when it's truthy, b otherwise
+
+ex: name || "anonymous"
+**/
+func evalLogicalExpression(node *ast.InfixExpression, left object.Object, env *object.Environment) object.Object {
+	if isTruthy(left) == (node.Operator == "||") {
+		return left
 	}
+
+	return Eval(node.Right, env)
 }
 
 func bothAreIntegers(a, b object.Object) bool {
 	return isInteger(a) && isInteger(b)
 }
 
+// integers or floats, in any combination
+func bothAreNumbers(a, b object.Object) bool {
+	return isNumber(a) && isNumber(b)
+}
+
 func bothAreStrings(a, b object.Object) bool {
 	return isString(a) && isString(b)
 }
@@ -323,13 +410,75 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 
 	switch operator {
 	case "+":
-		return &object.Integer{Value: leftVal + rightVal}
+		return object.InternInteger(leftVal + rightVal)
 	case "-":
-		return &object.Integer{Value: leftVal - rightVal}
+		return object.InternInteger(leftVal - rightVal)
 	case "*":
-		return &object.Integer{Value: leftVal * rightVal}
+		return object.InternInteger(leftVal * rightVal)
+	case "/", "%":
+		if rightVal == 0 {
+			return newError(catalog.DIVISION_BY_ZERO, leftVal, operator, rightVal)
+		}
+		if operator == "/" {
+			return object.InternInteger(leftVal / rightVal)
+		}
+		// the result has the sign of the left operand: -7 % 3 => -1
+		return object.InternInteger(leftVal % rightVal)
+	case "&":
+		return object.InternInteger(leftVal & rightVal)
+	case "|":
+		return object.InternInteger(leftVal | rightVal)
+	case "^":
+		return object.InternInteger(leftVal ^ rightVal)
+	case "<<", ">>":
+		return evalShift(operator, leftVal, rightVal)
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError(catalog.UNKNOWN_INFIX_OPERATOR, left.Type(), operator, right.Type())
+	}
+}
+
+/**
+Shifts past the width of an integer give 0 (or -1 when shifting a negative number right),
+negative shift counts are an error.
+**/
+func evalShift(operator string, value, count int64) object.Object {
+	if count < 0 {
+		return newError(catalog.NEGATIVE_SHIFT, value, operator, count)
+	}
+
+	if operator == "<<" {
+		return object.InternInteger(value << uint64(count))
+	}
+
+	// arithmetic shift, the sign is kept: -8 >> 1 => -4
+	return object.InternInteger(value >> uint64(count))
+}
+
+/**
+Mixed integer/float arithmetic: the integer is converted to a float so the result is always a float.
+ex: 1 + 0.5 => 1.5, 4 / 2.0 => 2.0
+**/
+func evalFloatInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal := toFloat(left)
+	rightVal := toFloat(right)
+
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
 	case "/":
-		return &object.Integer{Value: leftVal / rightVal}
+		return &object.Float{Value: leftVal / rightVal}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
@@ -339,8 +488,16 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	case "!=":
 		return nativeBoolToBooleanObject(leftVal != rightVal)
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newError(catalog.UNKNOWN_INFIX_OPERATOR, left.Type(), operator, right.Type())
+	}
+}
+
+// Only call with integers or floats (see isNumber)
+func toFloat(o object.Object) float64 {
+	if integer, ok := o.(*object.Integer); ok {
+		return float64(integer.Value)
 	}
+	return o.(*object.Float).Value
 }
 
 func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
@@ -350,13 +507,19 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Obje
 		return condition
 	}
 
+	var result object.Object
 	if isTruthy(condition) {
-		return Eval(ie.Consequence, env)
+		result = Eval(ie.Consequence, env)
 	} else if ie.Alternative != nil {
-		return Eval(ie.Alternative, env)
-	} else {
+		result = Eval(ie.Alternative, env)
+	}
+
+	// if is an expression, a branch without a value ({} or only let statements) is null
+	if result == nil {
 		return NULL
 	}
+
+	return result
 }
 
 func isTruthy(obj object.Object) bool {
@@ -391,8 +554,17 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	return result
 }
 
-func newError(format string, a ...interface{}) *object.Error {
-	return &object.Error{Message: fmt.Sprintf(format, a...)}
+// Creates an error object, the message comes from the catalog using the given code
+func newError(code catalog.Code, a ...interface{}) *object.Error {
+	return &object.Error{Code: string(code), Message: catalog.Message(code, a...)}
+}
+
+// The type of an object for error messages, evaluating some nodes (let statements, etc) returns nil
+func typeOf(obj object.Object) object.ObjectType {
+	if obj == nil {
+		return "nil"
+	}
+	return obj.Type()
 }
 
 func isError(obj object.Object) bool {
@@ -403,12 +575,20 @@ func isError(obj object.Object) bool {
 }
 
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
+	// where this identifier was found last time it was evaluated
+	if depth, slot, ok := node.Resolution(); ok {
+		if val, ok := env.GetSlot(node.Value, depth, slot); ok {
+			return val
+		}
+	}
+
 	// check if value exists in env
-	if val, ok := env.Get(node.Value); ok {
+	if val, depth, slot, ok := env.Resolve(node.Value); ok {
+		node.SetResolution(depth, slot)
 		return val
 	}
 
-	return newError("identifier not found: " + node.Value)
+	return identifierNotFound(node.Value, env)
 }
 
 // evaluate expressions (left to right)
@@ -432,42 +612,64 @@ func applyFunction(fn object.Object, args []object.Object) object.Object {
 
 	// check if its a regular function
 	case *object.Function:
-		arr, isArray := args[0].(*object.Array)
+		// the body runs later, one yield at a time
+		if fn.Generator {
+			return newGenerator(fn, args)
+		}
 		// Possibly a #map call
-		if isArray && len(args) == 1 {
-			return applyMapCall(arr, fn)
+		if len(args) == 1 {
+			if arr, isArray := args[0].(*object.Array); isArray {
+				return applyMapCall(arr, fn)
+			}
 		}
-		// create the inner function scope
-		extendedEnv := extendFunctionEnv(fn, args)
-		//evalute the function body with the inner scope
-		evaluated := Eval(fn.Body, extendedEnv)
-		// if the object has a return value, return that value
-		// else, return the object.
-		return unwrapReturnValue(evaluated)
+		return callFunction(fn, args)
 
 	// return the built in function, pass args
 	case *object.Builtin:
 		return fn.Fn(args...)
 
 	default:
-		return newError("not a function: %s", fn.Type())
+		return newError(catalog.NOT_A_FUNCTION, fn.Type())
 	}
 }
 
 func applyMapCall(arr *object.Array, fn *object.Function) object.Object {
 	res := &object.Array{}
 	for _, val := range arr.Elements {
-		args := []object.Object{val}
-		// create an inner function scope for each function call
-		extendedEnv := extendFunctionEnv(fn, args)
-		// Evaluate
-		evaluated := Eval(fn.Body, extendedEnv)
+		// one call (and inner function scope) for each element
+		evaluated := callFunction(fn, []object.Object{val})
+		// errors stop the map, including the limits running out inside the callback
+		if isError(evaluated) {
+			return evaluated
+		}
 		// Add result to the array
-		res.Elements = append(res.Elements, unwrapReturnValue(evaluated))
+		res.Elements = append(res.Elements, evaluated)
 	}
 	return res
 }
 
+/**
+Evaluates the body of the function in a new scope with the parameters bound to args.
+
+The scope comes from the function's environment, not the caller's, so calls made by builtins
+(map, operator methods, module members) count against the same limits as the rest of the script.
+**/
+func callFunction(fn *object.Function, args []object.Object) object.Object {
+	// create the inner function scope
+	extendedEnv := extendFunctionEnv(fn, args)
+
+	if !extendedEnv.EnterCall() {
+		return newError(catalog.DEPTH_LIMIT, extendedEnv.Limits().Depth)
+	}
+	defer extendedEnv.LeaveCall()
+
+	//evalute the function body with the inner scope
+	evaluated := Eval(fn.Body, extendedEnv)
+	// if the object has a return value, return that value
+	// else, return the object.
+	return unwrapReturnValue(evaluated)
+}
+
 func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
 	// create inner function scope
 	env := object.NewEnclosedEnvironment(fn.Env)
@@ -480,43 +682,75 @@ func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Enviro
 }
 
 /**
-returns the unwrapped *object.ReturnValue if it exists.
-This is necessary because:
-- otherwise a return statement would bubble up through several functions
-  - this would stop the evaluation in all of them
-- we only want to stop the evaluation of the last called function's body
-
-This is why we need to unwrap it, so evalBlockStatement wont stop evaluating statements in outer functions.
+The value of a function call:
+- the value of the return statement that stopped the body
+- or the value of the last statement (implicit return): fn(x) { x * 2 }
+- or null when the last statement doesn't produce a value (let, assignments, an empty body)
 **/
 func unwrapReturnValue(obj object.Object) object.Object {
 	if returnValue, ok := obj.(*object.ReturnValue); ok {
 		return returnValue.Value
 	}
 
+	if obj == nil {
+		return NULL
+	}
+
 	return obj
 }
 
 func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
-	// currently we only support concatenation: (string + string)
-	if operator != "+" {
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
-	}
-
 	leftVal := left.(*object.String).Value
 	rightVal := right.(*object.String).Value
 
-	return &object.String{Value: leftVal + rightVal}
+	// concatenation and comparison: (string + string), (string == string)
+	switch operator {
+	case "+":
+		return object.InternRuntimeString(leftVal + rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError(catalog.UNKNOWN_INFIX_OPERATOR, left.Type(), operator, right.Type())
+	}
+}
+
+/**
+"hello ${name}, you are ${age + 1}": the expressions are evaluated in order and
+written the same way puts prints them (strings as they are, [1, 2] for arrays, etc)
+**/
+func evalInterpolatedString(node *ast.InterpolatedString, env *object.Environment) object.Object {
+	var out strings.Builder
+
+	for _, part := range node.Parts {
+		value := Eval(part, env)
+		if isError(value) {
+			return value
+		}
+		// assignments don't produce a value
+		if value == nil {
+			value = NULL
+		}
+
+		out.WriteString(env.Display(value))
+	}
 
+	return object.InternRuntimeString(out.String())
 }
 
 func evalIndexExpression(left, index object.Object) object.Object {
 	switch {
 	case isArray(left) && isInteger(index):
 		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.BYTES_OBJ && isInteger(index):
+		return evalBytesIndexExpression(left, index)
+	case isString(left) && isInteger(index):
+		return evalStringIndexExpression(left, index)
 	case isHash(left):
 		return evalHashIndexExpression(left, index)
 	default:
-		return newError("index operator not supported: %s", left.Type())
+		return newError(catalog.INDEX_NOT_SUPPORTED, left.Type())
 	}
 }
 
@@ -533,10 +767,25 @@ func evalArrayIndexExpression(array, index object.Object) object.Object {
 	return arrayObject.Elements[idx]
 }
 
+func evalBytesIndexExpression(bytes, index object.Object) object.Object {
+	bytesObject := bytes.(*object.Bytes)
+	idx := index.(*object.Integer).Value
+	max := int64(len(bytesObject.Value) - 1)
+
+	if idx < 0 || idx > max {
+		return NULL
+	}
+
+	return object.InternInteger(int64(bytesObject.Value[idx]))
+}
+
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
 	pairs := make(map[object.HashKey]object.HashPair)
 
-	for keyNode, valueNode := range node.Pairs {
+	// in source order, so the side effects of keys and values happen in the order they're written
+	for _, keyNode := range node.OrderedKeys() {
+		valueNode := node.Pairs[keyNode]
+
 		key := Eval(keyNode, env)
 		if isError(key) {
 			return key
@@ -544,7 +793,7 @@ func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Obje
 
 		hashKey, ok := key.(object.Hashable)
 		if !ok {
-			return newError("unusable as hash key: %s", key.Type())
+			return newError(catalog.UNUSABLE_HASH_KEY, key.Type())
 		}
 
 		value := Eval(valueNode, env)
@@ -556,7 +805,7 @@ func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Obje
 		pairs[hashed] = object.HashPair{Key: key, Value: value}
 	}
 
-	return &object.Hash{Pairs: pairs}
+	return object.NewHash(pairs)
 }
 
 func evalHashIndexExpression(hash, index object.Object) object.Object {
@@ -565,13 +814,14 @@ func evalHashIndexExpression(hash, index object.Object) object.Object {
 	key, ok := index.(object.Hashable)
 
 	if !ok {
-		return newError("unusable as hash key: %s", index.Type())
+		return newError(catalog.UNUSABLE_HASH_KEY, index.Type())
 	}
 
-	pair, ok := hashObject.Pairs[key.HashKey()]
+	pair, ok := hashObject.Entries()[key.HashKey()]
 
-	if !ok {
-		return NULL
+	// deleted keys are kept around with a null key, see __delete__
+	if !ok || pair.Key == NULL {
+		return hashDefault(hashObject)
 	}
 
 	return pair.Value
@@ -589,19 +839,19 @@ func evalIndexAssignment(indexable, index, value object.Object) object.Object {
 		return evalArrayIndexAssignment(array, index, value)
 	}
 
-	return newError("Invalid type passed, expected a type of Hash or Array, got %T instead", indexable.Type())
+	return newError(catalog.INDEX_ASSIGNMENT, indexable.Type())
 }
 
 func evalHashKeyAssignment(hash *object.Hash, index, value object.Object) object.Object {
 	key, ok := index.(object.Hashable)
 
 	if !ok {
-		return newError("unusable value as hash key: %s", index.Type())
+		return newError(catalog.UNUSABLE_HASH_KEY, index.Type())
 	}
 
 	hashed_key := key.HashKey()
 
-	hash.Pairs[hashed_key] = object.HashPair{Key: index, Value: value}
+	hash.Own()[hashed_key] = object.HashPair{Key: index, Value: value}
 
 	return value
 }
@@ -610,41 +860,22 @@ func evalArrayIndexAssignment(array *object.Array, index, value object.Object) o
 	idx, ok := index.(*object.Integer)
 
 	if !ok {
-		return newError("Invalid index value passed, expected an integer, got: %T", index.Type())
+		return newError(catalog.INVALID_INDEX, index.Type())
 	}
 
-	array.Elements[idx.Value] = value
-
-	return value
-}
-
-func applyForLoop(forLoop *ast.ForLoopStatement, env *object.Environment) object.Object {
-	var result object.Object
-
-	//Evaluate the first loop condition
-	stopLoop := Eval(forLoop.LoopCondition, env)
-	loopCondition, ok := stopLoop.(*object.Boolean)
-
-	if !ok {
-		return newError("Invalid loop condition type: %T", loopCondition)
+	// the array isn't grown to fit the index
+	if idx.Value < 0 {
+		return newError(catalog.NEGATIVE_INDEX, idx.Value)
+	}
+	if idx.Value >= int64(len(array.Elements)) {
+		return newError(catalog.ASSIGNMENT_OUT_OF_RANGE, idx.Value, len(array.Elements))
 	}
 
-	// While this loop condition is false
-	for loopCondition.Value {
-		result = Eval(forLoop.LoopBlock, env)
-
-		updateVal := Eval(forLoop.CounterUpdate.Value, env)
-
-		env.Set(forLoop.CounterVar.Name.Value, updateVal)
-
-		//Continue evaluating the loop condition in the loop
-		loopCondition := Eval(forLoop.LoopCondition, env).(*object.Boolean)
+	// copy-on-write, see shareElements
+	ownElements(array)
+	array.Elements[idx.Value] = value
 
-		if !loopCondition.Value {
-			return result
-		}
-	}
-	return result
+	return value
 }
 
 func isArray(o object.Object) bool {
@@ -655,6 +886,14 @@ func isInteger(o object.Object) bool {
 	return o.Type() == object.INTEGER_OBJ
 }
 
+func isFloat(o object.Object) bool {
+	return o.Type() == object.FLOAT_OBJ
+}
+
+func isNumber(o object.Object) bool {
+	return isInteger(o) || isFloat(o)
+}
+
 func isString(o object.Object) bool {
 	return o.Type() == object.STRING_OBJ
 }
@@ -1,10 +1,18 @@
 package evaluator
 
 import (
+	"bytes"
+	"math"
+	"monkey/ast"
 	"monkey/lexer"
 	"monkey/object"
 	"monkey/parser"
+	"monkey/vfs"
+	"runtime/debug"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 )
 
 func TestEvalIntegerExpression(t *testing.T) {
@@ -16,6 +24,8 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"10", 10},
 		{"-5", -5},
 		{"-10", -10},
+		{"-9223372036854775808", math.MinInt64},
+		{"-9223372036854775807 - 1 - -9223372036854775808", 0},
 		{"5 + 5 + 5 + 5 - 10", 10},
 		{"2 * 2 * 2 * 2 * 2", 32},
 		{"-50 + 100 + -50", 0},
@@ -27,6 +37,19 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"3 * 3 * 3 + 10", 37},
 		{"3 * (3 * 3) + 10", 37},
 		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"7 % 3", 1},
+		{"1_000_000 + 1", 1000001},
+		{"-7 % 3", -1},
+		{"7 % -3", 1},
+		{"1 + 10 % 4 * 2", 5},
+		{"12 & 10", 8},
+		{"12 | 10", 14},
+		{"12 ^ 10", 6},
+		{"1 << 4", 16},
+		{"256 >> 4", 16},
+		{"-8 >> 1", -4},
+		{"1 << 64", 0},
+		{"1 | 2 ^ 6 & 3 << 1", 5},
 	}
 
 	for _, tt := range tests {
@@ -51,6 +74,24 @@ func testEval(input string) object.Object {
 	return Eval(program, env)
 }
 
+// Evaluates every input and compares what its result Inspect()s to with expected
+func testInspect(t *testing.T, tests []struct{ input, expected string }) {
+	t.Helper()
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		if evaluated == nil {
+			t.Errorf("%q: expected %q, got nil", tt.input, tt.expected)
+			continue
+		}
+
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: expected %q, got %q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
 func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 	result, ok := obj.(*object.Integer)
 
@@ -66,7 +107,7 @@ func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 	return true
 }
 
-func testEvalBooleanExpression(t *testing.T) {
+func TestEvalBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input    string
 		expected bool
@@ -95,6 +136,11 @@ func testEvalBooleanExpression(t *testing.T) {
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
 		testBooleanObject(t, evaluated, tt.expected)
+
+		// every boolean is one of the two shared instances
+		if evaluated != TRUE && evaluated != FALSE {
+			t.Errorf("%s: expected the shared TRUE or FALSE, got a new %T", tt.input, evaluated)
+		}
 	}
 }
 
@@ -154,6 +200,21 @@ func TestIfElseExpressions(t *testing.T) {
 		{"if (1 > 2) { 10 }", nil},
 		{"if (1 > 2) { 10 } else { 20 }", 20},
 		{"if (1 < 2) { 10 } else { 20 }", 10},
+		// if is an expression, its value can be used anywhere
+		{"let x = if (true) { 1 } else { 2 }; x", 1},
+		{"(if (false) { 1 } else { 2 }) * 3", 6},
+		{"let f = fn(n) { if (n > 0) { n } else { -n } }; f(-4)", 4},
+		{"if (true) { let a = 1; a + 1 }", 2},
+		// branches without a value
+		{"if (true) {}", nil},
+		{"if (false) { 1 } else {}", nil},
+		{"if (true) { let a = 1 }", nil},
+		// else if chains
+		{"let x = 2; if (x == 1) { 10 } else if (x == 2) { 20 } else { 30 }", 20},
+		{"let x = 3; if (x == 1) { 10 } else if (x == 2) { 20 } else { 30 }", 30},
+		{"let x = 3; if (x == 1) { 10 } else if (x == 2) { 20 }", nil},
+		{"let x = 1; if (x == 1) { 10 } else if (missing) { 20 }", 10},
+		{"let x = 999; if (x < 0) { 0 }" + strings.Repeat(" else if (x < 0) { 0 }", 998) + " else if (x == 999) { 999 } else { -1 }", 999},
 	}
 
 	for _, tt := range tests {
@@ -168,6 +229,37 @@ func TestIfElseExpressions(t *testing.T) {
 	}
 }
 
+func TestMatchExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`match (2) { 1 => "one", 2 => "two", _ => "other" }`, "two"},
+		{`match (7) { 1 => "one", 2 => "two", _ => "other" }`, "other"},
+		{`match (7) { 1 => "one" }`, "null"},
+		{`match (7) {}`, "null"},
+		{`match ("a" + "b") { "a" => 1, "ab" => 2 }`, "2"},
+		{`let k = "abcdefghij" + "abcdefghij"; match (k) { "abcdefghijabcdefghij" => "hit", _ => "miss" }`, "hit"},
+		{`match (2) { 1 + 1 => "sum", 2 => "literal" }`, "sum"},
+		{`match (1.0) { 1 => "int" }`, "int"},
+		{`match ("1") { 1 => "int", _ => "other" }`, "other"},
+		{`match (null) { null => "nothing" }`, "nothing"},
+		{`let x = 5; match (true) { x > 3 => "big", _ => "small" }`, "big"},
+		{`match (1) { 1 => { let a = 2; a * 10 } }`, "20"},
+		{`match (1) { 1 => {} }`, "null"},
+		{`match (1) { 1 => ({"a": 1}) }["a"]`, "1"},
+		{`let f = fn(x) { match (x) { 1 => { return "early" }, _ => "late" }; "after" }; [f(1), f(2)]`, "[early, after]"},
+		// the subject is evaluated once, patterns after the match aren't evaluated
+		{`let calls = {"n": 0}; let next = fn() { calls["n"] += 1; calls["n"] }; let r = match (next()) { 1 => calls["n"], missing => 0 }; [r, calls["n"]]`, "[1, 1]"},
+		{`match (2) { 1 => "one", missing => "?" }`, "ERROR [R2003]: identifier not found: missing"},
+		{`match (missing) { _ => 1 }`, "ERROR [R2003]: identifier not found: missing"},
+		// _ is a wildcard even when it's bound
+		{`let _ = 5; match (1) { _ => "any" }`, "any"},
+	}
+
+	testInspect(t, tests)
+}
+
 func TestReturnStatements(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -238,6 +330,10 @@ func TestErrorHandling(t *testing.T) {
 			"foobar",
 			"identifier not found: foobar",
 		},
+		{
+			"foobar = 10;",
+			"identifier not found: foobar",
+		},
 		{
 			`"Hello" - "World"`,
 			"unknown operator: STRING - STRING",
@@ -262,6 +358,52 @@ func TestErrorHandling(t *testing.T) {
 	}
 }
 
+func TestErrorCodes(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedCode string
+	}{
+		{"5 + true;", "R2004"},
+		{"-true", "R2001"},
+		{"true + false;", "R2002"},
+		{"foobar", "R2003"},
+		{"foobar = 1", "R2003"},
+		{"let x = 5; x(1)", "R2005"},
+		{`len(1)`, "R2012"},
+		{`first(1)`, "R2012"},
+		{`[1, 2].map(1)`, "R2012"},
+		{`[1, 2].map()`, "R2011"},
+		{`let x = 5; x[0] = 1`, "R2008"},
+		{`5 % 0`, "R2025"},
+		{`let s = "a"; s++`, "R2030"},
+		{`missing++`, "R2003"},
+		{`for (x in 5) { x }`, "R2033"},
+		{`for (let i = 0; i; i++) { i }`, "R2010"},
+		{`for (let i = 0; i < 3; i++) { i + true }`, "R2004"},
+		{`for (x in [1, 2]) { missing }`, "R2003"},
+		{`let gen = fn*() { yield 1; 1 + true; }; for (x in gen()) { x }`, "R2004"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("%q: no error object returned, got %T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Code != tt.expectedCode {
+			t.Errorf("%q: wrong error code, got %q expected %q", tt.input, errObj.Code, tt.expectedCode)
+		}
+
+		expectedInspect := "ERROR [" + tt.expectedCode + "]: " + errObj.Message
+		if errObj.Inspect() != expectedInspect {
+			t.Errorf("%q: wrong Inspect() output, got %q expected %q", tt.input, errObj.Inspect(), expectedInspect)
+		}
+	}
+}
+
 func TestLetStatements(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -320,6 +462,30 @@ func TestFunctionApplication(t *testing.T) {
 	}
 }
 
+func TestImplicitReturn(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(x) { x * 2 }(4)", "8"},
+		{"fn(x) { let y = x + 1; y }(1)", "2"},
+		{"fn(x) { if (x > 1) { x } else { 0 } }(5)", "5"},
+		{"fn(x) { if (x > 1) { x } else { 0 } }(1)", "0"},
+		{"fn(x) { if (x > 1) { x } }(0)", "null"},
+		{"fn(x) { if (x > 1) { return 1; }; 2 }(5)", "1"},
+		{"fn(x) { if (x > 1) { return 1; }; 2 }(0)", "2"},
+		{"fn() { fn() { 3 } }()()", "3"},
+		{"let sum = fn(n) { if (n < 1) { 0 } else { n + sum(n - 1) } }; sum(4)", "10"},
+		// no value to return
+		{"fn() { let y = 1; }()", "null"},
+		{"fn() { }()", "null"},
+		{"fn(x) { x = x + 1 }(1)", "null"},
+		{"[fn() { }()]", "[null]"},
+	}
+
+	testInspect(t, tests)
+}
+
 func TestClosures(t *testing.T) {
 	input := `
 	let newAdder = fn(x) {
@@ -348,6 +514,24 @@ func TestStringLiteral(t *testing.T) {
 	}
 }
 
+func TestCharLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"'a'", 97},
+		{"'🙈'", 0x1F648},
+		{`'\n'`, 10},
+		{"'z' - 'a'", 25},
+		{`if (ord("a") == 'a') { 1 } else { 0 }`, 1},
+		{`if (chr('a' + 1) == "b") { 1 } else { 0 }`, 1},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
 func TestStringConcatenation(t *testing.T) {
 	input := `"Hello" + " " + "World!"`
 
@@ -363,6 +547,43 @@ func TestStringConcatenation(t *testing.T) {
 	}
 }
 
+func TestStringComparison(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"a" == "a"`, "true"},
+		{`"a" != "a"`, "false"},
+		{`"a" == "b"`, "false"},
+		{`"a" != "b"`, "true"},
+		// the same whether or not the strings were interned
+		{`("hel" + "lo") == "hello"`, "true"},
+		{`let a = "abcdefghij"; a + a == a + a`, "true"},
+		{`let a = "abcdefghij"; a + a != a + a`, "false"},
+		{`"` + strings.Repeat("x", 100) + `" == "` + strings.Repeat("x", 100) + `"`, "true"},
+		{`"1" == 1`, "false"},
+		{`"a" < "b"`, "ERROR [R2002]: unknown operator: STRING < STRING"},
+	}
+
+	testInspect(t, tests)
+}
+
+func TestStringInterpolation(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let name = "monke"; let age = 3; "hello ${name}, you are ${age + 1}"`, "hello monke, you are 4"},
+		{`"${[1, 2]} ${true} ${1.5} ${len("abc")}"`, "[1, 2] true 1.5 3"},
+		{`let h = {"a": {"b": 1}}; "${h["a"]["b"]}"`, "1"},
+		{`let x = 1; "${"nested ${x + 1}"}!"`, "nested 2!"},
+		{`"no \${interpolation} here"`, "no ${interpolation} here"},
+		{`"${missing}"`, "ERROR [R2003]: identifier not found: missing"},
+	}
+
+	testInspect(t, tests)
+}
+
 func TestBuiltinFunctions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -371,21 +592,21 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("")`, 0},
 		{`len("four")`, 4},
 		{`len("hello world")`, 11},
-		{`len(1)`, "argument to `len` not supported, got INTEGER"},
-		{`len("one", "two")`, "wrong number of arguments. got 2, wanted 1"},
+		{`len(1)`, "len: argument 1 must be STRING, ARRAY or BYTES, got INTEGER"},
+		{`len("one", "two")`, "len: expected 1 argument, got 2"},
 		{`len([1, 2, 3])`, 3},
 		{`len([])`, 0},
 		{`puts("hello", "world!")`, nil},
 		{`first([1, 2, 3])`, 1},
 		{`first([])`, nil},
-		{`first(1)`, "argument to `first` must be ARRAY, got INTEGER"},
+		{`first(1)`, "first: argument 1 must be ARRAY, got INTEGER"},
 		{`last([1, 2, 3])`, 3},
 		{`last([])`, nil},
-		{`last(1)`, "argument to `last` must be ARRAY, got INTEGER"},
+		{`last(1)`, "last: argument 1 must be ARRAY, got INTEGER"},
 		{`rest([1, 2, 3])`, []int{2, 3}},
 		{`rest([])`, nil},
 		{`push([], 1)`, []int{1}},
-		{`push(1, 1)`, "argument to `push` must be ARRAY, got INTEGER"},
+		{`push(1, 1)`, "push: argument 1 must be ARRAY, got INTEGER"},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
@@ -535,12 +756,12 @@ func TestHashLiterals(t *testing.T) {
 		FALSE.HashKey():                            6,
 	}
 
-	if len(result.Pairs) != len(expected) {
-		t.Fatalf("Hash has wrong number of pairs, got %d", len(result.Pairs))
+	if len(result.Entries()) != len(expected) {
+		t.Fatalf("Hash has wrong number of pairs, got %d", len(result.Entries()))
 	}
 
 	for expectedKey, expectedValue := range expected {
-		pair, ok := result.Pairs[expectedKey]
+		pair, ok := result.Entries()[expectedKey]
 
 		if !ok {
 			t.Errorf("no pair for given key in Pairs")
@@ -550,6 +771,20 @@ func TestHashLiterals(t *testing.T) {
 	}
 }
 
+func TestHashLiteralEvaluationOrder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let seen = [0, 0, 0, 0, 0, 0, 0]; let f = fn(x) { seen[0] += 1; seen[seen[0]] = x; x }; {f(1): f(2), f(3): f(4), f(5): f(6)}; seen`, "[6, 1, 2, 3, 4, 5, 6]"},
+		{`let n = [0]; let next = fn() { n[0] += 1; n[0] }; let h = {"a": next(), "b": next(), "c": next()}; [h["a"], h["b"], h["c"]]`, "[1, 2, 3]"},
+	}
+
+	for i := 0; i < 20; i++ {
+		testInspect(t, tests)
+	}
+}
+
 func TestHashIndexExpressions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -744,7 +979,7 @@ func TestHashDigging(t *testing.T) {
 		}
 
 		if val.Value != tt.expected {
-			t.Errorf("Invalid value returned, expected: %s , got: %s", tt.expected, val)
+			t.Errorf("Invalid value returned, expected: %s , got: %s", tt.expected, val.Value)
 		}
 	}
 }
@@ -907,6 +1142,20 @@ func TestArrayShiftFunction(t *testing.T) {
 	}
 }
 
+func TestArrayPopShiftEmpty(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`pop([])`, "null"},
+		{`shift([])`, "null"},
+		{`let arr = []; arr.pop(); arr`, "[]"},
+		{`let arr = [1]; arr.shift(); [arr.shift(), arr]`, "[null, []]"},
+	}
+
+	testInspect(t, tests)
+}
+
 func TestArrayShiftFunctionReturn(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -1014,6 +1263,17 @@ func TestAssignmentExpressions(t *testing.T) {
 		{"let a = 5; a = 4; a;", 4},
 		{"let a = 5 * 5; a = 4 * 4; a;", 16},
 		{"let a = 5; let b = a; b = 300; b;", 300},
+		{"let a = 5; a += 2; a;", 7},
+		{"let a = 5; a -= 2 * 2; a;", 1},
+		{"let a = 5; a *= 3; a;", 15},
+		{"let a = 15; a /= 4; a;", 3},
+		{"let a = 15; a %= 4; a;", 3},
+		{`let h = {"n": 1}; h["n"] += 10; h["n"];`, 11},
+		{"let arr = [1, 2]; arr[1] *= 5; arr[1];", 10},
+		{"let total = 0; for (let i = 0; i < 5; i += 1) { total += i; }; total;", 10},
+		{"let total = 0; for (let i = 5; i > 0; i--) { total += i; }; total;", 15},
+		{"let a = 5; a = 4; [a, 1][0];", 4},
+		{"let a = 5; a *= 2; (fn() { a })();", 10},
 	}
 
 	for _, tt := range tests {
@@ -1021,6 +1281,28 @@ func TestAssignmentExpressions(t *testing.T) {
 	}
 }
 
+func TestPostfixExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let i = 1; i++; i;", "2"},
+		{"let i = 1; i--; i;", "0"},
+		{"let i = 1; i++;", "1"},
+		{"let i = 1; let j = i++ + 10; [i, j];", "[2, 11]"},
+		{"let f = 1.5; f++; f;", "2.5"},
+		{"let arr = [1, 2]; arr[1]++; arr;", "[1, 3]"},
+		{`let h = {"n": 5}; h["n"]--; h["n"];`, "4"},
+		{"let calls = [0]; let next = fn() { calls[0]++; 0 }; let arr = [7]; arr[next()]++; [arr, calls];", "[[8], [1]]"},
+		{"--5", "5"},
+		// spaced or on the next line, the operand isn't ambiguous (a--3 is a parse error)
+		{"let a = 5; a - -3", "8"},
+		{"let a = 5; a--\n3", "3"},
+	}
+
+	testInspect(t, tests)
+}
+
 func TestForLoopStatement(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -1050,3 +1332,1363 @@ func TestForLoopStatement(t *testing.T) {
 
 	}
 }
+
+func TestUnicodeStrings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`len("héllo")`, "5"},
+		{`"héllo".len()`, "5"},
+		{`len("日本語")`, "3"},
+		{`byte_len("héllo")`, "6"},
+		{`"日本語".byte_len()`, "9"},
+		{`len(bytes("日本語"))`, "9"},
+		{`"héllo"[1]`, "é"},
+		{`"héllo"[4]`, "o"},
+		{`"héllo"[5]`, "null"},
+		{`"héllo"[-1]`, "null"},
+		{`"abc"[0] == "a"`, "true"},
+		{`slice("héllo", 1, 3)`, "él"},
+		{`"héllo".slice(2)`, "llo"},
+		{`slice("héllo")`, "héllo"},
+		{`slice("héllo", 3, 100)`, "lo"},
+		{`slice("héllo", 4, 2)`, ""},
+		{`slice("hello", 1, 4)`, "ell"},
+		{`slice("hello", -1)`, "ERROR [R2014]: negative indexes not supported (yet), recieved value of -1"},
+		{`slice([1, 2, 3], 5)`, "[]"},
+		{`[1, 2, 3].slice(2, 1)`, "[]"},
+		{`[1, 2, 3].slice(1, 100)`, "[2, 3]"},
+		{`slice([1, 2, 3], -1)`, "ERROR [R2014]: negative indexes not supported (yet), recieved value of -1"},
+		{`"ÉTÉ".lower()`, "été"},
+		{`"ñandú".upper()`, "ÑANDÚ"},
+		{`chars("añb")`, "[a, ñ, b]"},
+	}
+
+	testInspect(t, tests)
+}
+
+func TestConstStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"const x = 5; x * 2", "10"},
+		{"const x = 5; x = 6", "ERROR [R2036]: cannot assign to x, it was declared with const"},
+		{"const x = 5; x += 1", "ERROR [R2036]: cannot assign to x, it was declared with const"},
+		{"const x = 5; x++", "ERROR [R2036]: cannot assign to x, it was declared with const"},
+		{"const x = 5; let x = 6", "ERROR [R2036]: cannot assign to x, it was declared with const"},
+		{"const x = 5; const x = 6", "ERROR [R2036]: cannot assign to x, it was declared with const"},
+		{"const x = 5; for (let i = 0; i < 3; i++) { x = i; }", "ERROR [R2036]: cannot assign to x, it was declared with const"},
+		{"const x = 5; let f = fn() { x = 6; }; f()", "ERROR [R2036]: cannot assign to x, it was declared with const"},
+		// the binding is constant, not the value
+		{"const arr = [1, 2]; arr[0] = 5; arr", "[5, 2]"},
+		// inner scopes can declare their own x
+		{"const x = 5; let f = fn() { let x = 6; x }; [f(), x]", "[6, 5]"},
+		{"const x = 5; let f = fn(x) { x = x + 1; x }; [f(1), x]", "[2, 5]"},
+		{"let x = 5; const x = 6; x", "6"},
+	}
+
+	testInspect(t, tests)
+}
+
+func TestNullLiteral(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"null", "null"},
+		{"null == null", "true"},
+		{"let x = 1; x == null", "false"},
+		{"null != 1", "true"},
+		{"!null", "true"},
+		{"if (null) { 1 } else { 2 }", "2"},
+		{"let f = fn() { }; f() == null", "true"},
+		{`let h = {"a": 1}; h["b"] == null`, "true"},
+		{"[null, 1]", "[null, 1]"},
+		{"let x = null; x = 5; x", "5"},
+	}
+
+	testInspect(t, tests)
+
+	if testEval("null") != object.NULL {
+		t.Errorf("null should evaluate to the object.NULL singleton")
+	}
+}
+
+func TestOptionalChaining(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"null?[0]", "null"},
+		{"let x = null; x?.len()", "null"},
+		{`let h = {"a": [1, 2]}; h?["a"]?[1]`, "2"},
+		{`"ab"?.len()`, "2"},
+		// the rest of the chain is skipped
+		{`let user = null; user?["address"]["city"].upper()`, "null"},
+		{`let user = {"name": null}; user["name"]?.upper().len()`, "null"},
+		// a null anywhere after an optional access ends the chain too
+		{`let h = {"a": null}; h?["a"].upper()`, "null"},
+		{`let h = {"a": null}; h["a"].upper()`, "ERROR [R2003]: identifier not found: upper"},
+		// including its indexes and arguments
+		{"let n = 0; let f = fn() { n++ }; null?[f()]; null?.split(f()); n", "0"},
+		// only null short-circuits
+		{`let h = {"a": 0}; h["a"]?.upper()`, "ERROR [R2003]: identifier not found: upper"},
+		{"null[0]", "ERROR [R2006]: index operator not supported: NULL"},
+	}
+
+	testInspect(t, tests)
+}
+
+func TestStringFormatting(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`repeat("ab", 3)`, "ababab"},
+		{`"-".repeat(0)`, ""},
+		{`repeat("ab", -1)`, "ERROR [R2023]: cannot repeat STRING a negative number of times, got -1"},
+		{`pad_left("7", 3, "0")`, "007"},
+		{`"id".pad_right(4) + "|"`, "id  |"},
+		{`"héllo".pad_left(6, "·")`, "·héllo"},
+		{`pad_left("long", 2)`, "long"},
+		{`pad_left("7", 3, "ab")`, "ERROR [R2015]: argument to `pad_left` must be a single character, got \"ab\""},
+		// the memory limit is 100 bytes
+		{`repeat("ab", 51)`, "ERROR [R2024]: memory limit exceeded: repeating STRING 51 times needs more than 100 bytes"},
+		{`pad_right("", 101)`, "ERROR [R2024]: memory limit exceeded: repeating STRING 101 times needs more than 100 bytes"},
+		{`reverse("héllo")`, "olléh"},
+		{`"".reverse()`, ""},
+		{`lines("a\nb\r\nc\n")`, "[a, b, c]"},
+		{`"a\n\nb".lines().len()`, "3"},
+		{`lines("")`, "[]"},
+	}
+
+	for _, tt := range tests {
+		env := object.NewEnvironment()
+		loadBuiltInMethods(env)
+		for name, builtin := range RepeatBuiltins(env) {
+			env.Set(name, builtin)
+		}
+		env.SetLimits(object.Limits{Memory: 100})
+
+		evaluated := Eval(parser.New(lexer.New(tt.input)).ParseProgram(), env)
+
+		var got string
+		if str, ok := evaluated.(*object.String); ok {
+			got = str.Value
+		} else if evaluated != nil {
+			got = evaluated.Inspect()
+		}
+
+		if got != tt.expected {
+			t.Errorf("%q: expected %s, got %s", tt.input, tt.expected, got)
+		}
+	}
+}
+
+// without a memory limit, huge widths are capped like repetition is (see MAX_REPETITION_SIZE)
+func TestRepeatBuiltinsWithoutMemoryLimit(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"-".pad_left(100000000000000)`, "ERROR [R2024]: memory limit exceeded: repeating STRING 99999999999999 times needs more than 1073741824 bytes"},
+		{`pad_right("x", 100000000000000, "é")`, "ERROR [R2024]: memory limit exceeded: repeating STRING 99999999999999 times needs more than 1073741824 bytes"},
+		{`"ab".repeat(100000000000000)`, "ERROR [R2024]: memory limit exceeded: repeating STRING 100000000000000 times needs more than 1073741824 bytes"},
+		{`pad_left("7", 3, "0")`, "007"},
+	}
+
+	for _, tt := range tests {
+		env := object.NewEnvironment()
+		loadBuiltInMethods(env)
+		for name, builtin := range RepeatBuiltins(env) {
+			env.Set(name, builtin)
+		}
+
+		evaluated := Eval(parser.New(lexer.New(tt.input)).ParseProgram(), env)
+
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: expected %s, got %s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestArrayQueries(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`index_of([1, 2, 3], 2)`, "1"},
+		{`index_of([1, 2, 3], 4)`, "-1"},
+		{`index_of([1, 2, 2], 2)`, "1"},
+		{`[true, null].index_of(null)`, "1"},
+		{`let a = "abcdefghij"; index_of(["x", a + a], "abcdefghijabcdefghij")`, "1"},
+		{`find([1, 2, 3], fn(x) { x > 1 })`, "2"},
+		{`find([1, 2, 3], fn(x) { x > 5 })`, "null"},
+		{`[[1], [2, 3]].find(fn(arr) { len(arr) == 2 })`, "[2, 3]"},
+		{`any([1, 2, 3], fn(x) { x > 2 })`, "true"},
+		{`any([], fn(x) { true })`, "false"},
+		{`all([1, 2, 3], fn(x) { x > 0 })`, "true"},
+		{`[1, 2, 3].all(fn(x) { x > 2 })`, "false"},
+		{`all([], fn(x) { false })`, "true"},
+		{`count([1, 2, 3], fn(x) { x % 2 == 1 })`, "2"},
+		{`["a", null, "b"].count(fn(x) { x })`, "2"},
+		// predicates stop at the first answer
+		{`any([1, "a"], fn(x) { x + 1 > 0 })`, "true"},
+		{`all(["a", 1], fn(x) { x + 1 > 0 })`, "ERROR [R2004]: type mismatch: STRING + INTEGER"},
+		{`find([1, 2], 1)`, "ERROR [R2012]: find: argument 2 must be FUNCTION or BUILTIN, got INTEGER"},
+	}
+
+	testInspect(t, tests)
+}
+
+func TestArraySplitting(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`chunk([1, 2, 3, 4, 5], 2)`, "[[1, 2], [3, 4], [5]]"},
+		{`chunk([1, 2], 5)`, "[[1, 2]]"},
+		{`chunk([], 3)`, "[]"},
+		{`[1, 2, 3].chunk(1)`, "[[1], [2], [3]]"},
+		{`windows([1, 2, 3, 4], 2)`, "[[1, 2], [2, 3], [3, 4]]"},
+		{`windows([1, 2, 3], 3)`, "[[1, 2, 3]]"},
+		{`windows([1, 2], 3)`, "[]"},
+		{`let g = group_by([1, 2, 3, 4, 5], fn(x) { x % 2 == 0 }); [g[true], g[false]]`, "[[2, 4], [1, 3, 5]]"},
+		{`group_by(["ab", "c", "de"], len)[2]`, "[ab, de]"},
+		{`toArray(group_by([], fn(x) { x }))`, "[]"},
+		// writes to the original or a chunk don't show in the other
+		{`let a = [1, 2]; let first = chunk(a, 1)[0]; first[0] = 9; [a, first]`, "[[1, 2], [9]]"},
+		{`let a = [1, 2]; let first = windows(a, 2)[0]; first[0] = 9; a`, "[1, 2]"},
+		{`let a = [1, 2, 3]; let w = windows(a, 2); a[1] = 9; [a, w]`, "[[1, 9, 3], [[1, 2], [2, 3]]]"},
+		{`chunk([1], 0)`, "ERROR [R2043]: `chunk` needs a size of at least 1, got 0"},
+		{`windows([1], -1)`, "ERROR [R2043]: `windows` needs a size of at least 1, got -1"},
+		{`group_by([1], fn(x) { [x] })`, "ERROR [R2007]: unusable as hash key: ARRAY"},
+		{`group_by([1, "a"], fn(x) { x + 1 })`, "ERROR [R2004]: type mismatch: STRING + INTEGER"},
+		{`chunk([1], "2")`, "ERROR [R2012]: chunk: argument 2 must be INTEGER, got STRING"},
+	}
+
+	testInspect(t, tests)
+}
+
+func TestCopyingUpdates(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let h = {"a": 1}; let hb = set(h, "b", 2); [h["b"], hb["a"], hb["b"]]`, "[null, 1, 2]"},
+		{`{"a": 1}.set("a", 5)`, `{"a" : "5"}`},
+		{`let h = delete({"a": 1, "b": 2}, "a"); len(set(h, "c", 3).toArray())`, "4"},
+		{`withDefault({}, 0).set("a", 1)["missing"]`, "0"},
+		{`set({}, fn() {}, 1)`, "ERROR [R2007]: unusable as hash key: FUNCTION"},
+		{`let a = [1, 3]; [insert(a, 1, 2), a]`, "[[1, 2, 3], [1, 3]]"},
+		{`[1].insert(0, 0).insert(2, 2)`, "[0, 1, 2]"},
+		{`insert([], 1, 1)`, "ERROR [R2040]: `insert`: index 1 is out of range for 1 elements"},
+		{`let a = [1, 2, 3]; [remove(a, 0), remove(a, 1), remove(a, 2), a]`, "[[2, 3], [1, 3], [1, 2], [1, 2, 3]]"},
+		{`remove([1], -1)`, "ERROR [R2014]: negative indexes not supported (yet), recieved value of -1"},
+		{`[].remove(0)`, "ERROR [R2040]: `remove`: index 0 is out of range for 0 elements"},
+		// shared elements are copied before they're written to
+		{`let a = [1, 2, 3]; let b = remove(a, 0); b[0] = 9; a[2] = 8; [a, b]`, "[[1, 2, 8], [9, 3]]"},
+		{`let a = [1, 2, 3]; let b = slice(a, 1); a[1] = 0; [a, b]`, "[[1, 0, 3], [2, 3]]"},
+		{`let a = [1, 2, 3]; a[10] = 1`, "ERROR [R2044]: index 10 is out of range for 3 elements"},
+		{`let a = [1, 2, 3]; a[-1] = 3`, "ERROR [R2014]: negative indexes not supported (yet), recieved value of -1"},
+		{`let a = []; a[0] = 1`, "ERROR [R2044]: index 0 is out of range for 0 elements"},
+		{`let a = [1, 2, 3]; let r = rest(a); r[0] = 0; [a, r, push(remove(a, 2), 4), a]`, "[[1, 2, 3], [0, 3], [1, 2, 4], [1, 2, 3]]"},
+		// every version of a hash made by set keeps its own pairs, in any order they're read
+		{`let first = {"a": 1}; let second = set(first, "b", 2); let third = set(second, "a", 3); let fourth = set(second, "c", 4); [third["a"], first["b"], fourth["c"], second["a"], second["c"], third["a"], len(fourth.toArray())]`, "[3, null, 4, 1, null, 3, 6]"},
+		{`let h = {}; for (let i = 0; i < 100; i += 1) { h = set(h, i, i * i) }; [len(h.toArray()), h[99], h[7]]`, "[200, 9801, 49]"},
+		// changing a version in place doesn't change the others
+		{`let first = {"a": 1}; let second = set(first, "b", 2); second["a"] = 5; delete(first, "a"); first["c"] = 3; [first, second["a"], second["c"], set(second, "d", 4)["a"], second["d"]]`, `[{"c" : "3"}, 5, null, 5, null]`},
+		{`let first = {"a": 1}; let second = set(first, "a", 2); first["a"] += 10; [first["a"], second["a"], "a" in set(first, "b", 0)]`, "[11, 2, true]"},
+		// inserts at the end share the room after the elements, the arrays stay independent
+		{`let a = [1]; let b = insert(a, 1, 2); let c = insert(a, 1, 3); let d = insert(b, 2, 4); [a, b, c, d]`, "[[1], [1, 2], [1, 3], [1, 2, 4]]"},
+		{`let a = []; for (let i = 0; i < 5; i += 1) { a = insert(a, len(a), i) }; let b = insert(a, 5, 9); a[0] = 7; pop(b); [a, b, insert(b, 5, 8), insert(a, 5, 6)]`, "[[7, 1, 2, 3, 4], [0, 1, 2, 3, 4], [0, 1, 2, 3, 4, 8], [7, 1, 2, 3, 4, 6]]"},
+	}
+
+	testInspect(t, tests)
+}
+
+func TestTimeModule(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`time.parse("2024-05-01T12:00:00Z")`, "2024-05-01T12:00:00Z"},
+		{`time.parse("01/05/2024", "02/01/2006").format("2006-01-02")`, "2024-05-01"},
+		{`time.unix(1714564800)`, "2024-05-01T12:00:00Z"},
+		{`time.unix(1714564800).unix()`, "1714564800"},
+		{`let t = time.parse("2024-05-01T12:30:45Z"); [t.year(), t.month(), t.day(), t.hour(), t.minute(), t.second(), t.weekday()]`, "[2024, 5, 1, 12, 30, 45, Wednesday]"},
+		{`time.duration("1h30m")`, "1h30m0s"},
+		{`time.duration("1500ms").seconds()`, "1.5"},
+		{`time.duration("1m").ms()`, "60000"},
+		// arithmetic
+		{`time.unix(0) + time.duration("36h")`, "1970-01-02T12:00:00Z"},
+		{`time.duration("1s") + time.unix(0)`, "1970-01-01T00:00:01Z"},
+		{`time.unix(0) - time.duration("1s")`, "1969-12-31T23:59:59Z"},
+		{`time.unix(90) - time.unix(0)`, "1m30s"},
+		{`time.duration("1m") - time.duration("90s")`, "-30s"},
+		{`time.duration("1m") * 3`, "3m0s"},
+		{`2 * time.duration("1m")`, "2m0s"},
+		{`time.duration("1m") / 4`, "15s"},
+		// comparison
+		{`time.unix(1) > time.unix(0)`, "true"},
+		{`time.unix(1) < time.unix(0)`, "false"},
+		{`time.unix(0) == time.parse("1970-01-01T01:00:00+01:00")`, "true"},
+		{`time.duration("60s") == time.duration("1m")`, "true"},
+		{`time.duration("1s") != time.duration("1m")`, "true"},
+		{`time.now() - time.now() < time.duration("1s")`, "true"},
+		{`time.since(time.now()) < time.duration("1m")`, "true"},
+		{`time.unix(0) == 0`, "false"},
+		// errors
+		{`time.parse("yesterday")`, `ERROR [R2041]: ` + "`time.parse`" + ` failed: parsing time "yesterday" as "2006-01-02T15:04:05Z07:00": cannot parse "yesterday" as "2006"`},
+		{`time.duration("1d")`, "ERROR [R2041]: `time.duration` failed: time: unknown unit \"d\" in duration \"1d\""},
+		{`time.unix(0) + 1`, "ERROR [R2004]: type mismatch: TIME + INTEGER"},
+		{`time.unix(0) * time.unix(0)`, "ERROR [R2002]: unknown operator: TIME * TIME"},
+		{`time.duration("1m") / 0`, "ERROR [R2025]: division by zero: 60000000000 / 0"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		env := object.NewEnvironment()
+		loadBuiltInMethods(env)
+		env.Set("time", TimeModule())
+
+		evaluated := Eval(parser.New(l).ParseProgram(), env)
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: expected %s, got %v", tt.input, tt.expected, evaluated)
+		}
+	}
+}
+
+func TestTimers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		elapsed  time.Duration
+	}{
+		// callbacks record what they did in a hash, they can't reassign the script's variables
+		{`let s = {"log": ""}; set_timeout(fn() { s["log"] += "b" }, 20); set_timeout(fn() { s["log"] += "a" }, 10); run_loop(); s["log"]`, "ab", 20 * time.Millisecond},
+		// same due time: the order they were set in
+		{`let s = {"log": ""}; set_timeout(fn() { s["log"] += "1" }, 5); set_timeout(fn() { s["log"] += "2" }, 5); run_loop(); s["log"]`, "12", 5 * time.Millisecond},
+		{`let s = {"n": 0}; let id = set_interval(fn() { s["n"] += 1 }, 10); set_timeout(fn() { clear_timer(id) }, 35); run_loop(); s["n"]`, "3", 35 * time.Millisecond},
+		// an interval can clear itself
+		{`let s = {"n": 0}; let id = set_interval(fn() { s["n"] += 1; if (s["n"] == 4) { clear_timer(s["id"]) } }, 10); s["id"] = id; run_loop(); s["n"]`, "4", 40 * time.Millisecond},
+		// callbacks can set timers too
+		{`let s = {"log": ""}; set_timeout(fn() { set_timeout(fn() { s["log"] += "inner" }, 5); s["log"] += "outer " }, 5); run_loop(); s["log"]`, "outer inner", 10 * time.Millisecond},
+		{`let id = set_timeout(fn() { }, 5); [clear_timer(id), clear_timer(id)]`, "[true, false]", 0},
+		{`run_loop()`, "null", 0},
+		{`set_timeout(fn() { 1 + true }, 5); run_loop()`, "ERROR [R2004]: type mismatch: INTEGER + BOOLEAN", 5 * time.Millisecond},
+		{`set_timeout(fn() { run_loop() }, 0); run_loop()`, "ERROR [R2038]: run_loop: the event loop is already running", 0},
+		{`set_timeout(fn() { }, -1)`, "ERROR [R2037]: `set_timeout` needs a delay of at least 0 ms, got -1", 0},
+		{`set_interval(fn() { }, 0)`, "ERROR [R2037]: `set_interval` needs a delay of at least 1 ms, got 0", 0},
+	}
+
+	for _, tt := range tests {
+		start := time.Unix(0, 0)
+		clock := start
+
+		loop := newEventLoop()
+		loop.now = func() time.Time { return clock }
+		loop.sleep = func(d time.Duration) { clock = clock.Add(d) }
+
+		env := object.NewEnvironment()
+		loadBuiltInMethods(env)
+		for name, builtin := range loop.builtins() {
+			env.Set(name, builtin)
+		}
+
+		evaluated := Eval(parser.New(lexer.New(tt.input)).ParseProgram(), env)
+
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: expected %s, got %v", tt.input, tt.expected, evaluated)
+		}
+
+		if elapsed := clock.Sub(start); elapsed != tt.elapsed {
+			t.Errorf("%q: expected the loop to wait %s, waited %s", tt.input, tt.elapsed, elapsed)
+		}
+	}
+}
+
+func TestForLoopScoping(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// closures capture the counter of their own iteration
+		{"let fns = []; for (let i = 0; i < 3; i++) { fns = push(fns, fn() { i }); }; [fns[0](), fns[1](), fns[2]()]", "[0, 1, 2]"},
+		// lets in the body don't leak into the next iteration or out of the loop
+		{"let r = []; for (let i = 0; i < 2; i++) { r = push(r, \"y\" in [1]); let y = i; }; r", "[false, false]"},
+		{"let x = 1; for (let i = 0; i < 2; i++) { let x = 10; }; x", "1"},
+		// changing the counter in the body carries over to the update
+		{"let r = []; for (let i = 0; i < 10; i++) { r = push(r, i); i = i + 3; }; r", "[0, 4, 8]"},
+		// return statements stop the loop
+		{"let f = fn() { for (let i = 0; i < 10; i++) { if (i == 3) { return i; } }; -1 }; f()", "3"},
+		{"let f = fn() { for (x in [1, 2, 3]) { if (x > 1) { return x * 10; } }; -1 }; f()", "20"},
+		{"let f = fn() { for (x in [1, 2, 3]) { x } }; f()", "3"},
+		{"let r = []; for (x in [1, 2]) { let double = fn() { x * 2 }; r = push(r, double); }; [r[0](), r[1]()]", "[2, 4]"},
+	}
+
+	testInspect(t, tests)
+}
+
+func TestForInStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let total = 0; for (x in [1, 2, 3]) { total += x; }; total", "6"},
+		{"let r = []; for (c in \"héllo\") { r = push(r, c); }; r", "[h, é, l, l, o]"},
+		{"let r = []; for (c in \"abc\") { if (c == \"b\") { r = push(r, c); } }; r", "[b]"},
+		{"let h = {\"a\": 1, \"b\": 2}; let total = 0; for (k in h) { total += h[k]; }; total", "3"},
+		{"let h = {\"a\": 1, \"b\": 2}; delete(h, \"a\"); let r = []; for (k in h) { r = push(r, k); }; r", "[b]"},
+		{"let gen = fn*() { yield 1; yield 2; yield 3; }; let total = 0; for (x in gen()) { total += x; }; total", "6"},
+		// yield inside a for-in loop of a generator
+		{"let evens = fn*(arr) { for (x in arr) { if (x % 2 == 0) { yield x; } } }; take(evens([1, 2, 3, 4]), 5)", "[2, 4]"},
+		// only the elements the array had when the loop started
+		{"let arr = [1, 2]; for (x in arr) { arr = push(arr, x); }; arr", "[1, 2, 1, 2]"},
+		{"let count = 0; for (x in []) { count++; }; count", "0"},
+		{"let x = 5; for (x in [1, 2]) { x }; x", "5"},
+	}
+
+	testInspect(t, tests)
+}
+
+func BenchmarkHotLoop(b *testing.B) {
+	input := `
+	let counts = {"even": 0, "odd": 0};
+	let total = 0;
+	for (let i = 0; i < 1000; i = i + 1) {
+		total = total + i;
+		counts["even"] = counts["even"] + 1;
+	};
+	total;
+	`
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		env := object.NewEnvironment()
+		Eval(program, env)
+	}
+}
+
+// short strings concatenated over and over, see object.InternRuntimeString
+func BenchmarkStringBuilding(b *testing.B) {
+	input := `
+	let keys = [];
+	for (let i = 0; i < 500; i = i + 1) {
+		let key = "user" + ":" + "id";
+		keys = push(keys, key + "s");
+	};
+	len(keys);
+	`
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		env := object.NewEnvironment()
+		loadBuiltInMethods(env)
+		Eval(program, env)
+	}
+}
+
+func TestStringCharacterBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`chars("abc")`, "[a, b, c]"},
+		{`chars("")`, "[]"},
+		{`chars("héllo")`, "[h, é, l, l, o]"},
+		{`bytes("hi")`, "bytes[104, 105]"},
+		{`bytes("é")`, "bytes[195, 169]"},
+		{`len(bytes("héllo"))`, "6"},
+		{`bytes("hi")[1]`, "105"},
+		{`bytes("hi")[2]`, "null"},
+		{`ord("a")`, "97"},
+		{`ord("é")`, "233"},
+		{`chr(97)`, "a"},
+		{`chr(ord("a") + 1)`, "b"},
+		{`chr(233)`, "é"},
+		{`let s = "abc"; s.chars()`, "[a, b, c]"},
+		{`chars(1)`, "ERROR [R2012]: chars: argument 1 must be STRING, got INTEGER"},
+		{`bytes("a", "b")`, "ERROR [R2011]: bytes: expected 1 argument, got 2"},
+		{`ord("ab")`, "ERROR [R2015]: argument to `ord` must be a single character, got \"ab\""},
+		{`ord("")`, "ERROR [R2015]: argument to `ord` must be a single character, got \"\""},
+		{`chr("a")`, "ERROR [R2012]: chr: argument 1 must be INTEGER, got STRING"},
+		{`chr(-1)`, "ERROR [R2016]: argument to `chr` is not a valid code point, got -1"},
+	}
+
+	testInspect(t, tests)
+}
+
+func TestHashDefaults(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`get({"a": 1}, "a", 0)`, "1"},
+		{`get({"a": 1}, "b", 0)`, "0"},
+		{`get({"a": 1}, "b")`, "null"},
+		{`let h = {"a": 1}; h.get("b", "none")`, "none"},
+		{`let h = {"a": 1}; h.delete("a"); h.get("a", 0)`, "0"},
+		{`let counts = withDefault({}, 0); counts["x"] = counts["x"] + 1; counts["x"] = counts["x"] + 1; counts["x"]`, "2"},
+		{`let h = withDefault({"a": 1}, 0); h["a"]`, "1"},
+		{`let h = withDefault({"a": 1}, 0); h["b"]`, "0"},
+		{`let h = withDefault({"a": 1}, 0); get(h, "b")`, "0"},
+		{`let h = withDefault({"a": 1}, 0); get(h, "b", 5)`, "5"},
+		// the original hash isn't modified
+		{`let h = {"a": 1}; let d = withDefault(h, 0); h["b"]`, "null"},
+		// deleted keys are missing
+		{`let h = withDefault({"a": 1}, 0); delete(h, "a"); [h["a"], get(h, "a")]`, "[0, 0]"},
+		// the default value is shared, a function gives every missing key its own
+		{`let h = withDefault({}, [0]); h["x"][0] = 5; h["y"]`, "[5]"},
+		{`let h = withDefault({}, fn() { [0] }); h["x"][0] = 5; [h["x"], h["y"], get(h, "z")]`, "[[0], [0], [0]]"},
+		{`withDefault({}, fn(x) { x })`, "ERROR [R2013]: argument to `withDefault` not supported, got a function with parameters (it's called without arguments)"},
+		{`get([1], 0, 0)`, "ERROR [R2012]: get: argument 1 must be HASH, got ARRAY"},
+		{`get({}, fn(x) { x }, 0)`, "ERROR [R2007]: unusable as hash key: FUNCTION"},
+		{`get({}, 1, 2, 3)`, "ERROR [R2011]: get: expected 2 to 3 arguments, got 4"},
+		{`withDefault({})`, "ERROR [R2011]: withDefault: expected 2 arguments, got 1"},
+	}
+
+	testInspect(t, tests)
+}
+
+func TestOperatorMethods(t *testing.T) {
+	point := `
+	let point = fn(x, y) {
+		{
+			"x": x,
+			"y": y,
+			"__add__": fn(a, b) { point(a["x"] + b["x"], a["y"] + b["y"]) },
+			"__sub__": fn(a, b) { point(a["x"] - b["x"], a["y"] - b["y"]) },
+			"__mul__": fn(a, b) { point(a["x"] * b, a["y"] * b) },
+			"__eq__": fn(a, b) { if (a["x"] == b["x"]) { a["y"] == b["y"] } else { false } },
+			"__lt__": fn(a, b) { a["x"] < b["x"] }
+		}
+	};
+	`
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{point + `let p = point(1, 2) + point(3, 4); [p["x"], p["y"]]`, "[4, 6]"},
+		{point + `let p = point(5, 5) - point(1, 2); [p["x"], p["y"]]`, "[4, 3]"},
+		{point + `let p = point(1, 2) * 3; [p["x"], p["y"]]`, "[3, 6]"},
+		{point + `point(1, 2) == point(1, 2)`, "true"},
+		{point + `point(1, 2) == point(2, 1)`, "false"},
+		// != falls back to __eq__
+		{point + `point(1, 2) != point(2, 1)`, "true"},
+		{point + `point(1, 2) < point(2, 1)`, "true"},
+		// only defined on the right operand
+		{`let v = {"__add__": fn(a, b) { a + b["n"] }, "n": 2}; 1 + v`, "3"},
+		// hashes without operator functions behave as before
+		{`{"a": 1} + {"b": 2}`, "ERROR [R2002]: unknown operator: HASH + HASH"},
+		{point + `point(1, 2) > point(0, 0)`, "ERROR [R2002]: unknown operator: HASH > HASH"},
+		{`{"__add__": 1} + {}`, "ERROR [R2002]: unknown operator: HASH + HASH"},
+		// errors inside the operator function are returned
+		{`let v = {"__add__": fn(a, b) { b + true }}; v + 1`, "ERROR [R2004]: type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	testInspect(t, tests)
+}
+
+func TestGenerators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let gen = fn*() { yield 1; yield 2; }; let g = gen(); [next(g), next(g), next(g)]`, "[1, 2, null]"},
+		{`let gen = fn*(x) { yield x; yield x * 2; }; take(gen(3), 5)`, "[3, 6]"},
+		{`let gen = fn*(x) { yield x; yield x * 2; }; let g = gen(3); next(g); take(g, 5)`, "[6]"},
+		// the body only runs when a value is requested
+		{`let naturals = fn*() { for (let i = 0; i > -1; i = i + 1) { yield i; } }; take(naturals(), 4)`, "[0, 1, 2, 3]"},
+		{`let gen = fn*() { let x = 1; yield x; x = x + 1; yield x; }; let g = gen(); g.next(); g.next()`, "2"},
+		{`let gen = fn*() { yield 1; return 5; yield 2; }; take(gen(), 5)`, "[1]"},
+		{`let gen = fn*() { yield 1; }; gen()`, "generator"},
+		{`let gen = fn*() { yield 1; }; let g = gen(); take(g, 2); g`, "generator(done)"},
+		// take closes the generator, the rest of the body doesn't run
+		{`let gen = fn*() { yield 1; yield 2; }; let g = gen(); take(g, 1); [g, next(g)]`, "[generator(done), null]"},
+		{`let state = {"after": false}; let gen = fn*() { yield 1; state["after"] = true; }; take(gen(), 1); state["after"]`, "false"},
+		// generators are independent of each other
+		{`let gen = fn*() { yield 1; yield 2; }; let a = gen(); let b = gen(); next(a); [next(a), next(b)]`, "[2, 1]"},
+		// generators can consume other generators
+		{`let gen = fn*() { yield 1; yield 2; }; let double = fn*(g) { yield next(g) * 2; yield next(g) * 2; }; take(double(gen()), 2)`, "[2, 4]"},
+		{`let gen = fn*() { yield 1; yield 1 + true; yield 3; }; take(gen(), 3)`, "ERROR [R2004]: type mismatch: INTEGER + BOOLEAN"},
+		{`let gen = fn*() { yield 1; yield 1 + true; }; let g = gen(); next(g); next(g)`, "ERROR [R2004]: type mismatch: INTEGER + BOOLEAN"},
+		{`let g = 0; let gen = fn*() { yield next(g); }; g = gen(); next(g)`, "ERROR [R2018]: generator is already running"},
+		{`yield 1`, "ERROR [R2017]: yield outside of a generator function"},
+		{`let gen = fn*() { let f = fn() { yield 1; }; yield f(); }; next(gen())`, "ERROR [R2017]: yield outside of a generator function"},
+		{`next([1])`, "ERROR [R2012]: next: argument 1 must be GENERATOR, got ARRAY"},
+		{`let gen = fn*() { yield 1; }; take(gen(), -1)`, "ERROR [R2014]: negative indexes not supported (yet), recieved value of -1"},
+	}
+
+	testInspect(t, tests)
+}
+
+func TestInspectBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`inspect([1, [2, [3]]])`, "[1, [2, [3]]]"},
+		{`inspect("monke")`, "monke"},
+		{`let h = {"a": 1, "b": 2}; delete(h, "a"); inspect(h)`, `{"b" : "2"}`},
+		{`inspect([1, [2, [3]]], {"depth": 2})`, "[1, [2, [...]]]"},
+		{`inspect([1, 2, 3], {"elements": 2})`, "[1, 2, ... 1 more]"},
+		{`inspect([1, [2]], {"multiline": true})`, "[\n  1,\n  [\n    2\n  ]\n]"},
+		{`inspect([1], {"depth": "2"})`, "ERROR [R2012]: inspect: argument 2 must be INTEGER for \"depth\", got STRING"},
+		{`inspect([1], {"multiline": 1})`, "ERROR [R2012]: inspect: argument 2 must be BOOLEAN for \"multiline\", got INTEGER"},
+		{`inspect([1], {"colors": true})`, "ERROR [R2013]: argument to `inspect` not supported, got colors"},
+		{`inspect([1], 2)`, "ERROR [R2012]: inspect: argument 2 must be HASH, got INTEGER"},
+		{`inspect()`, "ERROR [R2011]: inspect: expected 1 to 2 arguments, got 0"},
+	}
+
+	testInspect(t, tests)
+}
+
+func TestFileBuiltins(t *testing.T) {
+	files := vfs.NewMemory(map[string]string{"hello.txt": "hi"})
+	builtins := FileBuiltins(files)
+
+	tests := []struct {
+		builtin  string
+		args     []object.Object
+		expected string
+	}{
+		{"read_file", []object.Object{&object.String{Value: "hello.txt"}}, "hi"},
+		{"write_file", []object.Object{&object.String{Value: "out.txt"}, &object.String{Value: "monke"}}, "null"},
+		{"read_file", []object.Object{&object.String{Value: "out.txt"}}, "monke"},
+		{"write_file", []object.Object{&object.String{Value: "raw.txt"}, &object.Bytes{Value: []byte("ok")}}, "null"},
+		{"read_file", []object.Object{&object.String{Value: "raw.txt"}}, "ok"},
+		{"read_file", []object.Object{&object.String{Value: "missing.txt"}}, "ERROR [R2019]: `read_file` failed: open missing.txt: file does not exist"},
+		{"read_file", []object.Object{&object.Integer{Value: 1}}, "ERROR [R2012]: read_file: argument 1 must be STRING, got INTEGER"},
+		{"write_file", []object.Object{&object.String{Value: "out.txt"}, &object.Integer{Value: 1}}, "ERROR [R2012]: write_file: argument 2 must be STRING or BYTES, got INTEGER"},
+		{"write_file", []object.Object{&object.String{Value: "out.txt"}}, "ERROR [R2011]: write_file: expected 2 arguments, got 1"},
+	}
+
+	for _, tt := range tests {
+		result := builtins[tt.builtin].Fn(tt.args...)
+
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.builtin, tt.expected, result.Inspect())
+		}
+	}
+
+	if _, ok := builtins["glob"]; ok || BUILTIN["glob"] != nil {
+		t.Errorf("glob should only be bound by the host, see GlobBuiltin")
+	}
+
+	readOnly := FileBuiltins(vfs.ReadOnly(fstest.MapFS{}))
+	result := readOnly["write_file"].Fn(&object.String{Value: "out.txt"}, &object.String{Value: "monke"})
+
+	if result.Inspect() != "ERROR [R2019]: `write_file` failed: write out.txt: read-only file system" {
+		t.Errorf("wrong error writing to a read-only file system, got %q", result.Inspect())
+	}
+}
+
+func TestGlobMatch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`glob_match("*.txt", "notes.txt")`, "true"},
+		{`glob_match("*.txt", "notes.mk")`, "false"},
+		{`glob_match("note?.txt", "notes.txt")`, "true"},
+		{`glob_match("[a-c]*", "banana")`, "true"},
+		{`glob_match("*", "lib/x.mk")`, "false"},
+		{`glob_match("[", "x")`, "ERROR [R2020]: invalid pattern passed to `glob_match`: \"[\""},
+		{`glob_match("*", 1)`, "ERROR [R2012]: glob_match: argument 2 must be STRING, got INTEGER"},
+	}
+
+	testInspect(t, tests)
+}
+
+func TestGlob(t *testing.T) {
+	files := vfs.NewMemory(map[string]string{"lib/math.mk": "", "lib/strings.mk": "", "lib/notes.txt": "", "main.mk": ""})
+	glob := GlobBuiltin(files)
+
+	tests := []struct {
+		dir      string
+		pattern  string
+		expected string
+	}{
+		{"lib", "*.mk", "[lib/math.mk, lib/strings.mk]"},
+		{".", "*.mk", "[main.mk]"},
+		{"lib", "*.go", "[]"},
+		{"missing", "*", "ERROR [R2019]: `glob` failed: readdir missing: file does not exist"},
+		{"lib", "[", "ERROR [R2020]: invalid pattern passed to `glob`: \"[\""},
+	}
+
+	for _, tt := range tests {
+		result := glob.Fn(&object.String{Value: tt.dir}, &object.String{Value: tt.pattern})
+
+		if result.Inspect() != tt.expected {
+			t.Errorf("glob(%q, %q): expected %q, got %q", tt.dir, tt.pattern, tt.expected, result.Inspect())
+		}
+	}
+}
+
+func TestNumberBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`parse_int("42")`, "42"},
+		{`parse_int(" -7 ")`, "-7"},
+		{`parse_int("ff", 16)`, "255"},
+		{`parse_int("-101", 2)`, "-5"},
+		{`parse_int("12abc")`, "ERROR [R2021]: `parse_int` could not parse \"12abc\" as a number"},
+		{`parse_int("2", 2)`, "ERROR [R2021]: `parse_int` could not parse \"2\" as a number"},
+		{`parse_int("1", 37)`, "ERROR [R2022]: base passed to `parse_int` must be between 2 and 36, got 37"},
+		{`parse_int(1)`, "ERROR [R2012]: parse_int: argument 1 must be STRING, got INTEGER"},
+		{`parse_float("3.14")`, "3.14"},
+		{`parse_float("1e3")`, "1000.0"},
+		{`parse_float("2")`, "2.0"},
+		{`parse_float("pi")`, "ERROR [R2021]: `parse_float` could not parse \"pi\" as a number"},
+		{`to_base(255, 16)`, "ff"},
+		{`to_base(-5, 2)`, "-101"},
+		{`to_base(35, 36)`, "z"},
+		{`to_base(10, 1)`, "ERROR [R2022]: base passed to `to_base` must be between 2 and 36, got 1"},
+		{`to_base("10", 2)`, "ERROR [R2012]: to_base: argument 1 must be INTEGER, got STRING"},
+		{`format_float(3.14159, 2)`, "3.14"},
+		{`format_float(2.5, 0)`, "2"},
+		{`format_float(2, 3)`, "2.000"},
+		{`format_float(1.5, -1)`, "ERROR [R2013]: argument to `format_float` not supported, got -1"},
+		{`format_float("1.5", 1)`, "ERROR [R2012]: format_float: argument 1 must be FLOAT or INTEGER, got STRING"},
+		{`parse_int(to_base(1000, 7), 7)`, "1000"},
+		{`number_format(1234567.891, 2)`, "1,234,567.89"},
+		{`number_format(1234567.891, 2, ".", ",")`, "1.234.567,89"},
+		{`number_format(1234567.891, 0, " ")`, "1 234 568"},
+		{`number_format(-1234567)`, "-1,234,567"},
+		{`number_format(9223372036854775807)`, "9,223,372,036,854,775,807"},
+		{`number_format(999, 2)`, "999.00"},
+		{`number_format(1000, 1, "", ",")`, "1000,0"},
+		// halves round to even, like format_float
+		{`number_format(2.5)`, "2"},
+		{`number_format(-0.001, 2)`, "0.00"},
+		{`number_format(123456, 0, "'")`, "123'456"},
+		{`number_format(1.5, -1)`, "ERROR [R2013]: argument to `number_format` not supported, got -1"},
+		{`number_format("1")`, "ERROR [R2012]: number_format: argument 1 must be FLOAT or INTEGER, got STRING"},
+	}
+
+	testInspect(t, tests)
+}
+
+// identifiers resolved through a few enclosing function scopes, see object.Environment
+func BenchmarkClosureLookup(b *testing.B) {
+	input := `
+	let total = 0;
+	let offset = 3;
+	let makeAdder = fn(step) {
+		fn(x) {
+			let scaled = x * step;
+			scaled + offset + step
+		}
+	};
+	let add = makeAdder(2);
+	for (let i = 0; i < 500; i = i + 1) {
+		total = add(i) + total;
+	};
+	total;
+	`
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		env := object.NewEnvironment()
+		loadBuiltInMethods(env)
+		Eval(program, env)
+	}
+}
+
+// identifiers cache where they were found, these make sure stale positions aren't used
+func TestIdentifierResolution(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// the same node evaluated in scopes where the binding has different slots
+		{`let f = fn(a, b) { b }; [f(1, 2), f(3, 4)]`, "[2, 4]"},
+		{`let f = fn(c) { if (c) { let a = 1; }; let b = 2; b }; [f(true), f(false)]`, "[2, 2]"},
+		// a binding added later to a closer scope shadows the one found before
+		// (every iteration has its own scope, so the second one sees the outer x again)
+		{`let x = 1; let f = fn() { let r = []; for (let i = 0; i < 2; i = i + 1) { r = push(r, x); let x = 5; }; r }; f()`, "[1, 1]"},
+		{`let x = 1; let f = fn() { let a = x; x = 2; let b = x; [a, b] }; [f(), x]`, "[[1, 2], 1]"},
+		// assigning to x in the loop binds it in the function's scope, see object.Environment.Assign
+		{`let x = 1; let f = fn() { let r = []; for (let i = 0; i < 2; i = i + 1) { r = push(r, x); x = 5; }; r }; [f(), x]`, "[[1, 5], 1]"},
+		// the same closure called from different depths
+		{`let x = 10; let f = fn() { x }; let g = fn() { let x = 20; f() }; [f(), g()]`, "[10, 10]"},
+		{`let make = fn(n) { fn() { n } }; let a = make(1); let b = make(2); [a(), b(), a()]`, "[1, 2, 1]"},
+	}
+
+	testInspect(t, tests)
+}
+
+// compute heavy: lots of short-lived integers, see object.InternInteger
+func BenchmarkFibonacci(b *testing.B) {
+	input := `
+	let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } };
+	fib(15);
+	`
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		env := object.NewEnvironment()
+		Eval(program, env)
+	}
+}
+
+func TestFloatExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"3.14", "3.14"},
+		{"-2.5", "-2.5"},
+		{"1.5 + 1.5", "3.0"},
+		{"1 + 0.5", "1.5"},
+		{"0.5 + 1", "1.5"},
+		{"10 / 4.0", "2.5"},
+		{"10 / 4", "2"},
+		{"2.5 * 2", "5.0"},
+		{"1.5 - 3", "-1.5"},
+		{"1.5 < 2", "true"},
+		{"2 > 2.5", "false"},
+		{"2 == 2.0", "true"},
+		{"2.0 != 2.5", "true"},
+		{"1.0 / 0", "+Inf"},
+		{"1e3", "1000.0"},
+		{"2.5e-3 * 2", "0.005"},
+		{"1e3 == 1000", "true"},
+		{`let h = {1.5: "a"}; h[1.5]`, "a"},
+		{`1.5 + "a"`, "ERROR [R2004]: type mismatch: FLOAT + STRING"},
+		{`1.5 + true`, "ERROR [R2004]: type mismatch: FLOAT + BOOLEAN"},
+		{`5.5 % 2`, "ERROR [R2002]: unknown operator: FLOAT % INTEGER"},
+		{`let x = 0; 10 % x`, "ERROR [R2025]: division by zero: 10 % 0"},
+		{`let x = 0; 10 / x`, "ERROR [R2025]: division by zero: 10 / 0"},
+		{`1 << -1`, "ERROR [R2028]: negative shift count: 1 << -1"},
+		{`1.5 & 1`, "ERROR [R2002]: unknown operator: FLOAT & INTEGER"},
+		{`true | false`, "ERROR [R2002]: unknown operator: BOOLEAN | BOOLEAN"},
+	}
+
+	testInspect(t, tests)
+}
+
+func TestRepetition(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"ab" * 3`, "ababab"},
+		{`3 * "ab"`, "ababab"},
+		{`"ab" * 0`, ""},
+		{`[0] * 3`, "[0, 0, 0]"},
+		{`2 * [1, 2]`, "[1, 2, 1, 2]"},
+		{`[] * 1000000000000`, "[]"},
+		{`"" * 1000000000000`, ""},
+		{`let row = [0]; let grid = [row] * 2; row[0] = 1; grid`, "[[1], [1]]"},
+		{`"ab" * -1`, "ERROR [R2023]: cannot repeat STRING a negative number of times, got -1"},
+		{`[1] * -2`, "ERROR [R2023]: cannot repeat ARRAY a negative number of times, got -2"},
+		{`"ab" * 9223372036854775807`, "ERROR [R2024]: memory limit exceeded: repeating STRING 9223372036854775807 times needs more than 1073741824 bytes"},
+		// without a memory limit, results are still capped to MAX_REPETITION_SIZE
+		{`"ab" * 100000000000000`, "ERROR [R2024]: memory limit exceeded: repeating STRING 100000000000000 times needs more than 1073741824 bytes"},
+		{`[1] * 100000000000000`, "ERROR [R2024]: memory limit exceeded: repeating ARRAY 100000000000000 times needs more than 1073741824 bytes"},
+		{`"ab" * 2.5`, "ERROR [R2004]: type mismatch: STRING * FLOAT"},
+		{`"ab" * "c"`, "ERROR [R2002]: unknown operator: STRING * STRING"},
+	}
+
+	testInspect(t, tests)
+}
+
+func TestRepetitionMemoryLimit(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"ab" * 50`, strings.Repeat("ab", 50)},
+		{`"ab" * 51`, "ERROR [R2024]: memory limit exceeded: repeating STRING 51 times needs more than 100 bytes"},
+		{`[1] * 6`, "[1, 1, 1, 1, 1, 1]"},
+		{`[1] * 7`, "ERROR [R2024]: memory limit exceeded: repeating ARRAY 7 times needs more than 100 bytes"},
+		// inherited by function scopes
+		{`let f = fn() { "a" * 101 }; f()`, "ERROR [R2024]: memory limit exceeded: repeating STRING 101 times needs more than 100 bytes"},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		env := object.NewEnvironment()
+		env.SetLimits(object.Limits{Memory: 100})
+
+		evaluated := Eval(program, env)
+
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestFuelAndDepthLimits(t *testing.T) {
+	tests := []struct {
+		input    string
+		limits   object.Limits
+		expected string
+	}{
+		{`let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; [f(40), f(40)]`, object.Limits{Depth: 50}, "[0, 0]"},
+		{`let f = fn(n) { f(n + 1) }; f(0)`, object.Limits{Depth: 50}, "ERROR [R2035]: depth limit exceeded: more than 50 nested function calls"},
+		{`let x = 0; for (let i = 0; i > -1; i++) { x = i; }`, object.Limits{Fuel: 1000}, "ERROR [R2034]: fuel limit exceeded: evaluated more than 1000 nodes"},
+		{`let total = 0; for (x in [1, 2, 3]) { total += x; }; total`, object.Limits{Fuel: 1000}, "6"},
+		// callbacks called by builtins count against the same limits
+		{`let f = fn(x) { f(x) }; [1].map(f)`, object.Limits{Depth: 50}, "ERROR [R2035]: depth limit exceeded: more than 50 nested function calls"},
+		{`[1, 2].map(fn(x) { for (let i = 0; i > -1; i++) { x } })`, object.Limits{Fuel: 1000}, "ERROR [R2034]: fuel limit exceeded: evaluated more than 1000 nodes"},
+		{`let v = {"__add__": fn(a, b) { a + b }}; v + v`, object.Limits{Depth: 50}, "ERROR [R2035]: depth limit exceeded: more than 50 nested function calls"},
+		{`let gen = fn*() { for (let i = 0; i > -1; i++) { yield i; } }; for (x in gen()) { x }`, object.Limits{Fuel: 1000}, "ERROR [R2034]: fuel limit exceeded: evaluated more than 1000 nodes"},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		env := object.NewEnvironment()
+		env.SetLimits(tt.limits)
+
+		evaluated := Eval(program, env)
+
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: expected %q, got %v", tt.input, tt.expected, evaluated)
+		}
+	}
+}
+
+func TestMembershipOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`3 in [1, 2, 3]`, "true"},
+		{`4 in [1, 2, 3]`, "false"},
+		{`4 !in [1, 2, 3]`, "true"},
+		{`2.0 in [1, 2]`, "true"},
+		{`"a" in ["a", "b"]`, "true"},
+		{`let a = "abcdefghij"; a + a in ["x", "abcdefghijabcdefghij"]`, "true"},
+		{`"1" in [1]`, "false"},
+		{`let h = {"key": 1}; "key" in h`, "true"},
+		{`let h = {"key": 1}; "other" in h`, "false"},
+		{`let h = {"key": 1}; h.delete("key"); "key" in h`, "false"},
+		{`let h = {"key": 1}; "key" !in h`, "false"},
+		{`"sub" in "substring"`, "true"},
+		{`"" in "abc"`, "true"},
+		{`"x" !in "abc"`, "true"},
+		{`1 + 2 in [3]`, "true"},
+		{`[1] in {}`, "ERROR [R2007]: unusable as hash key: ARRAY"},
+		{`1 in "abc"`, "ERROR [R2004]: type mismatch: INTEGER in STRING"},
+		{`1 in 5`, "ERROR [R2002]: unknown operator: INTEGER in INTEGER"},
+	}
+
+	testInspect(t, tests)
+}
+
+func TestMethodCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"hello".upper()`, "HELLO"},
+		{`"hello".upper().split("L")`, "[HE, , O]"},
+		{`"  a b  c ".split()`, "[a, b, c]"},
+		{`" Hi ".trim().lower().len()`, "2"},
+		{`"a-b-c".replace("-", "+")`, "a+b+c"},
+		{`"monke.mk".starts_with("monke")`, "true"},
+		{`"monke.mk".ends_with(".go")`, "false"},
+		{`"ff".parse_int(16)`, "255"},
+		{`[1, 2, 3].slice(1).join(", ")`, "2, 3"},
+		{`["a", 1, true].join()`, "a1true"},
+		{`let h = {"a": 1}; h.get("a")`, "1"},
+		{`"héllo".chars().len()`, "5"},
+		// not a method of the type: falls back to the functions in scope
+		{`let double = fn(x) { x * 2 }; 2.double()`, "4"},
+		{`let greet = fn(name, greeting) { greeting + " " + name }; "monke".upper().greet("hi")`, "hi MONKE"},
+		{`let len = fn(x) { 0 }; "abc".len()`, "3"},
+		{`5.upper()`, "ERROR [R2003]: identifier not found: upper"},
+		{`"a".upper(1)`, "ERROR [R2011]: upper: expected 1 argument, got 2"},
+		{`"a".replace("a")`, "ERROR [R2011]: replace: expected 3 arguments, got 2"},
+		{`"a".split(missing)`, "ERROR [R2003]: identifier not found: missing"},
+		{`missing.upper()`, "ERROR [R2003]: identifier not found: missing"},
+	}
+
+	testInspect(t, tests)
+}
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`true && true`, "true"},
+		{`true && false`, "false"},
+		{`false || true`, "true"},
+		{`false || false`, "false"},
+		{`1 < 2 && 2 < 3`, "true"},
+		{`let h = {}; h["name"] || "default"`, "default"},
+		{`"name" || "default"`, "name"},
+		{`let h = {}; h["name"] && 1`, "null"},
+		{`1 && 2`, "2"},
+		{`true || false && false`, "true"},
+		// the right side isn't evaluated when the left one decides the result
+		{`let c = {"calls": 0}; let f = fn() { c["calls"] = c["calls"] + 1; true }; true || f(); false && f(); c["calls"]`, "0"},
+		{`let c = {"calls": 0}; let f = fn() { c["calls"] = c["calls"] + 1; true }; false || f(); true && f(); c["calls"]`, "2"},
+		{`true || missing`, "true"},
+		{`false || missing`, "ERROR [R2003]: identifier not found: missing"},
+		{`missing && true`, "ERROR [R2003]: identifier not found: missing"},
+	}
+
+	testInspect(t, tests)
+}
+
+func TestScriptBindings(t *testing.T) {
+	declare := `flags.string("name", "world", "who to greet"); flags.int("times", 1, "how many greetings"); flags.bool("shout", false);`
+
+	tests := []struct {
+		args     []string
+		input    string
+		expected string
+		output   string
+	}{
+		{[]string{"a", "b"}, `args()`, "[a, b]", ""},
+		{nil, `args()`, "[]", ""},
+		{nil, declare + `let opts = flags.parse(); [opts["name"], opts["times"], opts["shout"]]`, "[world, 1, false]", ""},
+		{
+			[]string{"--name=monke", "-times", "3", "--shout", "rest", "--not-a-flag"},
+			declare + `let opts = flags.parse(); [opts["name"], opts["times"], opts["shout"], flags.args()]`,
+			"[monke, 3, true, [rest, --not-a-flag]]", "",
+		},
+		{
+			[]string{"--help"},
+			declare + `flags.parse()`,
+			"null",
+			"usage: tool.mk [options]\n  -name string\n    \twho to greet (default \"world\")\n  -shout\n    \t\n  -times int\n    \thow many greetings (default 1)\n",
+		},
+		{[]string{"--times=many"}, declare + `flags.parse()`,
+			"ERROR [R2027]: `flags.parse` failed: invalid value \"many\" for flag -times: parse error", ""},
+		{[]string{"--other"}, declare + `flags.parse()`,
+			"ERROR [R2027]: `flags.parse` failed: flag provided but not defined: -other", ""},
+		{nil, `flags.string("name", "a"); flags.string("name", "b")`,
+			"ERROR [R2027]: `flags.string` failed: flag redefined: name", ""},
+		{nil, `flags.int("n", "1")`, "ERROR [R2012]: flags.int: argument 2 must be INTEGER, got STRING", ""},
+		{nil, `flags.missing()`, "ERROR [R2026]: module flags has no member missing", ""},
+		{nil, `flags`, "<module flags>", ""},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		env := object.NewEnvironment()
+		for name, value := range ScriptBindings("tool.mk", tt.args, &out) {
+			env.Set(name, value)
+		}
+
+		evaluated := Eval(parser.New(lexer.New(tt.input)).ParseProgram(), env)
+
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.input, tt.expected, evaluated.Inspect())
+		}
+
+		if out.String() != tt.output {
+			t.Errorf("%s: expected output %q, got %q", tt.input, tt.output, out.String())
+		}
+	}
+}
+
+func TestConfigBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let server = toml_decode("[server]\nport = 8080\nhosts = [\"a\", \"b\"]")["server"]; [server["port"], server["hosts"]]`, "[8080, [a, b]]"},
+		{`toml_decode("ratio = 0.5\nenabled = true")["ratio"]`, "0.5"},
+		{`toml_decode("a = ")`, "ERROR [R2031]: `toml_decode` failed: line 1: expected a value"},
+		{`toml_encode({"name": "monke", "server": {"port": 80}})`, "name = \"monke\"\n\n[server]\nport = 80\n"},
+		{`toml_encode({"a": [1, 2]})`, "a = [1, 2]\n"},
+		{`let h = {"a": 1, "b": 2}; delete(h, "b"); toml_encode(h)`, "a = 1\n"},
+		{`toml_encode({"a": {"b": [1]}["c"]})`, "ERROR [R2031]: `toml_encode` failed: a is null, TOML has no null"},
+		{`toml_encode({1: 2})`, "ERROR [R2031]: `toml_encode` failed: keys must be strings, got INTEGER"},
+		{`toml_encode([1])`, "ERROR [R2012]: toml_encode: argument 1 must be HASH, got ARRAY"},
+		{`yaml_decode("ports:\n  - 80\n  - 443\nname: monke\ndebug: ~")["ports"]`, "[80, 443]"},
+		{`yaml_decode("- a\n- b: 1")`, `[a, {"b" : "1"}]`},
+		{`yaml_decode("a: 1\na: 2")`, "ERROR [R2032]: `yaml_decode` failed: line 2: key a is defined twice"},
+		{`yaml_encode({"name": "monke", "tags": ["a", "true"]})`, "name: monke\ntags:\n  - a\n  - \"true\"\n"},
+		{`yaml_encode(5)`, "5\n"},
+		{`yaml_encode({"f": fn() { 1 }})`, "ERROR [R2032]: `yaml_encode` failed: cannot encode FUNCTION"},
+		{`let config = {"a": [1, {"b": 2.5}], "c": "x"}; yaml_decode(yaml_encode(config))["a"]`, `[1, {"b" : "2.5"}]`},
+		{`let config = {"a": [1, {"b": 2.5}], "c": "x"}; toml_decode(toml_encode(config))["a"]`, `[1, {"b" : "2.5"}]`},
+	}
+
+	testInspect(t, tests)
+}
+
+func TestCSVBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`csv_parse("a,b\n1,2\n")`, "[[a, b], [1, 2]]"},
+		{`csv_parse("name,note\nmonke,\"says \"\"hi\"\", twice\"")[1][1]`, `says "hi", twice`},
+		{`csv_parse("a;b\n1;2", {"separator": ";"})`, "[[a, b], [1, 2]]"},
+		{`let rows = csv_parse("a,b\n1,2\n3,4", {"header": true}); [len(rows), rows[1]["b"]]`, "[2, 4]"},
+		{`csv_parse("", {"header": true})`, "[]"},
+		{`csv_parse("a,b\n1")`, "ERROR [R2029]: `csv_parse` failed: record on line 2: wrong number of fields"},
+		{`csv_parse("a", {"separator": "::"})`, "ERROR [R2015]: argument to `csv_parse` must be a single character, got \"::\""},
+		{`csv_parse("a", {"header": 1})`, "ERROR [R2012]: csv_parse: argument 2 must be BOOLEAN for \"header\", got INTEGER"},
+		{`csv_parse("a", {"quote": "'"})`, "ERROR [R2013]: argument to `csv_parse` not supported, got quote"},
+		{`csv_encode([["a", "b"], [1, true], ["x,y", "say \"hi\""]])`, "a,b\n1,true\n\"x,y\",\"say \"\"hi\"\"\"\n"},
+		{`csv_encode([{"b": 2, "a": 1}, {"a": 3}])`, "a,b\n1,2\n3,\n"},
+		{`csv_encode([[1, 2]], {"separator": "\t"})`, "1\t2\n"},
+		{`csv_encode([])`, ""},
+		{`csv_encode([[1], 2])`, "ERROR [R2012]: csv_encode: argument 1 must be ARRAY or HASH for row 2, got INTEGER"},
+		{`csv_encode(csv_parse("a,b\n1,2\n"))`, "a,b\n1,2\n"},
+		{`csv_encode(csv_parse("a,b\n1,2\n", {"header": true}))`, "a,b\n1,2\n"},
+	}
+
+	testInspect(t, tests)
+}
+
+func TestEvalSafe(t *testing.T) {
+	program := parser.New(lexer.New(`let x = 1; boom(x)`)).ParseProgram()
+	env := object.NewEnvironment()
+	env.Set("boom", &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		var arr []object.Object
+		return arr[len(args)]
+	}})
+
+	result := EvalSafe(program, env)
+	errObj, ok := result.(*object.Error)
+	if !ok {
+		t.Fatalf("expected an *object.Error, got %T (%+v)", result, result)
+	}
+
+	if errObj.Inspect() != "ERROR [R2039]: internal interpreter error: runtime error: index out of range [1] with length 0" {
+		t.Errorf("wrong error, got %s", errObj.Inspect())
+	}
+	if !strings.Contains(errObj.Stack, "TestEvalSafe") {
+		t.Errorf("expected the stack to lead to the panic, got %s", errObj.Stack)
+	}
+
+	// without a panic it's just Eval
+	if result := EvalSafe(parser.New(lexer.New("1 + 1")).ParseProgram(), object.NewEnvironment()); result.Inspect() != "2" {
+		t.Errorf("expected 2, got %s", result.Inspect())
+	}
+}
+
+// records the nodes the hooks see, "+" when entering and "-" when leaving
+type nodeRecorder struct {
+	events []string
+}
+
+func (r *nodeRecorder) BeforeNode(node ast.Node, env *object.Environment) {
+	r.events = append(r.events, "+"+node.String())
+}
+
+func (r *nodeRecorder) AfterNode(node ast.Node, result object.Object) {
+	r.events = append(r.events, "-"+node.String())
+}
+
+func TestDeepInfixChains(t *testing.T) {
+	// recursing once per operator would need far more than this
+	defer debug.SetMaxStack(debug.SetMaxStack(8 << 20))
+
+	const terms = 200_000
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{strings.Repeat("1 + ", terms-1) + "1", "200000"},
+		{strings.Repeat("true && ", terms-1) + "true", "true"},
+		{"let x = 1; " + strings.Repeat("x * ", terms-1) + "x", "1"},
+		{`"a" + ` + strings.Repeat("1 + ", terms-1) + "1", "ERROR [R2004]: type mismatch: STRING + INTEGER"},
+	}
+
+	testInspect(t, tests)
+
+	// the nested expressions cost fuel and are seen by hooks like every other node (program, statement, then the chain)
+	program := parser.New(lexer.New("1 + 2 - 3")).ParseProgram()
+	env := object.NewEnvironment()
+	recorder := &nodeRecorder{}
+	env.AddHook(recorder)
+
+	if result := Eval(program, env); result.Inspect() != "0" {
+		t.Fatalf("expected 0, got %s", result.Inspect())
+	}
+
+	expected := "+((1 + 2) - 3) +((1 + 2) - 3) +((1 + 2) - 3) +(1 + 2) +1 -1 +2 -2 -(1 + 2) +3 -3 -((1 + 2) - 3) -((1 + 2) - 3) -((1 + 2) - 3)"
+	if got := strings.Join(recorder.events, " "); got != expected {
+		t.Errorf("wrong hook calls, expected %q, got %q", expected, got)
+	}
+
+	env = object.NewEnvironment()
+	env.SetLimits(object.Limits{Fuel: 1000})
+	if result := Eval(parser.New(lexer.New(strings.Repeat("1 + ", 2000)+"1")).ParseProgram(), env); result.Inspect() != "ERROR [R2034]: fuel limit exceeded: evaluated more than 1000 nodes" {
+		t.Errorf("expected the fuel limit error, got %s", result.Inspect())
+	}
+}
+
+func TestIdentifierSuggestions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let counter = 0; countre + 1`, "ERROR [R2003]: identifier not found: countre, did you mean 'counter'?"},
+		{`let counter = 0; conuter = 1`, "ERROR [R2003]: identifier not found: conuter, did you mean 'counter'?"},
+		{`let f = fn(value) { valeu }; f(1)`, "ERROR [R2003]: identifier not found: valeu, did you mean 'value'?"},
+		{`lenn([1])`, "ERROR [R2003]: identifier not found: lenn, did you mean 'len'?"},
+		// too far from every name
+		{`let counter = 0; cnt`, "ERROR [R2003]: identifier not found: cnt"},
+		{`let x = 0; y`, "ERROR [R2003]: identifier not found: y"},
+	}
+
+	testInspect(t, tests)
+
+	distances := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "abc", 3},
+		{"counter", "countre", 1},
+		{"ca", "abc", 3},
+		{"kitten", "sitting", 3},
+		{"héllo", "hello", 1},
+	}
+
+	for _, tt := range distances {
+		if got := editDistance(tt.a, tt.b); got != tt.expected {
+			t.Errorf("editDistance(%q, %q): expected %d, got %d", tt.a, tt.b, tt.expected, got)
+		}
+	}
+}
+
+func TestBuiltinDocs(t *testing.T) {
+	env := object.NewEnvironment()
+	loadBuiltInMethods(env)
+	env.Set("runtime_info", RuntimeInfo(env))
+	for name, value := range RepeatBuiltins(env) {
+		env.Set(name, value)
+	}
+	for name, value := range TimerBuiltins() {
+		env.Set(name, value)
+	}
+	env.Set("args", ScriptBindings("test", nil, &bytes.Buffer{})["args"])
+	for name, builtin := range FileBuiltins(vfs.OS()) {
+		env.Set(name, builtin)
+	}
+	env.Set("glob", GlobBuiltin(vfs.OS()))
+
+	var out bytes.Buffer
+	env.Set("puts", Puts(env, &out))
+	env.Set("help", HelpBuiltin(env, &out))
+
+	// every builtin function in scope is documented
+	for _, name := range env.AllNames() {
+		value, _ := env.Get(name)
+		builtin, ok := value.(*object.Builtin)
+		if !ok {
+			continue
+		}
+
+		if builtin.Doc == nil {
+			t.Errorf("%s has no documentation", name)
+		} else if builtin.Doc.Name != name || !strings.HasPrefix(builtin.Doc.Signature, name+"(") {
+			t.Errorf("%s has the documentation of %s (%s)", name, builtin.Doc.Name, builtin.Doc.Signature)
+		}
+	}
+
+	// every method and module member is documented too
+	for objType, methods := range METHODS {
+		for name, method := range methods {
+			if method.Doc == nil {
+				t.Errorf("%s method %s has no documentation", objType, name)
+			} else if method.Doc.Name != name {
+				t.Errorf("%s method %s has the documentation of %s", objType, name, method.Doc.Name)
+			}
+		}
+	}
+
+	for _, module := range []*object.Module{TimeModule(), FlagsModule("test", nil, &bytes.Buffer{})} {
+		for name, member := range module.Members {
+			qualified := module.Name + "." + name
+			builtin, ok := member.(*object.Builtin)
+			if !ok || builtin.Doc == nil {
+				t.Errorf("%s has no documentation", qualified)
+			} else if builtin.Doc.Name != qualified || !strings.HasPrefix(builtin.Doc.Signature, qualified+"(") {
+				t.Errorf("%s has the documentation of %s (%s)", qualified, builtin.Doc.Name, builtin.Doc.Signature)
+			}
+		}
+	}
+	env.Set("time", TimeModule())
+
+	program := parser.New(lexer.New(`help("first")`)).ParseProgram()
+	if result := Eval(program, env); result != NULL {
+		t.Fatalf("expected null, got %s", result.Inspect())
+	}
+	if out.String() != "first(array)\n    The first element of the array, null when it's empty.\n" {
+		t.Errorf("wrong help output, got %q", out.String())
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`help("frist")`, "ERROR [R2003]: identifier not found: frist, did you mean 'first'?"},
+		{`let double = fn(x) { x * 2 }; help("double")`, "ERROR [R2042]: no documentation for double, only builtin functions are documented"},
+		{`help(len)`, "ERROR [R2012]: help: argument 1 must be STRING, got BUILTIN"},
+		{`help("time.nope")`, "ERROR [R2026]: module time has no member nope"},
+		{`help("first.nope")`, "ERROR [R2042]: no documentation for first.nope, only builtin functions are documented"},
+	}
+
+	for _, tt := range tests {
+		result := Eval(parser.New(lexer.New(tt.input)).ParseProgram(), env)
+
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.input, tt.expected, result.Inspect())
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	env := object.NewEnvironment()
+	loadBuiltInMethods(env)
+	env.Set("time", TimeModule())
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"len", "len(value)\n    Number of elements of an array, bytes of a byte array, characters (not bytes) of a string.\n    len(\"héllo\") => 5"},
+		{"upper", "str.upper()\n    The string in uppercase: \"Hello\".upper() => \"HELLO\""},
+		{"weekday", "t.weekday()\n    The day of the week of the time: \"Monday\""},
+		{"time.now", "time.now()\n    The current (local) time."},
+		{"time", "module time\n    time.duration(text)\n    time.format(t[, layout])\n    time.now()\n    time.parse(text[, layout])\n    time.since(t)\n    time.unix(seconds)"},
+	}
+
+	for _, tt := range tests {
+		doc, err := Describe(tt.name, env)
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", tt.name, err.Inspect())
+			continue
+		}
+
+		if doc != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.expected, doc)
+		}
+	}
+}
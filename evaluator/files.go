@@ -0,0 +1,132 @@
+package evaluator
+
+import (
+	"monkey/catalog"
+	"monkey/object"
+	"monkey/vfs"
+	"path"
+)
+
+/**
+Returns the file builtins (read_file, write_file) backed by the given file system.
+BUILTIN uses the host's file system, embedders can bind these in an environment instead
+to serve files from somewhere else (see interpreter.WithFS).
+**/
+func FileBuiltins(fsys vfs.FS) map[string]*object.Builtin {
+	return map[string]*object.Builtin{
+		"read_file":  {Doc: DOCS["read_file"], Fn: readFileBuiltin(fsys)},
+		"write_file": {Doc: DOCS["write_file"], Fn: writeFileBuiltin(fsys)},
+	}
+}
+
+/**
+Returns glob listing the directories of the given file system.
+It isn't in BUILTIN: scripts can only list directories when the host binds it in an environment
+(the CLI with the host's file system, interpreter.WithFS with an FS that can list directories).
+**/
+func GlobBuiltin(fsys vfs.ReadDirFS) *object.Builtin {
+	return &object.Builtin{Doc: DOCS["glob"], Fn: globBuiltin(fsys)}
+}
+
+// read_file("notes.txt") => contents of the file as a string
+func readFileBuiltin(fsys vfs.FS) object.BuiltinFunction {
+	return func(args ...object.Object) object.Object {
+		if err := object.CheckArgs("read_file", args, object.Arg(object.STRING_OBJ)); err != nil {
+			return err
+		}
+
+		name := args[0].(*object.String)
+
+		data, readErr := fsys.ReadFile(name.Value)
+		if readErr != nil {
+			return newError(catalog.FILE_ERROR, "read_file", readErr)
+		}
+
+		return &object.String{Value: string(data)}
+	}
+}
+
+// write_file("notes.txt", "monke") => replaces the contents of the file (creating it if needed)
+func writeFileBuiltin(fsys vfs.FS) object.BuiltinFunction {
+	return func(args ...object.Object) object.Object {
+		if err := object.CheckArgs("write_file", args, object.Arg(object.STRING_OBJ), object.Arg(object.STRING_OBJ, object.BYTES_OBJ)); err != nil {
+			return err
+		}
+
+		name := args[0].(*object.String)
+
+		var data []byte
+
+		switch content := args[1].(type) {
+		case *object.String:
+			data = []byte(content.Value)
+		case *object.Bytes:
+			data = content.Value
+		}
+
+		if writeErr := fsys.WriteFile(name.Value, data); writeErr != nil {
+			return newError(catalog.FILE_ERROR, "write_file", writeErr)
+		}
+
+		return NULL
+	}
+}
+
+/**
+glob("lib", "*.mk") => ["lib/math.mk", "lib/strings.mk"]
+
+Only matches the entries directly inside the directory (no "**"), sorted by name.
+See glob_match for the pattern syntax.
+**/
+func globBuiltin(fsys vfs.ReadDirFS) object.BuiltinFunction {
+	return func(args ...object.Object) object.Object {
+		if err := object.CheckArgs("glob", args, object.Arg(object.STRING_OBJ), object.Arg(object.STRING_OBJ)); err != nil {
+			return err
+		}
+
+		dir, pattern := args[0].(*object.String), args[1].(*object.String)
+
+		if _, err := path.Match(pattern.Value, ""); err != nil {
+			return newError(catalog.INVALID_PATTERN, "glob", pattern.Value)
+		}
+
+		names, readErr := fsys.ReadDir(dir.Value)
+		if readErr != nil {
+			return newError(catalog.FILE_ERROR, "glob", readErr)
+		}
+
+		matches := &object.Array{Elements: []object.Object{}}
+
+		for _, name := range names {
+			if matched, _ := path.Match(pattern.Value, name); matched {
+				matches.Elements = append(matches.Elements, &object.String{Value: path.Join(dir.Value, name)})
+			}
+		}
+
+		return matches
+	}
+}
+
+/**
+glob_match("*.txt", "notes.txt") => true
+
+Patterns use the path.Match syntax:
+- * matches any run of characters except "/"
+- ? matches a single character (except "/")
+- [abc], [a-z], [^a-z] match a single character in (or not in) the set
+- \ escapes the next character
+**/
+func __glob_match__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("glob_match", args, object.Arg(object.STRING_OBJ), object.Arg(object.STRING_OBJ)); err != nil {
+		return err
+	}
+
+	pattern, name := args[0].(*object.String), args[1].(*object.String)
+
+	matched, err := path.Match(pattern.Value, name.Value)
+	if err != nil {
+		return newError(catalog.INVALID_PATTERN, "glob_match", pattern.Value)
+	}
+
+	return nativeBoolToBooleanObject(matched)
+}
@@ -0,0 +1,59 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/catalog"
+	"monkey/object"
+)
+
+/**
+Calling a generator function doesn't run its body, it returns a generator that runs
+the body up to the next yield every time a value is requested (next(gen), take(gen, n)).
+
+- a return statement (or reaching the end of the body) finishes the generator, the returned value is dropped
+- runtime errors inside the body are handed out as the generator's last value
+- the generator is closed when the run it's created in is over, if it hasn't finished (see Environment.CloseGenerators)
+**/
+func newGenerator(fn *object.Function, args []object.Object) *object.Generator {
+	gen := object.NewGenerator(func(gen *object.Generator) object.Object {
+		env := extendFunctionEnv(fn, args)
+		env.SetGenerator(gen)
+
+		evaluated := Eval(fn.Body, env)
+
+		if isError(evaluated) {
+			return evaluated
+		}
+
+		return nil
+	})
+	fn.Env.TrackGenerator(gen)
+
+	return gen
+}
+
+func evalYieldStatement(node *ast.YieldStatement, env *object.Environment) object.Object {
+	gen := env.Generator()
+	if gen == nil {
+		return newError(catalog.YIELD_OUTSIDE_GENERATOR)
+	}
+
+	val := Eval(node.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	gen.Yield(val)
+
+	return NULL
+}
+
+// Returns the generator's next value, false once it's finished
+func generatorNext(gen *object.Generator) (object.Object, bool) {
+	// the body asking for its own next value would wait on itself forever
+	if gen.Running() {
+		return newError(catalog.GENERATOR_RUNNING), true
+	}
+
+	return gen.Next()
+}
@@ -0,0 +1,147 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/catalog"
+	"monkey/object"
+)
+
+/**
+for (let i = 0; i < 10; i = i + 1) { ... }
+
+Every iteration runs in its own scope (see object.NewLoopEnvironment):
+- the counter is bound in the loop's scope, it isn't visible after the loop
+- the next iteration starts with a copy of the counter, the update is applied to the copy,
+  so closures created in the body keep the value the counter had in their iteration
+- assignments to variables declared outside of the loop update them (total = total + i)
+**/
+func evalForLoopStatement(loop *ast.ForLoopStatement, env *object.Environment) object.Object {
+	var result object.Object
+	name := loop.CounterVar.Name.Value
+
+	scope := object.NewLoopEnvironment(env)
+
+	if counter := Eval(loop.CounterVar, scope); isError(counter) {
+		return counter
+	}
+
+	for {
+		condition := Eval(loop.LoopCondition, scope)
+		if isError(condition) {
+			return condition
+		}
+
+		keepGoing, ok := condition.(*object.Boolean)
+		if !ok {
+			return newError(catalog.INVALID_LOOP_CONDITION, typeOf(condition))
+		}
+
+		if !keepGoing.Value {
+			return result
+		}
+
+		result = Eval(loop.LoopBlock, scope)
+		if stopsLoop(result) {
+			return result
+		}
+
+		counter, _ := scope.Get(name)
+		scope = object.NewLoopEnvironment(env)
+		scope.Set(name, counter)
+
+		if updated := Eval(loop.CounterUpdate, scope); isError(updated) {
+			return updated
+		}
+	}
+}
+
+/**
+for (x in iterable) { ... }, the variable is bound to:
+- arrays: every element
+- strings: every character
+- hashes: every key (in no particular order, same as printing the hash)
+- generators: every value until the generator is finished
+
+Like the other for loop, every iteration gets its own scope.
+**/
+func evalForInStatement(loop *ast.ForInStatement, env *object.Environment) object.Object {
+	iterable := Eval(loop.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	var result object.Object
+
+	// runs the body once, false when the loop should stop (return statement or error)
+	iterate := func(value object.Object) bool {
+		scope := object.NewLoopEnvironment(env)
+		scope.Set(loop.Variable.Value, value)
+
+		result = Eval(loop.Body, scope)
+		return !stopsLoop(result)
+	}
+
+	switch iterable := iterable.(type) {
+	case *object.Array:
+		// the body can push to the array, only the elements it had when the loop started are visited
+		elements := iterable.Elements
+		for _, el := range elements {
+			if !iterate(el) {
+				break
+			}
+		}
+
+	case *object.String:
+		for _, char := range iterable.Value {
+			if !iterate(object.InternRuntimeString(string(char))) {
+				break
+			}
+		}
+
+	case *object.Hash:
+		keys := []object.Object{}
+		for _, pair := range iterable.Entries() {
+			// deleted keys, see __delete__
+			if pair.Key != NULL {
+				keys = append(keys, pair.Key)
+			}
+		}
+
+		for _, key := range keys {
+			if !iterate(key) {
+				break
+			}
+		}
+
+	case *object.Generator:
+		for {
+			value, ok := generatorNext(iterable)
+			if !ok {
+				break
+			}
+
+			if isError(value) {
+				return value
+			}
+
+			if !iterate(value) {
+				break
+			}
+		}
+
+	default:
+		return newError(catalog.NOT_ITERABLE, typeOf(iterable))
+	}
+
+	return result
+}
+
+// Whether the result of a loop body ends the loop: a return statement or an error
+func stopsLoop(result object.Object) bool {
+	if result == nil {
+		return false
+	}
+
+	rt := result.Type()
+	return rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ
+}
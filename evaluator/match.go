@@ -0,0 +1,46 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/object"
+)
+
+/**
+Evaluates the subject once, then the patterns in order until one is == to it (see evalInfixExpression),
+the value of that arm is the value of the match:
+
+	match (code) { 200 => "ok", 404 => "not found", _ => "error" }
+
+The patterns after the matching arm aren't evaluated. Without a matching arm the match is null.
+**/
+func evalMatchExpression(me *ast.MatchExpression, env *object.Environment) object.Object {
+	subject := Eval(me.Subject, env)
+	if isError(subject) {
+		return subject
+	}
+
+	for _, arm := range me.Arms {
+		if !arm.IsWildcard() {
+			pattern := Eval(arm.Pattern, env)
+			if isError(pattern) {
+				return pattern
+			}
+
+			equal := evalInfixExpression("==", subject, pattern, env)
+			if isError(equal) {
+				return equal
+			}
+			if !isTruthy(equal) {
+				continue
+			}
+		}
+
+		// like if, an arm without a value ({} or only let statements) is null
+		if result := Eval(arm.Body, env); result != nil {
+			return result
+		}
+		return NULL
+	}
+
+	return NULL
+}
@@ -0,0 +1,55 @@
+package evaluator
+
+import (
+	"monkey/catalog"
+	"monkey/object"
+	"strings"
+)
+
+/**
+Evaluates `in` and `!in`:
+- 3 in [1, 2, 3]: some element == 3 (same comparison as the == operator)
+- "key" in {"key": 1}: the hash has the key (deleted keys don't count)
+- "sub" in "substring": substring check, the left side has to be a string
+**/
+func evalMembershipExpression(operator string, left, right object.Object, env *object.Environment) object.Object {
+	var found bool
+
+	switch container := right.(type) {
+	case *object.Array:
+		for _, el := range container.Elements {
+			result := evalInfixExpression("==", el, left, env)
+			if isError(result) {
+				return result
+			}
+			if isTruthy(result) {
+				found = true
+				break
+			}
+		}
+
+	case *object.Hash:
+		key, ok := left.(object.Hashable)
+		if !ok {
+			return newError(catalog.UNUSABLE_HASH_KEY, left.Type())
+		}
+		pair, exists := container.Entries()[key.HashKey()]
+		found = exists && pair.Key != NULL
+
+	case *object.String:
+		str, ok := left.(*object.String)
+		if !ok {
+			return newError(catalog.TYPE_MISMATCH, left.Type(), operator, right.Type())
+		}
+		found = strings.Contains(container.Value, str.Value)
+
+	default:
+		return newError(catalog.UNKNOWN_INFIX_OPERATOR, left.Type(), operator, right.Type())
+	}
+
+	if operator == "!in" {
+		found = !found
+	}
+
+	return nativeBoolToBooleanObject(found)
+}
@@ -0,0 +1,107 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/catalog"
+	"monkey/object"
+)
+
+/**
+Builtins namespaced by the type they work on, called with dot syntax on a value of that type:
+"hello".upper().split("l") is split(upper("hello"), "l").
+
+- the value is passed as the first argument
+- names that aren't methods of the type fall back to the functions in scope (builtins and user defined),
+  so arr.myFunction(1) still calls myFunction(arr, 1)
+- some methods (upper, split, join, etc) only exist here, they aren't global builtins
+
+Filled in init(): map and take call back into the evaluator, which looks up methods.
+**/
+var METHODS map[object.ObjectType]map[string]*object.Builtin
+
+func init() {
+	METHODS = map[object.ObjectType]map[string]*object.Builtin{
+		object.STRING_OBJ: {
+			"len":         {Fn: __len__},
+			"chars":       {Fn: __chars__},
+			"bytes":       {Fn: __bytes__},
+			"byte_len":    {Fn: __byte_len__},
+			"slice":       {Fn: __slice__},
+			"upper":       {Fn: __upper__},
+			"lower":       {Fn: __lower__},
+			"trim":        {Fn: __trim__},
+			"split":       {Fn: __split__},
+			"replace":     {Fn: __replace__},
+			"starts_with": {Fn: __starts_with__},
+			"ends_with":   {Fn: __ends_with__},
+			"reverse":     {Fn: __reverse__},
+			"lines":       {Fn: __lines__},
+			"parse_int":   {Fn: __parse_int__},
+			"parse_float": {Fn: __parse_float__},
+		},
+		object.ARRAY_OBJ: {
+			"len":      {Fn: __len__},
+			"first":    {Fn: __first__},
+			"last":     {Fn: __last__},
+			"rest":     {Fn: __rest__},
+			"push":     {Fn: __push__},
+			"pop":      {Fn: __pop__},
+			"shift":    {Fn: __shift__},
+			"slice":    {Fn: __slice__},
+			"map":      {Fn: __map__},
+			"join":     {Fn: __join__},
+			"index_of": {Fn: __index_of__},
+			"find":     {Fn: __find__},
+			"any":      {Fn: __any__},
+			"all":      {Fn: __all__},
+			"count":    {Fn: __count__},
+			"insert":   {Fn: __insert__},
+			"remove":   {Fn: __remove__},
+		},
+		object.HASH_OBJ: {
+			"delete":      {Fn: __delete__},
+			"valuesAt":    {Fn: __valuesAt__},
+			"toArray":     {Fn: __toArray__},
+			"dig":         {Fn: __dig__},
+			"get":         {Fn: __get__},
+			"withDefault": {Fn: __withDefault__},
+			"set":         {Fn: __set__},
+		},
+		object.BYTES_OBJ: {
+			"len": {Fn: __len__},
+		},
+		object.TIME_OBJ:     timeMethods,
+		object.DURATION_OBJ: durationMethods,
+	}
+
+	for _, methods := range METHODS {
+		for name, method := range methods {
+			method.Doc = methodDoc(name)
+		}
+	}
+}
+
+// Calls a member of the module, the arguments are passed as they are
+func evalModuleCall(module *object.Module, node *ast.InternalFunctionCall, env *object.Environment) object.Object {
+	member, ok := module.Members[node.FunctionIdentifier.Value]
+	if !ok {
+		return newError(catalog.UNKNOWN_MEMBER, module.Name, node.FunctionIdentifier.Value)
+	}
+
+	args := evalExpressions(node.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	return applyFunction(member, args)
+}
+
+// Finds the method with the given name for the type of the value, see METHODS
+func lookupMethod(value object.Object, name string) (object.Object, bool) {
+	method, ok := METHODS[value.Type()][name]
+	if !ok {
+		return nil, false
+	}
+
+	return method, true
+}
@@ -0,0 +1,183 @@
+package evaluator
+
+import (
+	"monkey/catalog"
+	"monkey/object"
+	"strconv"
+	"strings"
+)
+
+// Bases accepted by parse_int and to_base (strconv's limits: digits 0-9 then a-z)
+const (
+	MIN_BASE = 2
+	MAX_BASE = 36
+)
+
+/**
+parse_int("42") => 42
+parse_int("ff", 16) => 255
+parse_int("-101", 2) => -5
+
+Surrounding whitespace is ignored, anything else that isn't a digit in the base is an error.
+**/
+func __parse_int__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("parse_int", args, object.Arg(object.STRING_OBJ), object.OptionalArg(object.INTEGER_OBJ)); err != nil {
+		return err
+	}
+
+	str := args[0].(*object.String)
+
+	base := int64(10)
+	if len(args) == 2 {
+		if err := checkBase("parse_int", args[1].(*object.Integer)); err != nil {
+			return err
+		}
+		base = args[1].(*object.Integer).Value
+	}
+
+	value, err := strconv.ParseInt(strings.TrimSpace(str.Value), int(base), 64)
+	if err != nil {
+		return newError(catalog.INVALID_NUMBER, "parse_int", str.Value)
+	}
+
+	return object.InternInteger(value)
+}
+
+// parse_float("3.14") => 3.14, also accepts integers and exponents: parse_float("1e3") => 1000.0
+func __parse_float__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("parse_float", args, object.Arg(object.STRING_OBJ)); err != nil {
+		return err
+	}
+
+	str := args[0].(*object.String)
+
+	value, parseErr := strconv.ParseFloat(strings.TrimSpace(str.Value), 64)
+	if parseErr != nil {
+		return newError(catalog.INVALID_NUMBER, "parse_float", str.Value)
+	}
+
+	return &object.Float{Value: value}
+}
+
+// to_base(255, 16) => "ff", to_base(-5, 2) => "-101"
+func __to_base__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("to_base", args, object.Arg(object.INTEGER_OBJ), object.Arg(object.INTEGER_OBJ)); err != nil {
+		return err
+	}
+
+	integer, base := args[0].(*object.Integer), args[1].(*object.Integer)
+
+	if err := checkBase("to_base", base); err != nil {
+		return err
+	}
+
+	return &object.String{Value: strconv.FormatInt(integer.Value, int(base.Value))}
+}
+
+/**
+format_float(3.14159, 2) => "3.14"
+format_float(2, 3) => "2.000"
+
+The number is rounded to the given amount of decimals (integers are formatted as floats).
+**/
+func __format_float__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("format_float", args, object.Arg(object.FLOAT_OBJ, object.INTEGER_OBJ), object.Arg(object.INTEGER_OBJ)); err != nil {
+		return err
+	}
+
+	precision := args[1].(*object.Integer)
+
+	if precision.Value < 0 {
+		return newError(catalog.ARGUMENT_NOT_SUPPORTED, "format_float", precision.Inspect())
+	}
+
+	return &object.String{Value: strconv.FormatFloat(toFloat(args[0]), 'f', int(precision.Value), 64)}
+}
+
+/**
+number_format(1234567.891, 2) => "1,234,567.89"
+number_format(1234567.891, 2, ".", ",") => "1.234.567,89"
+number_format(-1234567) => "-1,234,567"
+
+number_format(n[, decimals[, thousands_sep[, decimal_sep]]]), the separators default to "," and "."
+and don't depend on the host's locale. Floats are rounded to the decimals (0 by default),
+integers are formatted exactly however big they are.
+**/
+func __number_format__(args ...object.Object) object.Object {
+	err := object.CheckArgs("number_format", args,
+		object.Arg(object.FLOAT_OBJ, object.INTEGER_OBJ),
+		object.OptionalArg(object.INTEGER_OBJ),
+		object.OptionalArg(object.STRING_OBJ),
+		object.OptionalArg(object.STRING_OBJ),
+	)
+	if err != nil {
+		return err
+	}
+
+	decimals, thousandsSep, decimalSep := int64(0), ",", "."
+	if len(args) > 1 {
+		decimals = args[1].(*object.Integer).Value
+	}
+	if len(args) > 2 {
+		thousandsSep = args[2].(*object.String).Value
+	}
+	if len(args) > 3 {
+		decimalSep = args[3].(*object.String).Value
+	}
+
+	if decimals < 0 {
+		return newError(catalog.ARGUMENT_NOT_SUPPORTED, "number_format", args[1].Inspect())
+	}
+
+	// digits with a . before the decimals, ex: "-1234567.89"
+	var digits string
+	switch n := args[0].(type) {
+	case *object.Integer:
+		digits = strconv.FormatInt(n.Value, 10)
+		if decimals > 0 {
+			digits += "." + strings.Repeat("0", int(decimals))
+		}
+	case *object.Float:
+		digits = strconv.FormatFloat(n.Value, 'f', int(decimals), 64)
+	}
+
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+
+	whole, fraction := digits, ""
+	if dot := strings.IndexByte(digits, '.'); dot >= 0 {
+		whole, fraction = digits[:dot], digits[dot+1:]
+	}
+
+	// rounded to zero, ex: number_format(-0.001) => "0"
+	if strings.Trim(whole+fraction, "0") == "" {
+		sign = ""
+	}
+
+	var out strings.Builder
+	out.WriteString(sign)
+	for idx, digit := range whole {
+		// NaN and Inf aren't digits, they're left alone
+		if idx > 0 && (len(whole)-idx)%3 == 0 && whole[0] >= '0' && whole[0] <= '9' {
+			out.WriteString(thousandsSep)
+		}
+		out.WriteRune(digit)
+	}
+
+	if fraction != "" {
+		out.WriteString(decimalSep + fraction)
+	}
+
+	return &object.String{Value: out.String()}
+}
+
+// Validates the base passed to parse_int and to_base
+func checkBase(funcName string, base *object.Integer) *object.Error {
+	if base.Value < MIN_BASE || base.Value > MAX_BASE {
+		return newError(catalog.INVALID_BASE, funcName, MIN_BASE, MAX_BASE, base.Value)
+	}
+
+	return nil
+}
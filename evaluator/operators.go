@@ -0,0 +1,92 @@
+package evaluator
+
+import "monkey/object"
+
+/**
+Hash keys a script can set to a function to define how an operator behaves for its own data.
+
+ex:
+let point = fn(x, y) {
+	{ "x": x, "y": y, "__add__": fn(a, b) { point(a["x"] + b["x"], a["y"] + b["y"]) } }
+};
+let p = point(1, 2) + point(3, 4); // { x: 4, y: 6, ... }
+**/
+var OPERATOR_METHODS = map[string]string{
+	"+":  "__add__",
+	"-":  "__sub__",
+	"*":  "__mul__",
+	"/":  "__div__",
+	"%":  "__mod__",
+	"&":  "__and__",
+	"|":  "__or__",
+	"^":  "__xor__",
+	"<<": "__lshift__",
+	">>": "__rshift__",
+	"<":  "__lt__",
+	">":  "__gt__",
+	"==": "__eq__",
+	"!=": "__ne__",
+}
+
+/**
+Looks for a user defined operator on the operands, the left operand is checked first.
+The function is always called with (left, right), whichever operand it was found on.
+
+- `!=` falls back to the negation of `__eq__` when `__ne__` isn't defined
+- returns false if neither operand defines the operator
+**/
+func evalOperatorMethod(operator string, left, right object.Object) (object.Object, bool) {
+	if fn, ok := findOperatorMethod(operator, left, right); ok {
+		return applyFunction(fn, []object.Object{left, right}), true
+	}
+
+	if operator == "!=" {
+		if fn, ok := findOperatorMethod("==", left, right); ok {
+			result := applyFunction(fn, []object.Object{left, right})
+			if isError(result) {
+				return result, true
+			}
+			return nativeBoolToBooleanObject(!isTruthy(result)), true
+		}
+	}
+
+	return nil, false
+}
+
+func findOperatorMethod(operator string, operands ...object.Object) (object.Object, bool) {
+	name, ok := OPERATOR_METHODS[operator]
+	if !ok {
+		return nil, false
+	}
+
+	key := (&object.String{Value: name}).HashKey()
+
+	for _, operand := range operands {
+		hash, isHash := operand.(*object.Hash)
+		if !isHash {
+			continue
+		}
+
+		pair, exists := hash.Entries()[key]
+		if !exists {
+			continue
+		}
+
+		switch pair.Value.(type) {
+		case *object.Function, *object.Builtin:
+			return pair.Value, true
+		}
+	}
+
+	return nil, false
+}
+
+/**
+Dev notes:
+
+- Dispatch is on the *operands*, not on their types: there are no records/classes (yet), so
+  every hash carries its own operator functions (usually set by a constructor function like point above).
+- Checking the right operand too means `2 * vector` works as long as the vector defines __mul__,
+  the function has to check which side it's on (ex: with a type check on a["x"]).
+- Using the same operator on the hash itself inside the function (`a + b` inside __add__) recurses forever.
+**/
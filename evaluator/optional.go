@@ -0,0 +1,27 @@
+package evaluator
+
+import "monkey/ast"
+
+/**
+Optional chaining: x?[0] and x?.method() are null when x is null, instead of an error.
+
+The rest of the chain is skipped as well, so user?["address"]["city"].upper() is null when user is null:
+every index expression and method call after an optional one treats a null left side the same way.
+Nothing after the null is evaluated (indexes, arguments).
+
+Returns whether an access with a null left side should evaluate to null.
+**/
+func nullSafe(optional bool, left ast.Expression) bool {
+	for !optional {
+		switch link := left.(type) {
+		case *ast.IndexExpression:
+			optional, left = link.Optional, link.Left
+		case *ast.InternalFunctionCall:
+			optional, left = link.Optional, link.Caller
+		default:
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,74 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/catalog"
+	"monkey/object"
+)
+
+/**
+Evaluates i++ and i-- (arr[0]++, hash["count"]--, etc):
+- the binding is updated with the value +/- 1, only numbers can be incremented
+- the result is the value *before* the update, so let j = i++ sets j to the old i
+- identifiers are updated with env.Assign, same as the assignment expression
+**/
+func evalPostfixExpression(node *ast.PostfixExpression, env *object.Environment) object.Object {
+	// "++" => "+", "--" => "-"
+	operator := node.Operator[:1]
+	one := &object.Integer{Value: 1}
+
+	switch target := node.Left.(type) {
+	case *ast.Identifier:
+		value := Eval(target, env)
+		if isError(value) {
+			return value
+		}
+
+		updated := evalPostfixUpdate(node.Operator, operator, value, one, env)
+		if isError(updated) {
+			return updated
+		}
+
+		if _, ok := env.Assign(target.Value, updated); !ok {
+			return newError(catalog.CONSTANT_ASSIGNMENT, target.Value)
+		}
+		return value
+
+	case *ast.IndexExpression:
+		// evaluate the indexable and the index once: arr[next()]++
+		left := Eval(target.Left, env)
+		if isError(left) {
+			return left
+		}
+
+		index := Eval(target.Index, env)
+		if isError(index) {
+			return index
+		}
+
+		value := evalIndexExpression(left, index)
+		if isError(value) {
+			return value
+		}
+
+		updated := evalPostfixUpdate(node.Operator, operator, value, one, env)
+		if isError(updated) {
+			return updated
+		}
+
+		if result := evalIndexAssignment(left, index, updated); isError(result) {
+			return result
+		}
+		return value
+	}
+
+	return newError(catalog.UNKNOWN_POSTFIX_OPERATOR, node.Left.String(), node.Operator)
+}
+
+func evalPostfixUpdate(postfix, operator string, value, one object.Object, env *object.Environment) object.Object {
+	if !isNumber(value) {
+		return newError(catalog.UNKNOWN_POSTFIX_OPERATOR, value.Type(), postfix)
+	}
+
+	return evalInfixExpression(operator, value, one, env)
+}
@@ -0,0 +1,35 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/catalog"
+	"monkey/object"
+	"runtime/debug"
+)
+
+/**
+Same as Eval, but a Go panic while evaluating (a bug in the interpreter or in a builtin) is returned as an
+error instead of taking the whole process down, for hosts that run scripts they don't control:
+
+	ERROR [R2039]: internal interpreter error: runtime error: index out of range [1] with length 1
+
+The error's Stack has the Go stack trace of the panic, to include in a bug report.
+The environment might be left half updated, it shouldn't be reused.
+**/
+func EvalSafe(node ast.Node, env *object.Environment) (result object.Object) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = InternalError(r)
+		}
+	}()
+
+	return Eval(node, env)
+}
+
+// The internal error (R2039) for a recovered panic, must be called from the deferred function so Stack is the panic's
+func InternalError(r interface{}) *object.Error {
+	err := newError(catalog.INTERNAL_ERROR, r)
+	err.Stack = string(debug.Stack())
+
+	return err
+}
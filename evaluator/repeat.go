@@ -0,0 +1,138 @@
+package evaluator
+
+import (
+	"monkey/catalog"
+	"monkey/object"
+	"strings"
+	"unicode/utf8"
+)
+
+// Estimated size of an array element (an interface value), used to check arrays against the memory limit
+const ELEMENT_SIZE = 16
+
+// Limit (in bytes) for the result of a repetition when there's no memory limit, so "ab" * 100000000000000 is an error instead of crashing the process
+const MAX_REPETITION_SIZE = 1 << 30
+
+// "ab" * 3, 3 * "ab", [0] * 5, 5 * [0]
+func isRepetition(operator string, left, right object.Object) bool {
+	if operator != "*" {
+		return false
+	}
+
+	return (isString(left) || isArray(left)) && isInteger(right) ||
+		isInteger(left) && (isString(right) || isArray(right))
+}
+
+/**
+Repeats a string or array:
+- "ab" * 3 => "ababab"
+- [0] * 3 => [0, 0, 0] (the elements aren't copied: [[]] * 2 holds the same array twice)
+
+Errors out for negative counts and for results bigger than the memory limit (see object.Limits),
+or than MAX_REPETITION_SIZE when there's none.
+**/
+func evalRepetition(left, right object.Object, limits object.Limits) object.Object {
+	value, count := left, right
+	if isInteger(left) {
+		value, count = right, left
+	}
+
+	times := count.(*object.Integer).Value
+	if times < 0 {
+		return newError(catalog.NEGATIVE_REPEAT, value.Type(), times)
+	}
+
+	var length, unitSize int64
+	switch value := value.(type) {
+	case *object.String:
+		length, unitSize = int64(len(value.Value)), 1
+	case *object.Array:
+		length, unitSize = int64(len(value.Elements)), ELEMENT_SIZE
+	}
+
+	limit := limits.Memory
+	if limit <= 0 {
+		limit = MAX_REPETITION_SIZE
+	}
+
+	// nothing to repeat, skips the loop below for huge counts
+	if length == 0 {
+		times = 0
+	}
+
+	// checked with a division so a huge count can't overflow the multiplication
+	if times != 0 && times > limit/(length*unitSize) {
+		return newError(catalog.MEMORY_LIMIT, value.Type(), times, limit)
+	}
+
+	switch value := value.(type) {
+	case *object.String:
+		return &object.String{Value: strings.Repeat(value.Value, int(times))}
+
+	default:
+		elements := value.(*object.Array).Elements
+		repeated := make([]object.Object, 0, int64(len(elements))*times)
+
+		for i := int64(0); i < times; i++ {
+			repeated = append(repeated, elements...)
+		}
+
+		return &object.Array{Elements: repeated}
+	}
+}
+
+/**
+Builtins that repeat a string, they're checked against the memory limit of env (or MAX_REPETITION_SIZE) like "ab" * 3 is:
+
+	repeat("ab", 3)         => "ababab"
+	pad_left("7", 3, "0")   => "007"
+	pad_right("id", 4)      => "id  "
+
+The width of pad_left and pad_right counts characters, the fill (a space by default) has to be one.
+**/
+func RepeatBuiltins(env *object.Environment) map[string]object.Object {
+	return map[string]object.Object{
+		"repeat": &object.Builtin{Doc: DOCS["repeat"], Fn: func(args ...object.Object) object.Object {
+			if err := object.CheckArgs("repeat", args, object.Arg(object.STRING_OBJ), object.Arg(object.INTEGER_OBJ)); err != nil {
+				return err
+			}
+
+			return evalRepetition(args[0], args[1], env.Limits())
+		}},
+		"pad_left":  &object.Builtin{Doc: DOCS["pad_left"], Fn: padBuiltin("pad_left", env, true)},
+		"pad_right": &object.Builtin{Doc: DOCS["pad_right"], Fn: padBuiltin("pad_right", env, false)},
+	}
+}
+
+func padBuiltin(funcName string, env *object.Environment, left bool) object.BuiltinFunction {
+	return func(args ...object.Object) object.Object {
+		if err := object.CheckArgs(funcName, args, object.Arg(object.STRING_OBJ), object.Arg(object.INTEGER_OBJ), object.OptionalArg(object.STRING_OBJ)); err != nil {
+			return err
+		}
+
+		str, width := args[0].(*object.String), args[1].(*object.Integer).Value
+
+		fill := object.Object(object.InternString(" "))
+		if len(args) == 3 {
+			if utf8.RuneCountInString(args[2].(*object.String).Value) != 1 {
+				return newError(catalog.INVALID_CHARACTER, funcName, args[2].(*object.String).Value)
+			}
+			fill = args[2]
+		}
+
+		missing := width - int64(utf8.RuneCountInString(str.Value))
+		if missing <= 0 {
+			return str
+		}
+
+		padding := evalRepetition(fill, object.InternInteger(missing), env.Limits())
+		if isError(padding) {
+			return padding
+		}
+
+		if left {
+			return &object.String{Value: padding.(*object.String).Value + str.Value}
+		}
+		return &object.String{Value: str.Value + padding.(*object.String).Value}
+	}
+}
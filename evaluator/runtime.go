@@ -0,0 +1,80 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"sort"
+)
+
+// Version of the interpreter, printed by `monke version` and returned by runtime_info()
+const VERSION = "0.5.0"
+
+// How programs are run, monke only has the tree-walking evaluator (there's no bytecode vm yet)
+const ENGINE = "eval"
+
+/**
+Language features scripts can check for before using them, returned by runtime_info():
+
+	if ("toml" in runtime_info()["capabilities"]) { ... }
+
+Embedders (or plugins) that add features of their own can append to it before running scripts.
+**/
+var CAPABILITIES = []string{
+	"bitwise",
+	"compound_assignment",
+	"csv",
+	"flags",
+	"generators",
+	"membership",
+	"modules",
+	"operator_methods",
+	"postfix_increment",
+	"string_methods",
+	"time",
+	"timers",
+	"toml",
+	"yaml",
+}
+
+/**
+runtime_info() => {"version": "0.5.0", "engine": "eval", "capabilities": [...], "limits": {"memory": 0, "fuel": 0, "depth": 0}}
+
+The limits are read from env when it's called, so they match what the host set with
+SetLimits (0 means no limit).
+**/
+func RuntimeInfo(env *object.Environment) *object.Builtin {
+	return &object.Builtin{Doc: DOCS["runtime_info"], Fn: func(args ...object.Object) object.Object {
+		if err := object.CheckArgs("runtime_info", args); err != nil {
+			return err
+		}
+
+		names := append([]string{}, CAPABILITIES...)
+		sort.Strings(names)
+
+		capabilities := &object.Array{Elements: make([]object.Object, len(names))}
+		for idx, name := range names {
+			capabilities.Elements[idx] = object.InternString(name)
+		}
+
+		limits := newHash()
+		setHashValue(limits, "memory", object.InternInteger(env.Limits().Memory))
+		setHashValue(limits, "fuel", object.InternInteger(env.Limits().Fuel))
+		setHashValue(limits, "depth", object.InternInteger(int64(env.Limits().Depth)))
+
+		info := newHash()
+		setHashValue(info, "version", object.InternString(VERSION))
+		setHashValue(info, "engine", object.InternString(ENGINE))
+		setHashValue(info, "capabilities", capabilities)
+		setHashValue(info, "limits", limits)
+
+		return info
+	}}
+}
+
+func newHash() *object.Hash {
+	return object.NewHash(nil)
+}
+
+func setHashValue(hash *object.Hash, key string, value object.Object) {
+	hashKey := &object.String{Value: key}
+	hash.Own()[hashKey.HashKey()] = object.HashPair{Key: hashKey, Value: value}
+}
@@ -0,0 +1,166 @@
+package evaluator
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"monkey/catalog"
+	"monkey/object"
+)
+
+/**
+Returns the bindings that depend on how the script was started, to set in its environment:
+
+- args(): the arguments passed after the script (monke -f tool.mk a b => ["a", "b"])
+- flags: module to declare options and parse them from args(), see FlagsModule
+
+name is the script's name in the --help output, which is written to out.
+**/
+func ScriptBindings(name string, args []string, out io.Writer) map[string]object.Object {
+	return map[string]object.Object{
+		"args":  &object.Builtin{Doc: DOCS["args"], Fn: argsBuiltin(args)},
+		"flags": FlagsModule(name, args, out),
+	}
+}
+
+// args() => ["a", "b"], a new array every call so scripts can't change what the next caller sees
+func argsBuiltin(args []string) object.BuiltinFunction {
+	return func(callArgs ...object.Object) object.Object {
+		if err := object.CheckArgs("args", callArgs); err != nil {
+			return err
+		}
+
+		return stringArray(args)
+	}
+}
+
+/**
+Declares options and parses them from the script's arguments, with the same syntax as Go's flag package
+(-name value, --name=value, bool flags without a value):
+
+	flags.string("name", "world", "who to greet")
+	flags.int("times", 1, "how many greetings")
+	flags.bool("shout", false, "greet in uppercase")
+	let opts = flags.parse()
+	opts["name"]    // "world" unless --name was passed
+	flags.args()    // what's left after the options
+
+--help (or -h) writes the usage of every declared option to out and makes parse() return null,
+so scripts can stop with: if (!opts) { return; }
+**/
+func FlagsModule(name string, args []string, out io.Writer) *object.Module {
+	set := flag.NewFlagSet(name, flag.ContinueOnError)
+	// errors are returned as error objects, the usage is only written for --help
+	set.SetOutput(ioutil.Discard)
+	set.Usage = func() {}
+
+	return &object.Module{
+		Name: "flags",
+		Members: map[string]object.Object{
+			"string": &object.Builtin{Fn: defineFlag(set, "flags.string", object.STRING_OBJ), Doc: MODULE_DOCS["flags.string"]},
+			"int":    &object.Builtin{Fn: defineFlag(set, "flags.int", object.INTEGER_OBJ), Doc: MODULE_DOCS["flags.int"]},
+			"bool":   &object.Builtin{Fn: defineFlag(set, "flags.bool", object.BOOLEAN_OBJ), Doc: MODULE_DOCS["flags.bool"]},
+			"parse":  &object.Builtin{Fn: parseFlags(set, args, out), Doc: MODULE_DOCS["flags.parse"]},
+			"args":   &object.Builtin{Fn: remainingArgs(set), Doc: MODULE_DOCS["flags.args"]},
+		},
+	}
+}
+
+// flags.string(name, default, help), the default's type is the flag's type
+func defineFlag(set *flag.FlagSet, funcName string, flagType object.ObjectType) object.BuiltinFunction {
+	return func(args ...object.Object) object.Object {
+		if err := object.CheckArgs(funcName, args, object.Arg(object.STRING_OBJ), object.Arg(flagType), object.OptionalArg(object.STRING_OBJ)); err != nil {
+			return err
+		}
+
+		name := args[0].(*object.String).Value
+		help := ""
+		if len(args) == 3 {
+			help = args[2].(*object.String).Value
+		}
+
+		// the flag package panics on redefinitions
+		if set.Lookup(name) != nil {
+			return newError(catalog.INVALID_FLAG, funcName, fmt.Sprintf("flag redefined: %s", name))
+		}
+
+		switch value := args[1].(type) {
+		case *object.String:
+			set.String(name, value.Value, help)
+		case *object.Integer:
+			set.Int64(name, value.Value, help)
+		case *object.Boolean:
+			set.Bool(name, value.Value, help)
+		}
+
+		return NULL
+	}
+}
+
+// flags.parse() => {"name": "monke", "times": 2}, every declared flag is in the hash
+func parseFlags(set *flag.FlagSet, args []string, out io.Writer) object.BuiltinFunction {
+	return func(callArgs ...object.Object) object.Object {
+		if err := object.CheckArgs("flags.parse", callArgs); err != nil {
+			return err
+		}
+
+		err := set.Parse(args)
+		if err == flag.ErrHelp {
+			printUsage(set, out)
+			return NULL
+		}
+		if err != nil {
+			return newError(catalog.INVALID_FLAG, "flags.parse", err)
+		}
+
+		values := make(map[object.HashKey]object.HashPair)
+
+		set.VisitAll(func(f *flag.Flag) {
+			key := &object.String{Value: f.Name}
+			values[key.HashKey()] = object.HashPair{Key: key, Value: flagValue(f)}
+		})
+
+		return object.NewHash(values)
+	}
+}
+
+// flags.args() => the arguments left after the options, empty until flags.parse() is called
+func remainingArgs(set *flag.FlagSet) object.BuiltinFunction {
+	return func(callArgs ...object.Object) object.Object {
+		if err := object.CheckArgs("flags.args", callArgs); err != nil {
+			return err
+		}
+
+		return stringArray(set.Args())
+	}
+}
+
+func flagValue(f *flag.Flag) object.Object {
+	switch value := f.Value.(flag.Getter).Get().(type) {
+	case int64:
+		return object.InternInteger(value)
+	case bool:
+		return nativeBoolToBooleanObject(value)
+	default:
+		return &object.String{Value: f.Value.String()}
+	}
+}
+
+func printUsage(set *flag.FlagSet, out io.Writer) {
+	fmt.Fprintf(out, "usage: %s [options]\n", set.Name())
+
+	set.SetOutput(out)
+	set.PrintDefaults()
+	set.SetOutput(ioutil.Discard)
+}
+
+func stringArray(values []string) *object.Array {
+	arr := &object.Array{Elements: make([]object.Object, 0, len(values))}
+
+	for _, value := range values {
+		arr.Elements = append(arr.Elements, &object.String{Value: value})
+	}
+
+	return arr
+}
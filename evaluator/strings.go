@@ -0,0 +1,226 @@
+package evaluator
+
+import (
+	"monkey/catalog"
+	"monkey/object"
+	"strings"
+	"unicode/utf8"
+)
+
+/**
+Strings are UTF-8 encoded, but everything that counts or picks characters (len, indexing, slice, chars)
+works on characters (runes), so "héllo"[1] is "é" and not half of it. byte_len and bytes are there
+for when the encoded size is what matters.
+**/
+
+// "héllo".byte_len() => 6, the size of the UTF-8 encoding
+func __byte_len__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("byte_len", args, object.Arg(object.STRING_OBJ)); err != nil {
+		return err
+	}
+
+	return object.InternInteger(int64(len(args[0].(*object.String).Value)))
+}
+
+// "héllo"[1] => "é", null when the index is out of range
+func evalStringIndexExpression(str, index object.Object) object.Object {
+	value := str.(*object.String).Value
+	idx := index.(*object.Integer).Value
+
+	if idx < 0 {
+		return NULL
+	}
+
+	for _, char := range value {
+		if idx == 0 {
+			return object.InternRuntimeString(string(char))
+		}
+		idx--
+	}
+
+	return NULL
+}
+
+/**
+slice("héllo", 1, 3) => "él", slice("héllo", 2) => "llo"
+Indexes count characters, indexes past the end are clamped to the end of the string.
+**/
+func sliceString(str *object.String, indexes []object.Object) object.Object {
+	bounds := []int64{0, -1}
+
+	for pos, idx := range indexes {
+		value := idx.(*object.Integer).Value
+		if value < 0 {
+			return newError(catalog.NEGATIVE_INDEX, value)
+		}
+		bounds[pos] = value
+	}
+
+	// ASCII strings (the common case) can be sliced by byte offsets directly
+	if utf8.RuneCountInString(str.Value) == len(str.Value) {
+		start, end := clampSlice(bounds, len(str.Value))
+		return object.InternRuntimeString(str.Value[start:end])
+	}
+
+	chars := []rune(str.Value)
+	start, end := clampSlice(bounds, len(chars))
+	return object.InternRuntimeString(string(chars[start:end]))
+}
+
+// Fits [start, end) into [0, length], an end of -1 means the end of the string
+func clampSlice(bounds []int64, length int) (int, int) {
+	start, end := bounds[0], bounds[1]
+
+	if end < 0 || end > int64(length) {
+		end = int64(length)
+	}
+
+	if start > end {
+		start = end
+	}
+
+	return int(start), int(end)
+}
+
+// "Hello".upper() => "HELLO"
+func __upper__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("upper", args, object.Arg(object.STRING_OBJ)); err != nil {
+		return err
+	}
+
+	return &object.String{Value: strings.ToUpper(args[0].(*object.String).Value)}
+}
+
+// "Hello".lower() => "hello"
+func __lower__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("lower", args, object.Arg(object.STRING_OBJ)); err != nil {
+		return err
+	}
+
+	return &object.String{Value: strings.ToLower(args[0].(*object.String).Value)}
+}
+
+// "  hi \n".trim() => "hi"
+func __trim__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("trim", args, object.Arg(object.STRING_OBJ)); err != nil {
+		return err
+	}
+
+	return &object.String{Value: strings.TrimSpace(args[0].(*object.String).Value)}
+}
+
+/**
+"a,b,c".split(",") => ["a", "b", "c"]
+"a b  c".split() => ["a", "b", "c"] (no separator: splits around runs of whitespace)
+**/
+func __split__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("split", args, object.Arg(object.STRING_OBJ), object.OptionalArg(object.STRING_OBJ)); err != nil {
+		return err
+	}
+
+	str := args[0].(*object.String).Value
+
+	var parts []string
+	if len(args) == 2 {
+		parts = strings.Split(str, args[1].(*object.String).Value)
+	} else {
+		parts = strings.Fields(str)
+	}
+
+	arr := &object.Array{Elements: make([]object.Object, 0, len(parts))}
+	for _, part := range parts {
+		arr.Elements = append(arr.Elements, &object.String{Value: part})
+	}
+
+	return arr
+}
+
+// ["a", 1, true].join(", ") => "a, 1, true", elements are joined the way puts prints them
+func __join__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("join", args, object.Arg(object.ARRAY_OBJ), object.OptionalArg(object.STRING_OBJ)); err != nil {
+		return err
+	}
+
+	sep := ""
+	if len(args) == 2 {
+		sep = args[1].(*object.String).Value
+	}
+
+	parts := []string{}
+	for _, el := range args[0].(*object.Array).Elements {
+		parts = append(parts, el.Inspect())
+	}
+
+	return &object.String{Value: strings.Join(parts, sep)}
+}
+
+// "a-b-c".replace("-", "+") => "a+b+c", every occurrence is replaced
+func __replace__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("replace", args, object.Arg(object.STRING_OBJ), object.Arg(object.STRING_OBJ), object.Arg(object.STRING_OBJ)); err != nil {
+		return err
+	}
+
+	str, old, new := args[0].(*object.String), args[1].(*object.String), args[2].(*object.String)
+
+	return &object.String{Value: strings.ReplaceAll(str.Value, old.Value, new.Value)}
+}
+
+// "monke.mk".starts_with("monke") => true
+func __starts_with__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("starts_with", args, object.Arg(object.STRING_OBJ), object.Arg(object.STRING_OBJ)); err != nil {
+		return err
+	}
+
+	str, prefix := args[0].(*object.String), args[1].(*object.String)
+
+	return nativeBoolToBooleanObject(strings.HasPrefix(str.Value, prefix.Value))
+}
+
+// "monke.mk".ends_with(".mk") => true
+func __ends_with__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("ends_with", args, object.Arg(object.STRING_OBJ), object.Arg(object.STRING_OBJ)); err != nil {
+		return err
+	}
+
+	str, suffix := args[0].(*object.String), args[1].(*object.String)
+
+	return nativeBoolToBooleanObject(strings.HasSuffix(str.Value, suffix.Value))
+}
+
+// "héllo".reverse() => "olléh", reverses the characters (not the bytes)
+func __reverse__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("reverse", args, object.Arg(object.STRING_OBJ)); err != nil {
+		return err
+	}
+
+	runes := []rune(args[0].(*object.String).Value)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+
+	return &object.String{Value: string(runes)}
+}
+
+/**
+"a\nb\r\nc\n".lines() => ["a", "b", "c"]
+
+Splits on \n and \r\n, a newline at the end of the string doesn't start another line.
+**/
+func __lines__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("lines", args, object.Arg(object.STRING_OBJ)); err != nil {
+		return err
+	}
+
+	arr := &object.Array{Elements: []object.Object{}}
+
+	str := args[0].(*object.String).Value
+	if str == "" {
+		return arr
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(str, "\n"), "\n") {
+		arr.Elements = append(arr.Elements, &object.String{Value: strings.TrimSuffix(line, "\r")})
+	}
+
+	return arr
+}
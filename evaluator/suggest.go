@@ -0,0 +1,87 @@
+package evaluator
+
+import (
+	"monkey/catalog"
+	"monkey/object"
+)
+
+/**
+The error for a name that isn't bound, with the closest visible name when there's one:
+
+	let counter = 0; countre + 1  // identifier not found: countre, did you mean 'counter'?
+
+The names are only compared when the error happens, so looking names up stays as fast as before.
+**/
+func identifierNotFound(name string, env *object.Environment) *object.Error {
+	err := newError(catalog.IDENTIFIER_NOT_FOUND, name)
+
+	if suggestion, ok := suggestName(name, env); ok {
+		err.Message += ", " + catalog.Message(catalog.DID_YOU_MEAN, suggestion)
+	}
+
+	return err
+}
+
+/**
+Returns the name visible from env that's closest to the given one (by edit distance).
+
+- names more than a third of their length away don't count, short names (ex: x) never get a suggestion
+- on a tie the first name in alphabetical order wins
+**/
+func suggestName(name string, env *object.Environment) (string, bool) {
+	maxDistance := len([]rune(name)) / 3
+	best, bestDistance := "", maxDistance+1
+
+	for _, candidate := range env.AllNames() {
+		if distance := editDistance(name, candidate); distance > 0 && distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+
+	return best, best != ""
+}
+
+/**
+Edit distance between a and b: the number of inserted, removed or replaced characters, or swapped neighbours
+(countre => counter is 1), to turn a into b.
+**/
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	// distances from prefixes of a to every prefix of b, the last 3 rows (i-2, i-1, i)
+	prevPrev := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			// removed, inserted, replaced (or kept)
+			curr[j] = prev[j] + 1
+			if curr[j-1]+1 < curr[j] {
+				curr[j] = curr[j-1] + 1
+			}
+			if prev[j-1]+cost < curr[j] {
+				curr[j] = prev[j-1] + cost
+			}
+
+			// swapped
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] && prevPrev[j-2]+1 < curr[j] {
+				curr[j] = prevPrev[j-2] + 1
+			}
+		}
+
+		prevPrev, prev, curr = prev, curr, prevPrev
+	}
+
+	return prev[len(rb)]
+}
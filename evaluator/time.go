@@ -0,0 +1,264 @@
+package evaluator
+
+import (
+	"monkey/catalog"
+	"monkey/object"
+	"time"
+)
+
+/**
+Dates, times and durations:
+
+	let start = time.now()
+	let deadline = time.parse("2024-05-01T12:00:00Z") + time.duration("36h")
+	deadline.format("2006-01-02")     => "2024-05-03"
+	deadline - start                  => a duration, ex: 2h30m0s
+	time.since(start) > time.duration("1s")
+
+Times and durations are values of their own (TIME and DURATION), not integer timestamps:
+time + duration, time - duration and duration * integer are times/durations again,
+time - time is the duration between them, and both can be compared with <, >, == and !=.
+
+Layouts are Go's: the way the reference time, Mon Jan 2 15:04:05 MST 2006, would be written.
+Without one, times are parsed and formatted as RFC 3339 (2006-01-02T15:04:05Z07:00).
+**/
+func TimeModule() *object.Module {
+	return &object.Module{
+		Name: "time",
+		Members: map[string]object.Object{
+			"now":      &object.Builtin{Fn: __time_now__, Doc: MODULE_DOCS["time.now"]},
+			"unix":     &object.Builtin{Fn: __time_unix__, Doc: MODULE_DOCS["time.unix"]},
+			"parse":    &object.Builtin{Fn: __time_parse__, Doc: MODULE_DOCS["time.parse"]},
+			"format":   &object.Builtin{Fn: __time_format__, Doc: MODULE_DOCS["time.format"]},
+			"duration": &object.Builtin{Fn: __time_duration__, Doc: MODULE_DOCS["time.duration"]},
+			"since":    &object.Builtin{Fn: __time_since__, Doc: MODULE_DOCS["time.since"]},
+		},
+	}
+}
+
+// The members of times and durations called with a dot (see methods.go): t.year(), d.seconds()
+var (
+	timeMethods = map[string]*object.Builtin{
+		"format":  {Fn: __time_format__},
+		"unix":    {Fn: __time_unix_seconds__},
+		"year":    {Fn: timeField("year", func(t time.Time) int64 { return int64(t.Year()) })},
+		"month":   {Fn: timeField("month", func(t time.Time) int64 { return int64(t.Month()) })},
+		"day":     {Fn: timeField("day", func(t time.Time) int64 { return int64(t.Day()) })},
+		"hour":    {Fn: timeField("hour", func(t time.Time) int64 { return int64(t.Hour()) })},
+		"minute":  {Fn: timeField("minute", func(t time.Time) int64 { return int64(t.Minute()) })},
+		"second":  {Fn: timeField("second", func(t time.Time) int64 { return int64(t.Second()) })},
+		"weekday": {Fn: __time_weekday__},
+	}
+	durationMethods = map[string]*object.Builtin{
+		"seconds": {Fn: __duration_seconds__},
+		"ms":      {Fn: __duration_ms__},
+	}
+)
+
+// time.now() => the current (local) time
+func __time_now__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("time.now", args); err != nil {
+		return err
+	}
+
+	return &object.Time{Value: time.Now()}
+}
+
+// time.unix(1714564800) => 2024-05-01T12:00:00Z, the time that many seconds after the epoch (in UTC)
+func __time_unix__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("time.unix", args, object.Arg(object.INTEGER_OBJ)); err != nil {
+		return err
+	}
+
+	return &object.Time{Value: time.Unix(args[0].(*object.Integer).Value, 0).UTC()}
+}
+
+// time.parse(text) or time.parse(text, layout)
+func __time_parse__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("time.parse", args, object.Arg(object.STRING_OBJ), object.OptionalArg(object.STRING_OBJ)); err != nil {
+		return err
+	}
+
+	parsed, err := time.Parse(layoutArg(args, 1), args[0].(*object.String).Value)
+	if err != nil {
+		return newError(catalog.INVALID_TIME, "time.parse", err)
+	}
+
+	return &object.Time{Value: parsed}
+}
+
+// time.format(t) or time.format(t, layout), also t.format(layout)
+func __time_format__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("format", args, object.Arg(object.TIME_OBJ), object.OptionalArg(object.STRING_OBJ)); err != nil {
+		return err
+	}
+
+	return &object.String{Value: args[0].(*object.Time).Value.Format(layoutArg(args, 1))}
+}
+
+// time.duration("1h30m"), units go from ns to h (there are no days, they aren't always 24h long)
+func __time_duration__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("time.duration", args, object.Arg(object.STRING_OBJ)); err != nil {
+		return err
+	}
+
+	duration, err := time.ParseDuration(args[0].(*object.String).Value)
+	if err != nil {
+		return newError(catalog.INVALID_TIME, "time.duration", err)
+	}
+
+	return &object.Duration{Value: duration}
+}
+
+// time.since(t) => how long ago t was, time.now() - t
+func __time_since__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("time.since", args, object.Arg(object.TIME_OBJ)); err != nil {
+		return err
+	}
+
+	return &object.Duration{Value: time.Since(args[0].(*object.Time).Value)}
+}
+
+// t.unix() => seconds since the epoch, the opposite of time.unix
+func __time_unix_seconds__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("unix", args, object.Arg(object.TIME_OBJ)); err != nil {
+		return err
+	}
+
+	return object.InternInteger(args[0].(*object.Time).Value.Unix())
+}
+
+// t.weekday() => "Monday"
+func __time_weekday__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("weekday", args, object.Arg(object.TIME_OBJ)); err != nil {
+		return err
+	}
+
+	return &object.String{Value: args[0].(*object.Time).Value.Weekday().String()}
+}
+
+// t.year(), t.month() (1 to 12), ...
+func timeField(funcName string, field func(time.Time) int64) object.BuiltinFunction {
+	return func(args ...object.Object) object.Object {
+		if err := object.CheckArgs(funcName, args, object.Arg(object.TIME_OBJ)); err != nil {
+			return err
+		}
+
+		return object.InternInteger(field(args[0].(*object.Time).Value))
+	}
+}
+
+// d.seconds() => 90.5 (a float, durations can be fractions of a second)
+func __duration_seconds__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("seconds", args, object.Arg(object.DURATION_OBJ)); err != nil {
+		return err
+	}
+
+	return &object.Float{Value: args[0].(*object.Duration).Value.Seconds()}
+}
+
+// d.ms() => the whole milliseconds in d, the unit set_timeout takes
+func __duration_ms__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("ms", args, object.Arg(object.DURATION_OBJ)); err != nil {
+		return err
+	}
+
+	return object.InternInteger(args[0].(*object.Duration).Value.Milliseconds())
+}
+
+// The layout passed at args[idx], RFC 3339 if there's none
+func layoutArg(args []object.Object, idx int) string {
+	if len(args) > idx {
+		return args[idx].(*object.String).Value
+	}
+
+	return time.RFC3339
+}
+
+/**
+The operators on times and durations, see TimeModule:
+
+	time +/- duration => time
+	time - time => duration
+	duration +/- duration => duration
+	duration * integer (or integer * duration), duration / integer => duration
+	<, >, == and != between two times or two durations
+**/
+func evalTimeInfixExpression(operator string, left, right object.Object) object.Object {
+	switch {
+	case left.Type() == object.TIME_OBJ && right.Type() == object.DURATION_OBJ:
+		t, d := left.(*object.Time).Value, right.(*object.Duration).Value
+		switch operator {
+		case "+":
+			return &object.Time{Value: t.Add(d)}
+		case "-":
+			return &object.Time{Value: t.Add(-d)}
+		}
+
+	case left.Type() == object.DURATION_OBJ && right.Type() == object.TIME_OBJ && operator == "+":
+		return &object.Time{Value: right.(*object.Time).Value.Add(left.(*object.Duration).Value)}
+
+	case left.Type() == object.TIME_OBJ && right.Type() == object.TIME_OBJ:
+		l, r := left.(*object.Time).Value, right.(*object.Time).Value
+		switch operator {
+		case "-":
+			return &object.Duration{Value: l.Sub(r)}
+		case "<":
+			return nativeBoolToBooleanObject(l.Before(r))
+		case ">":
+			return nativeBoolToBooleanObject(l.After(r))
+		case "==":
+			return nativeBoolToBooleanObject(l.Equal(r))
+		case "!=":
+			return nativeBoolToBooleanObject(!l.Equal(r))
+		}
+
+	case left.Type() == object.DURATION_OBJ && right.Type() == object.DURATION_OBJ:
+		l, r := left.(*object.Duration).Value, right.(*object.Duration).Value
+		switch operator {
+		case "+":
+			return &object.Duration{Value: l + r}
+		case "-":
+			return &object.Duration{Value: l - r}
+		case "<":
+			return nativeBoolToBooleanObject(l < r)
+		case ">":
+			return nativeBoolToBooleanObject(l > r)
+		case "==":
+			return nativeBoolToBooleanObject(l == r)
+		case "!=":
+			return nativeBoolToBooleanObject(l != r)
+		}
+
+	case left.Type() == object.DURATION_OBJ && isInteger(right):
+		d, n := left.(*object.Duration).Value, right.(*object.Integer).Value
+		switch operator {
+		case "*":
+			return &object.Duration{Value: d * time.Duration(n)}
+		case "/":
+			if n == 0 {
+				return newError(catalog.DIVISION_BY_ZERO, d, operator, n)
+			}
+			return &object.Duration{Value: d / time.Duration(n)}
+		}
+
+	case isInteger(left) && right.Type() == object.DURATION_OBJ && operator == "*":
+		return &object.Duration{Value: time.Duration(left.(*object.Integer).Value) * right.(*object.Duration).Value}
+	}
+
+	// the same as for other values
+	switch {
+	case operator == "==":
+		return nativeBoolToBooleanObject(left == right)
+	case operator == "!=":
+		return nativeBoolToBooleanObject(left != right)
+	case left.Type() != right.Type():
+		return newError(catalog.TYPE_MISMATCH, left.Type(), operator, right.Type())
+	}
+
+	return newError(catalog.UNKNOWN_INFIX_OPERATOR, left.Type(), operator, right.Type())
+}
+
+func isTimeValue(o object.Object) bool {
+	return o.Type() == object.TIME_OBJ || o.Type() == object.DURATION_OBJ
+}
@@ -0,0 +1,154 @@
+package evaluator
+
+import (
+	"monkey/catalog"
+	"monkey/object"
+	"time"
+)
+
+/**
+Timers, for scripts that schedule work instead of doing it right away:
+
+	set_timeout(fn() { puts("later") }, 100)      // runs once, 100ms from now
+	let id = set_interval(fn() { puts("tick") }, 50) // runs every 50ms
+	set_timeout(fn() { clear_timer(id) }, 200)
+	run_loop()
+
+Nothing runs in the background (there are no goroutines), run_loop() waits for the next timer that's
+due, calls it and repeats until there are no timers left. Callbacks can set and clear timers too.
+Timers that are due at the same time run in the order they were set.
+**/
+func TimerBuiltins() map[string]object.Object {
+	return newEventLoop().builtins()
+}
+
+type timer struct {
+	id       int64
+	fn       object.Object
+	due      time.Time
+	interval time.Duration // 0 for timeouts
+}
+
+type eventLoop struct {
+	timers  []*timer
+	lastID  int64
+	running bool
+	// replaced in tests, so they don't have to wait
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+func newEventLoop() *eventLoop {
+	return &eventLoop{now: time.Now, sleep: time.Sleep}
+}
+
+func (loop *eventLoop) builtins() map[string]object.Object {
+	return map[string]object.Object{
+		"set_timeout":  &object.Builtin{Doc: DOCS["set_timeout"], Fn: loop.setTimer("set_timeout", false)},
+		"set_interval": &object.Builtin{Doc: DOCS["set_interval"], Fn: loop.setTimer("set_interval", true)},
+		"clear_timer":  &object.Builtin{Doc: DOCS["clear_timer"], Fn: loop.clearTimer},
+		"run_loop":     &object.Builtin{Doc: DOCS["run_loop"], Fn: loop.run},
+	}
+}
+
+// set_timeout(fn, ms) and set_interval(fn, ms) => the timer's id, for clear_timer
+func (loop *eventLoop) setTimer(funcName string, repeat bool) object.BuiltinFunction {
+	return func(args ...object.Object) object.Object {
+		if err := object.CheckArgs(funcName, args, object.Arg(object.FUNCTION_OBJ, object.BUILTIN_OBJ), object.Arg(object.INTEGER_OBJ)); err != nil {
+			return err
+		}
+
+		ms := args[1].(*object.Integer).Value
+
+		// an interval of 0 would never let the loop move on
+		min := int64(0)
+		if repeat {
+			min = 1
+		}
+		if ms < min {
+			return newError(catalog.INVALID_DELAY, funcName, min, ms)
+		}
+
+		delay := time.Duration(ms) * time.Millisecond
+		loop.lastID++
+		t := &timer{id: loop.lastID, fn: args[0], due: loop.now().Add(delay)}
+		if repeat {
+			t.interval = delay
+		}
+		loop.timers = append(loop.timers, t)
+
+		return object.InternInteger(t.id)
+	}
+}
+
+// clear_timer(id) => whether the timer was still pending
+func (loop *eventLoop) clearTimer(args ...object.Object) object.Object {
+	if err := object.CheckArgs("clear_timer", args, object.Arg(object.INTEGER_OBJ)); err != nil {
+		return err
+	}
+
+	id := args[0].(*object.Integer).Value
+
+	for idx, t := range loop.timers {
+		if t.id == id {
+			loop.timers = append(loop.timers[:idx], loop.timers[idx+1:]...)
+			return TRUE
+		}
+	}
+
+	return FALSE
+}
+
+/**
+run_loop() runs the timers until there are none left, then returns null.
+
+An error in a callback stops the loop and is returned, the timers that are left stay scheduled
+(another run_loop() picks them up).
+**/
+func (loop *eventLoop) run(args ...object.Object) object.Object {
+	if err := object.CheckArgs("run_loop", args); err != nil {
+		return err
+	}
+
+	// called from a callback
+	if loop.running {
+		return newError(catalog.LOOP_RUNNING)
+	}
+	loop.running = true
+	defer func() { loop.running = false }()
+
+	for len(loop.timers) > 0 {
+		t := loop.next()
+
+		if wait := t.due.Sub(loop.now()); wait > 0 {
+			loop.sleep(wait)
+		}
+
+		// rescheduled before the call, so the callback can clear its own interval
+		if t.interval > 0 {
+			t.due = t.due.Add(t.interval)
+			loop.timers = append(loop.timers, t)
+		}
+
+		if result := applyFunction(t.fn, []object.Object{}); isError(result) {
+			return result
+		}
+	}
+
+	return NULL
+}
+
+// Removes and returns the timer that's due first, the oldest one for ties
+func (loop *eventLoop) next() *timer {
+	first := 0
+	for idx, t := range loop.timers {
+		if t.due.Before(loop.timers[first].due) || (t.due.Equal(loop.timers[first].due) && t.id < loop.timers[first].id) {
+			first = idx
+		}
+	}
+
+	t := loop.timers[first]
+	loop.timers = append(loop.timers[:first], loop.timers[first+1:]...)
+
+	return t
+}
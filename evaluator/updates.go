@@ -0,0 +1,116 @@
+package evaluator
+
+import (
+	"monkey/catalog"
+	"monkey/object"
+)
+
+/**
+Updates that leave the value they're given alone and return a changed copy instead:
+
+	let config = {"debug": false}
+	set(config, "debug", true)   => {"debug": true} (config is still {"debug": false})
+	insert([1, 3], 1, 2)         => [1, 2, 3]
+	remove([1, 2, 3], 0)         => [2, 3]
+
+The copies share what they have in common with the original instead of copying it (copy-on-write):
+- set makes a new version of the hash that takes its pairs over, the original only keeps the pair it had for the key,
+  so building a hash one set at a time is O(1) per call (see object.Hash.With)
+- insert at the end takes the room left after the elements, O(1) on average (see object.Array.Appended)
+- remove at either end (like rest, slice, chunk and windows) shares the elements
+Arrays sharing their elements copy them before one of them is written to (arr[0] = x), hashes copy their pairs
+the first time they're changed in place (h["a"] = 1, delete) when other versions of them exist.
+Inserting or removing anywhere else moves the elements after the index, which copies the array.
+**/
+func __set__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("set", args, object.Arg(object.HASH_OBJ), object.Arg(), object.Arg()); err != nil {
+		return err
+	}
+
+	hash := args[0].(*object.Hash)
+	key, ok := args[1].(object.Hashable)
+	if !ok {
+		return newError(catalog.UNUSABLE_HASH_KEY, args[1].Type())
+	}
+
+	return hash.With(key.HashKey(), object.HashPair{Key: args[1], Value: args[2]})
+}
+
+// insert(arr, idx, value), idx can be len(arr) to add it at the end
+func __insert__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("insert", args, object.Arg(object.ARRAY_OBJ), object.Arg(object.INTEGER_OBJ), object.Arg()); err != nil {
+		return err
+	}
+
+	arr := args[0].(*object.Array)
+	idx := args[1].(*object.Integer).Value
+	if err := checkUpdateIndex("insert", idx, int64(len(arr.Elements))+1); err != nil {
+		return err
+	}
+
+	if idx == int64(len(arr.Elements)) {
+		return arr.Appended(args[2])
+	}
+
+	elements := make([]object.Object, 0, len(arr.Elements)+1)
+	elements = append(elements, arr.Elements[:idx]...)
+	elements = append(elements, args[2])
+	elements = append(elements, arr.Elements[idx:]...)
+
+	return &object.Array{Elements: elements}
+}
+
+// remove(arr, idx) => the array without the element at idx
+func __remove__(args ...object.Object) object.Object {
+	if err := object.CheckArgs("remove", args, object.Arg(object.ARRAY_OBJ), object.Arg(object.INTEGER_OBJ)); err != nil {
+		return err
+	}
+
+	arr := args[0].(*object.Array)
+	idx := args[1].(*object.Integer).Value
+	length := int64(len(arr.Elements))
+	if err := checkUpdateIndex("remove", idx, length); err != nil {
+		return err
+	}
+
+	switch idx {
+	case 0:
+		return shareElements(arr, 1, length)
+	case length - 1:
+		return shareElements(arr, 0, length-1)
+	}
+
+	elements := make([]object.Object, 0, length-1)
+	elements = append(elements, arr.Elements[:idx]...)
+	elements = append(elements, arr.Elements[idx+1:]...)
+
+	return &object.Array{Elements: elements}
+}
+
+func checkUpdateIndex(funcName string, idx int64, length int64) object.Object {
+	if idx < 0 {
+		return newError(catalog.NEGATIVE_INDEX, idx)
+	}
+	if idx >= length {
+		return newError(catalog.INDEX_OUT_OF_RANGE, funcName, idx, length)
+	}
+
+	return nil
+}
+
+// A new array with arr.Elements[from:to], without copying them (both arrays are marked as shared)
+func shareElements(arr *object.Array, from, to int64) *object.Array {
+	arr.Shared = true
+	// the capacity is cut, so appending to one of them can't write over the other's elements
+	return &object.Array{Elements: arr.Elements[from:to:to], Shared: true}
+}
+
+// Gives the array its own copy of the elements before they're written to, if they're shared
+func ownElements(arr *object.Array) {
+	if !arr.Shared {
+		return
+	}
+
+	arr.Elements = append([]object.Object{}, arr.Elements...)
+	arr.Shared = false
+}
@@ -4,24 +4,49 @@ import (
 	"io"
 	"io/ioutil"
 	"log"
+	"monkey/analysis"
 	"monkey/evaluator"
 	"monkey/lexer"
 	"monkey/object"
 	"monkey/parser"
 	"monkey/setuphelpers"
+	"monkey/types"
+	"monkey/vfs"
 	"os"
 	"path/filepath"
 )
 
-func EvaluateFile(in io.Reader, out io.Writer, filePath string) {
+// Passed as the file path to read the script from the input instead: cat script.mk | monke -f -
+const STDIN = "-"
+
+/**
+args are the script's own arguments (monke -f tool.mk a b => a, b), returned by args()
+autoSemicolons makes newlines end statements, see parser.WithNewlineTermination
+**/
+func EvaluateFile(in io.Reader, out io.Writer, filePath string, args []string, autoSemicolons bool) {
 	env := object.NewEnvironment()
+	defer env.CloseGenerators()
 	setuphelpers.LoadBuiltInMethods(env)
+	// the CLI runs the user's own scripts, they can list the host's directories
+	env.Set("glob", evaluator.GlobBuiltin(vfs.OS()))
+
+	var l *lexer.Lexer
+	fileName := filePath
+
+	if filePath == STDIN {
+		// tokenized while it's read, the script doesn't have to be loaded in memory first
+		l = lexer.NewFromReader(in)
+		fileName = "stdin"
+	} else {
+		// pass it through the lexer
+		l = lexer.New(locateFile(filePath))
+	}
+	for name, value := range evaluator.ScriptBindings(filepath.Base(fileName), args, out) {
+		env.Set(name, value)
+	}
 
-	fileContent := locateFile(filePath)
-	// pass it through the lexer
-	l := lexer.New(fileContent)
 	// pass lexer generated tokens to the parser
-	p := parser.New(l)
+	p := parser.NewWithFile(l, fileName, parser.WithNewlineTermination(autoSemicolons))
 	// parse the program
 	program := p.ParseProgram()
 
@@ -30,16 +55,44 @@ func EvaluateFile(in io.Reader, out io.Writer, filePath string) {
 		return
 	}
 
+	// best-effort static type checking, warnings don't stop the evaluation
+	if warnings := types.Check(program); len(warnings) != 0 {
+		setuphelpers.PrintWarnings(out, warnings)
+	}
+
 	//print the currently evaluated program
 	evaluated := evaluator.Eval(program, env)
 	if evaluated != nil {
 		// apply syntax highlighting
-		io.WriteString(out, evaluated.Inspect())
+		io.WriteString(out, env.Display(evaluated))
 		io.WriteString(out, "\n")
 	}
 
 }
 
+// Statically checks a file without evaluating it: type mismatches, unreachable code, etc.
+func VetFile(out io.Writer, filePath string, opts analysis.Options, autoSemicolons bool) {
+	fileContent := locateFile(filePath)
+	l := lexer.New(fileContent)
+	p := parser.NewWithFile(l, filePath, parser.WithNewlineTermination(autoSemicolons))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		setuphelpers.PrintParserErrors(out, p.Errors())
+		return
+	}
+
+	warnings := types.Check(program)
+
+	for _, diagnostic := range analysis.AnalyzeWithOptions(program, opts) {
+		warnings = append(warnings, diagnostic.String())
+	}
+
+	if len(warnings) != 0 {
+		setuphelpers.PrintWarnings(out, warnings)
+	}
+}
+
 func locateFile(filePath string) string {
 	path := formatUserFilePathInput(filePath)
 	return findFile(path)
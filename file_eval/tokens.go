@@ -0,0 +1,60 @@
+package file_eval
+
+import (
+	"encoding/json"
+	"io"
+	"monkey/lexer"
+	"monkey/token"
+)
+
+// One line of DumpTokens' output
+type tokenJSON struct {
+	Type    string `json:"type"` // the name of the token type, not its number
+	Literal string `json:"literal"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+}
+
+/**
+Writes the tokens of a file as JSON lines, one per token as it's lexed, the last one is the EOF:
+
+	{"type":"LET","literal":"let","line":1,"column":1,"start":0,"end":3}
+	{"type":"IDENT","literal":"x","line":1,"column":5,"start":4,"end":5}
+
+The file isn't parsed, so scripts with syntax errors can be dumped too (unknown chars are ILLEGAL tokens).
+Reads the input instead when the file path is STDIN.
+**/
+func DumpTokens(in io.Reader, out io.Writer, filePath string) error {
+	var l *lexer.Lexer
+	if filePath == STDIN {
+		l = lexer.NewFromReader(in)
+	} else {
+		l = lexer.New(locateFile(filePath))
+	}
+
+	encoder := json.NewEncoder(out)
+	// "<<" instead of "\u003c\u003c"
+	encoder.SetEscapeHTML(false)
+
+	for {
+		tok := l.NextToken()
+
+		err := encoder.Encode(tokenJSON{
+			Type:    tok.Type.String(),
+			Literal: tok.Literal,
+			Line:    tok.Line,
+			Column:  tok.Column,
+			Start:   tok.Start,
+			End:     tok.End,
+		})
+		if err != nil {
+			return err
+		}
+
+		if tok.Type == token.EOF {
+			return nil
+		}
+	}
+}
@@ -4,5 +4,5 @@ go 1.16
 
 require (
 	github.com/TwiN/go-color v1.1.0
-	github.com/c-bata/go-prompt v0.2.6 // indirect
+	github.com/c-bata/go-prompt v0.2.6
 )
@@ -0,0 +1,134 @@
+package interpreter
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"monkey/object"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+/**
+End-to-end regression cases, one script per case in CORPUS_DIR:
+
+- <name>.mk: the script
+- <name>.out: what it should print (puts, --help output), no file means nothing
+- <name>.err: the error it should stop with, parser errors one per line or the runtime error
+  (ERROR [R2003]: identifier not found: x), no file means no error
+
+Every case runs through every engine in corpusEngines and all of them have to match the expected files.
+New cases can be added with only the .mk file and the expectations written by:
+
+	go test ./interpreter -run TestCorpus -update
+**/
+const CORPUS_DIR = "../interpreter_tests"
+
+var updateCorpus = flag.Bool("update", false, "write the .out and .err files of the corpus from the current results")
+
+// Runs a script, returns what it printed and the error it stopped with ("" for none)
+type corpusEngine func(source string) (string, string)
+
+/**
+monke only has the tree-walking evaluator (see evaluator.ENGINE), other engines go here
+so they're checked against the same corpus.
+**/
+var corpusEngines = map[string]corpusEngine{
+	"eval": func(source string) (string, string) {
+		var stdout bytes.Buffer
+
+		// a broken case shouldn't hang the test run
+		interp := New(WithStdout(&stdout), WithFuelLimit(10_000_000), WithDepthLimit(10_000))
+
+		result, err := interp.Run(source)
+
+		var parseErr *ParseError
+		if errors.As(err, &parseErr) {
+			return stdout.String(), strings.Join(parseErr.Messages(), "\n")
+		}
+
+		if errObj, ok := result.(*object.Error); ok {
+			return stdout.String(), errObj.Inspect()
+		}
+
+		return stdout.String(), ""
+	},
+}
+
+func TestCorpus(t *testing.T) {
+	scripts, err := filepath.Glob(filepath.Join(CORPUS_DIR, "*.mk"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(scripts) == 0 {
+		t.Fatalf("no scripts found in %s", CORPUS_DIR)
+	}
+
+	for _, script := range scripts {
+		name := strings.TrimSuffix(script, ".mk")
+
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			source, err := ioutil.ReadFile(script)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if *updateCorpus {
+				stdout, errText := corpusEngines["eval"](string(source))
+				writeExpected(t, name+".out", stdout)
+				writeExpected(t, name+".err", errText)
+				return
+			}
+
+			expectedOut := readExpected(t, name+".out")
+			expectedErr := strings.TrimSuffix(readExpected(t, name+".err"), "\n")
+
+			for engine, run := range corpusEngines {
+				stdout, errText := run(string(source))
+
+				if stdout != expectedOut {
+					t.Errorf("%s: wrong output, expected:\n%s\ngot:\n%s", engine, expectedOut, stdout)
+				}
+
+				if errText != expectedErr {
+					t.Errorf("%s: wrong error, expected:\n%s\ngot:\n%s", engine, expectedErr, errText)
+				}
+			}
+		})
+	}
+}
+
+// Missing files are the same as empty ones
+func readExpected(t *testing.T, path string) string {
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ""
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(content)
+}
+
+// Empty expectations are removed instead of written, so cases only have the files they need
+func writeExpected(t *testing.T, path, content string) {
+	if content == "" {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
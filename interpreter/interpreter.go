@@ -0,0 +1,510 @@
+package interpreter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parsecache"
+	"monkey/parser"
+	"monkey/setuphelpers"
+	"monkey/vfs"
+	"os"
+	"strings"
+	"sync"
+)
+
+/**
+Entry point for embedding monke in other Go programs.
+
+ex:
+	interp := interpreter.New(interpreter.WithLogger(slog.Default()))
+	result, err := interp.Run(`let x = 5; x * 2`)
+**/
+type Interpreter struct {
+	logger Logger
+	fs     vfs.FS
+	lister vfs.ReadDirFS // see WithFS
+	cache  *parsecache.Cache
+	hooks  []object.Hook
+	limits object.Limits
+	args   []string
+	stdout io.Writer
+	// see WithOutputBuffering, output wraps stdout
+	outputMode OutputMode
+	outputSize int
+	output     *output
+	// see WithPanicRecovery
+	recoverPanics bool
+	// see WithDiagnostics
+	diagnostics func(object.Diagnostic)
+	// see WithDisplayOptions
+	display object.FormatOptions
+	// see WithParserOptions
+	parserOptions []parser.Option
+	// see WithOutputCapture
+	captureOutput bool
+	// see EvalInSession, nil until the first one
+	sessionMu sync.Mutex
+	session   *object.Environment
+}
+
+// Configures an Interpreter, passed to New()
+type Option func(*Interpreter)
+
+func New(opts ...Option) *Interpreter {
+	interp := &Interpreter{logger: nopLogger{}, fs: vfs.OS(), stdout: os.Stdout}
+
+	for _, opt := range opts {
+		opt(interp)
+	}
+	interp.output = newOutput(interp.stdout, interp.outputMode, interp.outputSize)
+
+	return interp
+}
+
+/**
+Serves every file the interpreter touches (RunFile, read_file, write_file) from the given file system
+instead of the host's, ex: vfs.ReadOnly(embeddedScripts) or vfs.NewMemory(files) as a sandbox.
+**/
+func WithFS(fsys vfs.FS) Option {
+	return func(i *Interpreter) {
+		if fsys != nil {
+			i.fs = fsys
+			// glob is only bound when the host passes an FS that can list directories
+			i.lister, _ = fsys.(vfs.ReadDirFS)
+		}
+	}
+}
+
+/**
+Parses scripts with the given options, ex: limits for services running untrusted source:
+
+	interpreter.New(interpreter.WithParserOptions(parser.WithMaxStringLength(64<<10), parser.WithMaxElements(10000)))
+
+The parse cache (see WithParseCache) keeps programs parsed with different options apart.
+**/
+func WithParserOptions(opts ...parser.Option) Option {
+	return func(i *Interpreter) {
+		i.parserOptions = append(i.parserOptions, opts...)
+	}
+}
+
+// Reuses the programs parsed by the given cache instead of parsing the same source again
+func WithParseCache(cache *parsecache.Cache) Option {
+	return func(i *Interpreter) {
+		i.cache = cache
+	}
+}
+
+/**
+Calls the hooks before and after every AST node is evaluated, see object.Hook.
+Can be passed more than once, hooks are called in the order they were added.
+**/
+func WithHook(hooks ...object.Hook) Option {
+	return func(i *Interpreter) {
+		i.hooks = append(i.hooks, hooks...)
+	}
+}
+
+/**
+Caps the bytes a single value created by a script can take (ex: "ab" * 1000000),
+scripts going over it get a runtime error instead of the allocation. 0 means no limit.
+**/
+func WithMemoryLimit(bytes int64) Option {
+	return func(i *Interpreter) {
+		i.limits.Memory = bytes
+	}
+}
+
+/**
+Caps the AST nodes a script can evaluate, scripts going over it (ex: an infinite loop)
+stop with a runtime error. 0 means no limit.
+**/
+func WithFuelLimit(nodes int64) Option {
+	return func(i *Interpreter) {
+		i.limits.Fuel = nodes
+	}
+}
+
+/**
+Caps how deep function calls can be nested, scripts going over it (ex: runaway recursion)
+stop with a runtime error instead of exhausting the Go stack. 0 means no limit.
+**/
+func WithDepthLimit(calls int) Option {
+	return func(i *Interpreter) {
+		i.limits.Depth = calls
+	}
+}
+
+/**
+Turns Go panics while a script runs (bugs in the interpreter or in a plugin's builtins) into an
+internal error result (R2039) instead of crashing the host, see evaluator.EvalSafe.
+The error's Stack has the Go stack trace, it's also logged with the runtime error.
+A panic while parsing is returned as an *InternalError instead, there's no result yet.
+**/
+func WithPanicRecovery(enabled bool) Option {
+	return func(i *Interpreter) {
+		i.recoverPanics = enabled
+	}
+}
+
+/**
+Turns on runtime warnings, every one is passed to the handler while the script keeps running:
+
+	interp := interpreter.New(interpreter.WithDiagnostics(func(d object.Diagnostic) {
+		log.Printf("%d:%d [%s] %s", d.Line, d.Column, d.Code, d.Message)  // 1:3 [W4001] integer division discards the remainder: 7 / 2 is 3, ...
+	}))
+
+Off by default, the evaluator only looks for them when there's a handler.
+**/
+func WithDiagnostics(handler func(object.Diagnostic)) Option {
+	return func(i *Interpreter) {
+		i.diagnostics = handler
+	}
+}
+
+/**
+How puts, string interpolation and inspect (without options) print values in this interpreter's scripts,
+ex: object.FormatOptions{MaxDepth: 3, MaxElements: 20, Multiline: true} to keep large nested structures readable.
+Everything on one line by default.
+**/
+func WithDisplayOptions(opts object.FormatOptions) Option {
+	return func(i *Interpreter) {
+		i.display = opts
+	}
+}
+
+// Arguments returned by args() and parsed by the flags module, --help output goes to stdout
+func WithArgs(args ...string) Option {
+	return func(i *Interpreter) {
+		i.args = args
+	}
+}
+
+// Where puts and the --help output of the flags module are written, os.Stdout by default
+func WithStdout(out io.Writer) Option {
+	return func(i *Interpreter) {
+		if out != nil {
+			i.stdout = out
+		}
+	}
+}
+
+/**
+Buffers what scripts print before it's written to stdout (see WithStdout), so embedders writing
+to a connection or a log per request don't pay for a write on every puts:
+
+- Unbuffered: every write goes straight to stdout (the default)
+- LineBuffered: written once a line is complete
+- FullyBuffered: written when the buffer (size bytes, 4096 when 0) is full, on Flush and when a run finishes
+**/
+func WithOutputBuffering(mode OutputMode, size int) Option {
+	return func(i *Interpreter) {
+		i.outputMode = mode
+		i.outputSize = size
+	}
+}
+
+/**
+Keeps a copy of what each run prints in its Result.Output (see RunWithResult),
+the output still goes to stdout too (WithStdout(io.Discard) to only capture it).
+**/
+func WithOutputCapture(enabled bool) Option {
+	return func(i *Interpreter) {
+		i.captureOutput = enabled
+	}
+}
+
+/**
+Writes the buffered script output to stdout, ex: every second from another goroutine while a long script runs.
+Runs flush when they finish, so this is only needed to see output earlier.
+**/
+func (i *Interpreter) Flush() error {
+	return i.output.Flush()
+}
+
+// Returned by Run() when the source can't be parsed, Errors keeps the code and position of each one
+type ParseError struct {
+	Errors []parser.ParserError
+}
+
+func (pe *ParseError) Error() string {
+	return fmt.Sprintf("parser has %d errors: %s", len(pe.Errors), strings.Join(pe.Messages(), "; "))
+}
+
+// The text of each parser error: 1:6: [E1001] expected next token to be =, got EOF instead
+func (pe *ParseError) Messages() []string {
+	messages := make([]string, len(pe.Errors))
+	for idx, err := range pe.Errors {
+		messages[idx] = err.Error()
+	}
+
+	return messages
+}
+
+// Lexes and parses the source code into an AST
+func (i *Interpreter) Parse(source string) (*ast.Program, error) {
+	return i.parse("", source)
+}
+
+/**
+Returned instead of a Go panic while parsing when WithPanicRecovery is on (a bug in the lexer or parser),
+Err is the internal error (R2039) with the Go stack trace.
+**/
+type InternalError struct {
+	Err *object.Error
+}
+
+func (ie *InternalError) Error() string {
+	return ie.Err.Inspect()
+}
+
+// filename is only used to locate parser errors, it can be empty
+func (i *Interpreter) parse(filename, source string) (program *ast.Program, err error) {
+	if i.recoverPanics {
+		defer func() {
+			if r := recover(); r != nil {
+				internal := evaluator.InternalError(r)
+				i.logger.Error(EVENT_PARSE_FINISH, "message", internal.Message, "stack", internal.Stack)
+				program, err = nil, &InternalError{Err: internal}
+			}
+		}()
+	}
+
+	i.logger.Debug(EVENT_PARSE_START, "bytes", len(source))
+
+	var errors []parser.ParserError
+
+	if i.cache != nil {
+		program, errors = i.cache.ParseFile(filename, source, i.parserOptions...)
+	} else {
+		p := parser.NewWithFile(lexer.New(source), filename, i.parserOptions...)
+		program, errors = p.ParseProgram(), p.ParseErrors()
+	}
+
+	if len(errors) != 0 {
+		i.logger.Warn(EVENT_PARSE_FINISH, "statements", len(program.Statements), "errors", len(errors))
+		return nil, &ParseError{Errors: errors}
+	}
+
+	i.logger.Debug(EVENT_PARSE_FINISH, "statements", len(program.Statements), "errors", 0)
+
+	return program, nil
+}
+
+/**
+Everything about a run, returned by RunWithResult:
+
+	result, err := interp.RunWithResult(script)
+	result.Value            // what Run returns, an *object.Error for runtime errors
+	result.Output           // what the script printed, with WithOutputCapture
+	result.Diagnostics      // the runtime warnings, in the order they happened
+	result.Stats.Nodes      // AST nodes evaluated
+	result.Stats.PeakDepth  // most function calls nested at once
+**/
+type Result struct {
+	Value       object.Object
+	Output      string
+	Diagnostics []object.Diagnostic
+	Stats       object.Stats
+}
+
+/**
+Parses and evaluates the source code in a fresh environment (with the builtin functions loaded).
+
+- parser errors are returned as a *ParseError
+- runtime errors are returned the same way the evaluator returns them, as an *object.Error
+**/
+func (i *Interpreter) Run(source string) (object.Object, error) {
+	program, err := i.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.eval(program), nil
+}
+
+/**
+Like Run, but reports everything about the run in a Result instead of only the value.
+Diagnostics are always collected (and passed to the WithDiagnostics handler when there's one).
+**/
+func (i *Interpreter) RunWithResult(source string) (*Result, error) {
+	program, err := i.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.evalWithResult(program), nil
+}
+
+// Evaluates the program in a fresh environment
+func (i *Interpreter) eval(program *ast.Program) object.Object {
+	env := i.newEnvironment(i.output)
+	defer env.CloseGenerators()
+
+	return i.evalIn(program, env)
+}
+
+// Evaluates the program in a fresh environment, collecting its output (when captured), diagnostics and stats
+func (i *Interpreter) evalWithResult(program *ast.Program) *Result {
+	result := &Result{}
+
+	var captured bytes.Buffer
+	var out io.Writer = i.output
+	if i.captureOutput {
+		out = io.MultiWriter(i.output, &captured)
+	}
+
+	env := i.newEnvironment(out)
+	defer env.CloseGenerators()
+	env.SetDiagnostics(func(d object.Diagnostic) {
+		result.Diagnostics = append(result.Diagnostics, d)
+		if i.diagnostics != nil {
+			i.diagnostics(d)
+		}
+	})
+
+	result.Value = i.evalIn(program, env)
+	result.Output = captured.String()
+	result.Stats = env.Stats()
+
+	return result
+}
+
+/**
+A global environment with the builtin functions loaded and the interpreter's options applied,
+what the script prints is written to out
+**/
+func (i *Interpreter) newEnvironment(out io.Writer) *object.Environment {
+	env := object.NewEnvironment()
+	setuphelpers.LoadBuiltInMethods(env)
+
+	// file builtins use this interpreter's file system
+	files := loggingFS{fsys: i.fs, logger: i.logger}
+	for name, builtin := range evaluator.FileBuiltins(files) {
+		env.Set(name, builtin)
+	}
+	if i.lister != nil {
+		env.Set("glob", evaluator.GlobBuiltin(loggingReadDirFS{loggingFS: files, lister: i.lister}))
+	}
+
+	env.Set("puts", evaluator.Puts(env, out))
+	env.Set("inspect", evaluator.Inspect(env))
+	env.Set("help", evaluator.HelpBuiltin(env, out))
+
+	for name, value := range evaluator.ScriptBindings("monke", i.args, out) {
+		env.Set(name, value)
+	}
+
+	for _, hook := range i.hooks {
+		env.AddHook(hook)
+	}
+	env.SetLimits(i.limits)
+	env.SetDiagnostics(i.diagnostics)
+	env.SetDisplayOptions(i.display)
+
+	return env
+}
+
+func (i *Interpreter) evalIn(program *ast.Program, env *object.Environment) object.Object {
+	var result object.Object
+	if i.recoverPanics {
+		result = evaluator.EvalSafe(program, env)
+	} else {
+		result = evaluator.Eval(program, env)
+	}
+
+	if errObj, ok := result.(*object.Error); ok {
+		args := []interface{}{"message", errObj.Message}
+		if errObj.Stack != "" {
+			args = append(args, "stack", errObj.Stack)
+		}
+		i.logger.Error(EVENT_RUNTIME_ERROR, args...)
+	}
+
+	if err := i.output.Flush(); err != nil {
+		i.logger.Error(EVENT_OUTPUT_ERROR, "error", err)
+	}
+
+	return result
+}
+
+/**
+Like Run, but every call evaluates in the same global environment (the session), like the lines of the REPL,
+so notebook-like hosts can evaluate one cell at a time:
+
+	interp.EvalInSession(`let total = 0; let double = fn(n) { n * 2 }`)
+	interp.EvalInSession(`total += double(5); total += 2; total`)  // 12
+
+- the session starts on the first call and lasts until ResetSession
+- a source that can't be parsed isn't evaluated, the session is left as it was
+- bindings made before a runtime error are kept
+- the limits (fuel, depth) apply to every call on its own, each one starts with a full budget
+
+Calls are evaluated one at a time, a call made while another one runs waits for it. Run doesn't see the session.
+**/
+func (i *Interpreter) EvalInSession(source string) (object.Object, error) {
+	program, err := i.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+
+	i.sessionMu.Lock()
+	defer i.sessionMu.Unlock()
+
+	if i.session == nil {
+		i.session = i.newEnvironment(i.output)
+	} else {
+		i.session.ResetUsage()
+	}
+
+	return i.evalIn(program, i.session), nil
+}
+
+// Forgets everything bound by EvalInSession, the next call starts a new session
+func (i *Interpreter) ResetSession() {
+	i.sessionMu.Lock()
+	defer i.sessionMu.Unlock()
+
+	if i.session != nil {
+		i.session.CloseGenerators()
+	}
+	i.session = nil
+}
+
+/**
+Reads the script from the interpreter's file system and runs it, see Run().
+Parser errors are located in the file: main.mk:3:7: [E1001] ...
+**/
+func (i *Interpreter) RunFile(name string) (object.Object, error) {
+	source, err := i.fs.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	program, err := i.parse(name, string(source))
+	if err != nil {
+		return nil, err
+	}
+
+	return i.eval(program), nil
+}
+
+// Like RunFile, but reports everything about the run in a Result, see RunWithResult
+func (i *Interpreter) RunFileWithResult(name string) (*Result, error) {
+	source, err := i.fs.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	program, err := i.parse(name, string(source))
+	if err != nil {
+		return nil, err
+	}
+
+	return i.evalWithResult(program), nil
+}
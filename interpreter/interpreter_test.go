@@ -0,0 +1,691 @@
+package interpreter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"monkey/ast"
+	"monkey/catalog"
+	"monkey/evaluator"
+	"monkey/object"
+	"monkey/parsecache"
+	"monkey/parser"
+	"monkey/vfs"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+type loggedEvent struct {
+	level string
+	msg   string
+	args  []interface{}
+}
+
+type testLogger struct {
+	events []loggedEvent
+}
+
+func (l *testLogger) log(level, msg string, args []interface{}) {
+	l.events = append(l.events, loggedEvent{level: level, msg: msg, args: args})
+}
+
+func (l *testLogger) Debug(msg string, args ...interface{}) { l.log("debug", msg, args) }
+func (l *testLogger) Info(msg string, args ...interface{})  { l.log("info", msg, args) }
+func (l *testLogger) Warn(msg string, args ...interface{})  { l.log("warn", msg, args) }
+func (l *testLogger) Error(msg string, args ...interface{}) { l.log("error", msg, args) }
+
+// Records "before"/"after" events for every node of the given kind
+type recordingHook struct {
+	name   string
+	events *[]string
+}
+
+func (h recordingHook) BeforeNode(node ast.Node, env *object.Environment) {
+	if _, ok := node.(*ast.CallExpression); ok {
+		*h.events = append(*h.events, h.name+" before "+node.String())
+	}
+}
+
+func (h recordingHook) AfterNode(node ast.Node, result object.Object) {
+	if _, ok := node.(*ast.CallExpression); ok {
+		*h.events = append(*h.events, h.name+" after "+node.String()+" = "+result.Inspect())
+	}
+}
+
+func TestRun(t *testing.T) {
+	interp := New()
+
+	result, err := interp.Run("let x = 5; x * 2")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	integer, ok := result.(*object.Integer)
+	if !ok || integer.Value != 10 {
+		t.Fatalf("expected 10, got %T (%+v)", result, result)
+	}
+
+	_, err = interp.Run("let x")
+	parseErr, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("expected a *ParseError, got %T (%+v)", err, err)
+	}
+
+	// the parser's errors are passed on as they are, with their code and position
+	first := parseErr.Errors[0]
+	if first.Code != catalog.UNEXPECTED_TOKEN || first.Line != 1 || first.Column != 6 {
+		t.Errorf("expected E1001 at 1:6, got %+v", first)
+	}
+	if parseErr.Messages()[0] != first.Error() || !strings.Contains(parseErr.Error(), first.Error()) {
+		t.Errorf("expected the messages to be the text of each error, got %q", parseErr.Error())
+	}
+}
+
+func TestLoggerEvents(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []loggedEvent
+	}{
+		{"1 + 1", []loggedEvent{
+			{"debug", EVENT_PARSE_START, nil},
+			{"debug", EVENT_PARSE_FINISH, nil},
+		}},
+		{"let x", []loggedEvent{
+			{"debug", EVENT_PARSE_START, nil},
+			{"warn", EVENT_PARSE_FINISH, nil},
+		}},
+		{"1 + true", []loggedEvent{
+			{"debug", EVENT_PARSE_START, nil},
+			{"debug", EVENT_PARSE_FINISH, nil},
+			{"error", EVENT_RUNTIME_ERROR, []interface{}{"message", "type mismatch: INTEGER + BOOLEAN"}},
+		}},
+	}
+
+	for _, tt := range tests {
+		logger := &testLogger{}
+		New(WithLogger(logger)).Run(tt.input)
+
+		if len(logger.events) != len(tt.expected) {
+			t.Fatalf("%q: expected %d events, got %d: %+v", tt.input, len(tt.expected), len(logger.events), logger.events)
+		}
+
+		for idx, expected := range tt.expected {
+			got := logger.events[idx]
+
+			if got.level != expected.level || got.msg != expected.msg {
+				t.Errorf("%q: expected event %s %q, got %s %q", tt.input, expected.level, expected.msg, got.level, got.msg)
+			}
+
+			for argIdx, arg := range expected.args {
+				if got.args[argIdx] != arg {
+					t.Errorf("%q: expected arg %v, got %v", tt.input, arg, got.args[argIdx])
+				}
+			}
+		}
+	}
+}
+
+func TestFileEvents(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []loggedEvent
+	}{
+		{`read_file("hello.txt")`, nil},
+		{`write_file("out.txt", "monke")`, []loggedEvent{
+			{"warn", EVENT_FILE_DENIED, []interface{}{"op", "write", "name", "out.txt"}},
+		}},
+		{`read_file("missing.txt")`, []loggedEvent{
+			{"warn", EVENT_FILE_ERROR, []interface{}{"op", "read", "name", "missing.txt"}},
+		}},
+		{`glob("missing", "*")`, []loggedEvent{
+			{"warn", EVENT_FILE_ERROR, []interface{}{"op", "readdir", "name", "missing"}},
+		}},
+	}
+
+	for _, tt := range tests {
+		logger := &testLogger{}
+		New(WithLogger(logger), WithFS(vfs.ReadOnly(fstest.MapFS{"hello.txt": {Data: []byte("hi")}}))).Run(tt.input)
+
+		// only the file events, the parse and runtime error events are checked in TestLoggerEvents
+		got := []loggedEvent{}
+		for _, event := range logger.events {
+			if event.msg == EVENT_FILE_DENIED || event.msg == EVENT_FILE_ERROR {
+				got = append(got, event)
+			}
+		}
+
+		if len(got) != len(tt.expected) {
+			t.Fatalf("%q: expected %d file events, got %d: %+v", tt.input, len(tt.expected), len(got), got)
+		}
+
+		for idx, expected := range tt.expected {
+			if got[idx].level != expected.level || got[idx].msg != expected.msg {
+				t.Errorf("%q: expected event %s %q, got %s %q", tt.input, expected.level, expected.msg, got[idx].level, got[idx].msg)
+			}
+
+			for argIdx, arg := range expected.args {
+				if got[idx].args[argIdx] != arg {
+					t.Errorf("%q: expected arg %v, got %v", tt.input, arg, got[idx].args[argIdx])
+				}
+			}
+		}
+	}
+}
+
+func TestRunClosesGenerators(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	// only read with next, the generators are still parked in yield when the run is over
+	_, err := New().Run(`let naturals = fn*() { for (let i = 0; i > -1; i += 1) { yield i; } }; let gens = [naturals(), naturals()]; [next(gens[0]), next(gens[1])]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for wait := 0; wait < 100 && runtime.NumGoroutine() > before; wait++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if left := runtime.NumGoroutine() - before; left > 0 {
+		t.Errorf("expected the generators to be closed, %d goroutines left", left)
+	}
+}
+
+func TestWithFS(t *testing.T) {
+	files := vfs.NewMemory(map[string]string{
+		"main.mk":   `let greeting = read_file("hello.txt"); write_file("out.txt", greeting + "!"); greeting`,
+		"hello.txt": "hi monke",
+	})
+
+	interp := New(WithFS(files))
+
+	result, err := interp.RunFile("main.mk")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if result.Inspect() != "hi monke" {
+		t.Errorf("wrong result, expected %q got %q", "hi monke", result.Inspect())
+	}
+
+	out, err := files.ReadFile("out.txt")
+	if err != nil || string(out) != "hi monke!" {
+		t.Errorf("expected the script to write out.txt, got %q (%v)", out, err)
+	}
+
+	// files outside the configured file system can't be read
+	result, _ = interp.Run(`read_file("interpreter.go")`)
+	if _, ok := result.(*object.Error); !ok {
+		t.Errorf("expected an error reading a file outside of the file system, got %s", result.Inspect())
+	}
+
+	if _, err := interp.RunFile("missing.mk"); err == nil {
+		t.Errorf("expected an error running a missing file")
+	}
+}
+
+func TestGlobNeedsAFileSystem(t *testing.T) {
+	tests := []struct {
+		interp   *Interpreter
+		expected string
+	}{
+		// the host has to pass a file system that can list directories
+		{New(), "ERROR [R2003]: identifier not found: glob"},
+		{New(WithFS(vfs.NewMemory(map[string]string{"lib/a.mk": "", "b.mk": ""}))), "[lib/a.mk]"},
+	}
+
+	for _, tt := range tests {
+		result, _ := tt.interp.Run(`glob("lib", "*.mk")`)
+		if result.Inspect() != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, result.Inspect())
+		}
+	}
+}
+
+func TestWithParseCache(t *testing.T) {
+	cache := parsecache.New("")
+	interp := New(WithParseCache(cache))
+
+	for run := 0; run < 2; run++ {
+		result, err := interp.Run("let x = 5; x * 2")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if result.Inspect() != "10" {
+			t.Errorf("run %d: wrong result, expected 10 got %s", run, result.Inspect())
+		}
+	}
+
+	if !cache.Contains("let x = 5; x * 2") {
+		t.Errorf("expected the program to be cached")
+	}
+
+	if _, err := interp.Run("let x"); err == nil {
+		t.Errorf("expected a parse error")
+	}
+}
+
+func TestWithParserOptions(t *testing.T) {
+	cache := parsecache.New("")
+	source := `[1, 2, 3]`
+
+	// the program cached without limits isn't used by the interpreter with them
+	if _, err := New(WithParseCache(cache)).Run(source); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, interp := range []*Interpreter{
+		New(WithParserOptions(parser.WithMaxElements(2))),
+		New(WithParseCache(cache), WithParserOptions(parser.WithMaxElements(2))),
+	} {
+		_, err := interp.Run(source)
+		if parseErr, ok := err.(*ParseError); !ok || parseErr.Errors[0].Code != catalog.LITERAL_TOO_LARGE {
+			t.Errorf("expected the element limit error, got %v", err)
+		}
+	}
+}
+
+func TestWithHook(t *testing.T) {
+	events := []string{}
+	interp := New(WithHook(recordingHook{"a", &events}), WithHook(recordingHook{"b", &events}))
+
+	if _, err := interp.Run("let double = fn(x) { x * 2 }; double(double(1))"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []string{
+		"a before double(double(1))",
+		"b before double(double(1))",
+		"a before double(1)",
+		"b before double(1)",
+		"b after double(1) = 2",
+		"a after double(1) = 2",
+		"b after double(double(1)) = 4",
+		"a after double(double(1)) = 4",
+	}
+
+	if strings.Join(events, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("wrong events, expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(events, "\n"))
+	}
+}
+
+func TestWithMemoryLimit(t *testing.T) {
+	interp := New(WithMemoryLimit(10))
+
+	if result, _ := interp.Run(`"ab" * 5`); result.Inspect() != "ababababab" {
+		t.Errorf("expected the repetition to fit, got %s", result.Inspect())
+	}
+
+	result, _ := interp.Run(`"ab" * 6`)
+	if _, ok := result.(*object.Error); !ok {
+		t.Errorf("expected an *object.Error, got %T (%+v)", result, result)
+	}
+}
+
+func TestWithFuelAndDepthLimits(t *testing.T) {
+	interp := New(WithFuelLimit(5000), WithDepthLimit(100))
+
+	result, _ := interp.Run(`let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; f(90)`)
+	if result.Inspect() != "0" {
+		t.Errorf("expected the recursion to fit, got %s", result.Inspect())
+	}
+
+	result, _ = interp.Run(`let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; f(101)`)
+	if result.Inspect() != "ERROR [R2035]: depth limit exceeded: more than 100 nested function calls" {
+		t.Errorf("wrong result, got %s", result.Inspect())
+	}
+
+	// every run starts with a full budget
+	for run := 0; run < 2; run++ {
+		result, _ = interp.Run(`let total = 0; for (let i = 0; i < 100; i++) { total += i; }; total`)
+		if result.Inspect() != "4950" {
+			t.Errorf("run %d: expected the loop to fit, got %s", run, result.Inspect())
+		}
+	}
+
+	// callbacks passed to builtins use the same budget
+	result, _ = interp.Run(`map([1, 2], fn(x) { let spin = fn() { spin() }; spin() })`)
+	if result.Inspect() != "ERROR [R2035]: depth limit exceeded: more than 100 nested function calls" {
+		t.Errorf("wrong result, got %s", result.Inspect())
+	}
+
+	result, _ = interp.Run(`let r = []; for (let i = 0; i < 100000; i++) { r = push(r, i); }`)
+	if result.Inspect() != "ERROR [R2034]: fuel limit exceeded: evaluated more than 5000 nodes" {
+		t.Errorf("wrong result, got %s", result.Inspect())
+	}
+}
+
+func TestRuntimeInfo(t *testing.T) {
+	interp := New(WithMemoryLimit(1024), WithDepthLimit(64))
+
+	result, err := interp.Run(`let info = runtime_info(); [info["version"], info["engine"], info["limits"]["memory"], info["limits"]["depth"], "toml" in info["capabilities"]]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := "[" + evaluator.VERSION + ", eval, 1024, 64, true]"
+	if result.Inspect() != expected {
+		t.Errorf("wrong result, expected %s got %s", expected, result.Inspect())
+	}
+
+	if result, _ := New().Run(`runtime_info()["limits"]["memory"]`); result.Inspect() != "0" {
+		t.Errorf("expected no memory limit by default, got %s", result.Inspect())
+	}
+
+	if result, _ := New().Run(`runtime_info(1)`); result.Inspect() != "ERROR [R2011]: runtime_info: expected 0 arguments, got 1" {
+		t.Errorf("wrong error, got %s", result.Inspect())
+	}
+}
+
+func TestWithArgs(t *testing.T) {
+	interp := New(WithArgs("--verbose", "input.txt"))
+
+	result, err := interp.Run(`flags.bool("verbose", false); let opts = flags.parse(); [opts["verbose"], flags.args(), args()]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if result.Inspect() != "[true, [input.txt], [--verbose, input.txt]]" {
+		t.Errorf("wrong result, got %s", result.Inspect())
+	}
+}
+
+func TestWithPanicRecovery(t *testing.T) {
+	// missing arguments aren't checked, the evaluator indexes past them
+	input := `let f = fn(a, b) { a }; f(1)`
+
+	logger := &testLogger{}
+	result, err := New(WithPanicRecovery(true), WithLogger(logger)).Run(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	errObj, ok := result.(*object.Error)
+	if !ok || errObj.Code != "R2039" || errObj.Stack == "" {
+		t.Fatalf("expected an internal error with a stack, got %T (%+v)", result, result)
+	}
+
+	last := logger.events[len(logger.events)-1]
+	if last.msg != EVENT_RUNTIME_ERROR || len(last.args) != 4 || last.args[2] != "stack" {
+		t.Errorf("expected the runtime error to be logged with its stack, got %+v", last)
+	}
+
+	// generator bodies run on their own goroutine
+	generator := `let g = fn*() { let f = fn(a, b) { a }; yield f(1); }; take(g(), 3)`
+	result, err = New(WithPanicRecovery(true)).Run(generator)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	errObj, ok = result.(*object.Error)
+	if !ok || errObj.Code != "R2039" || !strings.Contains(errObj.Stack, "runBody") {
+		t.Fatalf("expected an internal error from the generator, got %T (%+v)", result, result)
+	}
+
+	// a parser that lost its lexer crashes on any source, like a bug in the parser would
+	broken := WithParserOptions(func(p *parser.Parser) { *p = parser.Parser{} })
+	interp := New(WithPanicRecovery(true), broken)
+
+	for name, run := range map[string]func(string) error{
+		"Run":           func(source string) error { _, err := interp.Run(source); return err },
+		"RunWithResult": func(source string) error { _, err := interp.RunWithResult(source); return err },
+		"EvalInSession": func(source string) error { _, err := interp.EvalInSession(source); return err },
+	} {
+		err := run("let x = 1;")
+		internal, ok := err.(*InternalError)
+		if !ok || internal.Err.Code != "R2039" || internal.Err.Stack == "" {
+			t.Errorf("%s: expected an *InternalError with a stack for the parser panic, got %T (%v)", name, err, err)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected the panic to go through without WithPanicRecovery")
+		}
+	}()
+	New().Run(input)
+}
+
+// Records every write it gets, so tests can see how output was buffered
+type chunkWriter struct {
+	chunks []string
+	err    error
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	w.chunks = append(w.chunks, string(p))
+	return len(p), nil
+}
+
+func TestWithOutputBuffering(t *testing.T) {
+	tests := []struct {
+		mode     OutputMode
+		expected []string
+	}{
+		{Unbuffered, []string{"1\n", "2\n", "3\n"}},
+		{LineBuffered, []string{"1\n", "2\n", "3\n"}},
+		{FullyBuffered, []string{"1\n2\n3\n"}},
+	}
+
+	for _, tt := range tests {
+		out := &chunkWriter{}
+		if _, err := New(WithStdout(out), WithOutputBuffering(tt.mode, 0)).Run(`puts(1); puts(2); puts(3)`); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if strings.Join(out.chunks, "|") != strings.Join(tt.expected, "|") {
+			t.Errorf("mode %d: expected the writes %q, got %q", tt.mode, tt.expected, out.chunks)
+		}
+	}
+
+	// lines are written once they're complete, with what follows them
+	out := &chunkWriter{}
+	lines := newOutput(out, LineBuffered, 0)
+	lines.Write([]byte("a"))
+	lines.Write([]byte("b\nc"))
+	if strings.Join(out.chunks, "|") != "ab\nc" {
+		t.Errorf("expected a single write of the complete line, got %q", out.chunks)
+	}
+
+	// nothing is written until it's flushed
+	out = &chunkWriter{}
+	interp := New(WithStdout(out), WithOutputBuffering(FullyBuffered, 0))
+	interp.output.Write([]byte("partial"))
+	if len(out.chunks) != 0 {
+		t.Fatalf("expected the output to be buffered, got %q", out.chunks)
+	}
+	if err := interp.Flush(); err != nil || strings.Join(out.chunks, "|") != "partial" {
+		t.Errorf("expected the buffered output after Flush, got %q (%v)", out.chunks, err)
+	}
+
+	// write errors show up when the run flushes
+	logger := &testLogger{}
+	failing := &chunkWriter{err: io.ErrClosedPipe}
+	New(WithStdout(failing), WithOutputBuffering(FullyBuffered, 0), WithLogger(logger)).Run(`puts(1)`)
+
+	last := logger.events[len(logger.events)-1]
+	if last.msg != EVENT_OUTPUT_ERROR || last.args[1] != io.ErrClosedPipe {
+		t.Errorf("expected the write error to be logged, got %+v", last)
+	}
+}
+
+func TestWithDiagnostics(t *testing.T) {
+	var diagnostics []object.Diagnostic
+	interp := New(WithDiagnostics(func(d object.Diagnostic) {
+		diagnostics = append(diagnostics, d)
+	}))
+
+	// only the divisions with a remainder, in functions too
+	result, err := interp.Run("let half = fn(n) { n / 2 }; let x = 9; x /= 3; let results = [half(7), half(8), 7.0 / 2, -7 / 2];\nlet y = 7; y /= 2; results")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if result.Inspect() != "[3, 4, 3.5, -3]" {
+		t.Errorf("diagnostics shouldn't change the results, got %s", result.Inspect())
+	}
+
+	// where the / is, for x /= 2 that's the /=
+	expected := []string{
+		"1:22: [W4001] integer division discards the remainder: 7 / 2 is 3, divide a float to get 3.5",
+		"1:93: [W4001] integer division discards the remainder: -7 / 2 is -3, divide a float to get -3.5",
+		"2:14: [W4001] integer division discards the remainder: 7 / 2 is 3, divide a float to get 3.5",
+	}
+	got := []string{}
+	for _, d := range diagnostics {
+		got = append(got, fmt.Sprintf("%d:%d: [%s] %s", d.Line, d.Column, d.Code, d.Message))
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("wrong diagnostics, expected %q, got %q", expected, got)
+	}
+
+	// dividing by zero is an error, not a warning
+	if result, _ := interp.Run(`7 / 0`); result.Inspect() != "ERROR [R2025]: division by zero: 7 / 0" {
+		t.Errorf("expected the division by zero error, got %s", result.Inspect())
+	}
+}
+
+func TestWithDisplayOptions(t *testing.T) {
+	input := `let x = {"a": [1, [2, [3]]]}; puts(x); puts("${x}"); puts(inspect(x)); puts(inspect(x, {"depth": 1}))`
+
+	tests := []struct {
+		opts     object.FormatOptions
+		expected string
+	}{
+		{object.FormatOptions{}, strings.Repeat(`{"a" : "[1, [2, [3]]]"}`+"\n", 3) + `{"a" : "[...]"}` + "\n"},
+		{object.FormatOptions{MaxDepth: 2}, strings.Repeat(`{"a" : "[1, [...]]"}`+"\n", 3) + `{"a" : "[...]"}` + "\n"},
+	}
+
+	// two interpreters with their own options, one doesn't change how the other prints
+	for _, tt := range tests {
+		var out bytes.Buffer
+		interp := New(WithStdout(&out), WithDisplayOptions(tt.opts))
+		if _, err := interp.Run(input); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if out.String() != tt.expected {
+			t.Errorf("with %+v expected %q, got %q", tt.opts, tt.expected, out.String())
+		}
+	}
+}
+
+func TestEvalInSession(t *testing.T) {
+	var out bytes.Buffer
+	interp := New(WithStdout(&out), WithFuelLimit(2000))
+
+	steps := []struct {
+		source   string
+		expected string
+	}{
+		{`let total = 0; total`, "0"},
+		{`total = total + 5; total += 2; total`, "7"},
+		// functions defined by an earlier call
+		{`let double = fn(n) { n * 2 }; double(4)`, "8"},
+		{`double(total)`, "14"},
+		// bindings made before an error are kept
+		{`let before = 1; missing`, "ERROR [R2003]: identifier not found: missing"},
+		{`before`, "1"},
+		{`puts(total); len("abc")`, "3"},
+	}
+
+	for _, step := range steps {
+		result, err := interp.EvalInSession(step.source)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %s", step.source, err)
+		}
+		if result.Inspect() != step.expected {
+			t.Errorf("%q: expected %s, got %s", step.source, step.expected, result.Inspect())
+		}
+	}
+
+	if out.String() != "7\n" {
+		t.Errorf("expected the session's puts to write to stdout, got %q", out.String())
+	}
+
+	// sources that can't be parsed don't change the session
+	if _, err := interp.EvalInSession(`let total = ;`); err == nil {
+		t.Errorf("expected a parse error")
+	}
+	if result, _ := interp.EvalInSession(`total`); result.Inspect() != "7" {
+		t.Errorf("expected total to be unchanged, got %s", result.Inspect())
+	}
+
+	// Run doesn't see the session
+	if result, _ := interp.Run(`total`); result.Inspect() != "ERROR [R2003]: identifier not found: total" {
+		t.Errorf("expected Run to use a fresh environment, got %s", result.Inspect())
+	}
+
+	// every call starts with a full budget, 3 loops of 100 iterations don't fit in a single one
+	for call := 0; call < 3; call++ {
+		result, _ := interp.EvalInSession(`for (let i = 0; i < 100; i++) { double(i) }; true`)
+		if result.Inspect() != "true" {
+			t.Errorf("call %d: expected the loop to fit, got %s", call, result.Inspect())
+		}
+	}
+	if result, _ := interp.Run(`let f = fn() { 1 }; for (let i = 0; i < 300; i++) { f() }`); !strings.Contains(result.Inspect(), "R2034") {
+		t.Errorf("expected the fuel limit to be exceeded, got %s", result.Inspect())
+	}
+
+	interp.ResetSession()
+	if result, _ := interp.EvalInSession(`total`); result.Inspect() != "ERROR [R2003]: identifier not found: total" {
+		t.Errorf("expected ResetSession to forget the bindings, got %s", result.Inspect())
+	}
+}
+
+func TestRunWithResult(t *testing.T) {
+	var stdout bytes.Buffer
+	handled := 0
+	interp := New(WithStdout(&stdout), WithOutputCapture(true), WithDiagnostics(func(object.Diagnostic) { handled++ }))
+
+	result, err := interp.RunWithResult(`let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; puts("a"); f(3); puts(7 / 2); 5 / 2`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if result.Value.Inspect() != "2" {
+		t.Errorf("wrong value, got %s", result.Value.Inspect())
+	}
+	if result.Output != "a\n3\n" || stdout.String() != "a\n3\n" {
+		t.Errorf("expected the output to be captured and written to stdout, got %q and %q", result.Output, stdout.String())
+	}
+	if len(result.Diagnostics) != 2 || handled != 2 {
+		t.Errorf("expected 2 diagnostics collected and handled, got %d and %d", len(result.Diagnostics), handled)
+	}
+	// f(3) to f(0)
+	if result.Stats.PeakDepth != 4 {
+		t.Errorf("expected a peak depth of 4, got %d", result.Stats.PeakDepth)
+	}
+	if result.Stats.Nodes == 0 {
+		t.Errorf("expected the evaluated nodes to be counted")
+	}
+
+	// the same count the fuel limit uses, without one
+	limited, _ := New(WithFuelLimit(result.Stats.Nodes - 1)).Run(`let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; puts("a"); f(3); puts(7 / 2); 5 / 2`)
+	if !strings.Contains(limited.Inspect(), "R2034") {
+		t.Errorf("expected %d nodes to go over the limit, got %s", result.Stats.Nodes, limited.Inspect())
+	}
+
+	// nothing captured or collected unless asked for, errors are the value like Run
+	result, _ = New(WithStdout(io.Discard)).RunWithResult(`puts("b"); missing`)
+	if result.Output != "" || len(result.Diagnostics) != 0 {
+		t.Errorf("expected no output or diagnostics, got %q and %v", result.Output, result.Diagnostics)
+	}
+	if result.Value.Inspect() != "ERROR [R2003]: identifier not found: missing" {
+		t.Errorf("wrong value, got %s", result.Value.Inspect())
+	}
+
+	if _, err := interp.RunWithResult(`let = 1`); err == nil {
+		t.Errorf("expected a parse error")
+	}
+
+	files := vfs.NewMemory(map[string]string{"main.mk": `puts("file"); 1 + 1`})
+	result, err = New(WithFS(files), WithStdout(io.Discard), WithOutputCapture(true)).RunFileWithResult("main.mk")
+	if err != nil || result.Value.Inspect() != "2" || result.Output != "file\n" {
+		t.Errorf("unexpected file result: %+v, %v", result, err)
+	}
+}
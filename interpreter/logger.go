@@ -0,0 +1,92 @@
+package interpreter
+
+import (
+	"errors"
+	"io/fs"
+	"monkey/vfs"
+)
+
+// Event names passed as the message to the Logger
+const (
+	EVENT_PARSE_START   = "parse start"
+	EVENT_PARSE_FINISH  = "parse finish"
+	EVENT_RUNTIME_ERROR = "runtime error"
+	EVENT_OUTPUT_ERROR  = "output error"
+	// the file system refused a file builtin (ex: write_file with a read-only FS)
+	EVENT_FILE_DENIED = "file denied"
+	// any other failure of the file system (ex: read_file of a missing file)
+	EVENT_FILE_ERROR = "file error"
+)
+
+/**
+Receives structured events about what the interpreter is doing.
+
+- the method set matches *slog.Logger, so a slog logger can be passed in directly
+- args are alternating key/value pairs: "statements", 3, "errors", 0
+**/
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// Sends interpreter events to the given logger
+func WithLogger(logger Logger) Option {
+	return func(i *Interpreter) {
+		if logger != nil {
+			i.logger = logger
+		}
+	}
+}
+
+// Default logger, discards everything
+type nopLogger struct{}
+
+func (nopLogger) Debug(msg string, args ...interface{}) {}
+func (nopLogger) Info(msg string, args ...interface{})  {}
+func (nopLogger) Warn(msg string, args ...interface{})  {}
+func (nopLogger) Error(msg string, args ...interface{}) {}
+
+/**
+Logs the failures of the file system the file builtins use, see EVENT_FILE_DENIED and EVENT_FILE_ERROR.
+Wrapped when an environment is created, so the FS and the logger can be passed to New in any order.
+**/
+type loggingFS struct {
+	fsys   vfs.FS
+	logger Logger
+}
+
+func (l loggingFS) ReadFile(name string) ([]byte, error) {
+	data, err := l.fsys.ReadFile(name)
+	l.log("read", name, err)
+	return data, err
+}
+
+func (l loggingFS) WriteFile(name string, data []byte) error {
+	err := l.fsys.WriteFile(name, data)
+	l.log("write", name, err)
+	return err
+}
+
+func (l loggingFS) log(op, name string, err error) {
+	switch {
+	case err == nil:
+	case errors.Is(err, fs.ErrPermission) || errors.Is(err, vfs.ErrReadOnly):
+		l.logger.Warn(EVENT_FILE_DENIED, "op", op, "name", name, "error", err)
+	default:
+		l.logger.Warn(EVENT_FILE_ERROR, "op", op, "name", name, "error", err)
+	}
+}
+
+// loggingFS for file systems that can list directories (glob)
+type loggingReadDirFS struct {
+	loggingFS
+	lister vfs.ReadDirFS
+}
+
+func (l loggingReadDirFS) ReadDir(name string) ([]string, error) {
+	names, err := l.lister.ReadDir(name)
+	l.log("readdir", name, err)
+	return names, err
+}
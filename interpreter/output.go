@@ -0,0 +1,72 @@
+package interpreter
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sync"
+)
+
+// When what scripts print (puts, help, the flags module's --help) is written to the interpreter's stdout
+type OutputMode int
+
+const (
+	// every write goes straight to stdout (the default)
+	Unbuffered OutputMode = iota
+	// written once a line is complete
+	LineBuffered
+	// written when the buffer is full, Flush is called or a run finishes
+	FullyBuffered
+)
+
+/**
+Buffers script output on its way to stdout, see WithOutputBuffering:
+
+	interp := interpreter.New(interpreter.WithStdout(conn), interpreter.WithOutputBuffering(interpreter.FullyBuffered, 0))
+	interp.Run(script) // written to conn when the buffer fills up and once more when Run returns
+
+Safe to use from concurrent runs, writes are serialized.
+**/
+type output struct {
+	mu   sync.Mutex
+	mode OutputMode
+	out  io.Writer
+	buf  *bufio.Writer // nil when unbuffered
+}
+
+func newOutput(out io.Writer, mode OutputMode, size int) *output {
+	o := &output{mode: mode, out: out}
+	if mode != Unbuffered {
+		o.buf = bufio.NewWriterSize(out, size)
+	}
+
+	return o
+}
+
+func (o *output) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.buf == nil {
+		return o.out.Write(p)
+	}
+
+	n, err := o.buf.Write(p)
+	if err == nil && o.mode == LineBuffered && bytes.IndexByte(p, '\n') >= 0 {
+		err = o.buf.Flush()
+	}
+
+	return n, err
+}
+
+// Writes whatever is buffered to stdout
+func (o *output) Flush() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.buf == nil {
+		return nil
+	}
+
+	return o.buf.Flush()
+}
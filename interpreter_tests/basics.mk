@@ -0,0 +1,7 @@
+// arithmetic, strings and bindings
+let x = 5 * (2 + 3);
+puts(x);
+puts(7 / 2, 7 % 2, 2.5 * 2);
+puts("monke" + "!");
+let name = "world";
+puts("hello ${name}, ${x + 1}");
@@ -0,0 +1,7 @@
+let config = toml_decode("[server]\nport = 8080\nhosts = [\"a\", \"b\"]");
+puts(config["server"]["port"]);
+puts(config["server"]["hosts"]);
+
+let doc = yaml_decode("name: monke\ntags:\n  - fast\n  - small");
+puts(doc["tags"]);
+puts(yaml_encode([1, "two"]));
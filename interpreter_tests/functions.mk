@@ -0,0 +1,9 @@
+let fib = fn(n) {
+  if (n < 2) { return n; }
+  fib(n - 1) + fib(n - 2)
+};
+puts(fib(15));
+
+let makeAdder = fn(a) { fn(b) { a + b } };
+let addTwo = makeAdder(2);
+puts([1, 2, 3].map(addTwo));
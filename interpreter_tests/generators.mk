@@ -0,0 +1,15 @@
+let naturals = fn*() {
+  for (let i = 0; i > -1; i++) {
+    yield i;
+  }
+};
+puts(take(naturals(), 5));
+
+let evens = fn*(arr) {
+  for (x in arr) {
+    if (x % 2 == 0) { yield x; }
+  }
+};
+for (x in evens([1, 2, 3, 4, 5, 6])) {
+  puts(x);
+}
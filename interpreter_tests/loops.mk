@@ -0,0 +1,20 @@
+let total = 0;
+for (let i = 0; i < 5; i++) {
+  total += i;
+}
+puts(total);
+
+for (x in [1, 2, 3]) {
+  puts(x * 10);
+}
+
+for (c in "abc") {
+  puts(c);
+}
+
+// closures keep the counter of their own iteration
+let fns = [];
+for (let i = 0; i < 3; i++) {
+  fns = push(fns, fn() { i });
+}
+puts([fns[0](), fns[1](), fns[2]()]);
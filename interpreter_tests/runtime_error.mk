@@ -0,0 +1,5 @@
+// output before the error is kept
+puts("before");
+let f = fn(x) { x + missing };
+f(1);
+puts("never printed");
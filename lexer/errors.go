@@ -0,0 +1,69 @@
+package lexer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// How many bytes around the offending char are kept in Error.Snippet (on each side)
+const SNIPPET_CONTEXT = 20
+
+/**
+A problem found while tokenizing the input: a char that can't start any token,
+or a malformed escape sequence in a string.
+
+The lexer still returns an ILLEGAL token for it (so parsing goes on), these errors
+only give a more precise picture of what went wrong and where.
+**/
+type Error struct {
+	Message string
+	Char    string // the illegal char, or the malformed escape sequence
+	Offset  int    // byte offset in the whole input
+	Line    int
+	Column  int
+	Snippet string // the source code around the char, on the same line
+}
+
+// 1:11: illegal character '@' near "let x = 1 @ 2;"
+func (e Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s near %q", e.Line, e.Column, e.Message, e.Snippet)
+}
+
+// Returns every error found in the input tokenized so far
+func (l *Lexer) Errors() []Error {
+	return l.errors
+}
+
+/**
+Records an error for the char(s) starting at the given position of l.input.
+line and lineStart are the ones of that position, they could've changed since it was read.
+**/
+func (l *Lexer) addError(message, char string, position, line, lineStart int) {
+	// the rest of the line might not have been read yet
+	l.fill(position + SNIPPET_CONTEXT)
+
+	start := position - SNIPPET_CONTEXT
+	if start < lineStart {
+		start = lineStart
+	}
+	if start < 0 {
+		start = 0
+	}
+
+	end := position + SNIPPET_CONTEXT
+	if end > len(l.input) {
+		end = len(l.input)
+	}
+	if newline := strings.IndexByte(l.input[position:end], '\n'); newline >= 0 {
+		end = position + newline
+	}
+
+	l.errors = append(l.errors, Error{
+		Message: message,
+		Char:    char,
+		Offset:  l.discarded + position,
+		Line:    line,
+		Column:  position - lineStart + 1,
+		Snippet: strings.TrimSpace(l.input[start:end]),
+	})
+}
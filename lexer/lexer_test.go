@@ -1,7 +1,11 @@
 package lexer
 
 import (
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"monkey/token"
 )
@@ -26,7 +30,7 @@ func TestNextToken(t *testing.T) {
 
 	let result = add(five, ten);
 
-	!-/*5;
+	!-/ *5; // "/*" would start a block comment
 	5 < 10 > 5;
 
 	if (5 < 10) {
@@ -48,6 +52,8 @@ func TestNextToken(t *testing.T) {
 	x = 2
 
 	for (x = 2; x > 10; x = x + 1) { puts x }
+	fn(a: int) -> int { a }
+	10 % 3
 	`
 	// Lets make sure we get back the correct tokens based on our input.
 	tests := []struct {
@@ -174,6 +180,20 @@ func TestNextToken(t *testing.T) {
 		{token.IDENT, "puts"},
 		{token.IDENT, "x"},
 		{token.RBRACE, "}"},
+		{token.FUNCTION, "fn"},
+		{token.LPAREN, "("},
+		{token.IDENT, "a"},
+		{token.COLON, ":"},
+		{token.IDENT, "int"},
+		{token.RPAREN, ")"},
+		{token.ARROW, "->"},
+		{token.IDENT, "int"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "a"},
+		{token.RBRACE, "}"},
+		{token.INT, "10"},
+		{token.PERCENT, "%"},
+		{token.INT, "3"},
 		{token.EOF, ""},
 	}
 	// Create a new lexer
@@ -192,3 +212,789 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestIdentifierInterning(t *testing.T) {
+	l := New("let counter = counter + counter;")
+
+	idents := []string{}
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		if tok.Type == token.IDENT {
+			idents = append(idents, tok.Literal)
+		}
+	}
+
+	if len(idents) != 3 {
+		t.Fatalf("expected 3 identifiers, got %d", len(idents))
+	}
+
+	// one entry per name ("let" and "counter"), however many times it appears
+	if len(l.idents) != 2 {
+		t.Errorf("expected 2 interned names, got %d: %v", len(l.idents), l.idents)
+	}
+
+	if _, ok := l.idents["counter"]; !ok {
+		t.Fatalf("expected counter to be interned")
+	}
+
+	// later occurrences are found in the table instead of being added again
+	if got := l.intern(string([]byte("counter"))); got != "counter" || len(l.idents) != 2 {
+		t.Errorf("expected the interned counter to be reused, got %q (%d names)", got, len(l.idents))
+	}
+}
+
+func TestStringLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{`let name = "monke";`, []token.Token{
+			{Type: token.LET, Literal: "let"},
+			{Type: token.IDENT, Literal: "name"},
+			{Type: token.ASSIGN, Literal: "="},
+			{Type: token.STRING, Literal: "monke"},
+			{Type: token.SEMICOLON, Literal: ";"},
+			{Type: token.EOF, Literal: ""},
+		}},
+		{`""`, []token.Token{
+			{Type: token.STRING, Literal: ""},
+			{Type: token.EOF, Literal: ""},
+		}},
+		{`"héllo wörld"`, []token.Token{
+			{Type: token.STRING, Literal: "héllo wörld"},
+			{Type: token.EOF, Literal: ""},
+		}},
+		{`"a\nb\tc\r\"d\" \\"`, []token.Token{
+			{Type: token.STRING, Literal: "a\nb\tc\r\"d\" \\"},
+			{Type: token.EOF, Literal: ""},
+		}},
+		{`"\u{1F648} \u{61}"`, []token.Token{
+			{Type: token.STRING, Literal: "🙈 a"},
+			{Type: token.EOF, Literal: ""},
+		}},
+		{`"a\qb"; 1`, []token.Token{
+			{Type: token.ILLEGAL, Literal: `\q`},
+			{Type: token.SEMICOLON, Literal: ";"},
+			{Type: token.INT, Literal: "1"},
+		}},
+		{"`C:\\dir\\n \"quoted\"\nline two`", []token.Token{
+			{Type: token.STRING, Literal: "C:\\dir\\n \"quoted\"\nline two"},
+			{Type: token.EOF, Literal: ""},
+		}},
+		{"``", []token.Token{
+			{Type: token.STRING, Literal: ""},
+			{Type: token.EOF, Literal: ""},
+		}},
+		{`"hi ${name}, ${ {"a": 1}["a"] + 1 }!" "${"${x}"}" "\${x} $5"`, []token.Token{
+			{Type: token.STRING_START, Literal: "hi "},
+			{Type: token.IDENT, Literal: "name"},
+			{Type: token.STRING_MIDDLE, Literal: ", "},
+			{Type: token.LBRACE, Literal: "{"},
+			{Type: token.STRING, Literal: "a"},
+			{Type: token.COLON, Literal: ":"},
+			{Type: token.INT, Literal: "1"},
+			{Type: token.RBRACE, Literal: "}"},
+			{Type: token.LBRACKET, Literal: "["},
+			{Type: token.STRING, Literal: "a"},
+			{Type: token.RBRACKET, Literal: "]"},
+			{Type: token.PLUS, Literal: "+"},
+			{Type: token.INT, Literal: "1"},
+			{Type: token.STRING_END, Literal: "!"},
+			{Type: token.STRING_START, Literal: ""},
+			{Type: token.STRING_START, Literal: ""},
+			{Type: token.IDENT, Literal: "x"},
+			{Type: token.STRING_END, Literal: ""},
+			{Type: token.STRING_END, Literal: ""},
+			{Type: token.STRING, Literal: "${x} $5"},
+			{Type: token.EOF, Literal: ""},
+		}},
+		{`"\u{zz}" "\u{110000}" "\u41"`, []token.Token{
+			{Type: token.ILLEGAL, Literal: `\u{zz}`},
+			{Type: token.ILLEGAL, Literal: `\u{110000}`},
+			{Type: token.ILLEGAL, Literal: `\u`},
+			{Type: token.EOF, Literal: ""},
+		}},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+
+		for i, expected := range tt.expected {
+			tok := l.NextToken()
+
+			if tok.Type != expected.Type || tok.Literal != expected.Literal {
+				t.Errorf("%q: tokens[%d] wrong, expected %+v got %+v", tt.input, i, expected, tok)
+			}
+		}
+	}
+}
+
+func TestCharLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{`let c = 'a';`, []token.Token{
+			{Type: token.LET, Literal: "let"},
+			{Type: token.IDENT, Literal: "c"},
+			{Type: token.ASSIGN, Literal: "="},
+			{Type: token.CHAR, Literal: "a"},
+			{Type: token.SEMICOLON, Literal: ";"},
+			{Type: token.EOF, Literal: ""},
+		}},
+		{`'é' '🙈' '"'`, []token.Token{
+			{Type: token.CHAR, Literal: "é"},
+			{Type: token.CHAR, Literal: "🙈"},
+			{Type: token.CHAR, Literal: `"`},
+			{Type: token.EOF, Literal: ""},
+		}},
+		{`'\n' '\'' '\\' '\u{1F648}'`, []token.Token{
+			{Type: token.CHAR, Literal: "\n"},
+			{Type: token.CHAR, Literal: "'"},
+			{Type: token.CHAR, Literal: `\`},
+			{Type: token.CHAR, Literal: "🙈"},
+			{Type: token.EOF, Literal: ""},
+		}},
+		// the whole literal is illegal, lexing goes on after it
+		{`'' 'ab' 1`, []token.Token{
+			{Type: token.ILLEGAL, Literal: "''"},
+			{Type: token.ILLEGAL, Literal: "'ab'"},
+			{Type: token.INT, Literal: "1"},
+			{Type: token.EOF, Literal: ""},
+		}},
+		{"'a\n1", []token.Token{
+			{Type: token.ILLEGAL, Literal: "'a"},
+			{Type: token.INT, Literal: "1"},
+			{Type: token.EOF, Literal: ""},
+		}},
+		{`'\q' 1`, []token.Token{
+			{Type: token.ILLEGAL, Literal: `\q`},
+			{Type: token.INT, Literal: "1"},
+			{Type: token.EOF, Literal: ""},
+		}},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+
+		for i, expected := range tt.expected {
+			tok := l.NextToken()
+
+			if tok.Type != expected.Type || tok.Literal != expected.Literal {
+				t.Errorf("%q: tokens[%d] wrong, expected %+v got %+v", tt.input, i, expected, tok)
+			}
+		}
+	}
+}
+
+func TestHeredocs(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{"let s = <<EOF\n\"quoted\" \\n ${x}\n\nEOF;", []token.Token{
+			{Type: token.LET, Literal: "let"},
+			{Type: token.IDENT, Literal: "s"},
+			{Type: token.ASSIGN, Literal: "="},
+			{Type: token.STRING, Literal: "\"quoted\" \\n ${x}\n\n"},
+			{Type: token.SEMICOLON, Literal: ";"},
+			{Type: token.EOF, Literal: ""},
+		}},
+		// the closing tag can be indented, a longer word doesn't close it
+		{"f(<<END_2  \nEND_22\n  END_2)", []token.Token{
+			{Type: token.IDENT, Literal: "f"},
+			{Type: token.LPAREN, Literal: "("},
+			{Type: token.STRING, Literal: "END_22\n"},
+			{Type: token.RPAREN, Literal: ")"},
+			{Type: token.EOF, Literal: ""},
+		}},
+		{"<<~TXT\n    a\n\n      b\n    TXT", []token.Token{
+			{Type: token.STRING, Literal: "a\n\n  b\n"},
+			{Type: token.EOF, Literal: ""},
+		}},
+		{"<<EOF\nEOF", []token.Token{
+			{Type: token.STRING, Literal: ""},
+			{Type: token.EOF, Literal: ""},
+		}},
+		// shifts
+		{"x <<EOF; x << Y\nx <<y\n", []token.Token{
+			{Type: token.IDENT, Literal: "x"},
+			{Type: token.SHIFT_LEFT, Literal: "<<"},
+			{Type: token.IDENT, Literal: "EOF"},
+			{Type: token.SEMICOLON, Literal: ";"},
+			{Type: token.IDENT, Literal: "x"},
+			{Type: token.SHIFT_LEFT, Literal: "<<"},
+			{Type: token.IDENT, Literal: "Y"},
+			{Type: token.IDENT, Literal: "x"},
+			{Type: token.SHIFT_LEFT, Literal: "<<"},
+			{Type: token.IDENT, Literal: "y"},
+			{Type: token.EOF, Literal: ""},
+		}},
+		{"<<~EOF\nnever closed\nEOFS", []token.Token{
+			{Type: token.ILLEGAL, Literal: "<<~EOF"},
+			{Type: token.EOF, Literal: ""},
+		}},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+
+		for i, expected := range tt.expected {
+			tok := l.NextToken()
+
+			if tok.Type != expected.Type || tok.Literal != expected.Literal {
+				t.Errorf("%q: tokens[%d] wrong, expected %+v got %+v", tt.input, i, expected, tok)
+			}
+		}
+	}
+
+	// the lines after it are counted
+	l := New("<<EOF\na\nEOF\nx")
+	l.NextToken()
+	if tok := l.NextToken(); tok.Line != 4 || tok.Column != 1 || tok.Start != 12 {
+		t.Errorf("wrong position after the heredoc, got %+v", tok)
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x = 5;\n  x + \"ab\";\n\nfn"
+
+	tests := []struct {
+		literal string
+		line    int
+		column  int
+	}{
+		{"let", 1, 1},
+		{"x", 1, 5},
+		{"=", 1, 7},
+		{"5", 1, 9},
+		{";", 1, 10},
+		{"x", 2, 3},
+		{"+", 2, 5},
+		{"ab", 2, 7},
+		{";", 2, 11},
+		{"fn", 4, 1},
+		{"", 4, 3},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Literal != tt.literal || tok.Line != tt.line || tok.Column != tt.column {
+			t.Errorf("tests[%d] wrong token, expected %q at %d:%d got %q at %d:%d", i, tt.literal, tt.line, tt.column, tok.Literal, tok.Line, tok.Column)
+		}
+	}
+}
+
+func TestTokenRanges(t *testing.T) {
+	input := "let big = 1_000;\n  \"a\\tb ${x}!\" != `raw`; // done\n"
+	expected := []string{"let", "big", "=", "1_000", ";", "\"a\\tb ${", "x", "}!\"", "!=", "`raw`", ";", ""}
+
+	tests := []struct {
+		constructor string
+		lexer       *Lexer
+	}{
+		{"New", New(input)},
+		// the offsets count what was already discarded from the buffer
+		{"NewFromReader", NewFromReader(iotest.OneByteReader(strings.NewReader(input)))},
+	}
+
+	for _, tt := range tests {
+		for i, text := range expected {
+			tok := tt.lexer.NextToken()
+
+			if tok.Start > tok.End || tok.End > len(input) || input[tok.Start:tok.End] != text {
+				t.Errorf("%s: tokens[%d] wrong range, expected %q got %d:%d (%+v)", tt.constructor, i, text, tok.Start, tok.End, tok)
+			}
+		}
+	}
+
+	if tok := New(`"open`).NextToken(); tok.Start != 0 || tok.End != 5 {
+		t.Errorf("wrong range for the unterminated string, expected 0:5 got %+v", tok)
+	}
+
+	// a malformed escape points to the escape itself
+	tok := New(`"ok\qx"`).NextToken()
+	if tok.Type != token.ILLEGAL || tok.Start != 3 || tok.End != 5 {
+		t.Errorf("wrong range for the malformed escape, expected 3:5 got %+v", tok)
+	}
+}
+
+func TestFloatLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{`let pi = 3.14;`, []token.Token{
+			{Type: token.LET, Literal: "let"},
+			{Type: token.IDENT, Literal: "pi"},
+			{Type: token.ASSIGN, Literal: "="},
+			{Type: token.FLOAT, Literal: "3.14"},
+			{Type: token.SEMICOLON, Literal: ";"},
+		}},
+		// a dot that isn't followed by a digit isn't part of the number
+		{`5.pow`, []token.Token{
+			{Type: token.INT, Literal: "5"},
+			{Type: token.DOT, Literal: "."},
+			{Type: token.IDENT, Literal: "pow"},
+		}},
+		{`0.5 + 10.25`, []token.Token{
+			{Type: token.FLOAT, Literal: "0.5"},
+			{Type: token.PLUS, Literal: "+"},
+			{Type: token.FLOAT, Literal: "10.25"},
+		}},
+		{`1e9 2.5e-3 6E+23 1_0e1_0`, []token.Token{
+			{Type: token.FLOAT, Literal: "1e9"},
+			{Type: token.FLOAT, Literal: "2.5e-3"},
+			{Type: token.FLOAT, Literal: "6E+23"},
+			{Type: token.FLOAT, Literal: "10e10"},
+		}},
+		// an e that isn't followed by digits isn't an exponent
+		{`1else 2e-x`, []token.Token{
+			{Type: token.INT, Literal: "1"},
+			{Type: token.ELSE, Literal: "else"},
+			{Type: token.INT, Literal: "2"},
+			{Type: token.IDENT, Literal: "e"},
+			{Type: token.MINUS, Literal: "-"},
+			{Type: token.IDENT, Literal: "x"},
+		}},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+
+		for i, expected := range tt.expected {
+			tok := l.NextToken()
+
+			if tok.Type != expected.Type || tok.Literal != expected.Literal {
+				t.Errorf("%q: tokens[%d] wrong, expected %+v got %+v", tt.input, i, expected, tok)
+			}
+		}
+	}
+}
+
+func TestNumberSeparators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{`1_000_000 3.141_592 1_0.5`, []token.Token{
+			{Type: token.INT, Literal: "1000000"},
+			{Type: token.FLOAT, Literal: "3.141592"},
+			{Type: token.FLOAT, Literal: "10.5"},
+		}},
+		// underscores only go between digits
+		{`1__0 2_ 3_.5 4.5_ 5_e1`, []token.Token{
+			{Type: token.ILLEGAL, Literal: "1__0"},
+			{Type: token.ILLEGAL, Literal: "2_"},
+			{Type: token.ILLEGAL, Literal: "3_.5"},
+			{Type: token.ILLEGAL, Literal: "4.5_"},
+			{Type: token.ILLEGAL, Literal: "5_e1"},
+		}},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+
+		for i, expected := range tt.expected {
+			tok := l.NextToken()
+
+			if tok.Type != expected.Type || tok.Literal != expected.Literal {
+				t.Errorf("%q: tokens[%d] wrong, expected %+v got %+v", tt.input, i, expected, tok)
+			}
+		}
+	}
+}
+
+func TestMultiCharOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{`3 in arr`, []token.Token{
+			{Type: token.INT, Literal: "3"},
+			{Type: token.IN, Literal: "in"},
+			{Type: token.IDENT, Literal: "arr"},
+		}},
+		{`3 !in arr`, []token.Token{
+			{Type: token.INT, Literal: "3"},
+			{Type: token.NOT_IN, Literal: "!in"},
+			{Type: token.IDENT, Literal: "arr"},
+		}},
+		// only a whole word is the operator
+		{`!inside index`, []token.Token{
+			{Type: token.BANG, Literal: "!"},
+			{Type: token.IDENT, Literal: "inside"},
+			{Type: token.IDENT, Literal: "index"},
+		}},
+		{`x !in`, []token.Token{
+			{Type: token.IDENT, Literal: "x"},
+			{Type: token.NOT_IN, Literal: "!in"},
+			{Type: token.EOF, Literal: ""},
+		}},
+		{`a && b || c`, []token.Token{
+			{Type: token.IDENT, Literal: "a"},
+			{Type: token.AND, Literal: "&&"},
+			{Type: token.IDENT, Literal: "b"},
+			{Type: token.OR, Literal: "||"},
+			{Type: token.IDENT, Literal: "c"},
+		}},
+		{`a & b | c ^ d << 1 >> 2 < >`, []token.Token{
+			{Type: token.IDENT, Literal: "a"},
+			{Type: token.BIT_AND, Literal: "&"},
+			{Type: token.IDENT, Literal: "b"},
+			{Type: token.BIT_OR, Literal: "|"},
+			{Type: token.IDENT, Literal: "c"},
+			{Type: token.BIT_XOR, Literal: "^"},
+			{Type: token.IDENT, Literal: "d"},
+			{Type: token.SHIFT_LEFT, Literal: "<<"},
+			{Type: token.INT, Literal: "1"},
+			{Type: token.SHIFT_RIGHT, Literal: ">>"},
+			{Type: token.INT, Literal: "2"},
+			{Type: token.LT, Literal: "<"},
+			{Type: token.GT, Literal: ">"},
+		}},
+		{`x += 1 -= 2 *= 3 /= 4 %= 5 -> - =`, []token.Token{
+			{Type: token.IDENT, Literal: "x"},
+			{Type: token.PLUS_ASSIGN, Literal: "+="},
+			{Type: token.INT, Literal: "1"},
+			{Type: token.MINUS_ASSIGN, Literal: "-="},
+			{Type: token.INT, Literal: "2"},
+			{Type: token.ASTERISK_ASSIGN, Literal: "*="},
+			{Type: token.INT, Literal: "3"},
+			{Type: token.SLASH_ASSIGN, Literal: "/="},
+			{Type: token.INT, Literal: "4"},
+			{Type: token.PERCENT_ASSIGN, Literal: "%="},
+			{Type: token.INT, Literal: "5"},
+			{Type: token.ARROW, Literal: "->"},
+			{Type: token.MINUS, Literal: "-"},
+			{Type: token.ASSIGN, Literal: "="},
+		}},
+		{`i++ j-- +++`, []token.Token{
+			{Type: token.IDENT, Literal: "i"},
+			{Type: token.INCREMENT, Literal: "++"},
+			{Type: token.IDENT, Literal: "j"},
+			{Type: token.DECREMENT, Literal: "--"},
+			{Type: token.INCREMENT, Literal: "++"},
+			{Type: token.PLUS, Literal: "+"},
+		}},
+		{`match (x) { 1 => a, _ => b } == =>`, []token.Token{
+			{Type: token.MATCH, Literal: "match"},
+			{Type: token.LPAREN, Literal: "("},
+			{Type: token.IDENT, Literal: "x"},
+			{Type: token.RPAREN, Literal: ")"},
+			{Type: token.LBRACE, Literal: "{"},
+			{Type: token.INT, Literal: "1"},
+			{Type: token.FAT_ARROW, Literal: "=>"},
+			{Type: token.IDENT, Literal: "a"},
+			{Type: token.COMMA, Literal: ","},
+			{Type: token.IDENT, Literal: "_"},
+			{Type: token.FAT_ARROW, Literal: "=>"},
+			{Type: token.IDENT, Literal: "b"},
+			{Type: token.RBRACE, Literal: "}"},
+			{Type: token.EQ, Literal: "=="},
+			{Type: token.FAT_ARROW, Literal: "=>"},
+		}},
+		{`a?.b()?[0]`, []token.Token{
+			{Type: token.IDENT, Literal: "a"},
+			{Type: token.OPTIONAL_DOT, Literal: "?."},
+			{Type: token.IDENT, Literal: "b"},
+			{Type: token.LPAREN, Literal: "("},
+			{Type: token.RPAREN, Literal: ")"},
+			{Type: token.OPTIONAL_LBRACKET, Literal: "?["},
+			{Type: token.INT, Literal: "0"},
+		}},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+
+		for i, expected := range tt.expected {
+			tok := l.NextToken()
+
+			if tok.Type != expected.Type || tok.Literal != expected.Literal {
+				t.Errorf("%q: tokens[%d] wrong, expected %+v got %+v", tt.input, i, expected, tok)
+			}
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// a line comment
+let x = 5; // trailing comment
+/* a block
+   comment */ let y /* inline */ = x / 2;
+/* unterminated`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedLine    int
+	}{
+		{token.LET, "let", 2},
+		{token.IDENT, "x", 2},
+		{token.ASSIGN, "=", 2},
+		{token.INT, "5", 2},
+		{token.SEMICOLON, ";", 2},
+		{token.LET, "let", 4},
+		{token.IDENT, "y", 4},
+		{token.ASSIGN, "=", 4},
+		{token.IDENT, "x", 4},
+		{token.SLASH, "/", 4},
+		{token.INT, "2", 4},
+		{token.SEMICOLON, ";", 4},
+		{token.EOF, "", 5},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral || tok.Line != tt.expectedLine {
+			t.Errorf("tokens[%d] wrong, expected %q %q line %d, got %q %q line %d",
+				i, tt.expectedType, tt.expectedLiteral, tt.expectedLine, tok.Type, tok.Literal, tok.Line)
+		}
+	}
+}
+
+func TestTrivia(t *testing.T) {
+	input := "// header\n\nlet x = 5; // five\nlet s = \"a ${ x } b\"; /* spans\n lines */\n\n\t/* last */ x\n// the end\n"
+
+	tests := []struct {
+		constructor string
+		lexer       *Lexer
+	}{
+		{"New", New(input).WithTrivia()},
+		{"NewFromReader", NewFromReader(iotest.OneByteReader(strings.NewReader(input))).WithTrivia()},
+	}
+
+	for _, tt := range tests {
+		var tokens []token.Token
+		for tok := tt.lexer.NextToken(); ; tok = tt.lexer.NextToken() {
+			tokens = append(tokens, tok)
+			if tok.Type == token.EOF {
+				break
+			}
+		}
+
+		// the trivia and the tokens give back the source
+		var out strings.Builder
+		for _, tok := range tokens {
+			for _, trivia := range tok.Trivia.Leading {
+				out.WriteString(trivia.Text)
+			}
+			out.WriteString(input[tok.Start:tok.End])
+			for _, trivia := range tok.Trivia.Trailing {
+				out.WriteString(trivia.Text)
+			}
+		}
+
+		if out.String() != input {
+			t.Errorf("%s: round trip changed the source, expected %q got %q", tt.constructor, input, out.String())
+		}
+
+		let, semicolon, last, eof := tokens[0], tokens[4], tokens[len(tokens)-2], tokens[len(tokens)-1]
+
+		expected := []struct {
+			name   string
+			trivia []token.Trivia
+			want   []token.Trivia
+		}{
+			{"let leading", let.Trivia.Leading, []token.Trivia{
+				{Kind: token.COMMENT, Text: "// header", Start: 0, End: 9},
+				{Kind: token.WHITESPACE, Text: "\n\n", Start: 9, End: 11},
+			}},
+			{"let trailing", let.Trivia.Trailing, []token.Trivia{{Kind: token.WHITESPACE, Text: " ", Start: 14, End: 15}}},
+			{"; trailing", semicolon.Trivia.Trailing, []token.Trivia{
+				{Kind: token.WHITESPACE, Text: " ", Start: 21, End: 22},
+				{Kind: token.COMMENT, Text: "// five", Start: 22, End: 29},
+				{Kind: token.WHITESPACE, Text: "\n", Start: 29, End: 30},
+			}},
+			{"x leading", last.Trivia.Leading, []token.Trivia{
+				// the block comment ended the line of the ;, the newline after it is left here
+				{Kind: token.WHITESPACE, Text: "\n\n\t", Start: 70, End: 73},
+				{Kind: token.COMMENT, Text: "/* last */", Start: 73, End: 83},
+				{Kind: token.WHITESPACE, Text: " ", Start: 83, End: 84},
+			}},
+			{"EOF leading", eof.Trivia.Leading, []token.Trivia{
+				{Kind: token.COMMENT, Text: "// the end", Start: 86, End: 96},
+				{Kind: token.WHITESPACE, Text: "\n", Start: 96, End: 97},
+			}},
+		}
+
+		for _, e := range expected {
+			if !reflect.DeepEqual(e.trivia, e.want) {
+				t.Errorf("%s: %s wrong, expected %+v got %+v", tt.constructor, e.name, e.want, e.trivia)
+			}
+		}
+	}
+
+	// off by default
+	if tok := New("// comment\nx").NextToken(); tok.Trivia != nil {
+		t.Errorf("expected no trivia, got %+v", tok)
+	}
+}
+
+func TestPeek(t *testing.T) {
+	input := `let add = fn(a, b) { a + b }; add(1, 2);`
+
+	var expected []token.Token
+	for l := New(input); len(expected) == 0 || expected[len(expected)-1].Type != token.EOF; {
+		expected = append(expected, l.NextToken())
+	}
+
+	tests := []struct {
+		constructor string
+		lexer       *Lexer
+	}{
+		{"New", New(input)},
+		{"NewFromReader", NewFromReader(iotest.OneByteReader(strings.NewReader(input)))},
+	}
+
+	for _, tt := range tests {
+		l := tt.lexer
+
+		// looking further and further ahead while consuming, so the buffer wraps around and grows
+		for i := range expected {
+			for n := 1; n <= i%7+1; n++ {
+				// EOF again after the end
+				want := expected[len(expected)-1]
+				if i+n-1 < len(expected) {
+					want = expected[i+n-1]
+				}
+
+				if got := l.Peek(n); got.Type != want.Type || got.Literal != want.Literal || got.Start != want.Start {
+					t.Fatalf("%s: Peek(%d) at tokens[%d] wrong, expected %+v got %+v", tt.constructor, n, i, want, got)
+				}
+			}
+
+			if got := l.NextToken(); got != expected[i] {
+				t.Fatalf("%s: tokens[%d] wrong, expected %+v got %+v", tt.constructor, i, expected[i], got)
+			}
+		}
+
+		if tok := l.NextToken(); tok.Type != token.EOF {
+			t.Errorf("%s: expected EOF after the last token, got %+v", tt.constructor, tok)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected Peek(0) to panic")
+		}
+	}()
+	New(input).Peek(0)
+}
+
+func TestNewFromReader(t *testing.T) {
+	input := strings.Repeat(`let add = fn(x, y) { x + y; }; // adds
+/* block
+   comment */ let s = "esc\taped \u{1F648}" + `+"`raw\\n`"+`;
+if (3.14 >= 2) { return [1, 2][0]; } else { {"a": !true}["a"] }
+"bad \q escape" @
+`, 200)
+
+	readers := map[string]func() *Lexer{
+		"whole":    func() *Lexer { return NewFromReader(strings.NewReader(input)) },
+		"one byte": func() *Lexer { return NewFromReader(iotest.OneByteReader(strings.NewReader(input))) },
+		"half":     func() *Lexer { return NewFromReader(iotest.HalfReader(strings.NewReader(input))) },
+	}
+
+	for name, newLexer := range readers {
+		expected, streamed := New(input), newLexer()
+
+		for i := 0; ; i++ {
+			want, got := expected.NextToken(), streamed.NextToken()
+
+			if got != want {
+				t.Fatalf("%s: tokens[%d] wrong, expected %+v got %+v", name, i, want, got)
+			}
+
+			if want.Type == token.EOF {
+				// the parser keeps asking for tokens after EOF
+				if tok := streamed.NextToken(); tok.Type != token.EOF {
+					t.Errorf("%s: expected EOF again, got %+v", name, tok)
+				}
+				break
+			}
+
+			// the tokenized input isn't kept around
+			if len(streamed.input) > 2*READ_CHUNK {
+				t.Fatalf("%s: buffered %d bytes of input", name, len(streamed.input))
+			}
+		}
+
+		if streamed.Err() != nil {
+			t.Errorf("%s: unexpected error: %s", name, streamed.Err())
+		}
+	}
+}
+
+func TestNewFromReaderError(t *testing.T) {
+	readErr := errors.New("connection reset")
+	l := NewFromReader(iotest.ErrReader(readErr))
+
+	if tok := l.NextToken(); tok.Type != token.EOF {
+		t.Errorf("expected EOF, got %+v", tok)
+	}
+
+	if l.Err() != readErr {
+		t.Errorf("expected the read error, got %v", l.Err())
+	}
+}
+
+func TestLexerErrors(t *testing.T) {
+	input := "let x = 1;\nlet y = x @ 2;\nlet s = \"a\\qb\";"
+
+	tests := []struct {
+		constructor string
+		lexer       *Lexer
+	}{
+		{"New", New(input)},
+		{"NewFromReader", NewFromReader(iotest.OneByteReader(strings.NewReader(input)))},
+	}
+
+	expected := []Error{
+		{Message: "illegal character '@'", Char: "@", Offset: 21, Line: 2, Column: 11, Snippet: "let y = x @ 2;"},
+		{Message: "invalid escape sequence \\q", Char: "\\q", Offset: 36, Line: 3, Column: 11, Snippet: "let s = \"a\\qb\";"},
+	}
+
+	for _, tt := range tests {
+		for tok := tt.lexer.NextToken(); tok.Type != token.EOF; tok = tt.lexer.NextToken() {
+		}
+
+		errs := tt.lexer.Errors()
+		if !reflect.DeepEqual(errs, expected) {
+			t.Errorf("%s: wrong errors, expected %+v got %+v", tt.constructor, expected, errs)
+		}
+	}
+
+	if msg := expected[0].Error(); msg != "2:11: illegal character '@' near \"let y = x @ 2;\"" {
+		t.Errorf("wrong error message, got %q", msg)
+	}
+
+	if errs := New("let x = 1;").Errors(); len(errs) != 0 {
+		t.Errorf("expected no errors, got %+v", errs)
+	}
+}
+
+// a large script, every token type is compared while lexing it, see token.TokenType
+func BenchmarkLexer(b *testing.B) {
+	input := strings.Repeat(`
+	let add = fn(a, b) { return a + b; };
+	let values = {"one": 1, "two": 2, "three": [1, 2, 3]};
+	for (let i = 0; i < 10; i++) {
+		if (i % 2 == 0 && i != 4) { values["one"] += add(i, 3.5) } else { puts("odd ${i}") }
+	}
+	`, 2000)
+
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l := New(input)
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package lexer
+
+import (
+	"fmt"
+	"monkey/token"
+)
+
+/**
+Returns the nth token NextToken will return, without consuming it: Peek(1) is the next token,
+Peek(2) the one after it, etc. Past the end of the input every token is EOF.
+
+The tokens are lexed once and kept until NextToken returns them, so looking ahead doesn't re-lex
+the input. Errors of the peeked tokens are already in Errors().
+**/
+func (l *Lexer) Peek(n int) token.Token {
+	if n < 1 {
+		panic(fmt.Sprintf("lexer: Peek(%d), n starts at 1", n))
+	}
+
+	for l.upcoming.len() < n {
+		var tok token.Token
+		l.lexToken(&tok)
+		l.upcoming.push(tok)
+	}
+
+	return l.upcoming.at(n - 1)
+}
+
+// Queue of tokens in a ring buffer, it grows when it's full
+type ring struct {
+	tokens []token.Token
+	head   int // index of the first token
+	count  int
+}
+
+func (r *ring) len() int {
+	return r.count
+}
+
+func (r *ring) push(tok token.Token) {
+	if r.count == len(r.tokens) {
+		r.grow()
+	}
+
+	r.tokens[(r.head+r.count)%len(r.tokens)] = tok
+	r.count++
+}
+
+func (r *ring) pop() (token.Token, bool) {
+	if r.count == 0 {
+		return token.Token{}, false
+	}
+
+	tok := r.tokens[r.head]
+	r.tokens[r.head] = token.Token{}
+	r.head = (r.head + 1) % len(r.tokens)
+	r.count--
+
+	return tok, true
+}
+
+// The idx-th token from the head, idx < count
+func (r *ring) at(idx int) token.Token {
+	return r.tokens[(r.head+idx)%len(r.tokens)]
+}
+
+// Doubles the buffer, the tokens are moved to the start of the new one
+func (r *ring) grow() {
+	size := 2 * len(r.tokens)
+	if size == 0 {
+		size = 4
+	}
+
+	tokens := make([]token.Token, size)
+	for idx := 0; idx < r.count; idx++ {
+		tokens[idx] = r.at(idx)
+	}
+
+	r.tokens, r.head = tokens, 0
+}
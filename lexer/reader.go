@@ -0,0 +1,65 @@
+package lexer
+
+import (
+	"io"
+)
+
+// How many bytes are read from the reader at a time
+const READ_CHUNK = 4096
+
+/**
+Returns a lexer that tokenizes the source code as it's read from the reader,
+so large scripts (or piped input) don't have to be loaded in memory first.
+
+- the tokens (and their positions) are the same as the ones lexer.New() gives for the whole input
+- only the part of the input belonging to the current token is kept around
+- a read error ends the input like EOF would, see Err()
+**/
+func NewFromReader(r io.Reader) *Lexer {
+	l := &Lexer{reader: r, buf: make([]byte, READ_CHUNK), idents: make(map[string]string), line: 1}
+	l.readChar()
+	return l
+}
+
+// Returns the error that stopped reading the input, nil when it was read until EOF (or isn't read from a reader)
+func (l *Lexer) Err() error {
+	return l.readErr
+}
+
+// Reads more input until the char at the given position is available (or the reader is done)
+func (l *Lexer) fill(position int) {
+	for l.reader != nil && position >= len(l.input) {
+		n, err := l.reader.Read(l.buf)
+		l.input += string(l.buf[:n])
+
+		if err != nil {
+			if err != io.EOF {
+				l.readErr = err
+			}
+			l.reader = nil
+		}
+	}
+}
+
+/**
+Drops the input before the current char, it's already been tokenized
+(except for the last few bytes, they're shown in error snippets).
+Positions are relative to the start of l.input, so they're moved back by the same amount.
+**/
+func (l *Lexer) discardRead() {
+	done := l.position - SNIPPET_CONTEXT
+	if l.buf == nil || done <= 0 {
+		return
+	}
+
+	// past the end of the input once EOF is reached
+	if done > len(l.input) {
+		done = len(l.input)
+	}
+
+	l.input = l.input[done:]
+	l.discarded += done
+	l.position -= done
+	l.readPosition -= done
+	l.lineStart -= done
+}
@@ -3,31 +3,125 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"log"
+	"monkey/analysis"
+	"monkey/evaluator"
 	"monkey/file_eval"
+	"monkey/plugins"
 	"monkey/repl"
 	"os"
+	"strings"
 )
 
+const PLUGIN_FLAG = "--plugin="
+
+// Also reports shadowed bindings when vetting a file
+const SHADOWING_FLAG = "--shadowing"
+
+// Newlines end statements, so scripts can leave out the semicolons (see parser.WithNewlineTermination)
+const AUTO_SEMICOLONS_FLAG = "--auto-semicolons"
+
 func main() {
+	args, scriptArgs := splitScriptArgs(os.Args[1:])
+	args = loadPlugins(args)
+	args, shadowing := extractFlag(args, SHADOWING_FLAG)
+	args, autoSemicolons := extractFlag(args, AUTO_SEMICOLONS_FLAG)
+
 	// no arguments passed
-	if len(os.Args) == 1 {
+	if len(args) == 0 {
 		printHelpMenu()
 		return
 	}
 
-	switch os.Args[1] {
+	switch args[0] {
 	case "--prompt":
 		repl.Start()
 	case "-f":
-		file_eval.EvaluateFile(os.Stdin, os.Stdout, os.Args[2])
+		if len(args) < 2 {
+			printHelpMenu()
+			return
+		}
+		file_eval.EvaluateFile(os.Stdin, os.Stdout, args[1], scriptArgs, autoSemicolons)
+	case "--vet":
+		if len(args) < 2 {
+			printHelpMenu()
+			return
+		}
+		file_eval.VetFile(os.Stdout, args[1], analysis.Options{Shadowing: shadowing}, autoSemicolons)
+	case "tokens", "--tokens":
+		if len(args) < 2 {
+			printHelpMenu()
+			return
+		}
+		if err := file_eval.DumpTokens(os.Stdin, os.Stdout, args[1]); err != nil {
+			log.Fatal(err)
+		}
+	case "version", "--version":
+		fmt.Printf("monke %s (%s)\n", evaluator.VERSION, evaluator.ENGINE)
 	default:
 		printHelpMenu()
 	}
 }
 
+/**
+Splits the arguments at the end of -f FILE, the ones after it belong to the script (see args())
+so the interpreter's flags aren't looked for in them:
+
+	monke --auto-semicolons -f tool.mk --shadowing  // the script gets --shadowing
+**/
+func splitScriptArgs(args []string) ([]string, []string) {
+	for idx, arg := range args {
+		if arg == "-f" && idx+1 < len(args) {
+			return args[:idx+2], args[idx+2:]
+		}
+	}
+
+	return args, nil
+}
+
+// Loads the builtins of every --plugin=FILE argument, returns the remaining arguments
+func loadPlugins(args []string) []string {
+	rest := []string{}
+
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, PLUGIN_FLAG) {
+			rest = append(rest, arg)
+			continue
+		}
+
+		if err := plugins.Load(strings.TrimPrefix(arg, PLUGIN_FLAG)); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	return rest
+}
+
+// Removes the flag from the arguments, reports whether it was passed
+func extractFlag(args []string, flag string) ([]string, bool) {
+	rest := []string{}
+	found := false
+
+	for _, arg := range args {
+		if arg == flag {
+			found = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+
+	return rest, found
+}
+
 func printHelpMenu() {
 	var out bytes.Buffer
 	out.WriteString("--prompt to use the interpreter\n")
-	out.WriteString("-f FILE to evaluate a .mk file\n")
+	out.WriteString("-f FILE [ARGS...] to evaluate a .mk file, the script reads ARGS with args() or the flags module (the interpreter's flags go before -f)\n")
+	out.WriteString("--vet FILE to statically check a .mk file without evaluating it\n")
+	out.WriteString("tokens FILE (or --tokens FILE) to print the tokens of a .mk file as JSON lines, for debugging and tooling\n")
+	out.WriteString("--shadowing with --vet, also warn about bindings that shadow an outer one\n")
+	out.WriteString("--auto-semicolons with -f or --vet, newlines end statements so semicolons can be left out\n")
+	out.WriteString("--plugin=FILE to load builtin functions from a Go plugin (.so), can be repeated\n")
+	out.WriteString("version to print the interpreter's version\n")
 	fmt.Println(out.String())
 }
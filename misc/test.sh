@@ -1,2 +1,2 @@
-subjects=(parser lexer ast token evaluator object)
+subjects=(parser lexer ast token evaluator object types analysis interpreter snapshot plugins catalog vfs parsecache)
 for subject in "${subjects[@]}"; do /usr/local/go/bin/go test "./$subject"; done
\ No newline at end of file
@@ -0,0 +1,142 @@
+package object
+
+import (
+	"fmt"
+	"monkey/catalog"
+	"strings"
+)
+
+/**
+Describes one argument of a builtin, see CheckArgs.
+
+- Types: the accepted types, any type is accepted when empty
+- Optional: the argument can be left out (only trailing arguments can be optional)
+- Variadic: describes every remaining argument (zero or more)
+**/
+type ArgSpec struct {
+	Types    []ObjectType
+	Optional bool
+	Variadic bool
+}
+
+// A required argument of one of the given types (any type when none are given)
+func Arg(types ...ObjectType) ArgSpec {
+	return ArgSpec{Types: types}
+}
+
+// An argument that can be left out
+func OptionalArg(types ...ObjectType) ArgSpec {
+	return ArgSpec{Types: types, Optional: true}
+}
+
+// Any number of remaining arguments, each of one of the given types
+func RestArgs(types ...ObjectType) ArgSpec {
+	return ArgSpec{Types: types, Variadic: true}
+}
+
+/**
+Validates the arguments passed to a builtin, returns nil when they match the spec.
+Every builtin uses it so argument errors read the same:
+
+	len: expected 1 argument, got 3
+	len: argument 1 must be STRING, ARRAY or BYTES, got INTEGER
+
+ex:
+	if err := object.CheckArgs("push", args, object.Arg(object.ARRAY_OBJ), object.Arg()); err != nil {
+		return err
+	}
+**/
+func CheckArgs(name string, args []Object, spec ...ArgSpec) *Error {
+	min, max := arity(spec)
+
+	if len(args) < min || (max >= 0 && len(args) > max) {
+		return newArgError(catalog.WRONG_ARGUMENT_COUNT, name, expectedCount(min, max), len(args))
+	}
+
+	for idx, arg := range args {
+		argSpec := spec[len(spec)-1]
+		if idx < len(spec) {
+			argSpec = spec[idx]
+		}
+
+		if !argSpec.accepts(arg) {
+			return newArgError(catalog.WRONG_ARGUMENT_TYPE, name, idx+1, joinTypes(argSpec.Types), typeName(arg))
+		}
+	}
+
+	return nil
+}
+
+// Smallest and largest amount of arguments allowed by the spec, max is -1 when there's no limit
+func arity(spec []ArgSpec) (min int, max int) {
+	for _, argSpec := range spec {
+		if argSpec.Variadic {
+			return min, -1
+		}
+
+		if !argSpec.Optional {
+			min++
+		}
+		max++
+	}
+
+	return min, max
+}
+
+func (s ArgSpec) accepts(arg Object) bool {
+	if len(s.Types) == 0 {
+		return true
+	}
+
+	for _, t := range s.Types {
+		if arg != nil && arg.Type() == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+// 1 argument, 2 arguments, 1 to 3 arguments, at least 2 arguments
+func expectedCount(min, max int) string {
+	plural := func(n int) string {
+		if n == 1 {
+			return "1 argument"
+		}
+		return fmt.Sprintf("%d arguments", n)
+	}
+
+	switch {
+	case max < 0:
+		return "at least " + plural(min)
+	case min == max:
+		return plural(min)
+	default:
+		return fmt.Sprintf("%d to %d arguments", min, max)
+	}
+}
+
+// STRING, ARRAY or BYTES
+func joinTypes(types []ObjectType) string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = string(t)
+	}
+
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+func typeName(obj Object) ObjectType {
+	if obj == nil {
+		return "nil"
+	}
+	return obj.Type()
+}
+
+func newArgError(code catalog.Code, args ...interface{}) *Error {
+	return &Error{Code: string(code), Message: catalog.Message(code, args...)}
+}
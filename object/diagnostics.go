@@ -0,0 +1,28 @@
+package object
+
+/**
+A runtime warning: something the script is allowed to do that's likely a mistake
+(ex: 7 / 2 is 3, the remainder is discarded). Unlike an *Error it doesn't stop the script.
+**/
+type Diagnostic struct {
+	Code    string
+	Message string
+	// where the code that caused it starts, both start at 1 like token.Token's
+	Line   int
+	Column int
+}
+
+/**
+Receives the diagnostics of everything evaluated in this environment and the scopes created from it afterwards,
+so it should be set on the global environment before evaluating.
+
+Diagnostics are opt-in: without a handler the evaluator doesn't look for them.
+**/
+func (e *Environment) SetDiagnostics(handler func(Diagnostic)) {
+	e.diagnostics = handler
+}
+
+// Returns the diagnostics handler of this environment, nil when diagnostics are off
+func (e *Environment) Diagnostics() func(Diagnostic) {
+	return e.diagnostics
+}
@@ -1,39 +1,248 @@
 package object
 
+import "sort"
+
+// Scopes with more bindings than this get a map index (ex: the global scope with the builtins),
+// smaller ones are searched linearly, which is faster than hashing the name
+const SMALL_SCOPE = 8
+
 type Environment struct {
-	store map[string]Object
-	outer *Environment //outer scope
+	// bindings are stored in slots: names[slot] is bound to values[slot]
+	names     []string
+	values    []Object
+	index     map[string]int  // name => slot, only built for scopes bigger than SMALL_SCOPE
+	outer     *Environment    //outer scope
+	generator *Generator      // set for the scope of a generator function's body
+	loop      bool            // the scope of a single loop iteration, see NewLoopEnvironment
+	constants map[string]bool // names bound with const in this scope, only allocated once there is one
+	hooks     []Hook          // inherited from the outer scope, see AddHook
+	limits    Limits          // inherited from the outer scope, see SetLimits
+	usage     *usage          // shared with the outer scope, see SetLimits
+	// inherited from the outer scope, see SetDiagnostics
+	diagnostics func(Diagnostic)
+	display     FormatOptions // inherited from the outer scope, see SetDisplayOptions
+	generators  *generatorSet // shared with the outer scope, see TrackGenerator
 }
 
 func NewEnvironment() *Environment {
-	s := make(map[string]Object)
-	return &Environment{store: s, outer: nil}
+	return &Environment{outer: nil, generators: &generatorSet{}}
 }
 
 func NewEnclosedEnvironment(outer *Environment) *Environment {
-	env := NewEnvironment()
-	env.outer = outer
+	return &Environment{
+		outer:       outer,
+		hooks:       outer.hooks,
+		limits:      outer.limits,
+		usage:       outer.usage,
+		diagnostics: outer.diagnostics,
+		display:     outer.display,
+		generators:  outer.generators,
+	}
+}
+
+/**
+Creates the scope of a single loop iteration (for loops give every iteration its own scope,
+so closures created in the body capture that iteration's bindings).
+
+- let statements in the body bind in the iteration scope
+- assignments to names bound outside of the loop update the outer binding, see Assign
+- yield statements in the body still belong to the enclosing generator
+**/
+func NewLoopEnvironment(outer *Environment) *Environment {
+	env := NewEnclosedEnvironment(outer)
+	env.loop = true
+	env.generator = outer.generator
 
 	return env
 }
 
 func (e *Environment) Get(name string) (Object, bool) {
-	obj, ok := e.store[name]
-	/**
-		If we cant find the identifier in the current scope
-		and we have an enclosing, outer scope, search in that scope
-	**/
-	if !ok && e.outer != nil {
-		obj, ok = e.outer.Get(name)
-	}
+	obj, _, _, ok := e.Resolve(name)
 	return obj, ok
 }
 
+/**
+Rebinds the name (x = 5), unlike Set the binding isn't always added to this scope:
+loop iteration scopes pass the assignment on to the scope the name is bound in,
+only stopping at the first scope that isn't a loop (ex: the function's scope).
+
+Returns false without changing anything when the name refers to a constant (see SetConstant),
+even when the assignment would only add a binding to a function's scope.
+**/
+func (e *Environment) Assign(name string, val Object) (Object, bool) {
+	scope := e
+
+	for scope.loop && scope.slotOf(name) < 0 {
+		scope = scope.outer
+	}
+
+	for bound := e; bound != nil; bound = bound.outer {
+		if bound.slotOf(name) >= 0 {
+			if bound.IsConstant(name) {
+				return nil, false
+			}
+			break
+		}
+	}
+
+	return scope.Set(name, val), true
+}
+
+// Binds the name in this scope like Set, Assign refuses to change it afterwards: const x = 5
+func (e *Environment) SetConstant(name string, val Object) Object {
+	if e.constants == nil {
+		e.constants = make(map[string]bool)
+	}
+	e.constants[name] = true
+
+	return e.Set(name, val)
+}
+
+// Whether the name is bound with SetConstant in this scope (outer scopes aren't searched)
+func (e *Environment) IsConstant(name string) bool {
+	return e.constants[name]
+}
+
 func (e *Environment) Set(name string, val Object) Object {
-	e.store[name] = val
+	if slot := e.slotOf(name); slot >= 0 {
+		e.values[slot] = val
+		return val
+	}
+
+	// most scopes are function calls with a few parameters, avoid growing the slices one by one
+	if e.names == nil {
+		e.names = make([]string, 0, 4)
+		e.values = make([]Object, 0, 4)
+	}
+
+	e.names = append(e.names, name)
+	e.values = append(e.values, val)
+
+	if e.index != nil {
+		e.index[name] = len(e.names) - 1
+	} else if len(e.names) > SMALL_SCOPE {
+		e.index = make(map[string]int, len(e.names))
+		for slot, n := range e.names {
+			e.index[n] = slot
+		}
+	}
+
 	return val
 }
 
+/**
+Finds the binding for the name, same as Get, but also returns where it was found:
+- depth: number of scopes walked up (0 = this scope)
+- slot: position of the binding in that scope
+
+The evaluator remembers these on the identifier (see ast.Identifier.Resolution) and uses GetSlot
+the next time the identifier is evaluated.
+**/
+func (e *Environment) Resolve(name string) (obj Object, depth, slot int, ok bool) {
+	for scope := e; scope != nil; scope = scope.outer {
+		if slot := scope.slotOf(name); slot >= 0 {
+			return scope.values[slot], depth, slot, true
+		}
+		depth++
+	}
+
+	return nil, 0, 0, false
+}
+
+/**
+Returns the binding at the given depth and slot, as returned by Resolve.
+
+The same identifier can be evaluated in different environments (every call creates a new scope)
+and bindings can be added to a scope later on (ex: a let inside of a loop), so the position is
+only a hint: false is returned when the name isn't bound there anymore or a closer scope
+now shadows it, the caller should then Resolve the name again.
+**/
+func (e *Environment) GetSlot(name string, depth, slot int) (Object, bool) {
+	scope := e
+
+	for ; depth > 0; depth-- {
+		if scope.slotOf(name) >= 0 {
+			return nil, false
+		}
+
+		scope = scope.outer
+
+		if scope == nil {
+			return nil, false
+		}
+	}
+
+	if slot >= len(scope.names) || scope.names[slot] != name {
+		return nil, false
+	}
+
+	return scope.values[slot], true
+}
+
+// Returns the slot of the name in this scope (not including the outer scopes), -1 if it isn't bound
+func (e *Environment) slotOf(name string) int {
+	if e.index != nil {
+		if slot, ok := e.index[name]; ok {
+			return slot
+		}
+		return -1
+	}
+
+	for slot, n := range e.names {
+		if n == name {
+			return slot
+		}
+	}
+
+	return -1
+}
+
+// Returns the names bound in this scope (not including the outer scopes)
+func (e *Environment) Names() []string {
+	names := make([]string, len(e.names))
+	copy(names, e.names)
+	sort.Strings(names)
+	return names
+}
+
+// Returns the names visible from this scope: the ones bound in it and in every outer scope, sorted and without duplicates
+func (e *Environment) AllNames() []string {
+	seen := make(map[string]bool)
+	var names []string
+
+	for scope := e; scope != nil; scope = scope.outer {
+		for _, name := range scope.names {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Marks this scope as the body of the given generator, so yield statements know where to send values
+func (e *Environment) SetGenerator(gen *Generator) {
+	e.generator = gen
+}
+
+/**
+Returns the generator this scope belongs to, nil when it isn't the body of a generator function.
+
+note: outer scopes aren't searched, a yield inside a regular function defined in a generator
+doesn't belong to the generator.
+**/
+func (e *Environment) Generator() *Generator {
+	return e.generator
+}
+
+// Returns the enclosing scope, nil for the global scope
+func (e *Environment) Outer() *Environment {
+	return e.outer
+}
+
 /**
 dev notes:
 - we need to preserve the bindings (let x = 1, let i = fn(){}) while at the same time
@@ -47,6 +256,11 @@ Searching for associations:
 associated with the given name, it should call the Get method of the enclosing environment (outer scope)
 - it should do this until there is no enclosing environment anymore (i.e. we've reached the global scope) and throw an error
 
+Slots:
+- bindings live in slices instead of a map per scope, most scopes (function calls) only hold a few
+  parameters and comparing a few (interned) strings is cheaper than hashing the name.
+- identifiers remember the (depth, slot) where they were last found, so the next lookup reads the slot
+  directly instead of searching the scope it's in, the scopes in between are only checked for shadowing.
 
 
 **/
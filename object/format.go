@@ -0,0 +1,134 @@
+package object
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/**
+Controls how nested arrays and hashes are printed.
+The zero value prints everything on one line, the same as Inspect() except that hash pairs
+are sorted by key so truncated output always keeps the same pairs.
+An array or hash that contains itself is printed as [...] / {...} where it repeats.
+
+- MaxDepth: nested arrays/hashes deeper than this are printed as [...] / {...} (0 = no limit)
+- MaxElements: only the first n elements/pairs of an array/hash are printed (0 = no limit)
+- Multiline: one element per line, indented by nesting level
+**/
+type FormatOptions struct {
+	MaxDepth    int
+	MaxElements int
+	Multiline   bool
+}
+
+// Indentation used for each nesting level when printing multiline
+const FORMAT_INDENT = "  "
+
+/**
+Sets the options used to display values to the user in this environment and the scopes created from it
+afterwards: puts, string interpolation, inspect without options and the REPL / file evaluation output.
+Hosts can change them to keep large nested structures readable, see interpreter.WithDisplayOptions.
+**/
+func (e *Environment) SetDisplayOptions(opts FormatOptions) {
+	e.display = opts
+}
+
+// The options set with SetDisplayOptions, the zero value (everything on one line) by default
+func (e *Environment) DisplayOptions() FormatOptions {
+	return e.display
+}
+
+// Formats the object with the environment's display options
+func (e *Environment) Display(obj Object) string {
+	return Format(obj, e.display)
+}
+
+// Formats the object with the given options, see FormatOptions
+func Format(obj Object, opts FormatOptions) string {
+	return format(obj, opts, 0, map[Object]bool{})
+}
+
+// visiting holds the arrays and hashes being printed, to stop at the ones that contain themselves
+func format(obj Object, opts FormatOptions, depth int, visiting map[Object]bool) string {
+	switch obj := obj.(type) {
+	case *Array:
+		if visiting[obj] || (opts.MaxDepth > 0 && depth >= opts.MaxDepth) {
+			return "[...]"
+		}
+
+		visiting[obj] = true
+		defer delete(visiting, obj)
+
+		elements := []string{}
+		for _, e := range obj.Elements {
+			elements = append(elements, format(e, opts, depth+1, visiting))
+		}
+
+		return formatElements("[", truncate(elements, opts), "]", opts, depth)
+
+	case *Hash:
+		if visiting[obj] || (opts.MaxDepth > 0 && depth >= opts.MaxDepth) {
+			return "{...}"
+		}
+
+		visiting[obj] = true
+		defer delete(visiting, obj)
+
+		// map order is random, sorting by key keeps the output (and what truncate drops) stable
+		type formattedPair struct{ key, value string }
+		formatted := []formattedPair{}
+		for _, pair := range obj.Entries() {
+			// deleted keys are kept with a null key, they aren't part of the hash anymore
+			if pair.Key.Type() == NULL_OBJ {
+				continue
+			}
+			formatted = append(formatted, formattedPair{format(pair.Key, opts, depth+1, visiting), format(pair.Value, opts, depth+1, visiting)})
+		}
+		sort.Slice(formatted, func(i, j int) bool {
+			return formatted[i].key < formatted[j].key
+		})
+
+		pairs := []string{}
+		for _, pair := range formatted {
+			pairs = append(pairs, fmt.Sprintf(`"%s" : "%s"`, pair.key, pair.value))
+		}
+
+		return formatElements("{", truncate(pairs, opts), "}", opts, depth)
+	}
+
+	return obj.Inspect()
+}
+
+// Drops the elements past opts.MaxElements, replacing them with a count of what's left
+func truncate(elements []string, opts FormatOptions) []string {
+	if opts.MaxElements <= 0 || len(elements) <= opts.MaxElements {
+		return elements
+	}
+
+	hidden := len(elements) - opts.MaxElements
+
+	return append(elements[:opts.MaxElements], fmt.Sprintf("... %d more", hidden))
+}
+
+func formatElements(open string, elements []string, close string, opts FormatOptions, depth int) string {
+	if !opts.Multiline || len(elements) == 0 {
+		return open + strings.Join(elements, ", ") + close
+	}
+
+	indent := strings.Repeat(FORMAT_INDENT, depth+1)
+
+	var out strings.Builder
+
+	out.WriteString(open + "\n")
+	for idx, e := range elements {
+		out.WriteString(indent + e)
+		if idx < len(elements)-1 {
+			out.WriteString(",")
+		}
+		out.WriteString("\n")
+	}
+	out.WriteString(strings.Repeat(FORMAT_INDENT, depth) + close)
+
+	return out.String()
+}
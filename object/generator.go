@@ -0,0 +1,226 @@
+package object
+
+import (
+	"monkey/catalog"
+	"runtime/debug"
+)
+
+// Panicked by Yield once the generator is closed, unwinds the body without running the rest of it
+type generatorClosed struct{}
+
+/**
+Iterator returned by calling a generator function (fn*() { yield 1; yield 2; }).
+
+The body runs in its own goroutine, but only while Next is waiting for it:
+- Next resumes the body and blocks until it yields a value or finishes
+- Yield hands the value to Next and blocks until the next call to Next
+so the body and the caller never run at the same time.
+
+Close stops a generator that isn't run to completion, its goroutine would stay parked in Yield otherwise.
+**/
+type Generator struct {
+	body     func(gen *Generator) Object
+	resume   chan struct{}
+	values   chan Object
+	done     chan struct{} // closed by Close, wakes the body up so it can unwind
+	started  bool
+	running  bool
+	finished bool
+}
+
+/**
+Creates a generator that runs the given body the first time Next is called.
+The body can return an error (or any value) which is handed out as the last value of the generator,
+return nil to finish without one.
+**/
+func NewGenerator(body func(gen *Generator) Object) *Generator {
+	return &Generator{
+		body:   body,
+		resume: make(chan struct{}),
+		values: make(chan Object),
+		done:   make(chan struct{}),
+	}
+}
+
+func (g *Generator) Type() ObjectType { return GENERATOR_OBJ }
+func (g *Generator) Inspect() string {
+	if g.finished {
+		return "generator(done)"
+	}
+	return "generator"
+}
+
+/**
+Runs the body until the next yield and returns the yielded value.
+Returns false once the body has finished.
+**/
+func (g *Generator) Next() (Object, bool) {
+	if g.finished {
+		return nil, false
+	}
+
+	if !g.started {
+		g.started = true
+		go g.run()
+	}
+
+	g.running = true
+	g.resume <- struct{}{}
+	val, ok := <-g.values
+	g.running = false
+
+	if !ok {
+		g.finished = true
+	}
+
+	return val, ok
+}
+
+/**
+Called from the body: hands the value to Next and waits to be resumed.
+Once the generator is closed it doesn't return, it panics to unwind the body (run recovers it).
+**/
+func (g *Generator) Yield(val Object) {
+	select {
+	case g.values <- val:
+	case <-g.done:
+		panic(generatorClosed{})
+	}
+
+	select {
+	case <-g.resume:
+	case <-g.done:
+		panic(generatorClosed{})
+	}
+}
+
+/**
+Finishes the generator without running the rest of its body, Next returns false afterwards.
+The body parked in Yield unwinds (deferred Go code runs, statements after the yield don't) and its goroutine exits,
+Close waits for it so the body never runs at the same time as the caller.
+
+Closing a finished (or already closed) generator does nothing. Like Next, it shouldn't be called from the body.
+**/
+func (g *Generator) Close() {
+	if g.finished {
+		return
+	}
+	g.finished = true
+	close(g.done)
+
+	if g.started {
+		// run closes values once the body has unwound
+		for range g.values {
+		}
+	}
+}
+
+// Whether the body is currently running (ex: the body calling next() on its own generator)
+func (g *Generator) Running() bool {
+	return g.running
+}
+
+// Whether the body has finished (or the generator was closed)
+func (g *Generator) Done() bool {
+	return g.finished
+}
+
+func (g *Generator) run() {
+	defer close(g.values)
+
+	<-g.resume
+
+	if last := g.runBody(); last != nil {
+		select {
+		case g.values <- last:
+		case <-g.done:
+			return
+		}
+
+		select {
+		case <-g.resume:
+		case <-g.done:
+		}
+	}
+}
+
+/**
+Runs the body, a Go panic in it (a bug in the interpreter or in a builtin) is returned as an internal error
+instead. The body has its own goroutine, so evaluator.EvalSafe can't catch it and it would take the whole process down.
+**/
+func (g *Generator) runBody() (last Object) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(generatorClosed); ok {
+				last = nil
+				return
+			}
+
+			last = &Error{
+				Code:    string(catalog.INTERNAL_ERROR),
+				Message: catalog.Message(catalog.INTERNAL_ERROR, r),
+				Stack:   string(debug.Stack()),
+			}
+		}
+	}()
+
+	return g.body(g)
+}
+
+/**
+Dev notes:
+
+- A generator that's never run to completion leaves its goroutine parked in Yield until it's closed:
+  take closes the generators it reads, and the scope they're created in closes the rest when the run
+  is over (see Environment.CloseGenerators).
+- The channels are unbuffered on purpose, that's what keeps the body one step ahead at most
+  and lets the body share the (non thread safe) environments with the caller.
+**/
+
+// The generators created in a run, shared by the root environment and every scope created from it
+type generatorSet struct {
+	generators []*Generator
+}
+
+/**
+Adds the generator to the ones closed by CloseGenerators (called on this environment or any other scope of the run).
+Finished generators are dropped as new ones are added, so a loop creating generators doesn't keep them all.
+**/
+func (e *Environment) TrackGenerator(gen *Generator) {
+	set := e.generators
+	if set == nil {
+		return
+	}
+
+	if len(set.generators) == cap(set.generators) {
+		running := set.generators[:0]
+		for _, tracked := range set.generators {
+			if !tracked.Done() {
+				running = append(running, tracked)
+			}
+		}
+		// clear the tail so the dropped generators can be collected
+		for idx := len(running); idx < len(set.generators); idx++ {
+			set.generators[idx] = nil
+		}
+		set.generators = running
+	}
+
+	set.generators = append(set.generators, gen)
+}
+
+/**
+Closes every generator created in the run that hasn't finished (see Generator.Close),
+hosts call it once the run is over so the generators' goroutines don't outlive it.
+**/
+func (e *Environment) CloseGenerators() {
+	set := e.generators
+	if set == nil {
+		return
+	}
+
+	for _, gen := range set.generators {
+		gen.Close()
+	}
+	set.generators = nil
+}
@@ -0,0 +1,32 @@
+package object
+
+import "monkey/ast"
+
+/**
+Observes the evaluation of every AST node, the foundation for instrumentation
+(tracing, profiling, debugging, coverage).
+
+- BeforeNode is called before the node is evaluated, with the environment it's evaluated in
+- AfterNode is called with the result, which can be nil (ex: let statements) or an *Error
+- hooks are called synchronously on the evaluating goroutine, keep them cheap
+**/
+type Hook interface {
+	BeforeNode(node ast.Node, env *Environment)
+	AfterNode(node ast.Node, result Object)
+}
+
+/**
+Registers a hook for everything evaluated in this environment and the scopes created from it afterwards
+(function calls, blocks, generators), so it should be added to the global environment before evaluating.
+**/
+func (e *Environment) AddHook(hook Hook) {
+	// copied so scopes created before this call keep the hooks they had
+	hooks := make([]Hook, len(e.hooks), len(e.hooks)+1)
+	copy(hooks, e.hooks)
+	e.hooks = append(hooks, hook)
+}
+
+// Returns the hooks observing this environment, in the order they were added
+func (e *Environment) Hooks() []Hook {
+	return e.hooks
+}
@@ -0,0 +1,109 @@
+package object
+
+import "sync"
+
+const (
+	// strings longer than this are never interned
+	MAX_INTERNED_STRING_LENGTH = 64
+	// stop interning new strings once the table is this big, so it can't grow forever
+	MAX_INTERNED_STRINGS = 4096
+	/**
+	strings built while the script runs (ex: concatenation) longer than this aren't interned,
+	lower than the literals limit so one-off intermediate results don't fill the table
+	**/
+	MAX_INTERNED_RUNTIME_STRING_LENGTH = 16
+)
+
+var internedStrings = struct {
+	sync.RWMutex
+	table map[string]*String
+}{table: make(map[string]*String)}
+
+/**
+Returns a shared *String for small strings (string literals, hash keys, etc).
+
+- the hash key of an interned string is computed once, when it's first interned
+- interned strings are shared between every environment (and goroutine), they must never be modified
+- large strings (or any string once the table is full) get a new, regular *String
+**/
+func InternString(value string) *String {
+	if len(value) > MAX_INTERNED_STRING_LENGTH {
+		return &String{Value: value}
+	}
+
+	internedStrings.RLock()
+	str, ok := internedStrings.table[value]
+	internedStrings.RUnlock()
+
+	if ok {
+		return str
+	}
+
+	internedStrings.Lock()
+	defer internedStrings.Unlock()
+
+	// someone else might've interned it while we were waiting for the lock
+	if str, ok := internedStrings.table[value]; ok {
+		return str
+	}
+
+	hashKey := hashString(value)
+	str = &String{Value: value, hashKey: &hashKey}
+
+	if len(internedStrings.table) < MAX_INTERNED_STRINGS {
+		internedStrings.table[value] = str
+	}
+
+	return str
+}
+
+/**
+Same as InternString for strings created while the script runs (concatenation, etc),
+with the lower MAX_INTERNED_RUNTIME_STRING_LENGTH limit.
+Short strings built over and over (separators, keys, "a" + "b" in a loop) are then allocated once.
+**/
+func InternRuntimeString(value string) *String {
+	if len(value) > MAX_INTERNED_RUNTIME_STRING_LENGTH {
+		return &String{Value: value}
+	}
+
+	return InternString(value)
+}
+
+// Range of the integers that are preallocated, see InternInteger
+const (
+	MIN_INTERNED_INTEGER = -256
+	MAX_INTERNED_INTEGER = 1024
+)
+
+var internedIntegers = func() []*Integer {
+	integers := make([]*Integer, MAX_INTERNED_INTEGER-MIN_INTERNED_INTEGER+1)
+	for idx := range integers {
+		integers[idx] = &Integer{Value: int64(idx + MIN_INTERNED_INTEGER)}
+	}
+	return integers
+}()
+
+/**
+Returns a shared, preallocated *Integer for small values (loop counters, indexes, lengths, etc),
+so compute heavy scripts don't create a new object for every intermediate result.
+
+- integers are never modified once created, so they can be shared (same as TRUE/FALSE/NULL)
+- values outside of [MIN_INTERNED_INTEGER, MAX_INTERNED_INTEGER] get a new *Integer
+**/
+func InternInteger(value int64) *Integer {
+	if value < MIN_INTERNED_INTEGER || value > MAX_INTERNED_INTEGER {
+		return &Integer{Value: value}
+	}
+
+	return internedIntegers[value-MIN_INTERNED_INTEGER]
+}
+
+/**
+Dev notes:
+
+- Why not a sync.Pool? Pooled objects have to be handed back once they're no longer used, but values
+  escape everywhere in a tree-walking interpreter (environments, arrays, hashes, closures) and there's
+  no ownership tracking to tell when they die. Reusing one too early would silently change a variable.
+  Sharing immutable objects gets most of the benefit without that risk.
+**/
@@ -0,0 +1,107 @@
+package object
+
+/**
+Resource limits for the scripts evaluated in an environment (0 means no limit).
+
+- Memory: the most bytes a single value created by the script can take
+  (ex: a repeated string or array), the evaluator errors out instead of allocating it
+- Fuel: the most AST nodes the script can evaluate, stops infinite loops and runaway recursion
+- Depth: the most function calls that can be nested, stops deep recursion before it overflows the Go stack
+**/
+type Limits struct {
+	Memory int64
+	Fuel   int64
+	Depth  int
+}
+
+/**
+What the script has used up so far. Shared by every scope created from the environment the limits
+were set on (function calls, loops, generators), so callbacks called by builtins (map, operator methods, etc)
+count against the same budget as the code that passed them.
+
+Counted even without limits (see Stats), environments that never had limits set count nothing.
+**/
+type usage struct {
+	fuel      int64
+	depth     int
+	peakDepth int
+}
+
+// What a script evaluated so far, see Environment.Stats
+type Stats struct {
+	Nodes     int64 // AST nodes evaluated
+	PeakDepth int   // most function calls nested at once
+}
+
+/**
+Applies the limits to everything evaluated in this environment and the scopes created from it afterwards,
+so they should be set on the global environment before evaluating.
+
+Setting the limits again starts over with a full budget.
+**/
+func (e *Environment) SetLimits(limits Limits) {
+	e.limits = limits
+	e.usage = &usage{}
+}
+
+/**
+Starts over with a full budget, keeping the limits. Unlike SetLimits it also applies to the scopes
+created before (ex: closures kept from an earlier evaluation in the same environment).
+**/
+func (e *Environment) ResetUsage() {
+	if e.usage != nil {
+		*e.usage = usage{}
+	}
+}
+
+// Returns the limits of this environment, the zero value when none were set
+func (e *Environment) Limits() Limits {
+	return e.limits
+}
+
+// What was evaluated since the limits were set (or ResetUsage), the zero value when they never were
+func (e *Environment) Stats() Stats {
+	if e.usage == nil {
+		return Stats{}
+	}
+
+	return Stats{Nodes: e.usage.fuel, PeakDepth: e.usage.peakDepth}
+}
+
+// Uses up one unit of fuel, false once the script has gone over the fuel limit
+func (e *Environment) UseFuel() bool {
+	if e.usage == nil {
+		return true
+	}
+
+	e.usage.fuel++
+	return e.limits.Fuel == 0 || e.usage.fuel <= e.limits.Fuel
+}
+
+/**
+Enters a function call, false when it would go over the depth limit.
+Every successful EnterCall has to be followed by a LeaveCall on the same environment (or one of its scopes).
+**/
+func (e *Environment) EnterCall() bool {
+	if e.usage == nil {
+		return true
+	}
+
+	if e.limits.Depth != 0 && e.usage.depth >= e.limits.Depth {
+		return false
+	}
+
+	e.usage.depth++
+	if e.usage.depth > e.usage.peakDepth {
+		e.usage.peakDepth = e.usage.depth
+	}
+	return true
+}
+
+func (e *Environment) LeaveCall() {
+	if e.usage == nil {
+		return
+	}
+
+	e.usage.depth--
+}
@@ -0,0 +1,15 @@
+package object
+
+/**
+A named group of values reached with dot syntax: flags.parse(), flags.string("name", "", "help").
+
+Unlike the methods of strings, arrays and hashes, calling a module member doesn't pass the module
+as the first argument.
+**/
+type Module struct {
+	Name    string
+	Members map[string]Object
+}
+
+func (m *Module) Type() ObjectType { return MODULE_OBJ }
+func (m *Module) Inspect() string  { return "<module " + m.Name + ">" }
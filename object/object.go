@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"math"
 	"monkey/ast"
+	"strconv"
 	"strings"
 )
 
@@ -12,6 +14,7 @@ type ObjectType string
 
 const (
 	INTEGER_OBJ      = "INTEGER"
+	FLOAT_OBJ        = "FLOAT"
 	BOOLEAN_OBJ      = "BOOLEAN"
 	NULL_OBJ         = "NULL"
 	RETURN_VALUE_OBJ = "RETURN VALUE"
@@ -21,12 +24,42 @@ const (
 	BUILTIN_OBJ      = "BUILTIN"
 	ARRAY_OBJ        = "ARRAY"
 	HASH_OBJ         = "HASH"
+	BYTES_OBJ        = "BYTES"
+	GENERATOR_OBJ    = "GENERATOR"
+	MODULE_OBJ       = "MODULE"
+	TIME_OBJ         = "TIME"
+	DURATION_OBJ     = "DURATION"
 )
 
 type BuiltinFunction func(args ...Object) Object
 
 type Builtin struct {
-	Fn BuiltinFunction
+	Fn  BuiltinFunction
+	Doc *BuiltinDoc // nil for builtins without documentation
+}
+
+/**
+Describes a builtin for help("len") and the REPL's :doc command:
+
+	len(value)
+	    Number of elements of an array, bytes of a byte array, characters of a string.
+**/
+type BuiltinDoc struct {
+	Name      string
+	Signature string // how it's called, optional arguments in brackets: split(str[, separator])
+	Help      string
+}
+
+// The signature, then every line of the help text indented
+func (d *BuiltinDoc) String() string {
+	var out strings.Builder
+	out.WriteString(d.Signature)
+
+	for _, line := range strings.Split(d.Help, "\n") {
+		out.WriteString("\n    " + line)
+	}
+
+	return out.String()
 }
 
 func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
@@ -49,6 +82,25 @@ func (i *Integer) Type() ObjectType {
 	return INTEGER_OBJ
 }
 
+type Float struct {
+	Value float64
+}
+
+// Always shows a decimal point (or exponent) so floats can be told apart from integers: 2.0, not 2
+func (f *Float) Inspect() string {
+	str := strconv.FormatFloat(f.Value, 'g', -1, 64)
+
+	if !strings.ContainsAny(str, ".eIN") {
+		str += ".0"
+	}
+
+	return str
+}
+
+func (f *Float) Type() ObjectType {
+	return FLOAT_OBJ
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -63,6 +115,9 @@ func (b *Boolean) Inspect() string {
 
 type Null struct{}
 
+// The only null value, so values can be compared to it: obj == object.NULL
+var NULL = &Null{}
+
 func (n *Null) Type() ObjectType {
 	return NULL_OBJ
 }
@@ -79,16 +134,25 @@ func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
 func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
 type Error struct {
+	Code    string // stable error code (see the catalog package), ex: R2004
 	Message string
+	// Go stack trace of internal errors (panics caught by evaluator.EvalSafe), for bug reports
+	Stack string
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
-func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
+func (e *Error) Inspect() string {
+	if e.Code == "" {
+		return "ERROR: " + e.Message
+	}
+	return "ERROR [" + e.Code + "]: " + e.Message
+}
 
 type Function struct {
 	Parameters []*ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment //the function scope
+	Generator  bool         // calling it returns a *Generator instead of running the body
 }
 
 func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
@@ -101,6 +165,9 @@ func (f *Function) Inspect() string {
 	}
 
 	out.WriteString("fn")
+	if f.Generator {
+		out.WriteString("*")
+	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") {\n")
@@ -113,6 +180,8 @@ func (f *Function) Inspect() string {
 
 type String struct {
 	Value string
+	// set for interned strings, which hash their value once when they're created
+	hashKey *HashKey
 }
 
 func (s *String) Type() ObjectType { return STRING_OBJ }
@@ -120,6 +189,9 @@ func (s *String) Inspect() string  { return s.Value }
 
 type Array struct {
 	Elements []Object
+	// whether other arrays use the same elements (ex: the result of slice), they're copied before one is written to
+	Shared bool
+	tail   *arrayTail // the room left after the elements, see Appended
 }
 
 func (a *Array) Type() ObjectType { return ARRAY_OBJ }
@@ -138,6 +210,27 @@ func (a *Array) Inspect() string {
 	return out.String()
 }
 
+// Raw bytes of a string: bytes("hi") => bytes[104, 105]
+type Bytes struct {
+	Value []byte
+}
+
+func (b *Bytes) Type() ObjectType { return BYTES_OBJ }
+func (b *Bytes) Inspect() string {
+	var out bytes.Buffer
+
+	values := []string{}
+	for _, v := range b.Value {
+		values = append(values, fmt.Sprintf("%d", v))
+	}
+
+	out.WriteString("bytes[")
+	out.WriteString(strings.Join(values, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
 type HashKey struct {
 	Type  ObjectType
 	Value uint64
@@ -159,11 +252,23 @@ func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
+func (f *Float) HashKey() HashKey {
+	return HashKey{Type: f.Type(), Value: math.Float64bits(f.Value)}
+}
+
 func (s *String) HashKey() HashKey {
+	if s.hashKey != nil {
+		return *s.hashKey
+	}
+
+	return hashString(s.Value)
+}
+
+func hashString(value string) HashKey {
 	h := fnv.New64a()
-	h.Write([]byte(s.Value))
+	h.Write([]byte(value))
 
-	return HashKey{Type: s.Type(), Value: h.Sum64()}
+	return HashKey{Type: STRING_OBJ, Value: h.Sum64()}
 }
 
 type HashPair struct {
@@ -172,7 +277,19 @@ type HashPair struct {
 }
 
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	// read them with Entries and change them with Own, they're only here while no other version of the hash has them
+	pairs   map[HashKey]HashPair
+	Default Object       // returned when indexing a missing key, nil if the hash has no default
+	version *hashVersion // set once the hash has versions made by With, see hashVersion
+}
+
+// A hash with the given pairs (nil for an empty one), the hash owns them from then on
+func NewHash(pairs map[HashKey]HashPair) *Hash {
+	if pairs == nil {
+		pairs = make(map[HashKey]HashPair)
+	}
+
+	return &Hash{pairs: pairs}
 }
 
 func (h *Hash) Type() ObjectType { return HASH_OBJ }
@@ -181,7 +298,11 @@ func (h *Hash) Inspect() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for _, pair := range h.Pairs {
+	for _, pair := range h.Entries() {
+		// deleted keys are kept with a null key, see format.go
+		if pair.Key.Type() == NULL_OBJ {
+			continue
+		}
 		pairs = append(pairs, fmt.Sprintf(`"%s" : "%s"`, pair.Key.Inspect(), pair.Value.Inspect()))
 	}
 
@@ -1,6 +1,12 @@
 package object
 
-import "testing"
+import (
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -17,3 +23,324 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+func TestInternString(t *testing.T) {
+	a := InternString("name")
+	b := InternString("name")
+
+	if a != b {
+		t.Errorf("interned strings with the same content should be the same object")
+	}
+
+	if a.HashKey() != (&String{Value: "name"}).HashKey() {
+		t.Errorf("interned strings should have the same hash key as regular strings")
+	}
+
+	long := strings.Repeat("a", MAX_INTERNED_STRING_LENGTH+1)
+	if InternString(long) == InternString(long) {
+		t.Errorf("strings longer than %d chars shouldn't be interned", MAX_INTERNED_STRING_LENGTH)
+	}
+}
+
+func TestInternRuntimeString(t *testing.T) {
+	if InternRuntimeString("a, b") != InternString("a, b") {
+		t.Errorf("short runtime strings should share the interned literals")
+	}
+
+	medium := strings.Repeat("a", MAX_INTERNED_RUNTIME_STRING_LENGTH+1)
+	if InternRuntimeString(medium) == InternRuntimeString(medium) {
+		t.Errorf("runtime strings longer than %d chars shouldn't be interned", MAX_INTERNED_RUNTIME_STRING_LENGTH)
+	}
+}
+
+func BenchmarkStringHashKey(b *testing.B) {
+	str := &String{Value: "some hash key"}
+	for i := 0; i < b.N; i++ {
+		str.HashKey()
+	}
+}
+
+func BenchmarkInternedStringHashKey(b *testing.B) {
+	str := InternString("some hash key")
+	for i := 0; i < b.N; i++ {
+		str.HashKey()
+	}
+}
+
+func TestFormat(t *testing.T) {
+	nested := &Array{Elements: []Object{
+		&Integer{Value: 1},
+		&Array{Elements: []Object{&Integer{Value: 2}, &Array{Elements: []Object{&Integer{Value: 3}}}}},
+		&Integer{Value: 4},
+		&Integer{Value: 5},
+	}}
+
+	tests := []struct {
+		opts     FormatOptions
+		expected string
+	}{
+		{FormatOptions{}, nested.Inspect()},
+		{FormatOptions{MaxDepth: 1}, "[1, [...], 4, 5]"},
+		{FormatOptions{MaxDepth: 2}, "[1, [2, [...]], 4, 5]"},
+		{FormatOptions{MaxElements: 2}, "[1, [2, [3]], ... 2 more]"},
+		{FormatOptions{MaxElements: 1, MaxDepth: 2}, "[1, ... 3 more]"},
+		{FormatOptions{Multiline: true, MaxDepth: 2}, "[\n  1,\n  [\n    2,\n    [...]\n  ],\n  4,\n  5\n]"},
+		{FormatOptions{Multiline: true, MaxElements: 1}, "[\n  1,\n  ... 3 more\n]"},
+	}
+
+	for _, tt := range tests {
+		if got := Format(nested, tt.opts); got != tt.expected {
+			t.Errorf("Format(%+v) wrong, expected %q got %q", tt.opts, tt.expected, got)
+		}
+	}
+
+	hash := &Hash{pairs: map[HashKey]HashPair{}}
+	key := &String{Value: "a"}
+	hash.pairs[key.HashKey()] = HashPair{Key: key, Value: nested}
+
+	if got := Format(hash, FormatOptions{MaxDepth: 2}); got != `{"a" : "[1, [...], 4, 5]"}` {
+		t.Errorf("wrong hash format, got %q", got)
+	}
+
+	sorted := &Hash{pairs: map[HashKey]HashPair{}}
+	for _, name := range []string{"d", "b", "a", "c", "e"} {
+		key := &String{Value: name}
+		sorted.pairs[key.HashKey()] = HashPair{Key: key, Value: &Integer{Value: 1}}
+	}
+
+	// the same pairs survive the truncation every time
+	for i := 0; i < 20; i++ {
+		if got := Format(sorted, FormatOptions{MaxElements: 2}); got != `{"a" : "1", "b" : "1", ... 3 more}` {
+			t.Fatalf("hash pairs should be sorted by key, got %q", got)
+		}
+	}
+
+	deleted := &Hash{pairs: map[HashKey]HashPair{}}
+	deleted.pairs[key.HashKey()] = HashPair{Key: &Null{}, Value: &Null{}}
+	if got := Format(deleted, FormatOptions{}); got != "{}" {
+		t.Errorf("deleted keys shouldn't be printed, got %q", got)
+	}
+
+	cyclic := &Array{Elements: []Object{&Integer{Value: 1}}}
+	cyclic.Elements = append(cyclic.Elements, cyclic)
+	if got := Format(cyclic, FormatOptions{}); got != "[1, [...]]" {
+		t.Errorf("wrong format for an array containing itself, got %q", got)
+	}
+
+	self := &Hash{pairs: map[HashKey]HashPair{}}
+	self.pairs[key.HashKey()] = HashPair{Key: key, Value: self}
+	if got := Format(self, FormatOptions{}); got != `{"a" : "{...}"}` {
+		t.Errorf("wrong format for a hash containing itself, got %q", got)
+	}
+
+	// a container shown twice without containing itself is printed in full
+	shared := &Array{Elements: []Object{&Integer{Value: 2}}}
+	if got := Format(&Array{Elements: []Object{shared, shared}}, FormatOptions{}); got != "[[2], [2]]" {
+		t.Errorf("wrong format for a shared array, got %q", got)
+	}
+
+	if got := Format(&Array{}, FormatOptions{Multiline: true}); got != "[]" {
+		t.Errorf("empty arrays should stay on one line, got %q", got)
+	}
+}
+
+func TestEnvironmentSlots(t *testing.T) {
+	global := NewEnvironment()
+	// big enough to get a map index
+	for i := 0; i < SMALL_SCOPE+2; i++ {
+		global.Set(strings.Repeat("g", i+1), &Integer{Value: int64(i)})
+	}
+	global.Set("x", &Integer{Value: 1})
+
+	inner := NewEnclosedEnvironment(global)
+	inner.Set("y", &Integer{Value: 2})
+
+	obj, depth, slot, ok := inner.Resolve("x")
+	if !ok || obj.Inspect() != "1" || depth != 1 || slot != SMALL_SCOPE+2 {
+		t.Fatalf("wrong resolution for x, got %v depth=%d slot=%d ok=%t", obj, depth, slot, ok)
+	}
+
+	if val, ok := inner.GetSlot("x", depth, slot); !ok || val.Inspect() != "1" {
+		t.Errorf("expected x from its slot, got %v (%t)", val, ok)
+	}
+
+	// shadowed by the inner scope now
+	inner.Set("x", &Integer{Value: 3})
+	if _, ok := inner.GetSlot("x", depth, slot); ok {
+		t.Errorf("expected the slot to be stale once x is shadowed")
+	}
+
+	if val, ok := inner.Get("x"); !ok || val.Inspect() != "3" {
+		t.Errorf("expected the inner x, got %v", val)
+	}
+
+	// a different name in the slot
+	if _, ok := inner.GetSlot("y", 0, 1); ok {
+		t.Errorf("expected the slot of another binding to be rejected")
+	}
+
+	if names := inner.Names(); strings.Join(names, ",") != "x,y" {
+		t.Errorf("wrong names, got %v", names)
+	}
+
+	// the g* names of the global scope, x once (bound in both scopes), y
+	if names := inner.AllNames(); len(names) != SMALL_SCOPE+4 || strings.Join(names[SMALL_SCOPE+2:], ",") != "x,y" {
+		t.Errorf("wrong visible names, got %v", names)
+	}
+}
+
+func TestInternInteger(t *testing.T) {
+	if InternInteger(7) != InternInteger(7) {
+		t.Errorf("small integers should be shared")
+	}
+
+	if InternInteger(MIN_INTERNED_INTEGER).Value != MIN_INTERNED_INTEGER || InternInteger(MAX_INTERNED_INTEGER).Value != MAX_INTERNED_INTEGER {
+		t.Errorf("wrong values at the edges of the interned range")
+	}
+
+	if InternInteger(MAX_INTERNED_INTEGER+1) == InternInteger(MAX_INTERNED_INTEGER+1) {
+		t.Errorf("integers outside of the range shouldn't be shared")
+	}
+
+	if InternInteger(MIN_INTERNED_INTEGER-1).Value != MIN_INTERNED_INTEGER-1 {
+		t.Errorf("wrong value for integers outside of the range")
+	}
+}
+
+func TestCheckArgs(t *testing.T) {
+	one, str := &Integer{Value: 1}, &String{Value: "a"}
+
+	tests := []struct {
+		args     []Object
+		spec     []ArgSpec
+		expected string
+	}{
+		{[]Object{str}, []ArgSpec{Arg(STRING_OBJ, ARRAY_OBJ)}, ""},
+		{[]Object{one}, []ArgSpec{Arg(STRING_OBJ, ARRAY_OBJ)}, "ERROR [R2012]: fn: argument 1 must be STRING or ARRAY, got INTEGER"},
+		{[]Object{str, one, str}, []ArgSpec{Arg(STRING_OBJ)}, "ERROR [R2011]: fn: expected 1 argument, got 3"},
+		{[]Object{}, []ArgSpec{Arg(), Arg()}, "ERROR [R2011]: fn: expected 2 arguments, got 0"},
+		{[]Object{str}, []ArgSpec{Arg(), OptionalArg(INTEGER_OBJ)}, ""},
+		{[]Object{str, str}, []ArgSpec{Arg(), OptionalArg(INTEGER_OBJ)}, "ERROR [R2012]: fn: argument 2 must be INTEGER, got STRING"},
+		{[]Object{}, []ArgSpec{Arg(), OptionalArg()}, "ERROR [R2011]: fn: expected 1 to 2 arguments, got 0"},
+		{[]Object{str, one, one, one}, []ArgSpec{Arg(STRING_OBJ), RestArgs(INTEGER_OBJ)}, ""},
+		{[]Object{str, one, str}, []ArgSpec{Arg(STRING_OBJ), RestArgs(INTEGER_OBJ)}, "ERROR [R2012]: fn: argument 3 must be INTEGER, got STRING"},
+		{[]Object{}, []ArgSpec{Arg(STRING_OBJ), RestArgs()}, "ERROR [R2011]: fn: expected at least 1 argument, got 0"},
+	}
+
+	for i, tt := range tests {
+		err := CheckArgs("fn", tt.args, tt.spec...)
+
+		if tt.expected == "" {
+			if err != nil {
+				t.Errorf("tests[%d]: unexpected error %q", i, err.Inspect())
+			}
+			continue
+		}
+
+		if err == nil || err.Inspect() != tt.expected {
+			t.Errorf("tests[%d]: expected %q, got %v", i, tt.expected, err)
+		}
+	}
+}
+
+// Waits (up to a second) for the goroutines started since before to exit
+func goroutinesLeft(before int) int {
+	for wait := 0; wait < 100 && runtime.NumGoroutine() > before; wait++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return runtime.NumGoroutine() - before
+}
+
+func TestGeneratorClose(t *testing.T) {
+	before := runtime.NumGoroutine()
+	env := NewEnvironment()
+	unwound := 0
+
+	counter := func(gen *Generator) Object {
+		defer func() { unwound++ }()
+		for i := int64(0); ; i++ {
+			gen.Yield(&Integer{Value: i})
+		}
+	}
+
+	// closed on their own, or by the environment they're created in
+	closed := NewGenerator(counter)
+	closed.Next()
+	closed.Close()
+	for i := 0; i < 10; i++ {
+		gen := NewGenerator(counter)
+		gen.Next()
+		NewEnclosedEnvironment(env).TrackGenerator(gen)
+	}
+	env.CloseGenerators()
+
+	if left := goroutinesLeft(before); left != 0 {
+		t.Errorf("expected the bodies' goroutines to exit, %d left", left)
+	}
+	if unwound != 11 {
+		t.Errorf("expected every body to unwind, got %d", unwound)
+	}
+	if val, ok := closed.Next(); ok || !closed.Done() {
+		t.Errorf("expected a closed generator to be done, got %v", val)
+	}
+
+	// closing a generator that never started or already finished does nothing
+	NewGenerator(counter).Close()
+	finished := NewGenerator(func(gen *Generator) Object { return nil })
+	finished.Next()
+	finished.Close()
+}
+
+func TestHashVersions(t *testing.T) {
+	versions := []*Hash{{pairs: map[HashKey]HashPair{}}}
+	for i := int64(0); i < 100; i++ {
+		key := &Integer{Value: i}
+		versions = append(versions, versions[len(versions)-1].With(key.HashKey(), HashPair{Key: key, Value: key}))
+	}
+
+	// every version uses the same table instead of a copy
+	table := reflect.ValueOf(versions[100].Entries()).Pointer()
+	for _, idx := range []int{0, 50, 100, 3} {
+		entries := versions[idx].Entries()
+		if len(entries) != idx {
+			t.Errorf("version %d: expected %d pairs, got %d", idx, idx, len(entries))
+		}
+		if reflect.ValueOf(entries).Pointer() != table {
+			t.Errorf("version %d: expected the table to be shared", idx)
+		}
+	}
+
+	// changing a version in place gives it its own pairs
+	key := &Integer{Value: 1000}
+	versions[3].Own()[key.HashKey()] = HashPair{Key: key, Value: key}
+	if len(versions[3].Entries()) != 4 || len(versions[4].Entries()) != 4 || len(versions[2].Entries()) != 2 {
+		t.Errorf("expected only the changed version to have the new pair")
+	}
+	if _, ok := versions[4].Entries()[key.HashKey()]; ok {
+		t.Errorf("expected the other versions not to see the change")
+	}
+}
+
+func TestArrayAppended(t *testing.T) {
+	arr := &Array{Elements: []Object{}}
+	for i := int64(0); i < 100; i++ {
+		arr = arr.Appended(&Integer{Value: i})
+	}
+
+	// the first one copies the elements with room to spare, the next ones take that room
+	grown := arr.Appended(&Integer{Value: 100})
+	again := grown.Appended(&Integer{Value: 101})
+	if &again.Elements[0] != &grown.Elements[0] {
+		t.Errorf("expected the elements to be shared")
+	}
+
+	// the room is taken, another array made from grown gets its own elements
+	other := grown.Appended(&Integer{Value: -1})
+	if &other.Elements[0] == &grown.Elements[0] {
+		t.Errorf("expected the elements to be copied")
+	}
+	if again.Elements[101].Inspect() != "101" || other.Elements[101].Inspect() != "-1" || len(grown.Elements) != 101 {
+		t.Errorf("expected the arrays to be independent, got %s and %s", again.Inspect(), other.Inspect())
+	}
+}
@@ -0,0 +1,169 @@
+package object
+
+/**
+Versions of a hash made by With share one table of pairs instead of copying it:
+- the version that holds the table (the root) has its pairs in it
+- every other version only records the pair it has that the next version (one step closer to the root) doesn't
+Reading a version that isn't the root moves the table to it first (rerooting), undoing the changes on the way,
+so the newest version is read for free and going back k versions costs O(k).
+
+note: rerooting changes the table on a read, the versions of a hash can't be read from several goroutines at once
+(the evaluator never does, generators and timers run one at a time).
+**/
+type hashVersion struct {
+	// the hash this version is, nil once the hash left the versions (see Own)
+	hash *Hash
+	// the version this one is a change away from, nil for the root
+	next *hashVersion
+	// the pairs, only for the root (its hash's pairs too)
+	table map[HashKey]HashPair
+	// the pair this version has for key (or doesn't, present is false) that the next version doesn't
+	key     HashKey
+	pair    HashPair
+	present bool
+}
+
+/**
+The pairs of the hash, to read them. Hashes made by With only have their pairs once they're read,
+so they're only read through here (see Own to change them).
+**/
+func (h *Hash) Entries() map[HashKey]HashPair {
+	h.reroot()
+	return h.pairs
+}
+
+/**
+The pairs of the hash, to change them in place (h[key] = value, delete).
+Other versions of the hash (see With) don't see the change: the hash gets its own copy of the pairs first,
+the only time the pairs are copied.
+**/
+func (h *Hash) Own() map[HashKey]HashPair {
+	if h.version == nil {
+		return h.pairs
+	}
+
+	h.reroot()
+
+	pairs := make(map[HashKey]HashPair, len(h.pairs))
+	for key, pair := range h.pairs {
+		pairs[key] = pair
+	}
+
+	// the other versions keep the table, it stays at this (now detached) version until one of them is read
+	h.version.hash = nil
+	h.version = nil
+	h.pairs = pairs
+
+	return pairs
+}
+
+/**
+A new version of the hash with the pair set, the hash isn't changed: set(hash, key, value).
+It takes the table of pairs over instead of copying it, so it's O(1) (plus rerooting the hash when it isn't the newest).
+**/
+func (h *Hash) With(key HashKey, pair HashPair) *Hash {
+	h.reroot()
+
+	if h.pairs == nil {
+		h.pairs = make(map[HashKey]HashPair)
+	}
+	if h.version == nil {
+		h.version = &hashVersion{hash: h, table: h.pairs}
+	}
+
+	pairs := h.pairs
+	updated := &Hash{pairs: pairs, Default: h.Default}
+	updated.version = &hashVersion{hash: updated, table: pairs}
+
+	// h becomes a change away from the new version
+	old, present := pairs[key]
+	pairs[key] = pair
+	*h.version = hashVersion{hash: h, next: updated.version, key: key, pair: old, present: present}
+	h.pairs = nil
+
+	return updated
+}
+
+// Moves the table of pairs to the hash's version, see hashVersion
+func (h *Hash) reroot() {
+	if h.version == nil || h.version.next == nil {
+		return
+	}
+
+	path := []*hashVersion{}
+	version := h.version
+	for ; version.next != nil; version = version.next {
+		path = append(path, version)
+	}
+
+	root := version
+	pairs := root.table
+
+	// one step at a time from the root back to h, each step swaps which side holds the change
+	for idx := len(path) - 1; idx >= 0; idx-- {
+		child := path[idx]
+
+		current, present := pairs[child.key]
+		if child.present {
+			pairs[child.key] = child.pair
+		} else {
+			delete(pairs, child.key)
+		}
+
+		root.setPairs(nil)
+		root.next, root.key, root.pair, root.present = child, child.key, current, present
+
+		child.next, child.pair = nil, HashPair{}
+		child.setPairs(pairs)
+		root = child
+	}
+}
+
+// Makes the version the root (or not, for nil pairs), keeping its hash's pairs in sync
+func (v *hashVersion) setPairs(pairs map[HashKey]HashPair) {
+	v.table = pairs
+	if v.hash != nil {
+		v.hash.pairs = pairs
+	}
+}
+
+// The room left at the end of the elements of arrays made by Appended, shared by every array using them
+type arrayTail struct {
+	last *Object // the last slot of the elements, tells which elements this is the room of
+	free int     // how many slots at the end no array took yet
+}
+
+/**
+A new array with the value added at the end, the array isn't changed: insert(arr, len(arr), value).
+When the array has room after its elements that no other array took yet, the new array takes it and shares
+the elements instead of copying them (both arrays are marked as Shared). Otherwise the elements are copied
+with room to spare, so adding one value at a time only copies them every time the room runs out (O(1) on average).
+**/
+func (a *Array) Appended(val Object) *Array {
+	elements, tail := a.Elements, a.tail
+	length := len(elements)
+	shared := a.hasRoom()
+
+	if shared {
+		a.Shared = true
+	} else {
+		elements = make([]Object, length, 2*length+4)
+		copy(elements, a.Elements)
+		tail = &arrayTail{last: &elements[:cap(elements)][cap(elements)-1], free: cap(elements) - length}
+	}
+
+	elements = append(elements, val)
+	tail.free--
+
+	return &Array{Elements: elements, Shared: shared, tail: tail}
+}
+
+// Whether the slot right after the elements is free: they're the ones the tail is about and no array took it yet
+func (a *Array) hasRoom() bool {
+	room := cap(a.Elements) - len(a.Elements)
+	if a.tail == nil || room == 0 || room != a.tail.free {
+		return false
+	}
+
+	return &a.Elements[:cap(a.Elements)][cap(a.Elements)-1] == a.tail.last
+}
@@ -0,0 +1,19 @@
+package object
+
+import "time"
+
+// A point in time, from the time module: time.now(), time.parse("2024-05-01T12:00:00Z")
+type Time struct {
+	Value time.Time
+}
+
+func (t *Time) Type() ObjectType { return TIME_OBJ }
+func (t *Time) Inspect() string  { return t.Value.Format(time.RFC3339Nano) }
+
+// An amount of time: time.duration("1h30m"), or the difference between two times
+type Duration struct {
+	Value time.Duration
+}
+
+func (d *Duration) Type() ObjectType { return DURATION_OBJ }
+func (d *Duration) Inspect() string  { return d.Value.String() }
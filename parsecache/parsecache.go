@@ -0,0 +1,204 @@
+package parsecache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"io/ioutil"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Bumped whenever the AST changes shape, so programs cached by older versions are parsed again
+const VERSION = "10"
+
+/**
+Caches parsed programs by the hash of their source code, so unchanged files aren't parsed again.
+
+- programs are always kept in memory
+- when a directory is given they're also stored on disk (encoded with gob), so they survive restarts
+- only programs without parser errors are cached
+- the disk cache is best-effort: failing to read or write it just means parsing the source again
+- the parser options are part of the key, the same source parsed with other options is parsed again
+
+note: the cached *ast.Program is shared by every caller that parses the same source, it shouldn't be modified.
+**/
+type Cache struct {
+	mu       sync.Mutex
+	programs map[string]*ast.Program
+	dir      string
+}
+
+// Creates a cache, dir can be empty for an in-memory only cache
+func New(dir string) *Cache {
+	return &Cache{programs: make(map[string]*ast.Program), dir: dir}
+}
+
+// Returns the parsed program and the parser errors, using the cached program when there is one
+func (c *Cache) Parse(source string, opts ...parser.Option) (*ast.Program, []parser.ParserError) {
+	return c.ParseFile("", source, opts...)
+}
+
+// Same as Parse, parser errors are located in the given file (see parser.NewWithFile)
+func (c *Cache) ParseFile(filename, source string, opts ...parser.Option) (*ast.Program, []parser.ParserError) {
+	key := hash(source, opts)
+
+	if program, ok := c.lookup(key); ok {
+		return program, nil
+	}
+
+	p := parser.NewWithFile(lexer.New(source), filename, opts...)
+	program := p.ParseProgram()
+
+	if len(p.ParseErrors()) != 0 {
+		return program, p.ParseErrors()
+	}
+
+	c.store(key, program)
+
+	return program, nil
+}
+
+// Whether the source has already been parsed with these options (in memory or on disk)
+func (c *Cache) Contains(source string, opts ...parser.Option) bool {
+	_, ok := c.lookup(hash(source, opts))
+	return ok
+}
+
+func (c *Cache) lookup(key string) (*ast.Program, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if program, ok := c.programs[key]; ok {
+		return program, true
+	}
+
+	if c.dir == "" {
+		return nil, false
+	}
+
+	program, err := c.readFromDisk(key)
+	if err != nil {
+		return nil, false
+	}
+
+	c.programs[key] = program
+
+	return program, true
+}
+
+func (c *Cache) store(key string, program *ast.Program) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.programs[key] = program
+
+	if c.dir != "" {
+		c.writeToDisk(key, program)
+	}
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key+".gob")
+}
+
+func (c *Cache) readFromDisk(key string) (*ast.Program, error) {
+	data, err := ioutil.ReadFile(c.path(key))
+	if err != nil {
+		return nil, err
+	}
+
+	program := &ast.Program{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(program); err != nil {
+		return nil, err
+	}
+
+	return program, nil
+}
+
+func (c *Cache) writeToDisk(key string, program *ast.Program) {
+	var buf bytes.Buffer
+
+	if err := gob.NewEncoder(&buf).Encode(program); err != nil {
+		return
+	}
+
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return
+	}
+
+	// write to a temporary file first so readers never see half written programs
+	tmp, err := ioutil.TempFile(c.dir, key+".*.tmp")
+	if err != nil {
+		return
+	}
+
+	_, err = tmp.Write(buf.Bytes())
+	closeErr := tmp.Close()
+
+	if err != nil || closeErr != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+func hash(source string, opts []parser.Option) string {
+	sum := sha256.Sum256([]byte(VERSION + "\x00" + parser.OptionsKey(opts...) + "\x00" + source))
+	return hex.EncodeToString(sum[:])
+}
+
+// gob needs to know every type stored in the ast.Statement / ast.Expression interfaces
+var nodes = []ast.Node{
+	&ast.LetStatement{},
+	&ast.ReturnStatement{},
+	&ast.YieldStatement{},
+	&ast.ExpressionStatement{},
+	&ast.BlockStatement{},
+	&ast.ForLoopStatement{},
+	&ast.ForInStatement{},
+	&ast.Identifier{},
+	&ast.IntegerLiteral{},
+	&ast.CharLiteral{},
+	&ast.FloatLiteral{},
+	&ast.PrefixExpression{},
+	&ast.PostfixExpression{},
+	&ast.InterpolatedString{},
+	&ast.InfixExpression{},
+	&ast.Boolean{},
+	&ast.NullLiteral{},
+	&ast.IfExpression{},
+	&ast.MatchExpression{},
+	&ast.FunctionLiteral{},
+	&ast.CallExpression{},
+	&ast.StringLiteral{},
+	&ast.ArrayLiteral{},
+	&ast.IndexExpression{},
+	&ast.IndexAssignment{},
+	&ast.HashLiteral{},
+	&ast.InternalFunctionCall{},
+	&ast.AssignmentExpression{},
+}
+
+func init() {
+	for _, node := range nodes {
+		gob.Register(node)
+	}
+}
+
+/**
+Dev notes:
+
+- New AST nodes have to be added to nodes, otherwise programs using them can't be encoded
+  and are only cached in memory (the test checks every node of its program is registered).
+- Bump VERSION when existing nodes change, gob ignores unknown fields and zeroes missing ones
+  so an old entry could silently decode into a different program.
+**/
@@ -0,0 +1,151 @@
+package parsecache
+
+import (
+	"io/ioutil"
+	"monkey/ast"
+	"monkey/parser"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// uses every kind of AST node, so a node missing from the gob registrations fails the disk test
+const everyNode = `
+let add = fn(x: int, y: int) -> int { return x + y; };
+let gen = fn*() { yield 1; };
+let arr = [1, 2, -3, !true, false];
+let h = {"a": 1};
+h["b"] = 2;
+let s = "monke";
+s = "gorilla";
+arr.push(4);
+if (add(1, 2) > 2) { arr[0] } else { h["a"] };
+for (let i = 0; i < 3; i = i + 1) { puts(i); };
+match (s) { "monke" => 1, _ => { 2 } };
+let f = 1.5;
+let c = 'a';
+let n = null;
+let count = 0;
+count++;
+for (x in arr) { puts("x is ${x}"); };
+`
+
+/**
+Collects the type of every node of the program (used) and the types stored in interface fields
+(stored, for ast.Statement and ast.Expression), the ones gob has to know.
+**/
+func nodeTypes(v reflect.Value, used, stored map[reflect.Type]bool) {
+	switch v.Kind() {
+	case reflect.Interface:
+		if !v.IsNil() {
+			stored[v.Elem().Type()] = true
+			nodeTypes(v.Elem(), used, stored)
+		}
+	case reflect.Ptr:
+		if !v.IsNil() {
+			used[v.Type()] = true
+			nodeTypes(v.Elem(), used, stored)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			nodeTypes(v.Field(i), used, stored)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			nodeTypes(v.Index(i), used, stored)
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			nodeTypes(key, used, stored)
+			nodeTypes(v.MapIndex(key), used, stored)
+		}
+	}
+}
+
+func TestEveryNodeIsRegistered(t *testing.T) {
+	program, errors := New("").Parse(everyNode)
+	if len(errors) != 0 {
+		t.Fatalf("unexpected parser errors: %v", errors)
+	}
+
+	used := map[reflect.Type]bool{}
+	stored := map[reflect.Type]bool{}
+	nodeTypes(reflect.ValueOf(program), used, stored)
+
+	registered := map[reflect.Type]bool{}
+	for _, node := range nodes {
+		registered[reflect.TypeOf(node)] = true
+
+		if !used[reflect.TypeOf(node)] {
+			t.Errorf("%T isn't used by the test program, add it to everyNode", node)
+		}
+	}
+
+	for nodeType := range stored {
+		if nodeType.Implements(reflect.TypeOf((*ast.Node)(nil)).Elem()) && !registered[nodeType] {
+			t.Errorf("%s isn't registered with gob", nodeType)
+		}
+	}
+}
+
+func TestMemoryCache(t *testing.T) {
+	cache := New("")
+
+	first, errors := cache.Parse(everyNode)
+	if len(errors) != 0 {
+		t.Fatalf("unexpected parser errors: %v", errors)
+	}
+
+	second, _ := cache.Parse(everyNode)
+	if first != second {
+		t.Errorf("expected the cached program to be returned")
+	}
+
+	if _, errors := cache.Parse("let x"); len(errors) == 0 {
+		t.Errorf("expected parser errors")
+	}
+
+	if cache.Contains("let x") {
+		t.Errorf("programs with parser errors shouldn't be cached")
+	}
+
+	// options with the same effect share the program, other options parse the source again (and check their limits)
+	if !cache.Contains(everyNode, parser.WithMaxElements(0)) {
+		t.Errorf("options with the same effect should share the cached program")
+	}
+	if cache.Contains(everyNode, parser.WithMaxElements(1)) {
+		t.Errorf("the program shouldn't be cached for other options yet")
+	}
+	if _, errors := cache.Parse(everyNode, parser.WithMaxElements(1)); len(errors) == 0 {
+		t.Errorf("expected the element limit to be checked")
+	}
+}
+
+func TestDiskCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "parsecache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	original, errors := New(dir).Parse(everyNode)
+	if len(errors) != 0 {
+		t.Fatalf("unexpected parser errors: %v", errors)
+	}
+
+	if _, err := ioutil.ReadFile(filepath.Join(dir, hash(everyNode, nil)+".gob")); err != nil {
+		t.Fatalf("expected the program to be written to disk: %s", err)
+	}
+
+	// a new cache (ex: the next run) reads the program from disk
+	restarted := New(dir)
+	if !restarted.Contains(everyNode) {
+		t.Fatalf("expected the program to be found on disk")
+	}
+
+	restored, _ := restarted.Parse(everyNode)
+	if restored.String() != original.String() {
+		t.Errorf("restored program differs:\n%s\nexpected:\n%s", restored.String(), original.String())
+	}
+}
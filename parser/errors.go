@@ -0,0 +1,39 @@
+package parser
+
+import (
+	"fmt"
+	"monkey/catalog"
+	"monkey/token"
+)
+
+/**
+A problem found while parsing, see Parser.ParseErrors.
+
+Errors() only gives the text of each one, these keep what editors need to point at it:
+the code, the token that caused it (its Start and End are the range to highlight) and,
+for a missing token, what would have been valid there.
+**/
+type ParserError struct {
+	Code    catalog.Code
+	Message string // from the catalog, without the position or code
+	File    string // empty when the source doesn't come from a file, see NewWithFile
+	Line    int
+	Column  int
+	// the offending token, the position is its own
+	Token token.Token
+	/**
+	The token types that would have been valid instead of Token, the first one is the one in the message:
+	[1, 2; => ] or , (nil for errors that aren't about a missing token)
+	**/
+	Expected []token.TokenType
+}
+
+// lib/util.mk:12:5: [E1001] expected next token to be ), got ; instead
+func (e ParserError) Error() string {
+	msg := fmt.Sprintf("%d:%d: [%s] %s", e.Line, e.Column, e.Code, e.Message)
+	if e.File != "" {
+		msg = e.File + ":" + msg
+	}
+
+	return msg
+}
@@ -0,0 +1,112 @@
+package parser
+
+import "fmt"
+
+/**
+Changes how the parser behaves, passed to New (or NewWithFile):
+
+	p := parser.New(l, parser.WithMaxErrors(20), parser.WithTrailingCommas(true))
+
+Without options the parser reports every error, doesn't limit nesting, only allows a trailing comma in hashes
+and needs semicolons between statements on the same line.
+**/
+type Option func(*Parser)
+
+/**
+Stops parsing after n errors, the ones after the first few are usually caused by them anyway.
+0 means no limit.
+**/
+func WithMaxErrors(n int) Option {
+	return func(p *Parser) {
+		p.maxErrors = n
+	}
+}
+
+/**
+Reports an error (E1011) instead of parsing expressions nested more than n levels deep:
+parentheses, calls, arrays, function bodies, etc. Each one adds a level, so ((1)) is 3 levels.
+
+Keeps generated or hostile input from overflowing the stack, parsing stops at the first one.
+0 means no limit.
+**/
+func WithMaxDepth(n int) Option {
+	return func(p *Parser) {
+		p.maxDepth = n
+	}
+}
+
+/**
+Reports an error (E1014) for string literals longer than n bytes, instead of keeping them in the AST.
+Heredocs count too, so does the text of an interpolated string ("a ${x} b" is 4 bytes).
+
+Meant for services parsing untrusted source, parsing stops at the first one like WithMaxDepth.
+0 means no limit.
+**/
+func WithMaxStringLength(n int) Option {
+	return func(p *Parser) {
+		p.maxStringLength = n
+	}
+}
+
+/**
+Reports an error (E1014) for array literals with more than n elements and hash literals with more than n pairs,
+so a short source like [0, 0, 0, ...] can't turn into a huge AST.
+
+Meant for services parsing untrusted source, parsing stops at the first one like WithMaxDepth.
+0 means no limit.
+**/
+func WithMaxElements(n int) Option {
+	return func(p *Parser) {
+		p.maxElements = n
+	}
+}
+
+/**
+Allows a comma after the last element of a list, so lists that span lines can have one on every line:
+
+	let point = [
+		1,
+		2,
+	];
+	add(1, 2,)
+
+Hashes always allow it. Function parameters (fn(a, b,) { a + b }) allow it without the option,
+WithTrailingCommas(false) turns it off for them too.
+**/
+func WithTrailingCommas(allowed bool) Option {
+	return func(p *Parser) {
+		p.trailingCommas = allowed
+		p.trailingParamCommas = allowed
+	}
+}
+
+/**
+Makes newlines end statements wherever a ; could go, so scripts can leave the semicolons out:
+
+	let x = 5
+	(x + 1).print()   // without it: 5(x + 1).print()
+	return            // returns null, without it: returns the expression on the next line
+
+Like Go's rule, an expression never continues on the next line: operators go at the end of the line (a +\n b).
+The exceptions are lines that start with . or ?., they continue a method chain.
+Lists can still span lines: arguments, array elements and hash pairs.
+**/
+func WithNewlineTermination(enabled bool) Option {
+	return func(p *Parser) {
+		p.autoSemicolons = enabled
+	}
+}
+
+/**
+Describes what the options change, options with the same effect give the same key (in any order).
+Used by caches of parsed programs: the same source parsed with different options can give a different program.
+**/
+func OptionsKey(opts ...Option) string {
+	p := &Parser{trailingParamCommas: true}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return fmt.Sprintf("errors=%d depth=%d strings=%d elements=%d commas=%t param_commas=%t newlines=%t",
+		p.maxErrors, p.maxDepth, p.maxStringLength, p.maxElements, p.trailingCommas, p.trailingParamCommas, p.autoSemicolons)
+}
@@ -1,11 +1,15 @@
 package parser
 
 import (
-	"fmt"
+	"errors"
+	"math"
 	"monkey/ast"
+	"monkey/catalog"
 	"monkey/lexer"
 	"monkey/token"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
 /**
@@ -26,12 +30,19 @@ TLDR:
 const (
 	_ int = iota
 	LOWEST
+	OR            // ||
+	AND           // &&
 	EQUALS        // ==
-	LESSGREATER   // < or >
+	LESSGREATER   // < or >, in
+	BIT_OR        // |
+	BIT_XOR       // ^
+	BIT_AND       // &
+	SHIFT         // << or >>
 	SUM           // +
-	PRODUCT       // *
+	PRODUCT       // *, / or %
 	PREFIX        // -X or !X
 	ASSIGN        // =
+	POSTFIX       // X++ or X--
 	CALL          // myFunction(x)
 	INDEX         //array[index]
 	INTERNAL_CALL // arr.pop, hash.delete, etc
@@ -45,31 +56,69 @@ ex:
 - these tokens have a lower precedence than token.ASTERISK and token.SLASH
 **/
 var precedences = map[token.TokenType]int{
-	token.EQ:       EQUALS,
-	token.NOT_EQ:   EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
-	token.LPAREN:   CALL,
-	token.LBRACKET: INDEX,
-	token.DOT:      INTERNAL_CALL,
-	token.ASSIGN:   ASSIGN,
+	token.OR:                OR,
+	token.AND:               AND,
+	token.EQ:                EQUALS,
+	token.NOT_EQ:            EQUALS,
+	token.LT:                LESSGREATER,
+	token.GT:                LESSGREATER,
+	token.IN:                LESSGREATER,
+	token.NOT_IN:            LESSGREATER,
+	token.BIT_OR:            BIT_OR,
+	token.BIT_XOR:           BIT_XOR,
+	token.BIT_AND:           BIT_AND,
+	token.SHIFT_LEFT:        SHIFT,
+	token.SHIFT_RIGHT:       SHIFT,
+	token.PLUS:              SUM,
+	token.MINUS:             SUM,
+	token.SLASH:             PRODUCT,
+	token.ASTERISK:          PRODUCT,
+	token.PERCENT:           PRODUCT,
+	token.LPAREN:            CALL,
+	token.LBRACKET:          INDEX,
+	token.OPTIONAL_LBRACKET: INDEX,
+	token.DOT:               INTERNAL_CALL,
+	token.OPTIONAL_DOT:      INTERNAL_CALL,
+	token.ASSIGN:            ASSIGN,
+	token.PLUS_ASSIGN:       ASSIGN,
+	token.MINUS_ASSIGN:      ASSIGN,
+	token.ASTERISK_ASSIGN:   ASSIGN,
+	token.SLASH_ASSIGN:      ASSIGN,
+	token.PERCENT_ASSIGN:    ASSIGN,
+	token.INCREMENT:         POSTFIX,
+	token.DECREMENT:         POSTFIX,
 }
 
 /**
-Prefix and infix parsing functions
+Returns the precedence table keyed by the operator's literal ("+", "*", "(", etc),
+a higher value binds tighter.
+
+note:
+- this is a copy, changing it doesn't change how programs are parsed
+- prefix operators (-X, !X) always use the PREFIX precedence and aren't part of the table
+**/
+func Precedences() map[string]int {
+	table := make(map[string]int, len(precedences))
+	for tokenType, precedence := range precedences {
+		table[tokenType.String()] = precedence
+	}
+	return table
+}
+
+/**
+Prefix, infix and postfix parsing functions
 
 examples:
-prefix expression: --5,
+prefix expression: -5,
 
 infix: a + b
+
+postfix: i++ (receives the expression before the operator, but doesn't parse one after it)
 **/
 type (
-	prefixParseFn func() ast.Expression
-	infixParseFn  func(ast.Expression) ast.Expression
+	prefixParseFn  func() ast.Expression
+	infixParseFn   func(ast.Expression) ast.Expression
+	postfixParseFn func(ast.Expression) ast.Expression
 )
 
 type Parser struct {
@@ -79,8 +128,28 @@ type Parser struct {
 	// token values
 	curToken  token.Token
 	peekToken token.Token
-	// slice of error strings
-	errors []string
+	// the errors found so far, see ParseErrors
+	errors []ParserError
+	// name of the file being parsed, used to locate errors (empty if the source doesn't come from a file)
+	filename string
+	// whether newlines end statements, see WithNewlineTermination
+	autoSemicolons bool
+	// see WithTrailingCommas, parameter lists allow one unless the option turns them off
+	trailingCommas      bool
+	trailingParamCommas bool
+	// limits, 0 for none (see WithMaxErrors, WithMaxDepth, WithMaxStringLength and WithMaxElements)
+	maxErrors       int
+	maxDepth        int
+	maxStringLength int
+	maxElements     int
+	// how deep parseExpression is nested right now
+	depth int
+	// set once a limit is reached, nothing else gets parsed (or reported)
+	halted bool
+	// set by an error, the rest of the statement is skipped (and its errors aren't reported), see synchronize
+	panicking bool
+	// how many { the tokens parsed so far opened and didn't close, see synchronize
+	braces int
 
 	//parsing functions
 	/**
@@ -90,13 +159,17 @@ type Parser struct {
 		- Each token type can have up to two parsing functions associated with it, depending on its position (prefix / infix)
 		// key: tokenType, res: prefix/infix function
 	**/
-	prefixParseFns map[token.TokenType]prefixParseFn
-	infixParseFns  map[token.TokenType]infixParseFn
+	prefixParseFns  map[token.TokenType]prefixParseFn
+	infixParseFns   map[token.TokenType]infixParseFn
+	postfixParseFns map[token.TokenType]postfixParseFn
 }
 
-func New(l *lexer.Lexer) *Parser {
+func New(l *lexer.Lexer, opts ...Option) *Parser {
 	// generate a pointer to this new Parser struct
-	p := &Parser{l: l, errors: []string{}}
+	p := &Parser{l: l, trailingParamCommas: true}
+	for _, opt := range opts {
+		opt(p)
+	}
 
 	// Read two tokens, so curToken and peekToken are both set
 	p.nextToken()
@@ -109,18 +182,27 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	// If we encounter a token of type token.INT, call parseIntegerLiteral
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
 	// If we encounter a token of type BANG (!), call this function
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	// --5 is still a double negation, the lexer reads -- as a single token
+	p.registerPrefix(token.DECREMENT, p.parseDoubleNegation)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
+	p.registerPrefix(token.NULL, p.parseNullLiteral)
 	// parse grouped expressions
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	// if expressions
 	p.registerPrefix(token.IF, p.parseIfExpression)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
 	// function expressions
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.CHAR, p.parseCharLiteral)
+	p.registerPrefix(token.STRING_START, p.parseInterpolatedString)
+	// chars (or string escapes) the lexer didn't recognize
+	p.registerPrefix(token.ILLEGAL, p.parseIllegal)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
 
@@ -130,18 +212,55 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
 	p.registerInfix(token.SLASH, p.parseInfixExpression)
 	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
+	p.registerInfix(token.PERCENT, p.parseInfixExpression)
 	p.registerInfix(token.EQ, p.parseInfixExpression)
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.IN, p.parseInfixExpression)
+	p.registerInfix(token.NOT_IN, p.parseInfixExpression)
+	p.registerInfix(token.AND, p.parseInfixExpression)
+	p.registerInfix(token.OR, p.parseInfixExpression)
+	p.registerInfix(token.BIT_AND, p.parseInfixExpression)
+	p.registerInfix(token.BIT_OR, p.parseInfixExpression)
+	p.registerInfix(token.BIT_XOR, p.parseInfixExpression)
+	p.registerInfix(token.SHIFT_LEFT, p.parseInfixExpression)
+	p.registerInfix(token.SHIFT_RIGHT, p.parseInfixExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.OPTIONAL_LBRACKET, p.parseIndexExpression)
 	p.registerInfix(token.DOT, p.parseInternalCallExpression)
+	p.registerInfix(token.OPTIONAL_DOT, p.parseInternalCallExpression)
 	p.registerInfix(token.ASSIGN, p.parseAssignmentExpression)
+	p.registerInfix(token.PLUS_ASSIGN, p.parseCompoundAssignment)
+	p.registerInfix(token.MINUS_ASSIGN, p.parseCompoundAssignment)
+	p.registerInfix(token.ASTERISK_ASSIGN, p.parseCompoundAssignment)
+	p.registerInfix(token.SLASH_ASSIGN, p.parseCompoundAssignment)
+	p.registerInfix(token.PERCENT_ASSIGN, p.parseCompoundAssignment)
+
+	// Initialize the postfix parse function map
+	p.postfixParseFns = make(map[token.TokenType]postfixParseFn)
+	p.registerPostfix(token.INCREMENT, p.parsePostfixExpression)
+	p.registerPostfix(token.DECREMENT, p.parsePostfixExpression)
+
+	return p
+}
 
+/**
+Same as New, but errors are prefixed with the location of the token that caused them:
+lib/util.mk:12:5: [E1001] expected next token to be ), got ; instead
+**/
+func NewWithFile(l *lexer.Lexer, filename string, opts ...Option) *Parser {
+	p := New(l, opts...)
+	p.filename = filename
 	return p
 }
 
+// Whether there's an implicit ; before the peek token, see WithNewlineTermination
+func (p *Parser) peekAfterAutoSemicolon() bool {
+	return p.autoSemicolons && p.peekToken.NewLine && !p.peekTokenIs(token.DOT) && !p.peekTokenIs(token.OPTIONAL_DOT)
+}
+
 func (p *Parser) registerPrefix(tokenType token.TokenType, fn prefixParseFn) {
 	p.prefixParseFns[tokenType] = fn
 }
@@ -150,6 +269,10 @@ func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
 	p.infixParseFns[tokenType] = fn
 }
 
+func (p *Parser) registerPostfix(tokenType token.TokenType, fn postfixParseFn) {
+	p.postfixParseFns[tokenType] = fn
+}
+
 func (p *Parser) parseIdentifier() ast.Expression {
 	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 }
@@ -159,6 +282,13 @@ func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
 	// parser.lexer.nextToken
 	p.peekToken = p.l.NextToken()
+
+	switch p.curToken.Type {
+	case token.LBRACE:
+		p.braces++
+	case token.RBRACE:
+		p.braces--
+	}
 }
 
 func (p *Parser) ParseProgram() *ast.Program {
@@ -166,8 +296,9 @@ func (p *Parser) ParseProgram() *ast.Program {
 	program := &ast.Program{}
 	// slice of statements
 	program.Statements = []ast.Statement{}
-	// Loop until we reach a null token / no token
-	for !p.curTokenIs(token.EOF) {
+	// Loop until we reach a null token / no token (or a limit, see WithMaxErrors)
+	for !p.curTokenIs(token.EOF) && !p.halted {
+		depth := p.bracesBefore()
 		// parse the current statement
 		stmt := p.parseStatement()
 
@@ -175,6 +306,10 @@ func (p *Parser) ParseProgram() *ast.Program {
 			// add the current statement to the program statements slice
 			program.Statements = append(program.Statements, stmt)
 		}
+		// skip what's left of a statement with an error
+		if p.panicking {
+			p.synchronize(depth)
+		}
 		// move onto the next token
 		p.nextToken()
 	}
@@ -182,14 +317,74 @@ func (p *Parser) ParseProgram() *ast.Program {
 	return program
 }
 
+/**
+Skips the rest of a statement that had an error, up to where the next one should start:
+- after a ; (or a newline, see WithNewlineTermination)
+- before a let, const, return, yield or for
+- before the } closing the block the statement is in
+
+depth is how many braces were open when the statement started, so the ; and } inside a function body or hash
+that's part of the broken statement don't count. The current token is left on the last skipped one.
+
+This keeps one mistake from being reported again by every statement parsed from the middle of it:
+	let f = fn(a b) { let z = a; z };  // only "expected next token to be ), got IDENT instead"
+**/
+func (p *Parser) synchronize(depth int) {
+	p.panicking = false
+
+	// the statement ended on the } closing its block
+	for p.braces >= depth && !p.peekTokenIs(token.EOF) {
+		if p.braces == depth && (p.curTokenIs(token.SEMICOLON) || p.peekAfterAutoSemicolon() ||
+			p.peekTokenIs(token.RBRACE) || startsStatement(p.peekToken.Type)) {
+			return
+		}
+
+		p.nextToken()
+	}
+}
+
+// How many braces were open before the current token, p.braces already counts it: {"a": 1}
+func (p *Parser) bracesBefore() int {
+	switch p.curToken.Type {
+	case token.LBRACE:
+		return p.braces - 1
+	case token.RBRACE:
+		return p.braces + 1
+	}
+
+	return p.braces
+}
+
+// Literals and identifiers, tokens that are a whole expression on their own
+func startsOperand(t token.TokenType) bool {
+	switch t {
+	case token.IDENT, token.INT, token.FLOAT, token.STRING, token.STRING_START, token.CHAR, token.TRUE, token.FALSE, token.NULL:
+		return true
+	}
+
+	return false
+}
+
+// Keywords that can only start a statement
+func startsStatement(t token.TokenType) bool {
+	switch t {
+	case token.LET, token.CONST, token.RETURN, token.YIELD, token.FOR:
+		return true
+	}
+
+	return false
+}
+
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
-	case token.LET:
+	case token.LET, token.CONST:
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.YIELD:
+		return p.parseYieldStatement()
 	case token.FOR:
-		return p.parseForLoopStatement()
+		return p.parseForStatement()
 	default:
 		// by default we'll parse it as an expression: x, foobar, x + y, etc
 		return p.parseExpressionStatement()
@@ -197,8 +392,8 @@ func (p *Parser) parseStatement() ast.Statement {
 }
 
 func (p *Parser) parseLetStatement() *ast.LetStatement {
-	// grabs the 'let' statement
-	stmt := &ast.LetStatement{Token: p.curToken}
+	// grabs the 'let' statement, const x = 5 is parsed the same way
+	stmt := &ast.LetStatement{Token: p.curToken, Constant: p.curTokenIs(token.CONST)}
 	// We expect to find an identifier: let x, let a, let etc
 	if !p.expectPeek(token.IDENT) {
 		return nil
@@ -207,6 +402,16 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	// now we have let <identifier>
 	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
+	// optional type annotation: let <identifier> : <type>
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		stmt.Name.Type = p.parseTypeAnnotation()
+
+		if stmt.Name.Type == nil {
+			return nil
+		}
+	}
+
 	// We then expect to find an equal sign after the identifier
 	// ex: let <identifier> <assign>
 	if !p.expectPeek(token.ASSIGN) {
@@ -226,6 +431,17 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{Token: p.curToken}
+
+	// return at the end of a line (or block) returns null, see WithNewlineTermination
+	if p.autoSemicolons && (p.peekAfterAutoSemicolon() || p.peekTokenIs(token.SEMICOLON) || p.peekTokenIs(token.RBRACE) || p.peekTokenIs(token.EOF)) {
+		stmt.ReturnValue = &ast.NullLiteral{Token: token.Token{Type: token.NULL, Literal: "null", Line: stmt.Token.Line, Column: stmt.Token.Column}}
+
+		if p.peekTokenIs(token.SEMICOLON) {
+			p.nextToken()
+		}
+		return stmt
+	}
+
 	// move up to the next token
 	p.nextToken()
 
@@ -238,13 +454,42 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return stmt
 }
 
+func (p *Parser) parseYieldStatement() *ast.YieldStatement {
+	stmt := &ast.YieldStatement{Token: p.curToken}
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if stmt.Value == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
 // Create an error when no prefix parse function has been found
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.addError(catalog.NO_PREFIX_PARSE_FN, t)
 }
 
 func (p *Parser) parseExpression(precedence int) ast.Expression {
+	if p.halted {
+		return nil
+	}
+
+	// see WithMaxDepth
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.maxDepth > 0 && p.depth > p.maxDepth {
+		p.addError(catalog.TOO_DEEPLY_NESTED, p.maxDepth)
+		p.halted = true
+		return nil
+	}
+
 	// See if the current token is registered to a parsing function
 	prefix := p.prefixParseFns[p.curToken.Type]
 
@@ -265,7 +510,22 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 		- Continue doing this until we encounter a token that has a higher precedence
 		than the one currently passed or we encounter a semicolon
 	*/
-	for !p.peekTokenIs(token.SEMICOLON) && precedence < p.peekPrecedence() {
+	for !p.peekTokenIs(token.SEMICOLON) && !p.peekAfterAutoSemicolon() && precedence < p.peekPrecedence() {
+		// postfix operators end the operand, there's no expression after them
+		// ex: curToken => i, peekToken => ++
+		if postfix := p.postfixParseFns[p.peekToken.Type]; postfix != nil {
+			p.nextToken()
+
+			// a--3 was a - (-3) before there were postfix operators, an operand right after one is an error
+			if startsOperand(p.peekToken.Type) && !p.peekToken.NewLine {
+				p.addErrorAt(p.curToken, catalog.AMBIGUOUS_POSTFIX, p.curToken.Literal, p.peekToken.Literal)
+				return nil
+			}
+
+			leftExp = postfix(leftExp)
+			continue
+		}
+
 		// grab the infix parsing function for this specific token (if it exists)
 		// ex: curToken => 5, peektoken => +
 		infix := p.infixParseFns[p.peekToken.Type]
@@ -330,15 +590,69 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 	}
 }
 
-//Returns any parser errors
+/**
+Same as expectPeek, others would have been valid there too but are handled by the caller (ex: the , between
+list elements), they're only added to the error's Expected
+**/
+func (p *Parser) expectPeekOr(t token.TokenType, others ...token.TokenType) bool {
+	if !p.peekTokenIs(t) {
+		p.peekError(append([]token.TokenType{t}, others...)...)
+		return false
+	}
+
+	p.nextToken()
+	return true
+}
+
+// Returns any parser errors, as text: 2:5: [E1001] expected next token to be =, got INT instead
 func (p *Parser) Errors() []string {
+	messages := make([]string, len(p.errors))
+	for i, err := range p.errors {
+		messages[i] = err.Error()
+	}
+
+	return messages
+}
+
+// Returns any parser errors, with their code, position and offending token (for editors, etc)
+func (p *Parser) ParseErrors() []ParserError {
 	return p.errors
 }
 
-// Adds any errors we encountered while peeking in expectPeek()
-func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+// Adds an error caused by the current token, using the message catalog: [E1001] expected next token...
+func (p *Parser) addError(code catalog.Code, args ...interface{}) {
+	p.addErrorAt(p.curToken, code, args...)
+}
+
+// Adds an error caused by the given token, prefixed with where it is: 2:5: [E1001] expected next token...
+func (p *Parser) addErrorAt(tok token.Token, code catalog.Code, args ...interface{}) {
+	p.addParserError(ParserError{Code: code, Message: catalog.Message(code, args...), Token: tok})
+}
+
+// Adds an error, filling in where it is from its token
+func (p *Parser) addParserError(err ParserError) {
+	// the statement already has an error, the ones after it are usually caused by it
+	if p.halted || p.panicking {
+		return
+	}
+	p.panicking = true
+
+	err.File, err.Line, err.Column = p.filename, err.Token.Line, err.Token.Column
+	p.errors = append(p.errors, err)
+
+	if p.maxErrors > 0 && len(p.errors) >= p.maxErrors {
+		p.halted = true
+	}
+}
+
+// Adds any errors we encountered while peeking in expectPeek(), expected are the token types that would have been valid
+func (p *Parser) peekError(expected ...token.TokenType) {
+	p.addParserError(ParserError{
+		Code:     catalog.UNEXPECTED_TOKEN,
+		Message:  catalog.Message(catalog.UNEXPECTED_TOKEN, expected[0], p.peekToken.Type),
+		Token:    p.peekToken,
+		Expected: expected,
+	})
 }
 
 /**
@@ -370,9 +684,35 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	// convert string into an int64
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
 
+	// there are no big integers, floats go further (with less precision)
+	if errors.Is(err, strconv.ErrRange) {
+		p.addError(catalog.INT_OUT_OF_RANGE, p.curToken.Literal, int64(math.MinInt64), int64(math.MaxInt64))
+		return nil
+	}
+
+	if err != nil {
+		p.addError(catalog.INVALID_INTEGER, p.curToken.Literal)
+		return nil
+	}
+
+	lit.Value = value
+
+	return lit
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.curToken}
+
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+
+	// 1e999 would be +Inf and 1e-999 would be 0, neither is the number that was written
+	if errors.Is(err, strconv.ErrRange) || (value == 0 && strings.ContainsAny(mantissa(p.curToken.Literal), "123456789")) {
+		p.addError(catalog.FLOAT_OUT_OF_RANGE, p.curToken.Literal)
+		return nil
+	}
+
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(catalog.INVALID_FLOAT, p.curToken.Literal)
 		return nil
 	}
 
@@ -381,10 +721,23 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	return lit
 }
 
+// 2.5e-3 => 2.5
+func mantissa(literal string) string {
+	if idx := strings.IndexAny(literal, "eE"); idx != -1 {
+		return literal[:idx]
+	}
+
+	return literal
+}
+
 func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
 }
 
+func (p *Parser) parseNullLiteral() ast.Expression {
+	return &ast.NullLiteral{Token: p.curToken}
+}
+
 // Parses expressions with prefixes: -5, !true, etc
 // anytime this function is called the tokens advance and the current token
 // is the one after the prefix operator
@@ -416,11 +769,38 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 
 		parseExpression returns this new node and uses it to fill the Right field of *ast.PrefixExpression
 	*/
+	if lit := p.foldMinInt64(expression.Token); lit != nil {
+		return lit
+	}
+
 	expression.Right = p.parseExpression(PREFIX)
 
 	return expression
 }
 
+/**
+-9223372036854775808 is the smallest int64, but 9223372036854775808 on its own is out of range.
+When minus is followed by that literal (and nothing that binds tighter, like -9223372036854775808[0]),
+both become a single integer literal. Every other negative number stays a prefix expression.
+**/
+func (p *Parser) foldMinInt64(minus token.Token) *ast.IntegerLiteral {
+	if minus.Type != token.MINUS || !p.curTokenIs(token.INT) || p.peekPrecedence() > PREFIX {
+		return nil
+	}
+
+	value, err := strconv.ParseInt("-"+p.curToken.Literal, 0, 64)
+	if err != nil || value != math.MinInt64 {
+		return nil
+	}
+
+	tok := minus
+	tok.Type = token.INT
+	tok.Literal = "-" + p.curToken.Literal
+	tok.End = p.curToken.End
+
+	return &ast.IntegerLiteral{Token: tok, Value: value}
+}
+
 /**
 - Takes an ast.Expression argument as the 'left' side of the infix expression
 - Grabs the precedence of the current token (operator of the infix expression)
@@ -461,10 +841,56 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
 func (p *Parser) parseIfExpression() ast.Expression {
 	expression := &ast.IfExpression{Token: p.curToken}
 
+	if !p.parseIfBranch(expression) {
+		return nil
+	}
+
+	/**
+	At this point we should be sitting on the right brace }
+	Check if there is an 'else', move up tokens if there is.
+
+	else if is sugar for an else block holding the next if expression:
+		if (a) { 1 } else if (b) { 2 } else { 3 }  =>  if (a) { 1 } else { if (b) { 2 } else { 3 } }
+	the chain is parsed in this loop instead of recursing, so long chains don't nest parser calls.
+	**/
+	current := expression
+	for p.peekTokenIs(token.ELSE) {
+		// we're currently sisting on the 'else' token, move up the tokens
+		p.nextToken()
+
+		if p.peekTokenIs(token.IF) {
+			p.nextToken()
+			next := &ast.IfExpression{Token: p.curToken}
+			if !p.parseIfBranch(next) {
+				return nil
+			}
+
+			current.Alternative = &ast.BlockStatement{
+				Token:      next.Token,
+				Statements: []ast.Statement{&ast.ExpressionStatement{Token: next.Token, Expression: next}},
+			}
+			current = next
+			continue
+		}
+
+		// If for some reason theres not a LBRACE token immediately after the else the expression is invalid
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+
+		current.Alternative = p.parseBlockStatement()
+		break
+	}
+
+	return expression
+}
+
+// Parses the (condition) { consequence } of an if expression, the current token is the if
+func (p *Parser) parseIfBranch(expression *ast.IfExpression) bool {
 	// we should expect a left parenthesis as the next token
 	// i.e. if ( x )
 	if !p.expectPeek(token.LPAREN) {
-		return nil
+		return false
 	}
 	// progress tokens, parse expression
 	p.nextToken()
@@ -478,6 +904,36 @@ func (p *Parser) parseIfExpression() ast.Expression {
 		- A side effect of the expectPeek function is it progresses the tokens
 		if the condition is true.
 	**/
+	if !p.expectPeek(token.RPAREN) {
+		return false
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return false
+	}
+
+	// The tokens get advanced enough so we are now sitting on the LBRACE
+	expression.Consequence = p.parseBlockStatement()
+
+	return true
+}
+
+/**
+match (subject) { pattern => value, pattern => { block }, _ => value }
+
+The arms are separated by commas (a trailing one is fine), a { after the => is always a block:
+a hash literal has to be wrapped in parentheses, 1 => ({"a": 1})
+**/
+func (p *Parser) parseMatchExpression() ast.Expression {
+	expression := &ast.MatchExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	expression.Subject = p.parseExpression(LOWEST)
+
 	if !p.expectPeek(token.RPAREN) {
 		return nil
 	}
@@ -486,21 +942,34 @@ func (p *Parser) parseIfExpression() ast.Expression {
 		return nil
 	}
 
-	// The tokens get advanced enough so we are now sitting on the LBRACE
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
 
-	expression.Consequence = p.parseBlockStatement()
+		arm := &ast.MatchArm{Token: p.curToken}
+		arm.Pattern = p.parseExpression(LOWEST)
 
-	// At this point we should be sitting on the right brace }
-	// Check if there is an 'else', move up tokens if there is
-	if p.peekTokenIs(token.ELSE) {
-		// we're currently sisting on the 'else' token, move up the tokens
+		if !p.expectPeek(token.FAT_ARROW) {
+			return nil
+		}
 		p.nextToken()
-		// If for some reason theres not a LBRACE token immediately after the else the expression is invalid
-		if !p.expectPeek(token.LBRACE) {
+
+		if p.curTokenIs(token.LBRACE) {
+			arm.Body = p.parseBlockStatement()
+		} else {
+			value := &ast.ExpressionStatement{Token: p.curToken, Expression: p.parseExpression(LOWEST)}
+			arm.Body = &ast.BlockStatement{Token: value.Token, Statements: []ast.Statement{value}}
+		}
+
+		expression.Arms = append(expression.Arms, arm)
+
+		// another arm or the end of the match
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeekOr(token.COMMA, token.RBRACE) {
 			return nil
 		}
+	}
 
-		expression.Alternative = p.parseBlockStatement()
+	if !p.expectPeek(token.RBRACE) {
+		return nil
 	}
 
 	return expression
@@ -509,6 +978,8 @@ func (p *Parser) parseIfExpression() ast.Expression {
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
 	block.Statements = []ast.Statement{}
+	// inside the block, see synchronize
+	depth := p.braces
 
 	// Jump over the LBRACE token
 	p.nextToken()
@@ -518,6 +989,15 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		if stmt != nil {
 			block.Statements = append(block.Statements, stmt)
 		}
+
+		if p.panicking {
+			p.synchronize(depth)
+
+			// the broken statement ended on the } of this block
+			if p.braces < depth {
+				break
+			}
+		}
 		p.nextToken()
 	}
 
@@ -527,12 +1007,28 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 func (p *Parser) parseFunctionLiteral() ast.Expression {
 	func_lit := &ast.FunctionLiteral{Token: p.curToken}
 
+	// generator function: fn*()
+	if p.peekTokenIs(token.ASTERISK) {
+		p.nextToken()
+		func_lit.Generator = true
+	}
+
 	if !p.expectPeek(token.LPAREN) {
 		return nil
 	}
 
 	func_lit.Parameters = p.parseFunctionParameters()
 
+	// optional return type annotation: fn(x) -> int
+	if p.peekTokenIs(token.ARROW) {
+		p.nextToken()
+		func_lit.ReturnType = p.parseTypeAnnotation()
+
+		if func_lit.ReturnType == nil {
+			return nil
+		}
+	}
+
 	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
@@ -552,30 +1048,77 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	}
 
 	// Move past the parenthesis we're currently on, point to the first identifier
-	p.nextToken()
+	// parameters are names, fn(1) {} and fn(,) {} are errors
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
 
 	// Grab first identifier
-	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	ident := p.parseFunctionParameter()
+	if ident == nil {
+		return nil
+	}
 	identifiers = append(identifiers, ident)
 
 	// Every time we encounter a comma, move token pointers up
 	// so we point to the identifiers
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
-		p.nextToken()
+		// fn(a, b,) is allowed unless WithTrailingCommas(false), parameter lists are often split over lines
+		if p.trailingParamCommas && p.peekTokenIs(token.RPAREN) {
+			break
+		}
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
 
-		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		ident := p.parseFunctionParameter()
+		if ident == nil {
+			return nil
+		}
 		identifiers = append(identifiers, ident)
 	}
 
 	// no closing parenthesis
-	if !p.expectPeek(token.RPAREN) {
+	if !p.expectPeekOr(token.RPAREN, token.COMMA) {
 		return nil
 	}
 
 	return identifiers
 }
 
+// parses a single parameter along with its optional type annotation: x, x: int
+func (p *Parser) parseFunctionParameter() *ast.Identifier {
+	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		ident.Type = p.parseTypeAnnotation()
+
+		if ident.Type == nil {
+			return nil
+		}
+	}
+
+	return ident
+}
+
+/**
+Parses the type name following a ':' or '->' token.
+- we should currently be sitting on the ':' or '->' token
+- type names are regular identifiers (int, string, bool, etc), the exception being 'fn'
+**/
+func (p *Parser) parseTypeAnnotation() *ast.TypeAnnotation {
+	if !p.peekTokenIs(token.IDENT) && !p.peekTokenIs(token.FUNCTION) {
+		p.peekError(token.IDENT, token.FUNCTION)
+		return nil
+	}
+
+	p.nextToken()
+
+	return &ast.TypeAnnotation{Token: p.curToken, Name: p.curToken.Literal}
+}
+
 // recieves the already parsed function as argument, uses it to construct call expression node.
 // "leftExp" in parseExpressions gets passed to this infix parsing function
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
@@ -585,17 +1128,106 @@ func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	*/
 	exp := &ast.CallExpression{Token: p.curToken, Function: function}
 	// (x,y,z)
-	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	exp.Arguments = p.parseExpressionList(token.RPAREN, 0)
 
 	return exp
 }
 
+// the lexer already checked it's a single char
+func (p *Parser) parseCharLiteral() ast.Expression {
+	value, _ := utf8.DecodeRuneInString(p.curToken.Literal)
+	return &ast.CharLiteral{Token: p.curToken, Value: value}
+}
+
 func (p *Parser) parseStringLiteral() ast.Expression {
+	if p.stringTooLong(p.curToken, len(p.curToken.Literal)) {
+		return nil
+	}
+
 	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+// Reports a string literal over the limit, see WithMaxStringLength
+func (p *Parser) stringTooLong(tok token.Token, length int) bool {
+	if p.maxStringLength > 0 && length > p.maxStringLength {
+		p.literalTooLarge(tok, "string", p.maxStringLength, "bytes")
+		return true
+	}
+
+	return false
+}
+
+// Reports a literal over one of the size limits, parsing stops there
+func (p *Parser) literalTooLarge(tok token.Token, kind string, limit int, unit string) {
+	p.addErrorAt(tok, catalog.LITERAL_TOO_LARGE, kind, limit, unit)
+	p.halted = true
+}
+
+/**
+Parses "a ${x} b ${y} c", the lexer splits it into:
+STRING_START("a "), x, STRING_MIDDLE(" b "), y, STRING_END(" c")
+**/
+func (p *Parser) parseInterpolatedString() ast.Expression {
+	str := &ast.InterpolatedString{Token: p.curToken}
+	str.Parts = append(str.Parts, &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal})
+	// of the text between the expressions, see WithMaxStringLength
+	length := len(p.curToken.Literal)
+
+	for {
+		p.nextToken()
+		str.Parts = append(str.Parts, p.parseExpression(LOWEST))
+
+		switch {
+		case p.peekTokenIs(token.STRING_MIDDLE):
+			p.nextToken()
+			str.Parts = append(str.Parts, &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal})
+
+			length += len(p.curToken.Literal)
+			if p.stringTooLong(str.Token, length) {
+				return nil
+			}
+
+		case p.peekTokenIs(token.STRING_END):
+			p.nextToken()
+			str.Parts = append(str.Parts, &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal})
+
+			length += len(p.curToken.Literal)
+			if p.stringTooLong(str.Token, length) {
+				return nil
+			}
+			return str
+
+		default:
+			// reports the missing }
+			p.expectPeek(token.RBRACE)
+			return nil
+		}
+	}
+}
+
+/**
+Reports the illegal token, the lexer gives strings with a malformed escape the escape as their literal (\q)
+and numbers with misplaced underscores the whole number (1__000)
+**/
+func (p *Parser) parseIllegal() ast.Expression {
+	if strings.HasPrefix(p.curToken.Literal, "\\") {
+		p.addError(catalog.INVALID_ESCAPE, p.curToken.Literal)
+	} else if strings.HasPrefix(p.curToken.Literal, "<<") {
+		p.addError(catalog.UNTERMINATED_HEREDOC, p.curToken.Literal, strings.TrimLeft(p.curToken.Literal, "<~"))
+	} else if strings.HasPrefix(p.curToken.Literal, "'") {
+		p.addError(catalog.INVALID_CHAR_LITERAL, p.curToken.Literal)
+	} else if lit := p.curToken.Literal; len(lit) > 1 && '0' <= lit[0] && lit[0] <= '9' {
+		p.addError(catalog.INVALID_SEPARATOR, p.curToken.Literal)
+	} else {
+		p.addError(catalog.ILLEGAL_CHARACTER, p.curToken.Literal)
+	}
+
+	return nil
+}
+
 // parses a list of expressions until we reach the end of the list (via the end token type)
-func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+// Parses the comma separated expressions up to end, more than maxElements (0 for no limit) is an array literal too large
+func (p *Parser) parseExpressionList(end token.TokenType, maxElements int) []ast.Expression {
 	list := []ast.Expression{}
 
 	// empty list
@@ -610,15 +1242,25 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 
 	// For each comma separated expression
 	for p.peekTokenIs(token.COMMA) {
-		// move up 2 tokens (so we land on the expressoin)
 		p.nextToken()
+		// [1, 2,] see WithTrailingCommas
+		if p.trailingCommas && p.peekTokenIs(end) {
+			break
+		}
+		// move up to the expression
 		p.nextToken()
+
+		if maxElements > 0 && len(list) == maxElements {
+			p.literalTooLarge(p.curToken, "array", maxElements, "elements")
+			return nil
+		}
+
 		// parse the expression
 		list = append(list, p.parseExpression(LOWEST))
 	}
 
 	// If for some reason we haven't reached the end token we passed, return nil
-	if !p.expectPeek(end) {
+	if !p.expectPeekOr(end, token.COMMA) {
 		return nil
 	}
 
@@ -630,7 +1272,7 @@ func (p *Parser) parseArrayLiteral() ast.Expression {
 	// the [ token
 	array := &ast.ArrayLiteral{Token: p.curToken}
 	// Grab all the elements before we reach the right bracket (end of array)
-	array.Elements = p.parseExpressionList(token.RBRACKET)
+	array.Elements = p.parseExpressionList(token.RBRACKET, p.maxElements)
 
 	return array
 }
@@ -642,7 +1284,7 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	**/
 	// curToken => [
 	// left => some identifier, array literal, etc
-	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+	exp := &ast.IndexExpression{Token: p.curToken, Left: left, Optional: p.curTokenIs(token.OPTIONAL_LBRACKET)}
 
 	// move pointer to where the index expression is
 	p.nextToken()
@@ -660,6 +1302,12 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	// If we are, the next token should be '='
 	// hash[a] = 2, arr[0] = 1
 	if p.peekTokenIs(token.ASSIGN) {
+		// there's nothing to assign to when hash is null: hash?[a] = 2
+		if exp.Optional {
+			p.invalidAssignment(p.peekToken, exp)
+			return nil
+		}
+
 		return p.parseIndexAssignment(exp, index)
 	}
 
@@ -698,14 +1346,18 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 	for !p.peekTokenIs(token.RBRACE) {
 		p.nextToken()
 
+		// see WithMaxElements
+		if p.maxElements > 0 && len(hash.Pairs) == p.maxElements {
+			p.literalTooLarge(p.curToken, "hash", p.maxElements, "pairs")
+			return nil
+		}
+
 		key := p.parseExpression(LOWEST)
 
-		// A key should be followed by a : (ex: "key":"value")
-		if !p.peekTokenIs(token.COLON) {
+		// A key should be followed by a : (ex: "key":"value"), move past it
+		if !p.expectPeek(token.COLON) {
 			return nil
 		}
-		// most past colon :
-		p.nextToken()
 
 		//value
 		p.nextToken()
@@ -715,7 +1367,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		hash.Pairs[key] = value
 
 		// If we haven't reached a right brace (end of hash) or comma (next pair)
-		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeekOr(token.COMMA, token.RBRACE) {
 			return nil
 		}
 	}
@@ -729,28 +1381,18 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 }
 
 func (p *Parser) parseInternalCallExpression(left ast.Expression) ast.Expression {
-	// the current token should be '.'
-	if !p.curTokenIs(token.DOT) {
+	// the current token should be '.' (or '?.')
+	if !p.curTokenIs(token.DOT) && !p.curTokenIs(token.OPTIONAL_DOT) {
 		return nil
 	}
 
 	dot := p.curToken
 
-	// Grab the identifier: arr, hash, etc.
-	ident, ok := left.(*ast.Identifier)
-
-	if !ok {
-		return nil
-	}
-
 	// We should now be at the function name: pop, delete, etc
-	p.nextToken()
-	internal_function_ident := p.parseIdentifier()
-	func_ident, ok := internal_function_ident.(*ast.Identifier)
-
-	if !ok {
+	if !p.expectPeek(token.IDENT) {
 		return nil
 	}
+	func_ident := p.parseIdentifier().(*ast.Identifier)
 
 	//After the function name we should expect a '('
 	if !p.expectPeek(token.LPAREN) {
@@ -758,17 +1400,15 @@ func (p *Parser) parseInternalCallExpression(left ast.Expression) ast.Expression
 	}
 
 	// After the '(' we should have either 0 -> expressions
-	args := p.parseExpressionList(token.RPAREN)
-
-	if !ok {
-		return nil
-	}
+	args := p.parseExpressionList(token.RPAREN, 0)
 
+	// left can be any expression (arr, "a string", arr.slice(1)), so calls chain
 	ifc := &ast.InternalFunctionCall{
-		CallerIdentifier:   ident,
+		Caller:             left,
 		Token:              dot,
 		FunctionIdentifier: func_ident,
 		Arguments:          args,
+		Optional:           dot.Type == token.OPTIONAL_DOT,
 	}
 	return ifc
 }
@@ -786,112 +1426,205 @@ func (p *Parser) parseAssignmentExpression(left ast.Expression) ast.Expression {
 	assignment.Token = assign
 
 	// Grab the identifier: arr, hash, etc.
+	// (index assignments are parsed by parseIndexExpression, anything else can't be assigned to: 1 = 2)
 	ident, ok := left.(*ast.Identifier)
 
 	if !ok {
+		p.invalidAssignment(assign, left)
 		return nil
 	}
 
 	assignment.Name = ident
 
 	// Now lets grab the expression after '='
+	// the ; ending it is left to the statement, so x = 1; [x] is two statements and not an index of the assignment
 	p.nextToken()
 	assignment.Value = p.parseExpression(LOWEST)
 
-	// If we reach a semicolon (terminating the expression), move onto the next token.
-	if p.peekTokenIs(token.SEMICOLON) {
-		p.nextToken()
-	}
-
 	return assignment
 
 }
 
-func (p *Parser) parseForLoopStatement() *ast.ForLoopStatement {
-	// the current token value here should be 'for'
-	if !p.curTokenIs(token.FOR) {
+/**
+Desugars x += 1 into x = x + 1 (and hash["k"] += 1 into hash["k"] = hash["k"] + 1),
+so the evaluator only ever sees regular assignments.
+
+note: the target is evaluated twice, hash[key()] += 1 calls key() twice
+**/
+func (p *Parser) parseCompoundAssignment(left ast.Expression) ast.Expression {
+	compound := p.curToken
+
+	// "+=" => "+" and "=", both at the position of the compound operator
+	literal := strings.TrimSuffix(compound.Literal, "=")
+	operator := token.Token{Type: token.FromString(literal), Literal: literal, Line: compound.Line, Column: compound.Column}
+	assign := token.Token{Type: token.ASSIGN, Literal: token.ASSIGN.String(), Line: compound.Line, Column: compound.Column}
+
+	index, isIndex := left.(*ast.IndexExpression)
+	ident, isIdent := left.(*ast.Identifier)
+
+	if (!isIndex && !isIdent) || (isIndex && index.Optional) {
+		p.invalidAssignment(compound, left)
 		return nil
 	}
 
-	forToken := p.curToken
+	p.nextToken()
+	value := &ast.InfixExpression{Token: operator, Left: left, Operator: literal, Right: p.parseExpression(LOWEST)}
 
-	loop := &ast.ForLoopStatement{
-		Token: forToken,
+	if isIndex {
+		return &ast.IndexAssignment{Left: index.Left, Index: index.Index, Token: assign, Value: value}
 	}
 
-	// Lets make sure the next token is an LPAREN '('
+	return &ast.AssignmentExpression{Token: assign, Name: ident, Value: value}
+}
+
+/**
+Parses i++ and i-- (the current token is the operator), only bindings can be updated:
+identifiers and index expressions (arr[0]++)
+**/
+func (p *Parser) parsePostfixExpression(left ast.Expression) ast.Expression {
+	switch left := left.(type) {
+	case *ast.Identifier:
+		return &ast.PostfixExpression{Token: p.curToken, Operator: p.curToken.Literal, Left: left}
+	case *ast.IndexExpression:
+		if !left.Optional {
+			return &ast.PostfixExpression{Token: p.curToken, Operator: p.curToken.Literal, Left: left}
+		}
+	}
+
+	p.invalidAssignment(p.curToken, left)
+	return nil
+}
+
+/**
+Reports that left can't be assigned to (the error is at tok, the operator).
+Nothing is reported when left comes from a statement that already has an error: it can be nil or only
+partly built (-) = 2 has a prefix expression without an operand), and that error is the one that matters.
+**/
+func (p *Parser) invalidAssignment(tok token.Token, left ast.Expression) {
+	if left == nil || p.panicking || p.halted {
+		return
+	}
+
+	p.addErrorAt(tok, catalog.INVALID_ASSIGNMENT, left.String())
+}
+
+// The update of a for loop: x++ => x = x + 1 (the value of the update isn't used)
+func (p *Parser) parseCounterStep(counter *ast.Identifier) ast.Expression {
+	step := p.curToken
+	literal := step.Literal[:1]
+	operator := token.Token{Type: token.FromString(literal), Literal: literal, Line: step.Line, Column: step.Column}
+	assign := token.Token{Type: token.ASSIGN, Literal: token.ASSIGN.String(), Line: step.Line, Column: step.Column}
+	one := &ast.IntegerLiteral{Token: token.Token{Type: token.INT, Literal: "1", Line: step.Line, Column: step.Column}, Value: 1}
+
+	return &ast.AssignmentExpression{
+		Token: assign,
+		Name:  counter,
+		Value: &ast.InfixExpression{Token: operator, Left: counter, Operator: literal, Right: one},
+	}
+}
+
+// --5 => (-(-5))
+func (p *Parser) parseDoubleNegation() ast.Expression {
+	minus := token.Token{Type: token.MINUS, Literal: token.MINUS.String(), Line: p.curToken.Line, Column: p.curToken.Column}
+	outer := &ast.PrefixExpression{Token: minus, Operator: minus.Literal}
+	inner := &ast.PrefixExpression{Token: minus, Operator: minus.Literal}
+
 	p.nextToken()
-	if !p.curTokenIs(token.LPAREN) {
+	inner.Right = p.parseExpression(PREFIX)
+	outer.Right = inner
+
+	return outer
+}
+
+/**
+Parses both kinds of for loops, the token after the '(' decides which one it is:
+- for (let i = 0; i < 10; i = i + 1) { ... } => *ast.ForLoopStatement
+- for (x in arr) { ... } => *ast.ForInStatement
+**/
+func (p *Parser) parseForStatement() ast.Statement {
+	// the current token value here should be 'for'
+	forToken := p.curToken
+
+	// Lets make sure the next token is an LPAREN '('
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	// for ( x in
+	if p.peekTokenIs(token.IDENT) {
+		if loop := p.parseForInStatement(forToken); loop != nil {
+			return loop
+		}
 		return nil
 	}
 
+	if loop := p.parseForLoopStatement(forToken); loop != nil {
+		return loop
+	}
+	return nil
+}
+
+func (p *Parser) parseForLoopStatement(forToken token.Token) *ast.ForLoopStatement {
+	loop := &ast.ForLoopStatement{
+		Token: forToken,
+	}
+
 	// Move onto the next token
 	// we should now be at the LET statement
 
 	// for ( let x = 0
-	p.nextToken()
-	counterVar := p.parseStatement()
+	if !p.expectPeek(token.LET) {
+		return nil
+	}
 
-	letStatement, ok := counterVar.(*ast.LetStatement)
+	letStatement := p.parseLetStatement()
 
-	if !ok {
+	if letStatement == nil {
 		return nil
 	}
 
 	loop.CounterVar = letStatement
 
 	// for ( let x = 0 ;
-	if !p.curTokenIs(token.SEMICOLON) {
+	if !p.curTokenIs(token.SEMICOLON) && !p.expectPeek(token.SEMICOLON) {
 		return nil
 	}
 
 	// x < 10
 	p.nextToken()
-	ident := p.parseIdentifier()
-
-	identifier, ok := ident.(*ast.Identifier)
+	loop.LoopCondition = p.parseExpression(LOWEST)
 
-	if !ok {
+	if loop.LoopCondition == nil {
 		return nil
 	}
 
-	p.nextToken()
-	loopCondition := p.parseInfixExpression(identifier)
-
-	condition, ok := loopCondition.(*ast.InfixExpression)
-
-	if !ok {
-		return nil
-	}
-
-	loop.LoopCondition = condition
-
 	// for ( let x = 0 ; x < 10 ;
-	if !p.peekTokenIs(token.SEMICOLON) {
+	if !p.expectPeek(token.SEMICOLON) {
 		return nil
 	}
 
-	p.nextToken()
-
 	// this should be pointing at 'x' now.
 	// x = x + 1
-	p.nextToken()
-
-	if !p.curTokenIs(token.IDENT) {
+	if !p.expectPeek(token.IDENT) {
 		return nil
 	}
 
-	ident = p.parseIdentifier()
-
-	identifier, ok = ident.(*ast.Identifier)
+	identifier := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
-	if !ok {
+	// =, a compound assignment (+=, -=, etc) or x++ / x--
+	p.nextToken()
+	var updateCounter ast.Expression
+	switch p.curToken.Type {
+	case token.ASSIGN:
+		updateCounter = p.parseAssignmentExpression(identifier)
+	case token.INCREMENT, token.DECREMENT:
+		updateCounter = p.parseCounterStep(identifier)
+	case token.PLUS_ASSIGN, token.MINUS_ASSIGN, token.ASTERISK_ASSIGN, token.SLASH_ASSIGN, token.PERCENT_ASSIGN:
+		updateCounter = p.parseCompoundAssignment(identifier)
+	default:
+		p.addError(catalog.UNEXPECTED_TOKEN, token.ASSIGN, p.curToken.Type)
 		return nil
 	}
-
-	// =
-	p.nextToken()
-	updateCounter := p.parseAssignmentExpression(identifier)
 	// lets make sure this is an assignment expression
 	counterUpdate, ok := updateCounter.(*ast.AssignmentExpression)
 
@@ -911,8 +1644,7 @@ func (p *Parser) parseForLoopStatement() *ast.ForLoopStatement {
 	}
 
 	// for ( let x = 0; x < 10; x = x + 1 ) { puts x
-	body := p.parseBlockStatement()
-	loop.LoopBlock = body
+	loop.LoopBlock = p.parseBlockStatement()
 
 	// for ( let x = 0; x < 10; x = x + 1 ) { puts x };
 	if p.peekTokenIs(token.SEMICOLON) {
@@ -923,6 +1655,41 @@ func (p *Parser) parseForLoopStatement() *ast.ForLoopStatement {
 	return loop
 }
 
+func (p *Parser) parseForInStatement(forToken token.Token) *ast.ForInStatement {
+	loop := &ast.ForInStatement{Token: forToken}
+
+	// for ( x
+	p.nextToken()
+	loop.Variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	// for ( x in
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+
+	// for ( x in arr
+	p.nextToken()
+	loop.Iterable = p.parseExpression(LOWEST)
+
+	if loop.Iterable == nil {
+		return nil
+	}
+
+	// for ( x in arr ) {
+	if !p.expectPeek(token.RPAREN) || !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	loop.Body = p.parseBlockStatement()
+
+	// for ( x in arr ) { puts(x) };
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return loop
+}
+
 /**
 Dev Notes:
 
@@ -959,8 +1726,8 @@ in the prefix position
 
 Postfix operator:
 - an operator "after" its operand.
-ex: 5++
-note: we won't have postfix operators in monke-lang (for now)
+ex: i++
+note: only ++ and -- are postfix operators in monke-lang, see parsePostfixExpression
 
 Infix Operators:
 - when the operator sits between its operands
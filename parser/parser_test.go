@@ -2,8 +2,13 @@ package parser
 
 import (
 	"fmt"
+	"math"
 	"monkey/ast"
+	"monkey/catalog"
 	"monkey/lexer"
+	"monkey/token"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -42,6 +47,45 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestLetStatementValues(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // the value
+	}{
+		{"let x = 5 * 5;", "(5 * 5)"},
+		{"let x = 1 + 2 * 3", "(1 + (2 * 3))"},
+		{"let add = fn(a, b) { a + b };", "fn(a, b) (a + b)"},
+		{"let f = fn() { 1 }(); let y = 2;", "fn() 1()"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.LetStatement)
+		if !ok {
+			t.Fatalf("%q: expected a *ast.LetStatement, got %T", tt.input, program.Statements[0])
+		}
+
+		if stmt.Value == nil {
+			t.Fatalf("%q: the value wasn't parsed", tt.input)
+		}
+		if stmt.Value.String() != tt.expected {
+			t.Errorf("%q: expected the value %s, got %s", tt.input, tt.expected, stmt.Value.String())
+		}
+	}
+
+	// the function literal is the value, not a separate statement
+	program := New(lexer.New("let add = fn(a, b) { a + b }; add(1, 2);")).ParseProgram()
+	if len(program.Statements) != 2 {
+		t.Fatalf("expected 2 statements, got %d", len(program.Statements))
+	}
+	if _, ok := program.Statements[0].(*ast.LetStatement).Value.(*ast.FunctionLiteral); !ok {
+		t.Errorf("expected a *ast.FunctionLiteral value, got %T", program.Statements[0].(*ast.LetStatement).Value)
+	}
+}
+
 func TestReturnStatements(t *testing.T) {
 	tests := []struct {
 		input         string
@@ -73,13 +117,43 @@ func TestReturnStatements(t *testing.T) {
 			t.Fatalf("returntStmt.TokenLiteral not 'return', got %q", returnStmt.TokenLiteral())
 		}
 
-		if testLiteralExpression(t, returnStmt.ReturnValue, tt.expectedValue) {
+		if !testLiteralExpression(t, returnStmt.ReturnValue, tt.expectedValue) {
 			return
 		}
 	}
 
 }
 
+func TestReturnStatementRoundTrip(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"return add(1, 2);", "return add(1, 2);"},
+		{"return 1 + 2 * 3", "return (1 + (2 * 3));"},
+		{"return -f(x)[0];", "return (-(f(x)[0]));"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("%q: expected %s, got %s", tt.input, tt.expected, program.String())
+		}
+
+		// parsing the output gives the same program back
+		p = New(lexer.New(program.String()))
+		again := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if again.String() != program.String() {
+			t.Errorf("%q: the round trip changed the program, got %s", tt.input, again.String())
+		}
+	}
+}
+
 func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 	// If not a let statement, throw err
 	if s.TokenLiteral() != "let" {
@@ -238,6 +312,50 @@ func testIdentifier(t *testing.T, exp ast.Expression, value string) bool {
 	return true
 }
 
+func TestMinInt64Literal(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"-9223372036854775808", "-9223372036854775808"},
+		{"-9_223_372_036_854_775_808", "-9223372036854775808"},
+		{"-9223372036854775808 + 1", "(-9223372036854775808 + 1)"},
+		{"- 9223372036854775807", "(-9223372036854775807)"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("%q: expected %q, got %q", tt.input, tt.expected, program.String())
+		}
+	}
+
+	p := New(lexer.New("-9223372036854775808"))
+	stmt := p.ParseProgram().Statements[0].(*ast.ExpressionStatement)
+
+	literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.IntegerLiteral. got=%T", stmt.Expression)
+	}
+
+	if literal.Value != math.MinInt64 {
+		t.Errorf("literal.Value not %d. got=%d", int64(math.MinInt64), literal.Value)
+	}
+
+	// only the minus right in front of the literal can make it fit
+	for _, input := range []string{"9223372036854775808", "-9223372036854775808[0]", "--9223372036854775808"} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 || !strings.Contains(p.Errors()[0], "[E1010]") {
+			t.Errorf("%q: expected E1010, got %v", input, p.Errors())
+		}
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input    string
@@ -297,10 +415,20 @@ func TestParsingInfixExpressions(t *testing.T) {
 		{"5 - 5;", 5, "-", 5},
 		{"5 * 5;", 5, "*", 5},
 		{"5 / 5;", 5, "/", 5},
+		{"5 % 5;", 5, "%", 5},
 		{"5 > 5;", 5, ">", 5},
 		{"5 < 5;", 5, "<", 5},
 		{"5 == 5;", 5, "==", 5},
 		{"5 != 5;", 5, "!=", 5},
+		{"5 in 5;", 5, "in", 5},
+		{"5 !in 5;", 5, "!in", 5},
+		{"5 && 5;", 5, "&&", 5},
+		{"5 || 5;", 5, "||", 5},
+		{"5 & 5;", 5, "&", 5},
+		{"5 | 5;", 5, "|", 5},
+		{"5 ^ 5;", 5, "^", 5},
+		{"5 << 5;", 5, "<<", 5},
+		{"5 >> 5;", 5, ">>", 5},
 	}
 
 	for _, tt := range infixTests {
@@ -325,6 +453,31 @@ func TestParsingInfixExpressions(t *testing.T) {
 	}
 }
 
+// Every pair of binary operators groups the way the precedence table says, equal precedences group to the left
+func TestPrecedenceClimbing(t *testing.T) {
+	operators := []string{"||", "&&", "==", "!=", "<", ">", "in", "!in", "|", "^", "&", "<<", ">>", "+", "-", "*", "/", "%"}
+	table := Precedences()
+
+	for _, first := range operators {
+		for _, second := range operators {
+			input := fmt.Sprintf("a %s b %s c", first, second)
+
+			expected := fmt.Sprintf("(a %s (b %s c))", first, second)
+			if table[first] >= table[second] {
+				expected = fmt.Sprintf("((a %s b) %s c)", first, second)
+			}
+
+			p := New(lexer.New(input))
+			program := p.ParseProgram()
+			checkParserErrors(t, p)
+
+			if program.String() != expected {
+				t.Errorf("%q: expected %s, got %s", input, expected, program.String())
+			}
+		}
+	}
+}
+
 func TestOperatorPrecedenceParsing(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -354,6 +507,10 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"a * b / c",
 			"((a * b) / c)",
 		},
+		{
+			"a + b % c * d",
+			"(a + ((b % c) * d))",
+		},
 		{
 			"a + b / c",
 			"(a + (b / c))",
@@ -374,6 +531,46 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"5 < 4 != 3 > 4",
 			"((5 < 4) != (3 > 4))",
 		},
+		{
+			"x += 1 * 2",
+			"x=(x + (1 * 2));",
+		},
+		{
+			"h[k] -= 1",
+			"h[k]=((h[k]) - 1)",
+		},
+		{
+			"i++ + 1",
+			"((i++) + 1)",
+		},
+		{
+			"-arr[0]--",
+			"(-((arr[0])--))",
+		},
+		{
+			"--5",
+			"(-(-5))",
+		},
+		{
+			"a | b ^ c & d << 1 + 2",
+			"(a | (b ^ (c & (d << (1 + 2)))))",
+		},
+		{
+			"a & b == c && d >> 1 < e",
+			"(((a & b) == c) && ((d >> 1) < e))",
+		},
+		{
+			"a || b && c == d || !e",
+			"((a || (b && (c == d))) || (!e))",
+		},
+		{
+			"-a.b(1).c() + d",
+			"((-a.b(1).c()) + d)",
+		},
+		{
+			"a + 1 in b == !(c !in d)",
+			"(((a + 1) in b) == (!(c !in d)))",
+		},
 		{
 			"3 + 4 * 5 == 3 * 1 + 4 * 5",
 			"((3 + (4 * 5)) == ((3 * 1) + (4 * 5)))",
@@ -551,6 +748,336 @@ func TestBooleanExpression(t *testing.T) {
 	}
 }
 
+func TestConstStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		constant bool
+	}{
+		{"const x = 5;", "const x = 5;", true},
+		{"const name: string = \"monke\";", "const name: string = monke;", true},
+		{"let y = x;", "let y = x;", false},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.LetStatement)
+		if !ok {
+			t.Fatalf("%q: expected *ast.LetStatement, got %T", tt.input, program.Statements[0])
+		}
+
+		if stmt.Constant != tt.constant {
+			t.Errorf("%q: expected Constant to be %t", tt.input, tt.constant)
+		}
+
+		if stmt.String() != tt.expected {
+			t.Errorf("%q: expected %s, got %s", tt.input, tt.expected, stmt.String())
+		}
+	}
+}
+
+func TestNullLiteral(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"null;", "null"},
+		{"x == null", "(x == null)"},
+		{"let x = null;", "let x = null;"},
+		{"[null, !null]", "[null, (!null)]"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("%q: expected %s, got %s", tt.input, tt.expected, program.String())
+		}
+	}
+
+	program := New(lexer.New("null")).ParseProgram()
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+
+	if _, ok := stmt.Expression.(*ast.NullLiteral); !ok {
+		t.Errorf("exp not *ast.NullLiteral, got=%T", stmt.Expression)
+	}
+}
+
+func TestOptionalChaining(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a?[0]", "(a?[0])"},
+		{"a?.len()", "a?.len()"},
+		{"a?[0][1]?.upper()", "((a?[0])[1])?.upper()"},
+		{"a?.split(\",\")?[0]", "(a?.split(,)?[0])"},
+		{"-a?[0]", "(-(a?[0]))"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("%q: expected %s, got %s", tt.input, tt.expected, program.String())
+		}
+	}
+
+	program := New(lexer.New("a?[0]")).ParseProgram()
+	index := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.IndexExpression)
+
+	if !index.Optional {
+		t.Errorf("expected the index expression to be optional")
+	}
+}
+
+func TestAutoSemicolons(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // with automatic semicolons
+		without  string
+	}{
+		{"let x = 5\n(x + 1)", "let x = 5;(x + 1)", "let x = 5((x + 1));"},
+		{"a\n[1]", "a[1]", "(a[1])"},
+		{"a\n-1", "a(-1)", "(a - 1)"},
+		// operators at the end of the line
+		{"a +\nb", "(a + b)", "(a + b)"},
+		// method chains
+		{"arr\n.len()\n?.str()", "arr.len()?.str()", "arr.len()?.str()"},
+		// lists
+		{"f(a,\nb\n)", "f(a, b)", "f(a, b)"},
+		{"if (a) { b }\nelse { c }", "ifa belse c", "ifa belse c"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input), WithNewlineTermination(true))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("%q: expected %s, got %s", tt.input, tt.expected, program.String())
+		}
+
+		p = New(lexer.New(tt.input))
+		if program := p.ParseProgram(); len(p.Errors()) == 0 && program.String() != tt.without {
+			t.Errorf("%q: expected %s without automatic semicolons, got %s", tt.input, tt.without, program.String())
+		}
+	}
+
+	// a return at the end of its line doesn't return the next one
+	p := New(lexer.New("fn() { return\n1 }"), WithNewlineTermination(true))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	body := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral).Body
+	if len(body.Statements) != 2 {
+		t.Fatalf("expected 2 statements in the body, got %d", len(body.Statements))
+	}
+
+	ret := body.Statements[0].(*ast.ReturnStatement)
+	if _, ok := ret.ReturnValue.(*ast.NullLiteral); !ok {
+		t.Errorf("expected a bare return to return null, got %T", ret.ReturnValue)
+	}
+}
+
+func TestTrailingCommas(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2,]", "[1, 2]"},
+		{"f(a,\n b,\n)", "f(a, b)"},
+		{"a.push(1,)", "a.push(1)"},
+		{"[1, 2]", "[1, 2]"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input), WithTrailingCommas(true))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("%q: expected %s, got %s", tt.input, tt.expected, program.String())
+		}
+
+		// they're still errors by default
+		p = New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 && strings.Contains(tt.input, ",)") {
+			t.Errorf("%q: expected an error without trailing commas", tt.input)
+		}
+	}
+
+	// only one comma
+	p := New(lexer.New("[1,,]"), WithTrailingCommas(true))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 {
+		t.Errorf("expected an error for [1,,]")
+	}
+
+	// parameter lists allow one by default, turning the option off turns it off for them too
+	for _, opts := range [][]Option{nil, {WithTrailingCommas(true)}} {
+		p = New(lexer.New("fn(a,) {}"), opts...)
+		p.ParseProgram()
+		checkParserErrors(t, p)
+	}
+
+	p = New(lexer.New("fn(a,) {}"), WithTrailingCommas(false))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != "1:6: [E1001] expected next token to be IDENT, got ) instead" {
+		t.Errorf("expected fn(a,) {} to be an error with WithTrailingCommas(false), got %q", p.Errors())
+	}
+
+	if OptionsKey() == OptionsKey(WithTrailingCommas(false)) {
+		t.Errorf("turning trailing commas off changes how parameters are parsed, the options key should change")
+	}
+}
+
+func TestMaxErrors(t *testing.T) {
+	input := "let = 1; let = 2; let = 3; let = 4;"
+
+	p := New(lexer.New(input))
+	p.ParseProgram()
+	if len(p.Errors()) < 4 {
+		t.Fatalf("expected at least 4 errors without a limit, got %d", len(p.Errors()))
+	}
+
+	p = New(lexer.New(input), WithMaxErrors(2))
+	p.ParseProgram()
+	if len(p.Errors()) != 2 {
+		t.Errorf("expected 2 errors, got %d: %v", len(p.Errors()), p.Errors())
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x 5; let y = 2;", []string{"1:7: [E1001] expected next token to be =, got INT instead"}},
+		{"let = 5; let y = 2;", []string{"1:5: [E1001] expected next token to be IDENT, got = instead"}},
+		{"let x = (1 + ; let y = 2;", []string{"1:14: [E1002] no prefix parse function for ; found"}},
+		{"let x = fn(a b) { let z = a; z }; let y = 2;", []string{"1:14: [E1001] expected next token to be ), got IDENT instead"}},
+		{"if (x { 1 }; let y = 2;", []string{"1:7: [E1001] expected next token to be ), got { instead"}},
+		{"let h = {\"a\" 1}; let y = 2;", []string{"1:14: [E1001] expected next token to be :, got INT instead"}},
+		// a statement starting with a {
+		{"{\"a\": 1 \"b\": 2}; let y = 2;", []string{"1:9: [E1001] expected next token to be ,, got STRING instead"}},
+		// a statement keyword ends the broken statement too
+		{"let a = [1, 2 let b = 3;", []string{"1:15: [E1001] expected next token to be ], got LET instead"}},
+		// inside a block, the rest of the block is still parsed
+		{"let f = fn() {\n let x 5;\n x +\n};\nlet y = 2;", []string{
+			"2:8: [E1001] expected next token to be =, got INT instead",
+			"4:1: [E1002] no prefix parse function for } found",
+		}},
+		// one error per mistake
+		{"let = 1; let x = 2; let = 3;", []string{
+			"1:5: [E1001] expected next token to be IDENT, got = instead",
+			"1:25: [E1001] expected next token to be IDENT, got = instead",
+		}},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		if !reflect.DeepEqual(p.Errors(), tt.expected) {
+			t.Errorf("wrong errors for %q.\nexpected: %q\ngot: %q", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
+func TestErrorRecoveryWithNewlines(t *testing.T) {
+	input := "let x 5\nlet y = 2\nputs(y"
+
+	p := New(lexer.New(input), WithNewlineTermination(true))
+	p.ParseProgram()
+
+	expected := []string{
+		"1:7: [E1001] expected next token to be =, got INT instead",
+		"3:7: [E1001] expected next token to be ), got EOF instead",
+	}
+	if !reflect.DeepEqual(p.Errors(), expected) {
+		t.Errorf("wrong errors.\nexpected: %q\ngot: %q", expected, p.Errors())
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	tests := []struct {
+		input    string
+		maxDepth int
+		expected string // the error, empty when it parses
+	}{
+		{"((1))", 3, ""},
+		{"(((1)))", 3, "1:4: [E1011] expression is nested too deeply (more than 3 levels)"},
+		{"[[[1]]]", 3, "1:4: [E1011] expression is nested too deeply (more than 3 levels)"},
+		{"fn() { fn() { 1 } }", 2, "1:15: [E1011] expression is nested too deeply (more than 2 levels)"},
+		{strings.Repeat("(", 1000) + "1" + strings.Repeat(")", 1000), 0, ""},
+		{strings.Repeat("(", 1000) + "1" + strings.Repeat(")", 1000), 500, "1:501: [E1011] expression is nested too deeply (more than 500 levels)"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input), WithMaxDepth(tt.maxDepth))
+		p.ParseProgram()
+
+		if tt.expected == "" {
+			checkParserErrors(t, p)
+			continue
+		}
+
+		// the errors while unwinding aren't reported
+		if len(p.Errors()) != 1 || p.Errors()[0] != tt.expected {
+			t.Errorf("%.20q: expected only %q, got %v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
+func TestMaxLiteralSizes(t *testing.T) {
+	tests := []struct {
+		input    string
+		opt      Option
+		expected string // the error, empty when it parses
+	}{
+		{`"abcd"`, WithMaxStringLength(4), ""},
+		{`let s = "abcde";`, WithMaxStringLength(4), "1:9: [E1014] string literal is too large (more than 4 bytes)"},
+		{"let s = <<EOF\nabcde\nEOF", WithMaxStringLength(4), "1:9: [E1014] string literal is too large (more than 4 bytes)"},
+		{`"ab${x}cd"`, WithMaxStringLength(4), ""},
+		{`"ab${x}c${y}de"`, WithMaxStringLength(4), "1:1: [E1014] string literal is too large (more than 4 bytes)"},
+		{`[1, 2, 3]`, WithMaxElements(3), ""},
+		{`[1, [2, 3, 4], 5, 6]`, WithMaxElements(3), "1:19: [E1014] array literal is too large (more than 3 elements)"},
+		{`{"a": 1, "b": 2}`, WithMaxElements(2), ""},
+		{`{"a": 1, "b": 2, "c": 3}`, WithMaxElements(2), "1:18: [E1014] hash literal is too large (more than 2 pairs)"},
+		// only literals, calls can have any number of arguments
+		{`f(1, 2, 3, 4)`, WithMaxElements(2), ""},
+		{"[" + strings.Repeat("0, ", 100000) + "0]", WithMaxElements(1000), "1:3002: [E1014] array literal is too large (more than 1000 elements)"},
+		{"[" + strings.Repeat("0, ", 100000) + "0]", WithMaxElements(0), ""},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input), tt.opt)
+		p.ParseProgram()
+
+		if tt.expected == "" {
+			checkParserErrors(t, p)
+			continue
+		}
+
+		// parsing stops at the first one
+		if len(p.Errors()) != 1 || p.Errors()[0] != tt.expected {
+			t.Errorf("%.30q: expected only %q, got %v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
 func TestIfExpression(t *testing.T) {
 	input := `if (x < y) { x }`
 
@@ -655,8 +1182,128 @@ func TestIfElseExpression(t *testing.T) {
 	}
 }
 
-func TestFunctionLiteralParsing(t *testing.T) {
-	input := `fn(x, y) { x + y; }`
+func TestElseIfChains(t *testing.T) {
+	input := `if (a) { 1 } else if (b) { 2 } else if (c) { 3 } else { 4 }`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.IfExpression. got=%T", stmt.Expression)
+	}
+
+	// every else if is an else block holding the next if expression
+	for idx, condition := range []string{"a", "b", "c"} {
+		if !testIdentifier(t, exp.Condition, condition) {
+			return
+		}
+		if !testIntegerLiteral(t, exp.Consequence.Statements[0].(*ast.ExpressionStatement).Expression, int64(idx+1)) {
+			return
+		}
+
+		if exp.Alternative == nil || len(exp.Alternative.Statements) != 1 {
+			t.Fatalf("expected an else block with a single statement after %s, got %+v", condition, exp.Alternative)
+		}
+		alternative := exp.Alternative.Statements[0].(*ast.ExpressionStatement).Expression
+
+		if condition == "c" {
+			testIntegerLiteral(t, alternative, 4)
+			break
+		}
+
+		if exp, ok = alternative.(*ast.IfExpression); !ok {
+			t.Fatalf("expected the else block of %s to hold an if expression, got %T", condition, alternative)
+		}
+	}
+
+	if program.String() != "ifa 1else ifb 2else ifc 3else 4" {
+		t.Errorf("wrong program string, got %q", program.String())
+	}
+
+	// long chains don't count as nesting
+	deep := "if (a) { 0 }" + strings.Repeat(" else if (a) { 0 }", 5000) + " else { 1 }"
+	p = New(lexer.New(deep), WithMaxDepth(50))
+	p.ParseProgram()
+	checkParserErrors(t, p)
+
+	p = New(lexer.New(`if (a) { 1 } else if { 2 }`))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || !strings.Contains(p.Errors()[0], "expected next token to be (, got { instead") {
+		t.Errorf("expected an error for the missing condition, got %v", p.Errors())
+	}
+}
+
+func TestMatchExpressions(t *testing.T) {
+	input := `match (x + 1) {
+		1 => "one",
+		"two" => { let y = 2; y },
+		_ => ({"a": 1}),
+	}`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.MatchExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.MatchExpression. got=%T", stmt.Expression)
+	}
+
+	if !testInfixExpression(t, exp.Subject, "x", "+", 1) {
+		return
+	}
+	if len(exp.Arms) != 3 {
+		t.Fatalf("expected 3 arms, got %d", len(exp.Arms))
+	}
+
+	testIntegerLiteral(t, exp.Arms[0].Pattern, 1)
+	if len(exp.Arms[1].Body.Statements) != 2 {
+		t.Errorf("expected the block arm to have 2 statements, got %d", len(exp.Arms[1].Body.Statements))
+	}
+	if exp.Arms[1].IsWildcard() || !exp.Arms[2].IsWildcard() {
+		t.Errorf("expected only the last arm to be a wildcard")
+	}
+	if _, ok := exp.Arms[2].Body.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.HashLiteral); !ok {
+		t.Errorf("expected the last arm to be a hash literal, got %s", exp.Arms[2].Body.String())
+	}
+
+	if program.String() != `match(x + 1) {1 => one, two => let y = 2;y, _ => {a:1}}` {
+		t.Errorf("wrong program string, got %q", program.String())
+	}
+
+	// no arms, no trailing comma, a match as a value
+	for _, input := range []string{`match (x) {}`, `match (x) { 1 => 2 }`, `let y = match (x) { _ => 1 } + 1;`} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		checkParserErrors(t, p)
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{`match x { 1 => 2 }`, "1:7: [E1001] expected next token to be (, got IDENT instead"},
+		{`match (x) { 1 2 }`, "1:15: [E1001] expected next token to be =>, got INT instead"},
+		{`match (x) { 1 => 2 3 => 4 }`, "1:20: [E1001] expected next token to be ,, got INT instead"},
+		{`match (x) { 1 => 2`, "1:19: [E1001] expected next token to be ,, got EOF instead"},
+	}
+
+	for _, tt := range errors {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("%q: expected %q, got %v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
+func TestFunctionLiteralParsing(t *testing.T) {
+	input := `fn(x, y) { x + y; }`
 
 	l := lexer.New(input)
 	p := New(l)
@@ -712,6 +1359,9 @@ func TestFunctionParameterParsing(t *testing.T) {
 		{input: "fn() {};", expectedParams: []string{}},
 		{input: "fn(x) {};", expectedParams: []string{"x"}},
 		{input: "fn(x, y, z) {};", expectedParams: []string{"x", "y", "z"}},
+		{input: "fn(x,\n y\n) {};", expectedParams: []string{"x", "y"}},
+		{input: "fn(x,) {};", expectedParams: []string{"x"}},
+		{input: "fn(x,\n y,\n) {};", expectedParams: []string{"x", "y"}},
 	}
 
 	for _, tt := range tests {
@@ -731,6 +1381,26 @@ func TestFunctionParameterParsing(t *testing.T) {
 			testLiteralExpression(t, function.Parameters[i], ident)
 		}
 	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(1) {}", "1:4: [E1001] expected next token to be IDENT, got INT instead"},
+		{"fn(,) {}", "1:4: [E1001] expected next token to be IDENT, got , instead"},
+		{"fn(x, 2) {}", "1:7: [E1001] expected next token to be IDENT, got INT instead"},
+		{"fn(x,,) {}", "1:6: [E1001] expected next token to be IDENT, got , instead"},
+		{"fn(x y) {}", "1:6: [E1001] expected next token to be ), got IDENT instead"},
+	}
+
+	for _, tt := range errors {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("%q: expected the error %q, got %v", tt.input, tt.expected, p.Errors())
+		}
+	}
 }
 
 func TestCallExpressionParsing(t *testing.T) {
@@ -819,6 +1489,44 @@ func TestCallExpressionParameterParsing(t *testing.T) {
 	}
 }
 
+// anything that evaluates to a function can be called: literals, calls, grouped expressions
+func TestCallExpressionCallees(t *testing.T) {
+	tests := []struct {
+		input        string
+		calleeType   string
+		expectedArgs string
+	}{
+		{"fn(x) { x }(5)", "*ast.FunctionLiteral", "5"},
+		{"makeAdder(1)(2)", "*ast.CallExpression", "2"},
+		{"(f)(1, 2)", "*ast.Identifier", "1, 2"},
+		{"hash[\"f\"](a * b)", "*ast.IndexExpression", "(a * b)"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		exp, ok := stmt.Expression.(*ast.CallExpression)
+		if !ok {
+			t.Fatalf("%q: stmt.Expression is not ast.CallExpression, got %T", tt.input, stmt.Expression)
+		}
+
+		if calleeType := fmt.Sprintf("%T", exp.Function); calleeType != tt.calleeType {
+			t.Errorf("%q: expected the function to be a %s, got %s", tt.input, tt.calleeType, calleeType)
+		}
+
+		args := []string{}
+		for _, arg := range exp.Arguments {
+			args = append(args, arg.String())
+		}
+		if strings.Join(args, ", ") != tt.expectedArgs {
+			t.Errorf("%q: expected the arguments %s, got %s", tt.input, tt.expectedArgs, strings.Join(args, ", "))
+		}
+	}
+}
+
 func TestStringLiteralExpression(t *testing.T) {
 	input := `"hello world";`
 
@@ -840,6 +1548,73 @@ func TestStringLiteralExpression(t *testing.T) {
 
 }
 
+func TestCharLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected rune
+		str      string
+	}{
+		{"'a'", 'a', "'a'"},
+		{"'🙈'", '🙈', "'🙈'"},
+		{`'\''`, '\'', `'\''`},
+		{`'\n'`, '\n', `'\n'`},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.CharLiteral)
+		if !ok {
+			t.Fatalf("expression not *ast.CharLiteral, got %T", stmt.Expression)
+		}
+
+		if literal.Value != tt.expected {
+			t.Errorf("%s: literal.Value not %q, got %q", tt.input, tt.expected, literal.Value)
+		}
+		if literal.String() != tt.str {
+			t.Errorf("%s: literal.String() not %s, got %s", tt.input, tt.str, literal.String())
+		}
+	}
+}
+
+func TestInterpolatedStringParsing(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedParts []string
+	}{
+		{`"hello ${name}, you are ${age + 1}"`, []string{"hello ", "name", ", you are ", "(age + 1)", ""}},
+		{`"${x}"`, []string{"", "x", ""}},
+		{`"a ${"b ${c}"}"`, []string{"a ", "b ${c}", ""}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		str, ok := stmt.Expression.(*ast.InterpolatedString)
+
+		if !ok {
+			t.Fatalf("%s: expression not *ast.InterpolatedString, got %T", tt.input, stmt.Expression)
+		}
+
+		if len(str.Parts) != len(tt.expectedParts) {
+			t.Fatalf("%s: wrong number of parts, expected %d got %d", tt.input, len(tt.expectedParts), len(str.Parts))
+		}
+
+		for i, part := range str.Parts {
+			if part.String() != tt.expectedParts[i] {
+				t.Errorf("%s: parts[%d] wrong, expected %q got %q", tt.input, i, tt.expectedParts[i], part.String())
+			}
+		}
+	}
+}
+
 func TestParsingArrayLiterals(t *testing.T) {
 	input := "[1, 2*2, 3 + 3]"
 
@@ -889,6 +1664,49 @@ func TestParsingIndexExpressoins(t *testing.T) {
 	}
 }
 
+func TestParsingArraysAndIndexes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[]", "[]"},
+		{"[fn(x) { x }, [1, [2]]]", "[fn(x) x, [1, [2]]]"},
+		{"arr[0][1]", "((arr[0])[1])"},
+		// INDEX binds tighter than CALL and the prefix operators
+		{"fns[0](1)", "(fns[0])(1)"},
+		{"f(1)[0]", "(f(1)[0])"},
+		{"-a[0]", "(-(a[0]))"},
+		{"[1, 2, 3][1 + 1]", "([1, 2, 3][(1 + 1)])"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("%q: expected %s, got %s", tt.input, tt.expected, program.String())
+		}
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"[1 2]", "1:4: [E1001] expected next token to be ], got INT instead"},
+		{"arr[1", "1:6: [E1001] expected next token to be ], got EOF instead"},
+	}
+
+	for _, tt := range errors {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("%q: expected the error %q, got %v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
 func TestParsingIndexAssignments(t *testing.T) {
 	input := "hash[1 + 1] = 2"
 
@@ -1051,12 +1869,12 @@ func TestParsingInternalFunctionCalls(t *testing.T) {
 		t.Fatalf("Invalid token for *ast.InternalFunctionCall, expected '%s', got '%s'", ".", ifc.Token.Literal)
 	}
 
-	if !testIdentifier(t, ifc.CallerIdentifier, "arr") {
-		t.Fatalf("Invalid identifier, expected %s, got %s", "arr", ifc.CallerIdentifier)
+	if !testIdentifier(t, ifc.Caller, "arr") {
+		t.Fatalf("Invalid identifier, expected %s, got %s", "arr", ifc.Caller)
 	}
 
 	if !testIdentifier(t, ifc.FunctionIdentifier, "slice") {
-		t.Fatalf("Invalid identifier, expected %s, got %s", "slice", ifc.CallerIdentifier)
+		t.Fatalf("Invalid identifier, expected %s, got %s", "slice", ifc.FunctionIdentifier)
 	}
 
 	if len(ifc.Arguments) != 2 {
@@ -1077,6 +1895,8 @@ func TestAssignmentExpressions(t *testing.T) {
 		{"let x = 5; x = 4; x;", 4},
 		{"let y = true; y = false; y;", false},
 		{"let foobar = y; foobar = x; foobar;", "x"},
+		// the ; ends the assignment, [x] is the next statement
+		{"let x = 5; x = 4; [x];", 4},
 	}
 
 	for _, tt := range tests {
@@ -1172,3 +1992,394 @@ func TestForLoopStatements(t *testing.T) {
 		}
 	}
 }
+
+func TestForInStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		variable string
+		iterable string
+		expected string
+	}{
+		{"for (x in [1, 2]) { puts(x) };", "x", "[1, 2]", "for(x in [1, 2]){puts(x)};"},
+		{"for (key in keys(h)) { key }", "key", "keys(h)", "for(key in keys(h)){key};"},
+		{"for (c in \"a\" + b) { c }", "c", "(a + b)", "for(c in (a + b)){c};"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ForInStatement)
+		if !ok {
+			t.Fatalf("Expected to get back an *ast.ForInStatement, got %T instead", program.Statements[0])
+		}
+
+		if stmt.Variable.Value != tt.variable {
+			t.Errorf("wrong loop variable, expected %s got %s", tt.variable, stmt.Variable.Value)
+		}
+
+		if stmt.Iterable.String() != tt.iterable {
+			t.Errorf("wrong iterable, expected %s got %s", tt.iterable, stmt.Iterable.String())
+		}
+
+		if stmt.String() != tt.expected {
+			t.Errorf("wrong string, expected %s got %s", tt.expected, stmt.String())
+		}
+	}
+}
+
+// Invalid loops are reported, not added to the program as nil statements
+func TestInvalidForLoops(t *testing.T) {
+	tests := []string{
+		"for x < 10 { x }",
+		"for (let i = 0 i < 3; i++) { i }",
+		"for (let i = 0; i < 3; i++ { i }",
+		"for (x in) { x }",
+		"for (x in arr) x",
+	}
+
+	for _, input := range tests {
+		l := lexer.New(input)
+		p := New(l)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected parser errors for %q, got none", input)
+		}
+
+		for _, stmt := range program.Statements {
+			if _, ok := stmt.(*ast.ForLoopStatement); ok {
+				t.Errorf("%q: invalid loop was added to the program", input)
+			}
+			if _, ok := stmt.(*ast.ForInStatement); ok {
+				t.Errorf("%q: invalid loop was added to the program", input)
+			}
+		}
+	}
+}
+
+func TestTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x: int = 5;", "let x: int = 5;"},
+		{"let name: string = \"monke\";", "let name: string = monke;"},
+		{"let add = fn(a: int, b) -> int { a + b };", "let add = fn(a: int, b) -> int (a + b);"},
+		{"fn(f: fn) -> fn { f }", "fn(f: fn) -> fn f"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestInvalidTypeAnnotations(t *testing.T) {
+	tests := []string{
+		"let x: = 5;",
+		"fn(a: 5) { a }",
+		"fn(a) -> { a }",
+	}
+
+	for _, input := range tests {
+		l := lexer.New(input)
+		p := New(l)
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected parser errors for %q, got none", input)
+		}
+	}
+}
+
+func TestParserErrorCodes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x 5;", "1:7: [E1001] expected next token to be =, got INT instead"},
+		{"let = 5;", "1:5: [E1001] expected next token to be IDENT, got = instead"},
+		{"}", "1:1: [E1002] no prefix parse function for } found"},
+		{"99999999999999999999", "1:1: [E1010] integer literal 99999999999999999999 is out of range for int64 (-9223372036854775808 to 9223372036854775807), consider using a float"},
+		{"09", "1:1: [E1003] could not parse \"09\" as integer"},
+		{`let s = "ok\qx";`, "1:12: [E1005] invalid escape sequence \\q in string"},
+		{"let x = 1 @ 2;", "1:11: [E1006] illegal character \"@\""},
+		{"5 += 1;", "1:3: [E1007] cannot assign to 5"},
+		{"f()++;", "1:4: [E1007] cannot assign to f()"},
+		{"1 = 2;", "1:3: [E1007] cannot assign to 1"},
+		{"f() = 2;", "1:5: [E1007] cannot assign to f()"},
+		{`"a ${1 2}"`, "1:8: [E1001] expected next token to be }, got INT instead"},
+		{"for (x of arr) { x }", "1:8: [E1001] expected next token to be in, got IDENT instead"},
+		{"for (let i = 0; i < 3; i) { i }", "1:25: [E1001] expected next token to be =, got ) instead"},
+		{"for (i = 0; i < 3; i++) { i }", "1:8: [E1001] expected next token to be in, got = instead"},
+		{"a?[0] = 1;", "1:7: [E1007] cannot assign to (a?[0])"},
+		{"a?[0]++;", "1:6: [E1007] cannot assign to (a?[0])"},
+		{"a ? b", "1:3: [E1006] illegal character \"?\""},
+		{"let n = 1__000;", "1:9: [E1008] invalid number 1__000, underscores can only go between digits"},
+		{"let f = 1e999;", "1:9: [E1009] float 1e999 is out of range"},
+		{"let f = 1e-999;", "1:9: [E1009] float 1e-999 is out of range"},
+		{"let c = 'ab';", "1:9: [E1012] invalid character literal 'ab', expected a single character between single quotes"},
+		{"let s = <<EOF\nnever closed", "1:9: [E1013] heredoc <<EOF is never closed, expected EOF on a line of its own"},
+		{"let c = '';", "1:9: [E1012] invalid character literal '', expected a single character between single quotes"},
+		{`let c = '\q';`, "1:10: [E1005] invalid escape sequence \\q in string"},
+		// a--3 and 5--3 meant a - (-3) and 5 - (-3) before there were postfix operators
+		{"let a = 5; a--3", "1:13: [E1015] ambiguous -- followed by 3, separate them with a space (a - -1) or a ; (a--; 1)"},
+		{"5--3", "1:2: [E1015] ambiguous -- followed by 3, separate them with a space (a - -1) or a ; (a--; 1)"},
+		{"let a = 5; a++b", "1:13: [E1015] ambiguous ++ followed by b, separate them with a space (a - -1) or a ; (a--; 1)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("%q: expected parser errors, got none", tt.input)
+			continue
+		}
+
+		if p.Errors()[0] != tt.expected {
+			t.Errorf("%q: wrong error, expected %q got %q", tt.input, tt.expected, p.Errors()[0])
+		}
+	}
+}
+
+// Assignments to an expression that failed to parse report the first error instead of crashing the parser
+func TestBrokenAssignmentTargets(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1__0++", "1:1: [E1008] invalid number 1__0, underscores can only go between digits"},
+		{"#++", "1:1: [E1006] illegal character \"#\""},
+		{"! ) ++", "1:3: [E1002] no prefix parse function for ) found"},
+		{"!) ++;", "1:2: [E1002] no prefix parse function for ) found"},
+		{"@ += 1", "1:1: [E1006] illegal character \"@\""},
+		{`"\q" += 1`, "1:2: [E1005] invalid escape sequence \\q in string"},
+		{"! += +=", "1:3: [E1002] no prefix parse function for += found"},
+		{"! ( @ +=", "1:5: [E1006] illegal character \"@\""},
+		{"-) = 2;", "1:2: [E1002] no prefix parse function for ) found"},
+		{"! ! >> =", "1:5: [E1002] no prefix parse function for >> found"},
+		{"a?[-)] = 1", "1:5: [E1002] no prefix parse function for ) found"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		if errors := p.Errors(); len(errors) != 1 || errors[0] != tt.expected {
+			t.Errorf("%q: expected only %q, got %q", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestStructuredParserErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		code     catalog.Code
+		literal  string // of the offending token
+		start    int
+		expected []token.TokenType
+	}{
+		{"let x 5;", catalog.UNEXPECTED_TOKEN, "5", 6, []token.TokenType{token.ASSIGN}},
+		{"let a = [1, 2;", catalog.UNEXPECTED_TOKEN, ";", 13, []token.TokenType{token.RBRACKET, token.COMMA}},
+		{"add(1 2)", catalog.UNEXPECTED_TOKEN, "2", 6, []token.TokenType{token.RPAREN, token.COMMA}},
+		{"{\"a\": 1 \"b\": 2}", catalog.UNEXPECTED_TOKEN, "b", 8, []token.TokenType{token.COMMA, token.RBRACE}},
+		{"fn(x: 1) { x }", catalog.UNEXPECTED_TOKEN, "1", 6, []token.TokenType{token.IDENT, token.FUNCTION}},
+		{"let x = ;", catalog.NO_PREFIX_PARSE_FN, ";", 8, nil},
+		{"5 += 1;", catalog.INVALID_ASSIGNMENT, "+=", 2, nil},
+	}
+
+	for _, tt := range tests {
+		p := NewWithFile(lexer.New(tt.input), "lib/util.mk")
+		p.ParseProgram()
+
+		errs := p.ParseErrors()
+		if len(errs) != 1 {
+			t.Errorf("%q: expected 1 error, got %v", tt.input, p.Errors())
+			continue
+		}
+
+		err := errs[0]
+		if err.Code != tt.code || err.Token.Literal != tt.literal || err.Token.Start != tt.start || !reflect.DeepEqual(err.Expected, tt.expected) {
+			t.Errorf("%q: wrong error, got %+v", tt.input, err)
+		}
+
+		if err.File != "lib/util.mk" || err.Line != err.Token.Line || err.Column != err.Token.Column {
+			t.Errorf("%q: wrong position, got %s %d:%d", tt.input, err.File, err.Line, err.Column)
+		}
+
+		// Errors() is the text of the same errors
+		if p.Errors()[0] != err.Error() {
+			t.Errorf("%q: expected %q, got %q", tt.input, err.Error(), p.Errors()[0])
+		}
+	}
+}
+
+func TestGeneratorFunctionParsing(t *testing.T) {
+	input := `fn*(x) { yield x; yield x + 1; }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+
+	function, ok := stmt.Expression.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.FunctionLiteral, got=%T", stmt.Expression)
+	}
+
+	if !function.Generator {
+		t.Fatalf("function.Generator is false, expected a generator function")
+	}
+
+	if len(function.Body.Statements) != 2 {
+		t.Fatalf("function.Body.Statements has not 2 statements. got=%d", len(function.Body.Statements))
+	}
+
+	yieldStmt, ok := function.Body.Statements[0].(*ast.YieldStatement)
+	if !ok {
+		t.Fatalf("function.Body.Statements[0] is not ast.YieldStatement. got=%T", function.Body.Statements[0])
+	}
+
+	testLiteralExpression(t, yieldStmt.Value, "x")
+
+	if yieldStmt.String() != "yield x;" {
+		t.Errorf("yieldStmt.String() wrong, got=%q", yieldStmt.String())
+	}
+
+	yieldStmt, ok = function.Body.Statements[1].(*ast.YieldStatement)
+	if !ok {
+		t.Fatalf("function.Body.Statements[1] is not ast.YieldStatement. got=%T", function.Body.Statements[1])
+	}
+
+	testInfixExpression(t, yieldStmt.Value, "x", "+", 1)
+}
+
+func TestPrecedences(t *testing.T) {
+	table := Precedences()
+
+	if table["*"] <= table["+"] {
+		t.Errorf("* should bind tighter than +, got * => %d, + => %d", table["*"], table["+"])
+	}
+
+	if table["("] != CALL {
+		t.Errorf("wrong precedence for calls, expected %d got %d", CALL, table["("])
+	}
+
+	// every binary operator should be in the table
+	for _, op := range token.Operators() {
+		if op == token.BANG {
+			continue
+		}
+
+		if _, ok := table[op.String()]; !ok {
+			t.Errorf("operator %s is missing from the precedence table", op)
+		}
+	}
+
+	table["+"] = CALL
+	if Precedences()["+"] != SUM {
+		t.Errorf("changing the returned table shouldn't change the parser's precedences")
+	}
+}
+
+func TestParserErrorsWithFile(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 5;\nlet = 10;", "lib/util.mk:2:5: [E1001] expected next token to be IDENT, got = instead"},
+		{"let x = 5;\n\n  let y 10;", "lib/util.mk:3:9: [E1001] expected next token to be =, got INT instead"},
+		{"let x = ;", "lib/util.mk:1:9: [E1002] no prefix parse function for ; found"},
+		{"99999999999999999999;", "lib/util.mk:1:1: [E1010] integer literal 99999999999999999999 is out of range for int64 (-9223372036854775808 to 9223372036854775807), consider using a float"},
+	}
+
+	for _, tt := range tests {
+		p := NewWithFile(lexer.New(tt.input), "lib/util.mk")
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("%q: expected errors, got none", tt.input)
+			continue
+		}
+
+		if errors[0] != tt.expected {
+			t.Errorf("%q: wrong error, expected %q got %q", tt.input, tt.expected, errors[0])
+		}
+	}
+}
+
+func TestFloatLiteralExpression(t *testing.T) {
+	l := lexer.New("3.14;")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program has not enough statements. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+
+	literal, ok := stmt.Expression.(*ast.FloatLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+	}
+
+	if literal.Value != 3.14 {
+		t.Errorf("literal.Value not %f. got=%f", 3.14, literal.Value)
+	}
+
+	if literal.TokenLiteral() != "3.14" {
+		t.Errorf("literal.TokenLiteral not %s. got=%s", "3.14", literal.TokenLiteral())
+	}
+}
+
+// a large script, the parser looks up every token type in its tables, see token.TokenType
+func BenchmarkParseProgram(b *testing.B) {
+	input := strings.Repeat(`
+	let add = fn(a, b) { return a + b; };
+	let values = {"one": 1, "two": 2, "three": [1, 2, 3]};
+	for (let i = 0; i < 10; i++) {
+		if (i % 2 == 0 && i != 4) { values["one"] += add(i, 3.5) } else { puts("odd ${i}") }
+	}
+	`, 2000)
+
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			b.Fatalf("parser errors: %v", p.Errors())
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package plugins
+
+import (
+	"fmt"
+	"monkey/evaluator"
+	"monkey/object"
+	"plugin"
+	"sort"
+	"strings"
+)
+
+// Name of the function every plugin must export
+const REGISTER_SYMBOL = "Register"
+
+/**
+Passed to a plugin's Register function so it can add its own builtin functions.
+
+ex (inside the plugin, built with: go build -buildmode=plugin -o mybuiltins.so):
+
+	func Register(reg plugins.BuiltinRegistry) {
+		reg.Register("shout", func(args ...object.Object) object.Object {
+			if err := object.CheckArgs("shout", args, object.Arg(object.STRING_OBJ)); err != nil {
+				return err
+			}
+			return &object.String{Value: strings.ToUpper(args[0].Inspect())}
+		})
+		reg.RegisterDoc(object.BuiltinDoc{Name: "shout", Signature: "shout(str)", Help: "The string in uppercase."})
+	}
+
+RegisterDoc documents a builtin for help("shout") and the REPL's :doc shout, it can be called before or after Register.
+**/
+type BuiltinRegistry interface {
+	Register(name string, fn object.BuiltinFunction)
+	RegisterDoc(doc object.BuiltinDoc)
+}
+
+// Collects the builtins a plugin registers before they're added to evaluator.BUILTIN
+type registry struct {
+	builtins map[string]*object.Builtin
+	docs     map[string]*object.BuiltinDoc
+}
+
+func (r *registry) Register(name string, fn object.BuiltinFunction) {
+	r.builtins[name] = &object.Builtin{Fn: fn}
+}
+
+func (r *registry) RegisterDoc(doc object.BuiltinDoc) {
+	r.docs[doc.Name] = &doc
+}
+
+/**
+Opens the Go plugin at the given path and adds the builtins it registers to evaluator.BUILTIN.
+
+note:
+- this has to be called before the builtins are loaded into an environment (setuphelpers.LoadBuiltInMethods)
+- plugins can't replace the builtins that already exist
+**/
+func Load(path string) error {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return err
+	}
+
+	sym, err := p.Lookup(REGISTER_SYMBOL)
+	if err != nil {
+		return err
+	}
+
+	return register(path, sym)
+}
+
+func register(path string, sym interface{}) error {
+	registerFn, ok := sym.(func(BuiltinRegistry))
+	if !ok {
+		return fmt.Errorf("plugin %s: %s should be a func(plugins.BuiltinRegistry), got %T", path, REGISTER_SYMBOL, sym)
+	}
+
+	reg := &registry{builtins: make(map[string]*object.Builtin), docs: make(map[string]*object.BuiltinDoc)}
+	registerFn(reg)
+
+	conflicts := []string{}
+	for name := range reg.builtins {
+		if _, exists := evaluator.BUILTIN[name]; exists {
+			conflicts = append(conflicts, name)
+		}
+	}
+
+	if len(conflicts) != 0 {
+		sort.Strings(conflicts)
+		return fmt.Errorf("plugin %s: builtins already exist: %s", path, strings.Join(conflicts, ", "))
+	}
+
+	for name, builtin := range reg.builtins {
+		builtin.Doc = reg.docs[name]
+		evaluator.BUILTIN[name] = builtin
+	}
+
+	return nil
+}
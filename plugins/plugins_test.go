@@ -0,0 +1,66 @@
+package plugins
+
+import (
+	"monkey/evaluator"
+	"monkey/object"
+	"testing"
+)
+
+func TestRegister(t *testing.T) {
+	registerFn := func(reg BuiltinRegistry) {
+		reg.Register("answer", func(args ...object.Object) object.Object {
+			return &object.Integer{Value: 42}
+		})
+		reg.RegisterDoc(object.BuiltinDoc{Name: "answer", Signature: "answer()", Help: "The answer."})
+	}
+
+	if err := register("test.so", registerFn); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer delete(evaluator.BUILTIN, "answer")
+
+	builtin, ok := evaluator.BUILTIN["answer"]
+	if !ok {
+		t.Fatalf("expected the plugin builtin to be registered")
+	}
+
+	if result := builtin.Fn(); result.Inspect() != "42" {
+		t.Errorf("wrong result, expected 42 got %s", result.Inspect())
+	}
+
+	if builtin.Doc == nil || builtin.Doc.String() != "answer()\n    The answer." {
+		t.Errorf("expected the plugin's documentation, got %+v", builtin.Doc)
+	}
+}
+
+func TestRegisterErrors(t *testing.T) {
+	tests := []struct {
+		sym      interface{}
+		expected string
+	}{
+		{
+			func() {},
+			"plugin test.so: Register should be a func(plugins.BuiltinRegistry), got func()",
+		},
+		{
+			func(reg BuiltinRegistry) {
+				reg.Register("len", func(args ...object.Object) object.Object { return nil })
+				reg.Register("puts", func(args ...object.Object) object.Object { return nil })
+			},
+			"plugin test.so: builtins already exist: len, puts",
+		},
+	}
+
+	for _, tt := range tests {
+		err := register("test.so", tt.sym)
+
+		if err == nil {
+			t.Errorf("expected error %q, got none", tt.expected)
+			continue
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error message, expected %q got %q", tt.expected, err.Error())
+		}
+	}
+}
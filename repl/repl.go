@@ -2,13 +2,16 @@ package repl
 
 import (
 	"fmt"
+	"monkey/ast"
 	"monkey/evaluator"
 	"monkey/lexer"
 	"monkey/object"
 	"monkey/parser"
 	"monkey/setuphelpers"
+	"monkey/vfs"
 	"os"
 	"os/user"
+	"sort"
 	"strings"
 
 	"github.com/TwiN/go-color"
@@ -27,6 +30,15 @@ var BLOCK_CURSOR = "... "
 
 const TERMINATOR = "exit()"
 
+// Prints the live operator precedence table
+const HELP_OPERATORS = ":help operators"
+
+// Prints the AST of the expression that follows, ex: `:explore 1 + 2 * 3`
+const EXPLORE = ":explore"
+
+// Prints the documentation of the builtin that follows, ex: `:doc len`
+const DOC = ":doc"
+
 // Global obj.Environment. Holds builtin functions
 var ENV = setupEnv()
 
@@ -64,6 +76,8 @@ func shouldContinue(char rune) bool {
 func setupEnv() *object.Environment {
 	env := object.NewEnvironment()
 	setuphelpers.LoadBuiltInMethods(env)
+	// the CLI runs the user's own code, it can list the host's directories
+	env.Set("glob", evaluator.GlobBuiltin(vfs.OS()))
 	return env
 }
 
@@ -71,6 +85,22 @@ func readInput(line string) {
 	if line == "exit()" {
 		exitRepl()
 	}
+
+	if strings.TrimSpace(line) == HELP_OPERATORS {
+		printOperatorHelp()
+		return
+	}
+
+	if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, EXPLORE+" ") {
+		explore(strings.TrimPrefix(trimmed, EXPLORE))
+		return
+	}
+
+	if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, DOC+" ") {
+		printDoc(strings.TrimSpace(strings.TrimPrefix(trimmed, DOC)))
+		return
+	}
+
 	evaluate(line)
 }
 
@@ -80,6 +110,9 @@ func completer(t prompt.Document) []prompt.Suggest {
 		{Text: "puts", Description: "print a value"},
 		{Text: "fn", Description: "declare a function literal"},
 		{Text: "if", Description: "declare a conditional statement"},
+		{Text: HELP_OPERATORS, Description: "show the operator precedence table"},
+		{Text: EXPLORE, Description: "print the AST of an expression"},
+		{Text: DOC, Description: "show the documentation of a builtin"},
 	}
 
 	// Check if we're evaluating the last block, reset cursor so indentation is correct.
@@ -104,6 +137,51 @@ func printInterpreterPrompt() {
 	fmt.Printf("Hello %s, (type '%s' to exit)\n", userName, terminator)
 }
 
+// Operators grouped by precedence, tightest binding first
+func printOperatorHelp() {
+	levels := map[int][]string{}
+	for op, precedence := range parser.Precedences() {
+		levels[precedence] = append(levels[precedence], op)
+	}
+	levels[parser.PREFIX] = append(levels[parser.PREFIX], "-X", "!X")
+
+	order := []int{}
+	for precedence, ops := range levels {
+		sort.Strings(ops)
+		order = append(order, precedence)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(order)))
+
+	fmt.Println("Operator precedence (higher binds tighter):")
+	for _, precedence := range order {
+		fmt.Printf("  %2d  %s\n", precedence, strings.Join(levels[precedence], " "))
+	}
+}
+
+// Parses the code (without evaluating it) and prints its AST as a tree
+func explore(code string) {
+	p := parser.New(lexer.New(code))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		printParserErrors(p.Errors())
+		return
+	}
+
+	fmt.Print(ast.Dump(program))
+}
+
+// Prints the signature and help text of the builtin bound to name
+func printDoc(name string) {
+	doc, err := evaluator.Describe(name, ENV)
+	if err != nil {
+		fmt.Println(err.Inspect())
+		return
+	}
+
+	fmt.Println(doc)
+}
+
 func printParserErrors(errors []string) {
 	fmt.Print("\n" + setuphelpers.MONKE + " Error!:\n")
 	for _, msg := range errors {
@@ -140,7 +218,7 @@ func evaluate(line string) {
 	evaluated := evaluator.Eval(program, ENV)
 	if evaluated != nil {
 		// apply syntax highlighting
-		str := setuphelpers.ApplyColorToText(evaluated.Inspect())
+		str := setuphelpers.ApplyColorToText(ENV.Display(evaluated))
 		fmt.Println(str)
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"io"
 	"monkey/evaluator"
 	"monkey/object"
+	"os"
 	"strings"
 
 	"github.com/TwiN/go-color"
@@ -16,6 +17,29 @@ func LoadBuiltInMethods(env *object.Environment) {
 	for key, value := range evaluator.BUILTIN {
 		env.Set(key, value)
 	}
+
+	// reads the limits of this environment when it's called
+	env.Set("runtime_info", evaluator.RuntimeInfo(env))
+
+	// checked against the memory limit of this environment
+	for key, value := range evaluator.RepeatBuiltins(env) {
+		env.Set(key, value)
+	}
+
+	env.Set("time", evaluator.TimeModule())
+
+	// looks names up in this environment
+	env.Set("help", evaluator.HelpBuiltin(env, os.Stdout))
+
+	// every environment gets its own timers
+	for key, value := range evaluator.TimerBuiltins() {
+		env.Set(key, value)
+	}
+
+	// no script arguments, file evaluation replaces these with the real ones
+	for key, value := range evaluator.ScriptBindings("monke", nil, os.Stdout) {
+		env.Set(key, value)
+	}
 }
 
 func PrintParserErrors(out io.Writer, errors []string) {
@@ -25,6 +49,13 @@ func PrintParserErrors(out io.Writer, errors []string) {
 	}
 }
 
+func PrintWarnings(out io.Writer, warnings []string) {
+	io.WriteString(out, "\n"+MONKE+" Warning!:\n")
+	for _, msg := range warnings {
+		io.WriteString(out, "> "+msg+"\n\n")
+	}
+}
+
 func ApplyColorToText(str string) string {
 	var out bytes.Buffer
 	text := strings.Split(str, "")
@@ -0,0 +1,343 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"monkey/evaluator"
+	"monkey/object"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+Saves the bindings of an environment to bytes and sets them again later, so scripts that keep their state
+in variables (counters, queues, config) can pick up where they left off after the host restarts.
+
+Limits:
+- a snapshot is taken between evaluations, not in the middle of one: the evaluator walks the AST on the Go stack
+  so there is no continuation to save, the host decides which script to run again after restoring
+- only data is saved, functions (and the closures they carry), generators, timers, modules and files can't be
+- values shared between bindings are restored as separate copies: let a = [1]; let b = a; are two arrays afterwards
+**/
+
+/**
+Bumped whenever the encoded format changes, older snapshots can still be restored:
+- 2: times, durations, bytes, infinite / NaN floats, hash defaults and constants
+**/
+const VERSION = 2
+
+type encodedSnapshot struct {
+	Version  int                     `json:"version"`
+	Bindings map[string]encodedValue `json:"bindings"`
+	// the bindings made with const, see Environment.SetConstant
+	Constants []string `json:"constants,omitempty"`
+}
+
+type encodedValue struct {
+	Type    object.ObjectType `json:"type"`
+	Integer int64             `json:"integer,omitempty"` // also durations, in nanoseconds
+	Float   float64           `json:"float,omitempty"`
+	// +Inf, -Inf and NaN, JSON numbers can't hold them
+	SpecialFloat string         `json:"special_float,omitempty"`
+	String       string         `json:"string,omitempty"` // also times, as RFC 3339 with nanoseconds
+	Boolean      bool           `json:"boolean,omitempty"`
+	Bytes        []byte         `json:"bytes,omitempty"`
+	Elements     []encodedValue `json:"elements,omitempty"`
+	Pairs        []encodedPair  `json:"pairs,omitempty"`
+	Default      *encodedValue  `json:"default,omitempty"`
+}
+
+type encodedPair struct {
+	Key   encodedValue `json:"key"`
+	Value encodedValue `json:"value"`
+}
+
+/**
+Encodes the bindings of the environment chain into bytes so they can be restored later
+(ex: after the host process restarts).
+
+- inner scopes shadow outer ones, same as env.Get()
+- builtins are skipped, the host is expected to load them again before restoring
+- only data can be captured (integers, floats, strings, booleans, null, bytes, times, durations, arrays and hashes),
+  bindings to functions and arrays / hashes that contain themselves can't be encoded and return an error
+- constants are restored as constants
+**/
+func Take(env *object.Environment) ([]byte, error) {
+	snap := encodedSnapshot{Version: VERSION, Bindings: make(map[string]encodedValue)}
+	unsupported := []string{}
+
+	for scope := env; scope != nil; scope = scope.Outer() {
+		for _, name := range scope.Names() {
+			// already captured from an inner scope
+			if _, exists := snap.Bindings[name]; exists {
+				continue
+			}
+
+			val, _ := scope.Get(name)
+
+			if _, isBuiltin := val.(*object.Builtin); isBuiltin {
+				continue
+			}
+
+			encoded, err := encode(val, map[object.Object]bool{})
+			if err != nil {
+				unsupported = append(unsupported, fmt.Sprintf("%s (%s)", name, err))
+				continue
+			}
+
+			snap.Bindings[name] = encoded
+			if scope.IsConstant(name) {
+				snap.Constants = append(snap.Constants, name)
+			}
+		}
+	}
+
+	if len(unsupported) != 0 {
+		return nil, fmt.Errorf("cannot snapshot bindings: %s", strings.Join(unsupported, ", "))
+	}
+
+	return json.Marshal(snap)
+}
+
+/**
+Sets every binding stored in the snapshot in the given environment.
+Every value is decoded first, on an error the environment is left as it was.
+**/
+func Restore(data []byte, env *object.Environment) error {
+	var snap encodedSnapshot
+
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return err
+	}
+
+	if snap.Version < 1 || snap.Version > VERSION {
+		return fmt.Errorf("unsupported snapshot version %d, expected %d", snap.Version, VERSION)
+	}
+
+	values := make(map[string]object.Object, len(snap.Bindings))
+	for name, encoded := range snap.Bindings {
+		val, err := decode(encoded)
+		if err != nil {
+			return fmt.Errorf("cannot restore %s: %s", name, err)
+		}
+		values[name] = val
+	}
+
+	constants := make(map[string]bool, len(snap.Constants))
+	for _, name := range snap.Constants {
+		if _, ok := values[name]; !ok {
+			return fmt.Errorf("cannot restore constant %s: it has no value", name)
+		}
+		constants[name] = true
+	}
+
+	for name, val := range values {
+		if constants[name] {
+			env.SetConstant(name, val)
+		} else {
+			env.Set(name, val)
+		}
+	}
+
+	return nil
+}
+
+/**
+Encodes a value, visiting holds the arrays and hashes it's nested in (an array that contains itself
+would be encoded forever). The same array can still show up twice side by side: [row, row]
+**/
+func encode(obj object.Object, visiting map[object.Object]bool) (encodedValue, error) {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return encodedValue{Type: obj.Type(), Integer: obj.Value}, nil
+
+	case *object.Float:
+		if math.IsInf(obj.Value, 0) || math.IsNaN(obj.Value) {
+			return encodedValue{Type: obj.Type(), SpecialFloat: strconv.FormatFloat(obj.Value, 'g', -1, 64)}, nil
+		}
+		return encodedValue{Type: obj.Type(), Float: obj.Value}, nil
+
+	case *object.Bytes:
+		return encodedValue{Type: obj.Type(), Bytes: obj.Value}, nil
+
+	case *object.Time:
+		return encodedValue{Type: obj.Type(), String: obj.Value.Format(time.RFC3339Nano)}, nil
+
+	case *object.Duration:
+		return encodedValue{Type: obj.Type(), Integer: int64(obj.Value)}, nil
+
+	case *object.String:
+		return encodedValue{Type: obj.Type(), String: obj.Value}, nil
+
+	case *object.Boolean:
+		return encodedValue{Type: obj.Type(), Boolean: obj.Value}, nil
+
+	case *object.Null:
+		return encodedValue{Type: obj.Type()}, nil
+
+	case *object.Array:
+		if visiting[obj] {
+			return encodedValue{}, fmt.Errorf("ARRAY contains itself")
+		}
+		visiting[obj] = true
+		defer delete(visiting, obj)
+
+		encoded := encodedValue{Type: obj.Type(), Elements: []encodedValue{}}
+
+		for _, el := range obj.Elements {
+			val, err := encode(el, visiting)
+			if err != nil {
+				return encodedValue{}, err
+			}
+			encoded.Elements = append(encoded.Elements, val)
+		}
+
+		return encoded, nil
+
+	case *object.Hash:
+		if visiting[obj] {
+			return encodedValue{}, fmt.Errorf("HASH contains itself")
+		}
+		visiting[obj] = true
+		defer delete(visiting, obj)
+
+		encoded := encodedValue{Type: obj.Type(), Pairs: []encodedPair{}}
+
+		for _, pair := range obj.Entries() {
+			// deleted keys, see evaluator's delete
+			if pair.Key == evaluator.NULL {
+				continue
+			}
+
+			key, err := encode(pair.Key, visiting)
+			if err != nil {
+				return encodedValue{}, err
+			}
+
+			value, err := encode(pair.Value, visiting)
+			if err != nil {
+				return encodedValue{}, err
+			}
+
+			encoded.Pairs = append(encoded.Pairs, encodedPair{Key: key, Value: value})
+		}
+
+		if obj.Default != nil {
+			def, err := encode(obj.Default, visiting)
+			if err != nil {
+				return encodedValue{}, err
+			}
+			encoded.Default = &def
+		}
+
+		return encoded, nil
+	}
+
+	if obj == nil {
+		return encodedValue{}, fmt.Errorf("cannot encode nil")
+	}
+
+	return encodedValue{}, fmt.Errorf("cannot encode %s", obj.Type())
+}
+
+func decode(encoded encodedValue) (object.Object, error) {
+	switch encoded.Type {
+	case object.INTEGER_OBJ:
+		return &object.Integer{Value: encoded.Integer}, nil
+
+	case object.FLOAT_OBJ:
+		if encoded.SpecialFloat != "" {
+			value, err := strconv.ParseFloat(encoded.SpecialFloat, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid float %q", encoded.SpecialFloat)
+			}
+			return &object.Float{Value: value}, nil
+		}
+		return &object.Float{Value: encoded.Float}, nil
+
+	case object.BYTES_OBJ:
+		return &object.Bytes{Value: encoded.Bytes}, nil
+
+	case object.TIME_OBJ:
+		value, err := time.Parse(time.RFC3339Nano, encoded.String)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time %q", encoded.String)
+		}
+		return &object.Time{Value: value}, nil
+
+	case object.DURATION_OBJ:
+		return &object.Duration{Value: time.Duration(encoded.Integer)}, nil
+
+	case object.STRING_OBJ:
+		return &object.String{Value: encoded.String}, nil
+
+	case object.BOOLEAN_OBJ:
+		// the evaluator compares booleans by pointer, reuse its singletons
+		if encoded.Boolean {
+			return evaluator.TRUE, nil
+		}
+		return evaluator.FALSE, nil
+
+	case object.NULL_OBJ:
+		return evaluator.NULL, nil
+
+	case object.ARRAY_OBJ:
+		arr := &object.Array{Elements: []object.Object{}}
+
+		for _, el := range encoded.Elements {
+			val, err := decode(el)
+			if err != nil {
+				return nil, err
+			}
+			arr.Elements = append(arr.Elements, val)
+		}
+
+		return arr, nil
+
+	case object.HASH_OBJ:
+		pairs := make(map[object.HashKey]object.HashPair)
+
+		for _, pair := range encoded.Pairs {
+			key, err := decode(pair.Key)
+			if err != nil {
+				return nil, err
+			}
+
+			hashable, ok := key.(object.Hashable)
+			if !ok {
+				return nil, fmt.Errorf("unusable as hash key: %s", key.Type())
+			}
+
+			value, err := decode(pair.Value)
+			if err != nil {
+				return nil, err
+			}
+
+			pairs[hashable.HashKey()] = object.HashPair{Key: key, Value: value}
+		}
+
+		hash := object.NewHash(pairs)
+		if encoded.Default != nil {
+			def, err := decode(*encoded.Default)
+			if err != nil {
+				return nil, err
+			}
+			hash.Default = def
+		}
+
+		return hash, nil
+	}
+
+	return nil, fmt.Errorf("unknown type %q", encoded.Type)
+}
+
+/**
+Dev notes:
+
+- A snapshot only captures *data*, it isn't a continuation: there's no way to pause the tree-walking
+  evaluator in the middle of a program. Scripts that want to survive a restart should snapshot
+  between steps and check the restored bindings when they start again.
+- Functions close over their environment and AST nodes, which have no stable encoding (yet).
+**/
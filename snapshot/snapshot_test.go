@@ -0,0 +1,193 @@
+package snapshot
+
+import (
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"testing"
+	"time"
+)
+
+func testEval(input string, env *object.Environment) object.Object {
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	return evaluator.Eval(program, env)
+}
+
+func TestTakeAndRestore(t *testing.T) {
+	env := object.NewEnvironment()
+	for name, builtin := range evaluator.BUILTIN {
+		env.Set(name, builtin)
+	}
+
+	testEval(`let count = 3; let ratio = 0.5; let name = "monke"; let done = false; let nothing = [][0]; let items = [1, "two", nothing, true]; let config = {"retries": 2, 1: [3]}; let older = {"a": 1, "gone": 0}; delete(older, "gone"); let newer = set(older, "b", 2);`, env)
+
+	data, err := Take(env)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	restored := object.NewEnvironment()
+	if err := Restore(data, restored); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"count + 1", "4"},
+		{"ratio * 3", "1.5"},
+		{"name", "monke"},
+		{"!done", "true"},
+		{"items[2]", "null"},
+		{"items[3] == true", "true"},
+		{`config["retries"]`, "2"},
+		{"config[1][0]", "3"},
+		// versions of a hash made by set keep their own pairs
+		{`[older["a"], older["b"], newer["a"], newer["b"], older["gone"]]`, "[1, null, 1, 2, null]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input, restored)
+
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: expected %s, got %v", tt.input, tt.expected, evaluated)
+		}
+	}
+
+	if _, ok := restored.Get("len"); ok {
+		t.Errorf("builtins shouldn't be part of the snapshot")
+	}
+}
+
+func TestTakeShadowedBindings(t *testing.T) {
+	outer := object.NewEnvironment()
+	outer.Set("x", &object.Integer{Value: 1})
+	outer.Set("y", &object.Integer{Value: 2})
+
+	inner := object.NewEnclosedEnvironment(outer)
+	inner.Set("x", &object.Integer{Value: 10})
+
+	data, err := Take(inner)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	restored := object.NewEnvironment()
+	Restore(data, restored)
+
+	x, _ := restored.Get("x")
+	y, _ := restored.Get("y")
+
+	if x.Inspect() != "10" || y.Inspect() != "2" {
+		t.Errorf("expected x=10 and y=2, got x=%s and y=%s", x.Inspect(), y.Inspect())
+	}
+}
+
+func TestTakeUnsupportedBindings(t *testing.T) {
+	env := object.NewEnvironment()
+	testEval("let add = fn(a, b) { a + b }; let x = 1;", env)
+
+	_, err := Take(env)
+	if err == nil {
+		t.Fatalf("expected an error when taking a snapshot of functions")
+	}
+
+	if err.Error() != "cannot snapshot bindings: add (cannot encode FUNCTION)" {
+		t.Errorf("wrong error message, got %q", err.Error())
+	}
+}
+
+func TestTakeMoreTypes(t *testing.T) {
+	env := object.NewEnvironment()
+	for name, builtin := range evaluator.BUILTIN {
+		env.Set(name, builtin)
+	}
+	env.Set("started", &object.Time{Value: time.Date(2024, 5, 1, 12, 30, 0, 5, time.UTC)})
+	env.Set("timeout", &object.Duration{Value: 90 * time.Second})
+	env.Set("raw", &object.Bytes{Value: []byte{0, 255}})
+
+	testEval(`const limit = 10; let big = 1e308 * 10; let small = -big; let nan = big - big; let counts = withDefault({"a": 1}, 0);`, env)
+
+	data, err := Take(env)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	restored := object.NewEnvironment()
+	if err := Restore(data, restored); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"started", "2024-05-01T12:30:00.000000005Z"},
+		{"timeout", "1m30s"},
+		{"raw", "bytes[0, 255]"},
+		{"big", "+Inf"},
+		{"small", "-Inf"},
+		{"nan", "NaN"},
+		{`counts["missing"]`, "0"},
+		{"limit = 5", "ERROR [R2036]: cannot assign to limit, it was declared with const"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input, restored)
+
+		if evaluated == nil || evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: expected %s, got %v", tt.input, tt.expected, evaluated)
+		}
+	}
+}
+
+func TestTakeSelfReferences(t *testing.T) {
+	env := object.NewEnvironment()
+	testEval(`let row = [1]; let grid = [row, row]; let loop = [1]; loop[0] = loop; let h = {}; h["self"] = h;`, env)
+
+	_, err := Take(env)
+	if err == nil {
+		t.Fatalf("expected an error for values that contain themselves")
+	}
+
+	if err.Error() != "cannot snapshot bindings: h (HASH contains itself), loop (ARRAY contains itself)" {
+		t.Errorf("wrong error message, got %q", err.Error())
+	}
+}
+
+func TestRestoreLeavesEnvironmentOnError(t *testing.T) {
+	input := `{"version": 2, "bindings": {"a": {"type": "INTEGER", "integer": 1}, "b": {"type": "TIME", "string": "yesterday"}}}`
+
+	env := object.NewEnvironment()
+	if err := Restore([]byte(input), env); err == nil || err.Error() != `cannot restore b: invalid time "yesterday"` {
+		t.Fatalf("expected an error for b, got %v", err)
+	}
+
+	if len(env.Names()) != 0 {
+		t.Errorf("expected nothing to be restored, got %v", env.Names())
+	}
+
+	// version 1 snapshots can still be restored
+	if err := Restore([]byte(`{"version": 1, "bindings": {"x": {"type": "INTEGER", "integer": 3}}}`), env); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestRestoreInvalidSnapshot(t *testing.T) {
+	tests := []string{
+		"not json",
+		`{"version": 99, "bindings": {}}`,
+		`{"version": 1, "bindings": {"x": {"type": "FUNCTION"}}}`,
+	}
+
+	for _, input := range tests {
+		if err := Restore([]byte(input), object.NewEnvironment()); err == nil {
+			t.Errorf("%q: expected an error, got none", input)
+		}
+	}
+}
@@ -1,59 +1,256 @@
 package token
 
-// Allows us to distinguish between different types of tokens
-type TokenType string
+import "fmt"
+
+/**
+Allows us to distinguish between different types of tokens.
+
+An int, so comparing token types and looking them up in the parser's tables doesn't compare strings.
+String() gives the name it used to be (token.LET => "LET", token.PLUS => "+"), see also FromString.
+**/
+type TokenType int
 
 const (
 	// Signifies a token we don't know about
-	ILLEGAL = "ILLEGAL"
+	ILLEGAL TokenType = iota
 	// end of file
-	EOF = "EOF"
+	EOF
 
 	// Idenfifiers + literals
-	IDENT  = "IDENT" // add, foobar, x, y, etc.
-	INT    = "INT"   // 123456
-	STRING = "STRING"
+	IDENT // add, foobar, x, y, etc.
+	INT   // 123456
+	FLOAT // 3.14
+	STRING
+	CHAR // 'a', its literal is the char
+	// the parts of an interpolated string around its ${expressions}: "a ${x} b ${y} c"
+	STRING_START  // "a "
+	STRING_MIDDLE // " b "
+	STRING_END    // " c"
 
 	// Operators
-	ASSIGN   = "="
-	PLUS     = "+"
-	MINUS    = "-"
-	BANG     = "!"
-	ASTERISK = "*"
-	SLASH    = "/"
-	LT       = "<" // less than
-	GT       = ">" // greater than
-	EQ       = "=="
-	NOT_EQ   = "!="
+	ASSIGN
+	PLUS
+	MINUS
+	BANG
+	ASTERISK
+	SLASH
+	PERCENT
+	LT // less than
+	GT // greater than
+	EQ
+	NOT_EQ
+	ARROW     // return type annotation: fn(x: int) -> int
+	FAT_ARROW // match arms: match (x) { 1 => "one" }
+	IN        // membership: 3 in arr, "key" in hash, "sub" in "string"
+	NOT_IN    // negated membership: 3 !in arr
+	AND
+	OR
+
+	// Bitwise operators (integers only)
+	BIT_AND
+	BIT_OR
+	BIT_XOR
+	SHIFT_LEFT
+	SHIFT_RIGHT
+
+	// Compound assignment: x += 1 is x = x + 1
+	PLUS_ASSIGN
+	MINUS_ASSIGN
+	ASTERISK_ASSIGN
+	SLASH_ASSIGN
+	PERCENT_ASSIGN
+
+	// Postfix operators: x++ is x = x + 1
+	INCREMENT
+	DECREMENT
 
 	// Delimiters
-	COMMA     = ","
-	SEMICOLON = ";"
-	COLON     = ":"
-	DOT       = "."
+	COMMA
+	SEMICOLON
+	COLON
+	DOT
+
+	// optional chaining: x?.method(), x?[0]
+	OPTIONAL_DOT
+	OPTIONAL_LBRACKET
 
 	//parenthesis + brackets
-	LPAREN   = "("
-	RPAREN   = ")"
-	LBRACE   = "{"
-	RBRACE   = "}"
-	LBRACKET = "["
-	RBRACKET = "]"
+	LPAREN
+	RPAREN
+	LBRACE
+	RBRACE
+	LBRACKET
+	RBRACKET
 
 	// Keywords
-	FUNCTION = "FUNCTION"
-	LET      = "LET"
-	FOR      = "FOR"
-	TRUE     = "TRUE"
-	FALSE    = "FALSE"
-	IF       = "IF"
-	ELSE     = "ELSE"
-	RETURN   = "RETURN"
+	FUNCTION
+	LET
+	CONST
+	FOR
+	TRUE
+	FALSE
+	NULL
+	IF
+	ELSE
+	RETURN
+	YIELD
+	MATCH
+
+	// The kinds of trivia
+	COMMENT    // a line or block comment, with its slashes
+	WHITESPACE // spaces, tabs and newlines (blank lines are more than one newline)
 )
 
+// The names of the token types, what they were before TokenType was an int
+var names = [...]string{
+	ILLEGAL:           "ILLEGAL",
+	EOF:               "EOF",
+	IDENT:             "IDENT",
+	INT:               "INT",
+	FLOAT:             "FLOAT",
+	STRING:            "STRING",
+	CHAR:              "CHAR",
+	STRING_START:      "STRING_START",
+	STRING_MIDDLE:     "STRING_MIDDLE",
+	STRING_END:        "STRING_END",
+	ASSIGN:            "=",
+	PLUS:              "+",
+	MINUS:             "-",
+	BANG:              "!",
+	ASTERISK:          "*",
+	SLASH:             "/",
+	PERCENT:           "%",
+	LT:                "<",
+	GT:                ">",
+	EQ:                "==",
+	NOT_EQ:            "!=",
+	ARROW:             "->",
+	FAT_ARROW:         "=>",
+	IN:                "in",
+	NOT_IN:            "!in",
+	AND:               "&&",
+	OR:                "||",
+	BIT_AND:           "&",
+	BIT_OR:            "|",
+	BIT_XOR:           "^",
+	SHIFT_LEFT:        "<<",
+	SHIFT_RIGHT:       ">>",
+	PLUS_ASSIGN:       "+=",
+	MINUS_ASSIGN:      "-=",
+	ASTERISK_ASSIGN:   "*=",
+	SLASH_ASSIGN:      "/=",
+	PERCENT_ASSIGN:    "%=",
+	INCREMENT:         "++",
+	DECREMENT:         "--",
+	COMMA:             ",",
+	SEMICOLON:         ";",
+	COLON:             ":",
+	DOT:               ".",
+	OPTIONAL_DOT:      "?.",
+	OPTIONAL_LBRACKET: "?[",
+	LPAREN:            "(",
+	RPAREN:            ")",
+	LBRACE:            "{",
+	RBRACE:            "}",
+	LBRACKET:          "[",
+	RBRACKET:          "]",
+	FUNCTION:          "FUNCTION",
+	LET:               "LET",
+	CONST:             "CONST",
+	FOR:               "FOR",
+	TRUE:              "TRUE",
+	FALSE:             "FALSE",
+	NULL:              "NULL",
+	IF:                "IF",
+	ELSE:              "ELSE",
+	RETURN:            "RETURN",
+	YIELD:             "YIELD",
+	MATCH:             "MATCH",
+	COMMENT:           "COMMENT",
+	WHITESPACE:        "WHITESPACE",
+}
+
+func (t TokenType) String() string {
+	if t < 0 || int(t) >= len(names) {
+		return fmt.Sprintf("TokenType(%d)", int(t))
+	}
+	return names[t]
+}
+
+// token types by name, see FromString
+var byName = make(map[string]TokenType, len(names))
+
+func init() {
+	for t, name := range names {
+		byName[name] = TokenType(t)
+	}
+}
+
+/**
+Returns the token type with the given name, for code written when token types were strings:
+
+	token.TokenType("+")  =>  token.FromString("+")
+
+Names that aren't a token type are ILLEGAL.
+**/
+func FromString(name string) TokenType {
+	if t, ok := byName[name]; ok {
+		return t
+	}
+	return ILLEGAL
+}
+
 type Token struct {
 	Type    TokenType
 	Literal string
+	// where the token starts in the source, both start at 1 (the column counts bytes)
+	Line   int
+	Column int
+	/**
+	The byte offsets of the token in the whole source, End is exclusive: source[Start:End] is the token's text.
+	That's not always the literal: strings are decoded ("\t" => a tab) and numbers lose their separators (1_000).
+	**/
+	Start int
+	End   int
+	// whether a newline separates the token from the one before it
+	NewLine bool
+	// comments and whitespace around the token, only set when the lexer keeps them (see lexer.WithTrivia)
+	// a pointer, so tokens can still be compared with ==
+	Trivia *TokenTrivia
+}
+
+type TokenTrivia struct {
+	Leading  []Trivia
+	Trailing []Trivia
+}
+
+/**
+Source text between tokens that doesn't change what the program means, kept for tools that
+have to give the code back as it was written (ex: a formatter that keeps the comments).
+
+A token's trailing trivia runs up to the end of its line (the newline included),
+everything after that belongs to the next token's leading trivia.
+**/
+type Trivia struct {
+	Kind  TokenType
+	Text  string
+	Start int // byte offsets in the whole source, like Token.Start and Token.End
+	End   int
+}
+
+// Operator tokens, in the order they're declared above
+var operators = []TokenType{ASSIGN, PLUS, MINUS, BANG, ASTERISK, SLASH, PERCENT, LT, GT, EQ, NOT_EQ, IN, NOT_IN, AND, OR,
+	BIT_AND, BIT_OR, BIT_XOR, SHIFT_LEFT, SHIFT_RIGHT,
+	PLUS_ASSIGN, MINUS_ASSIGN, ASTERISK_ASSIGN, SLASH_ASSIGN, PERCENT_ASSIGN, INCREMENT, DECREMENT}
+
+/**
+Returns the operator token types, ex: for tooling that lists the operators of the language.
+The name of an operator's token type is also its literal (token.PLUS.String() => "+").
+**/
+func Operators() []TokenType {
+	ops := make([]TokenType, len(operators))
+	copy(ops, operators)
+	return ops
 }
 
 // map these keywords to their token types
@@ -61,12 +258,17 @@ type Token struct {
 var keywords = map[string]TokenType{
 	"fn":     FUNCTION,
 	"let":    LET,
+	"const":  CONST,
 	"for":    FOR,
 	"true":   TRUE,
 	"false":  FALSE,
+	"null":   NULL,
 	"if":     IF,
 	"else":   ELSE,
 	"return": RETURN,
+	"yield":  YIELD,
+	"match":  MATCH,
+	"in":     IN,
 }
 
 /**
@@ -0,0 +1,38 @@
+package token
+
+import "testing"
+
+func TestTokenTypeNames(t *testing.T) {
+	for tokenType := ILLEGAL; tokenType <= WHITESPACE; tokenType++ {
+		name := tokenType.String()
+		if name == "" {
+			t.Errorf("token type %d has no name", int(tokenType))
+			continue
+		}
+
+		if FromString(name) != tokenType {
+			t.Errorf("FromString(%q) should be %d, got %d", name, int(tokenType), int(FromString(name)))
+		}
+	}
+
+	tests := []struct {
+		tokenType TokenType
+		expected  string
+	}{
+		{LET, "LET"},
+		{PLUS_ASSIGN, "+="},
+		{NOT_IN, "!in"},
+		{EOF, "EOF"},
+		{TokenType(-1), "TokenType(-1)"},
+	}
+
+	for _, tt := range tests {
+		if tt.tokenType.String() != tt.expected {
+			t.Errorf("wrong name, expected %q got %q", tt.expected, tt.tokenType.String())
+		}
+	}
+
+	if FromString("nope") != ILLEGAL {
+		t.Errorf("unknown names should be ILLEGAL, got %s", FromString("nope"))
+	}
+}
@@ -0,0 +1,477 @@
+package types
+
+import (
+	"fmt"
+	"monkey/ast"
+)
+
+// Type names that can be used in annotations: let x: int = 5;
+const (
+	INT    = "int"
+	FLOAT  = "float"
+	STRING = "string"
+	BOOL   = "bool"
+	ARRAY  = "array"
+	HASH   = "hash"
+	FN     = "fn"
+	NULL   = "null"
+	ANY    = "any"
+	// used internally when we can't figure out the type of an expression
+	UNKNOWN = ""
+)
+
+var knownTypes = map[string]bool{
+	INT:    true,
+	FLOAT:  true,
+	STRING: true,
+	BOOL:   true,
+	ARRAY:  true,
+	HASH:   true,
+	FN:     true,
+	NULL:   true,
+	ANY:    true,
+}
+
+// What we know about a binding: its declared (or inferred) type
+// and, for functions, the annotated signature.
+type binding struct {
+	typ      string
+	function *ast.FunctionLiteral
+	constant bool // declared with const
+	declared bool // typ comes from an annotation, reassignments have to match it
+}
+
+type scope struct {
+	bindings map[string]binding
+	outer    *scope
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{bindings: make(map[string]binding), outer: outer}
+}
+
+func (s *scope) get(name string) (binding, bool) {
+	b, ok := s.bindings[name]
+	if !ok && s.outer != nil {
+		return s.outer.get(name)
+	}
+	return b, ok
+}
+
+// Forget the inferred type of a binding that got reassigned, in the scope that holds it
+func (s *scope) widen(name string) {
+	for ; s != nil; s = s.outer {
+		if b, ok := s.bindings[name]; ok {
+			b.typ = UNKNOWN
+			s.bindings[name] = b
+			return
+		}
+	}
+}
+
+type Checker struct {
+	warnings []string
+	// return types of the functions we're currently inside of, innermost last
+	returnTypes []string
+}
+
+/**
+Performs a best-effort static check of the program.
+
+- Only obvious mismatches get reported (ex: let x: int = "hello")
+- Anything we can't infer is treated as 'any' and never produces a warning
+- Warnings are not fatal, the program can still be evaluated
+**/
+func Check(program *ast.Program) []string {
+	c := &Checker{warnings: []string{}}
+	c.checkStatements(program.Statements, newScope(nil))
+	return c.warnings
+}
+
+func (c *Checker) warn(format string, a ...interface{}) {
+	c.warnings = append(c.warnings, fmt.Sprintf(format, a...))
+}
+
+func (c *Checker) checkStatements(stmts []ast.Statement, s *scope) {
+	for _, stmt := range stmts {
+		c.checkStatement(stmt, s)
+	}
+}
+
+func (c *Checker) checkStatement(stmt ast.Statement, s *scope) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		c.checkLetStatement(stmt, s)
+
+	case *ast.ReturnStatement:
+		got := c.infer(stmt.ReturnValue, s)
+
+		if len(c.returnTypes) == 0 {
+			return
+		}
+
+		want := c.returnTypes[len(c.returnTypes)-1]
+		if !compatible(want, got) {
+			c.warn("type mismatch: function should return %s, got %s", want, got)
+		}
+
+	case *ast.YieldStatement:
+		c.infer(stmt.Value, s)
+
+	case *ast.ExpressionStatement:
+		c.infer(stmt.Expression, s)
+
+	case *ast.BlockStatement:
+		c.checkStatements(stmt.Statements, s)
+
+	case *ast.ForLoopStatement:
+		// the counter and what the body declares aren't visible after the loop
+		loop := newScope(s)
+		c.checkLetStatement(stmt.CounterVar, loop)
+		c.infer(stmt.LoopCondition, loop)
+		c.infer(stmt.CounterUpdate, loop)
+		c.checkStatements(stmt.LoopBlock.Statements, newScope(loop))
+
+	case *ast.ForInStatement:
+		c.infer(stmt.Iterable, s)
+		// the elements of arrays, hashes and generators can be anything
+		body := newScope(s)
+		body.bindings[stmt.Variable.Value] = binding{typ: UNKNOWN}
+		c.checkStatements(stmt.Body.Statements, body)
+	}
+}
+
+func (c *Checker) checkLetStatement(stmt *ast.LetStatement, s *scope) {
+	if stmt == nil || stmt.Name == nil {
+		return
+	}
+
+	got := c.infer(stmt.Value, s)
+	b := binding{typ: got, constant: stmt.Constant}
+
+	if fn, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+		b.function = fn
+	}
+
+	if stmt.Name.Type != nil {
+		want := c.checkAnnotation(stmt.Name.Type)
+
+		if !compatible(want, got) {
+			c.warn("type mismatch: %s declared as %s, got %s", stmt.Name.Value, want, got)
+		}
+
+		b.typ = want
+		b.declared = true
+	}
+
+	s.bindings[stmt.Name.Value] = b
+}
+
+// Make sure the annotation refers to a type we know about, returns the type name
+func (c *Checker) checkAnnotation(ta *ast.TypeAnnotation) string {
+	if !knownTypes[ta.Name] {
+		c.warn("unknown type: %s", ta.Name)
+		return ANY
+	}
+	return ta.Name
+}
+
+// Returns the type the expression evaluates to, or UNKNOWN if we can't tell.
+func (c *Checker) infer(exp ast.Expression, s *scope) string {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral, *ast.CharLiteral:
+		return INT
+
+	case *ast.FloatLiteral:
+		return FLOAT
+
+	case *ast.StringLiteral:
+		return STRING
+
+	case *ast.Boolean:
+		return BOOL
+
+	case *ast.NullLiteral:
+		return NULL
+
+	case *ast.ArrayLiteral:
+		for _, el := range exp.Elements {
+			c.infer(el, s)
+		}
+		return ARRAY
+
+	case *ast.HashLiteral:
+		for _, key := range exp.OrderedKeys() {
+			c.infer(key, s)
+			c.infer(exp.Pairs[key], s)
+		}
+		return HASH
+
+	case *ast.FunctionLiteral:
+		c.checkFunctionLiteral(exp, s)
+		return FN
+
+	case *ast.Identifier:
+		if b, ok := s.get(exp.Value); ok {
+			return b.typ
+		}
+		return UNKNOWN
+
+	case *ast.InterpolatedString:
+		for _, part := range exp.Parts {
+			c.infer(part, s)
+		}
+		return STRING
+
+	case *ast.PostfixExpression:
+		left := c.infer(exp.Left, s)
+
+		if !compatible(INT, left) && !compatible(FLOAT, left) {
+			c.warn("type mismatch: %s%s", left, exp.Operator)
+		}
+
+		if ident, ok := exp.Left.(*ast.Identifier); ok {
+			if b, ok := s.get(ident.Value); ok && b.constant {
+				c.warn("cannot assign to constant %s", ident.Value)
+			}
+		}
+		return left
+
+	case *ast.PrefixExpression:
+		right := c.infer(exp.Right, s)
+
+		switch exp.Operator {
+		case "!":
+			return BOOL
+		case "-":
+			// -1.5 is still a float
+			if isNumeric(right) {
+				return right
+			}
+			if !compatible(INT, right) {
+				c.warn("type mismatch: -%s", right)
+			}
+			return INT
+		}
+		return UNKNOWN
+
+	case *ast.InfixExpression:
+		return c.inferInfixExpression(exp, s)
+
+	case *ast.IfExpression:
+		c.infer(exp.Condition, s)
+		// every branch has its own scope, a binding declared in one doesn't decide the type of one after the if
+		c.checkStatements(exp.Consequence.Statements, newScope(s))
+
+		if exp.Alternative != nil {
+			c.checkStatements(exp.Alternative.Statements, newScope(s))
+		}
+		return UNKNOWN
+
+	case *ast.MatchExpression:
+		c.infer(exp.Subject, s)
+		for _, arm := range exp.Arms {
+			if !arm.IsWildcard() {
+				c.infer(arm.Pattern, s)
+			}
+			c.checkStatements(arm.Body.Statements, newScope(s))
+		}
+		return UNKNOWN
+
+	case *ast.CallExpression:
+		return c.inferCallExpression(exp, s)
+
+	case *ast.AssignmentExpression:
+		got := c.infer(exp.Value, s)
+
+		if b, ok := s.get(exp.Name.Value); ok && b.constant {
+			c.warn("cannot assign to constant %s", exp.Name.Value)
+		} else if ok && b.declared && !compatible(b.typ, got) {
+			c.warn("type mismatch: cannot assign %s to %s (%s)", got, exp.Name.Value, b.typ)
+		} else if ok && !b.declared && b.typ != got {
+			// without an annotation the binding can hold anything it's given
+			s.widen(exp.Name.Value)
+		}
+		return got
+
+	case *ast.IndexExpression:
+		c.infer(exp.Left, s)
+		c.infer(exp.Index, s)
+		return UNKNOWN
+
+	case *ast.IndexAssignment:
+		c.infer(exp.Left, s)
+		c.infer(exp.Index, s)
+		return c.infer(exp.Value, s)
+
+	case *ast.InternalFunctionCall:
+		c.infer(exp.Caller, s)
+		for _, arg := range exp.Arguments {
+			c.infer(arg, s)
+		}
+		return UNKNOWN
+	}
+
+	return UNKNOWN
+}
+
+func (c *Checker) inferInfixExpression(exp *ast.InfixExpression, s *scope) string {
+	left := c.infer(exp.Left, s)
+	right := c.infer(exp.Right, s)
+
+	switch exp.Operator {
+	case "==", "!=", "in", "!in":
+		return BOOL
+	case "&&", "||":
+		// the result is one of the operands
+		if left == right {
+			return left
+		}
+		return UNKNOWN
+	case "<", ">":
+		if isKnown(left) && isKnown(right) && (!isNumeric(left) || !isNumeric(right)) {
+			c.warn("type mismatch: %s %s %s", left, exp.Operator, right)
+		}
+		return BOOL
+	case "*":
+		// repetition: "ab" * 3, [0] * 5
+		if (left == STRING || left == ARRAY) && right == INT {
+			return left
+		}
+		if left == INT && (right == STRING || right == ARRAY) {
+			return right
+		}
+		return c.inferArithmetic(exp, left, right)
+	case "%", "&", "|", "^", "<<", ">>":
+		// integers only
+		if isKnown(left) && isKnown(right) && (left != INT || right != INT) {
+			c.warn("type mismatch: %s %s %s", left, exp.Operator, right)
+			return UNKNOWN
+		}
+		if left == INT && right == INT {
+			return INT
+		}
+		return UNKNOWN
+	case "+":
+		if left == STRING && right == STRING {
+			return STRING
+		}
+		fallthrough
+	case "-", "/":
+		return c.inferArithmetic(exp, left, right)
+	}
+
+	return UNKNOWN
+}
+
+// +, -, * and / on numbers
+func (c *Checker) inferArithmetic(exp *ast.InfixExpression, left, right string) string {
+	if isKnown(left) && isKnown(right) && (!isNumeric(left) || !isNumeric(right)) {
+		c.warn("type mismatch: %s %s %s", left, exp.Operator, right)
+		return UNKNOWN
+	}
+	// mixing integers and floats gives a float
+	if left == FLOAT || right == FLOAT {
+		return FLOAT
+	}
+	if left == INT && right == INT {
+		return INT
+	}
+
+	return UNKNOWN
+}
+
+func (c *Checker) inferCallExpression(exp *ast.CallExpression, s *scope) string {
+	args := []string{}
+	for _, arg := range exp.Arguments {
+		args = append(args, c.infer(arg, s))
+	}
+
+	var fn *ast.FunctionLiteral
+
+	switch function := exp.Function.(type) {
+	case *ast.Identifier:
+		if b, ok := s.get(function.Value); ok {
+			fn = b.function
+		}
+	case *ast.FunctionLiteral:
+		c.checkFunctionLiteral(function, s)
+		fn = function
+	}
+
+	if fn == nil {
+		return UNKNOWN
+	}
+
+	for idx, param := range fn.Parameters {
+		if param.Type == nil || idx >= len(args) {
+			continue
+		}
+
+		if !compatible(param.Type.Name, args[idx]) {
+			c.warn("type mismatch: argument %s of %s should be %s, got %s", param.Value, exp.Function.String(), param.Type.Name, args[idx])
+		}
+	}
+
+	// calling a generator function returns a generator, not its return value
+	if fn.Generator {
+		return UNKNOWN
+	}
+
+	if fn.ReturnType != nil && knownTypes[fn.ReturnType.Name] {
+		return fn.ReturnType.Name
+	}
+
+	return UNKNOWN
+}
+
+func (c *Checker) checkFunctionLiteral(fn *ast.FunctionLiteral, s *scope) {
+	inner := newScope(s)
+
+	for _, param := range fn.Parameters {
+		b := binding{typ: UNKNOWN}
+		if param.Type != nil {
+			b.typ, b.declared = c.checkAnnotation(param.Type), true
+		}
+		inner.bindings[param.Value] = b
+	}
+
+	returnType := ANY
+	if fn.ReturnType != nil {
+		returnType = c.checkAnnotation(fn.ReturnType)
+	}
+
+	c.returnTypes = append(c.returnTypes, returnType)
+	c.checkStatements(fn.Body.Statements, inner)
+	c.returnTypes = c.returnTypes[:len(c.returnTypes)-1]
+}
+
+func isNumeric(typ string) bool {
+	return typ == INT || typ == FLOAT
+}
+
+func isKnown(typ string) bool {
+	return typ != UNKNOWN && typ != ANY
+}
+
+// Unknown types and 'any' are compatible with everything, otherwise the types should match
+func compatible(want, got string) bool {
+	if !isKnown(want) || !isKnown(got) {
+		return true
+	}
+	// null stands for "no value yet": let x: int = null;
+	if got == NULL {
+		return true
+	}
+	return want == got
+}
+
+/**
+Dev notes:
+
+- This checker is purely optional, the evaluator never looks at type annotations.
+- Scopes are tracked the same way object.Environment tracks them (an inner scope pointing to an outer one)
+  so a function body can see the bindings it was defined next to.
+- Since monke is dynamically typed anything that we can't infer (function call results without a return type,
+  index expressions, etc) is UNKNOWN. We'd rather miss a mismatch than warn about valid code.
+**/
@@ -0,0 +1,90 @@
+package types
+
+import (
+	"monkey/lexer"
+	"monkey/parser"
+	"testing"
+)
+
+func testCheck(t *testing.T, input string) []string {
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser has errors: %v", p.Errors())
+	}
+
+	return Check(program)
+}
+
+func TestCheckWarnings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x: int = 5;", []string{}},
+		{"let x = 5; let y: int = x * 2;", []string{}},
+		{`let x: int = "hello";`, []string{"type mismatch: x declared as int, got string"}},
+		{`let x: bool = 1 < 2; let y: string = x;`, []string{"type mismatch: y declared as string, got bool"}},
+		{`let x: int = 5; x = "five";`, []string{"type mismatch: cannot assign string to x (int)"}},
+		{`let result = null; result = 5;`, []string{}},
+		{`let x = 0; x = x + 0.5;`, []string{}},
+		{`let x = "a"; x = 1; x + 1`, []string{}},
+		{`let f = fn(n: int) { n = "one"; }`, []string{"type mismatch: cannot assign string to n (int)"}},
+		{`let x: number = 5;`, []string{"unknown type: number"}},
+		{`5 + "five"`, []string{"type mismatch: int + string"}},
+		{`let x: string = "ab" * 3; let y: string = 2 * "-";`, []string{}},
+		{`let x: int = [0] * 5;`, []string{"type mismatch: x declared as int, got array"}},
+		{`"ab" * 1.5`, []string{"type mismatch: string * float"}},
+		{`let x: bool = 1 in [1]; let y: bool = "a" !in "abc";`, []string{}},
+		{`let x: int = 7 % 2;`, []string{}},
+		{`let x: int = null; let y: string = null;`, []string{}},
+		{`const max = 10; max = 11; max++;`, []string{"cannot assign to constant max", "cannot assign to constant max"}},
+		{`const max: int = "ten";`, []string{"type mismatch: max declared as int, got string"}},
+		{`null + 1`, []string{"type mismatch: null + int"}},
+		{`7.5 % 2`, []string{"type mismatch: float % int"}},
+		{`let mask: int = 1 << 3 | 1;`, []string{}},
+		{`"a" & 1`, []string{"type mismatch: string & int"}},
+		{`let i: int = 0; i++; let s: string = "a"; s--;`, []string{"type mismatch: string--"}},
+		{`let x: bool = 1 < 2 && 2 < 3; let y: string = "" || "default";`, []string{}},
+		{`let x: int = true || false;`, []string{"type mismatch: x declared as int, got bool"}},
+		{`let x: float = 1 + 0.5; let y: int = x * 2;`, []string{"type mismatch: y declared as int, got float"}},
+		{`let x: float = 2.5; x < 3;`, []string{}},
+		{`let x: float = -1.5; let y: int = -x;`, []string{"type mismatch: y declared as int, got float"}},
+		{`let x: int = -"a";`, []string{"type mismatch: -string"}},
+		{`let add = fn(a: int, b: int) -> int { a + b }; add(1, "2");`,
+			[]string{"type mismatch: argument b of add should be int, got string"}},
+		{`let greet = fn(name: string) -> string { name + "!" }; let x: int = greet("monke");`,
+			[]string{"type mismatch: x declared as int, got string"}},
+		{`fn(x) -> int { return "x"; }`, []string{"type mismatch: function should return int, got string"}},
+		{`match (1 + "a") { "b" - 1 => 1, _ => { let x: int = "c"; x } }`,
+			[]string{"type mismatch: int + string", "type mismatch: string - int", "type mismatch: x declared as int, got string"}},
+		// bindings declared in a block don't outlive it
+		{`if (true) { let x: int = 1; } else { let y: bool = false; }; x = "a"; y = "b";`, []string{}},
+		{`for (let i: int = 0; i < 3; i++) { let y: int = i; }; i = "a"; y = "b";`, []string{}},
+		{`match (1) { 1 => { let x: int = 1; x }, _ => 0 }; x = "a";`, []string{}},
+		{`let x: int = 1; if (true) { x = "a"; }`, []string{"type mismatch: cannot assign string to x (int)"}},
+		// anything we can't infer shouldn't produce warnings
+		{`let f = fn(x) { x }; let y: int = f("a");`, []string{}},
+		{`let arr = [1, "a"]; let y: string = arr[0];`, []string{}},
+		// in source order, whatever order the map gives the pairs in
+		{`{"a" - 1: 1 + "b", "c" * 1.5: -"d", "e": null + 1}`,
+			[]string{"type mismatch: string - int", "type mismatch: int + string", "type mismatch: string * float", "type mismatch: -string", "type mismatch: null + int"}},
+	}
+
+	for _, tt := range tests {
+		warnings := testCheck(t, tt.input)
+
+		if len(warnings) != len(tt.expected) {
+			t.Errorf("%q: expected %d warnings, got %d: %v", tt.input, len(tt.expected), len(warnings), warnings)
+			continue
+		}
+
+		for idx, msg := range tt.expected {
+			if warnings[idx] != msg {
+				t.Errorf("%q: expected warning %q, got %q", tt.input, msg, warnings[idx])
+			}
+		}
+	}
+}
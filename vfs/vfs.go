@@ -0,0 +1,191 @@
+package vfs
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Returned when writing to a file system that can only be read
+var ErrReadOnly = errors.New("read-only file system")
+
+/**
+Every file the interpreter reads or writes goes through an FS (scripts, read_file/write_file),
+so embedders can serve scripts from embedded assets, memory, or a sandboxed directory.
+
+- names are whatever the script passes in, each FS decides how to resolve them
+- errors should wrap fs.ErrNotExist, fs.ErrPermission, etc when it applies
+**/
+type FS interface {
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte) error
+}
+
+/**
+Implemented by file systems that can list directories (used by glob).
+It's a separate interface so an FS that only serves files doesn't have to support it.
+
+ReadDir returns the sorted names of the entries (files and directories) directly inside the directory.
+**/
+type ReadDirFS interface {
+	FS
+	ReadDir(name string) ([]string, error)
+}
+
+// The host's file system, names are regular OS paths (relative to the working directory)
+func OS() ReadDirFS {
+	return osFS{}
+}
+
+type osFS struct{}
+
+func (osFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+func (osFS) WriteFile(name string, data []byte) error {
+	return os.WriteFile(name, data, 0644)
+}
+
+func (osFS) ReadDir(name string) ([]string, error) {
+	entries, err := os.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return entryNames(entries), nil
+}
+
+/**
+Wraps a standard library file system (embed.FS, os.DirFS, fstest.MapFS, etc).
+Writes always fail with ErrReadOnly.
+
+note: io/fs names are unrooted, so a leading "/" or "./" is dropped: "/lib/x.mk" => "lib/x.mk"
+**/
+func ReadOnly(fsys fs.FS) ReadDirFS {
+	return readOnlyFS{fsys: fsys}
+}
+
+type readOnlyFS struct {
+	fsys fs.FS
+}
+
+func (r readOnlyFS) ReadFile(name string) ([]byte, error) {
+	return fs.ReadFile(r.fsys, clean(name))
+}
+
+func (r readOnlyFS) WriteFile(name string, data []byte) error {
+	return &fs.PathError{Op: "write", Path: name, Err: ErrReadOnly}
+}
+
+func (r readOnlyFS) ReadDir(name string) ([]string, error) {
+	dir := clean(name)
+	if dir == "" {
+		dir = "."
+	}
+
+	entries, err := fs.ReadDir(r.fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return entryNames(entries), nil
+}
+
+/**
+In-memory file system, nothing touches the disk.
+Useful as a sandbox: scripts can only see the files the host put in it (and the ones they wrote).
+**/
+type Memory struct {
+	mu    sync.RWMutex
+	files map[string][]byte
+}
+
+// Creates an in-memory file system with the given files (name => content)
+func NewMemory(files map[string]string) *Memory {
+	m := &Memory{files: make(map[string][]byte, len(files))}
+
+	for name, content := range files {
+		m.files[clean(name)] = []byte(content)
+	}
+
+	return m
+}
+
+func (m *Memory) ReadFile(name string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	data, ok := m.files[clean(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	// callers can't change the stored file through the returned slice
+	return append([]byte(nil), data...), nil
+}
+
+func (m *Memory) WriteFile(name string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.files[clean(name)] = append([]byte(nil), data...)
+
+	return nil
+}
+
+/**
+Directories only exist implicitly (through the files inside them),
+so any directory a file lives under can be listed, ex: "lib" for "lib/math/x.mk" => ["math"]
+**/
+func (m *Memory) ReadDir(name string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	prefix := clean(name)
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	seen := map[string]bool{}
+	names := []string{}
+
+	for file := range m.files {
+		if !strings.HasPrefix(file, prefix) {
+			continue
+		}
+
+		// only the first path element after the directory
+		entry := strings.SplitN(strings.TrimPrefix(file, prefix), "/", 2)[0]
+		if !seen[entry] {
+			seen[entry] = true
+			names = append(names, entry)
+		}
+	}
+
+	if len(names) == 0 && prefix != "" {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+func entryNames(entries []fs.DirEntry) []string {
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+
+	// os.ReadDir and fs.ReadDir already sort by name
+	return names
+}
+
+// ./lib/../x.mk, /x.mk => x.mk
+func clean(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
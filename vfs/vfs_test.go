@@ -0,0 +1,79 @@
+package vfs
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMemory(t *testing.T) {
+	m := NewMemory(map[string]string{"lib/math.mk": "let x = 1;"})
+
+	data, err := m.ReadFile("./lib/math.mk")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if string(data) != "let x = 1;" {
+		t.Errorf("wrong content, got %q", data)
+	}
+
+	if _, err := m.ReadFile("missing.mk"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist, got %v", err)
+	}
+
+	if err := m.WriteFile("/out.txt", []byte("monke")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	data, err = m.ReadFile("out.txt")
+	if err != nil || string(data) != "monke" {
+		t.Errorf("expected the written file, got %q (%v)", data, err)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	r := ReadOnly(fstest.MapFS{"lib/math.mk": {Data: []byte("let x = 1;")}})
+
+	data, err := r.ReadFile("/lib/math.mk")
+	if err != nil || string(data) != "let x = 1;" {
+		t.Errorf("expected the file content, got %q (%v)", data, err)
+	}
+
+	if err := r.WriteFile("out.txt", []byte("monke")); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestReadDir(t *testing.T) {
+	files := map[string]string{"lib/math.mk": "", "lib/strings.mk": "", "lib/util/x.mk": "", "main.mk": ""}
+
+	tests := []struct {
+		fsys     ReadDirFS
+		dir      string
+		expected []string
+	}{
+		{NewMemory(files), "lib", []string{"math.mk", "strings.mk", "util"}},
+		{NewMemory(files), "./", []string{"lib", "main.mk"}},
+		{ReadOnly(fstest.MapFS{"lib/math.mk": {}, "lib/util/x.mk": {}}), "/lib", []string{"math.mk", "util"}},
+		{ReadOnly(fstest.MapFS{"main.mk": {}}), "/", []string{"main.mk"}},
+	}
+
+	for _, tt := range tests {
+		names, err := tt.fsys.ReadDir(tt.dir)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tt.dir, err)
+			continue
+		}
+
+		if strings.Join(names, ",") != strings.Join(tt.expected, ",") {
+			t.Errorf("%s: wrong entries, expected %v got %v", tt.dir, tt.expected, names)
+		}
+	}
+
+	if _, err := NewMemory(files).ReadDir("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist, got %v", err)
+	}
+}